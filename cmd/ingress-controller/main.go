@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"time"
 
@@ -26,15 +27,22 @@ import (
 	"github.com/spf13/pflag"
 
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/component-base/config"
 	"k8s.io/component-base/logs"
 
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpexternalversions "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/localenvoy/controllers/gateway"
 	"github.com/kcp-dev/kcp/pkg/localenvoy/controllers/ingress"
 	envoycontrolplane "github.com/kcp-dev/kcp/pkg/localenvoy/controlplane"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/gatewaysplitter"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workload/ingresssplitter"
 )
 
@@ -54,6 +62,9 @@ func main() {
 
 					Has an optional Envoy XDS control plane that programs Envoy based on
 					ingresses in kcp.
+
+					Optionally splits and programs Envoy with the Gateway API HTTPRoutes
+					and Gateways too.
 				`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := genericapiserver.SetupSignalContext()
@@ -82,28 +93,75 @@ func main() {
 				return err
 			}
 
+			kcpClusterClient, err := kcpclient.NewClusterForConfig(configLoader)
+			if err != nil {
+				return err
+			}
+
+			dynamicClusterClient, err := dynamic.NewClusterForConfig(configLoader)
+			if err != nil {
+				return err
+			}
+
 			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient.Cluster(logicalcluster.Wildcard), resyncPeriod)
+			kcpInformerFactory := kcpexternalversions.NewSharedInformerFactoryWithOptions(kcpClusterClient.Cluster(logicalcluster.Wildcard), resyncPeriod)
 			ingressInformer := kubeInformerFactory.Networking().V1().Ingresses()
 			serviceInformer := kubeInformerFactory.Core().V1().Services()
+			secretInformer := kubeInformerFactory.Core().V1().Secrets()
+			deploymentInformer := kubeInformerFactory.Apps().V1().Deployments()
+			workloadClusterInformer := kcpInformerFactory.Workload().V1alpha1().WorkloadClusters()
+
+			// The Gateway API resources are only watched if enabled, as their CRDs may not be installed.
+			var gatewayInformer, httpRouteInformer informers.GenericInformer
+			var httpRouteLister, gatewayLister cache.GenericLister
+			dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClusterClient.Cluster(logicalcluster.Wildcard), resyncPeriod)
+			if options.GatewayAPI {
+				gatewayInformer = dynamicInformerFactory.ForResource(gatewaysplitter.GatewaysGVR)
+				httpRouteInformer = dynamicInformerFactory.ForResource(gatewaysplitter.HTTPRoutesGVR)
+				gatewayLister = gatewayInformer.Lister()
+				httpRouteLister = httpRouteInformer.Lister()
+			}
 
 			var ecp *envoycontrolplane.EnvoyControlPlane
 			aggregateLeavesStatus := true
 			if options.EnvoyXDSPort > 0 && options.EnvoyListenerPort > 0 {
 				aggregateLeavesStatus = false
 
-				ecp = envoycontrolplane.NewEnvoyControlPlane(options.EnvoyXDSPort, options.EnvoyListenerPort, ingressInformer.Lister(), nil)
-				isr := ingress.NewController(kubeClient, ingressInformer, ecp, options.Domain)
+				upstreamTLS := envoycontrolplane.UpstreamTLSOptions{InsecureSkipVerify: options.EnvoyUpstreamInsecureSkipVerify}
+				if options.EnvoyUpstreamCAFile != "" {
+					if upstreamTLS.CAData, err = ioutil.ReadFile(options.EnvoyUpstreamCAFile); err != nil {
+						return fmt.Errorf("failed to read --envoy-upstream-ca-file: %w", err)
+					}
+				}
+
+				ecp = envoycontrolplane.NewEnvoyControlPlane(options.EnvoyXDSPort, options.EnvoyListenerPort, options.EnvoyTLSListenerPort, upstreamTLS, ingressInformer.Lister(), secretInformer.Lister(), httpRouteLister, gatewayLister, nil)
+				isr := ingress.NewController(kubeClient, ingressInformer, secretInformer, ecp, options.Domain)
 				go isr.Start(ctx, numThreads)
+				if options.GatewayAPI {
+					gsr := gateway.NewController(gatewayInformer, httpRouteInformer, ecp)
+					go gsr.Start(ctx)
+				}
 				if err := ecp.Start(ctx); err != nil {
 					return err
 				}
 			}
 
-			ic := ingresssplitter.NewController(kubeClient, ingressInformer, serviceInformer, options.Domain, aggregateLeavesStatus)
+			ic := ingresssplitter.NewController(kubeClient, ingressInformer, serviceInformer, deploymentInformer, workloadClusterInformer, options.Domain, aggregateLeavesStatus)
+			var gc *gatewaysplitter.Controller
+			if options.GatewayAPI {
+				gc = gatewaysplitter.NewController(dynamicClusterClient, gatewayInformer, httpRouteInformer, serviceInformer)
+			}
 
 			kubeInformerFactory.Start(ctx.Done())
+			kcpInformerFactory.Start(ctx.Done())
+			dynamicInformerFactory.Start(ctx.Done())
 			kubeInformerFactory.WaitForCacheSync(ctx.Done())
+			kcpInformerFactory.WaitForCacheSync(ctx.Done())
+			dynamicInformerFactory.WaitForCacheSync(ctx.Done())
 
+			if gc != nil {
+				go gc.Start(ctx, numThreads)
+			}
 			ic.Start(ctx, numThreads)
 
 			<-ctx.Done()
@@ -122,12 +180,17 @@ func main() {
 }
 
 type Options struct {
-	Kubeconfig        string
-	Context           string
-	EnvoyXDSPort      uint
-	EnvoyListenerPort uint
-	Domain            string
-	Logs              *logs.Options
+	Kubeconfig           string
+	Context              string
+	EnvoyXDSPort         uint
+	EnvoyListenerPort    uint
+	EnvoyTLSListenerPort uint
+	Domain               string
+	GatewayAPI           bool
+	Logs                 *logs.Options
+
+	EnvoyUpstreamCAFile             string
+	EnvoyUpstreamInsecureSkipVerify bool
 }
 
 func NewDefaultOptions() *Options {
@@ -136,12 +199,14 @@ func NewDefaultOptions() *Options {
 	logs.Config.Verbosity = config.VerbosityLevel(2)
 
 	return &Options{
-		Kubeconfig:        "",
-		Context:           "",
-		EnvoyXDSPort:      18000,
-		EnvoyListenerPort: 80,
-		Domain:            "kcp-apps.127.0.0.1.nip.io",
-		Logs:              logs,
+		Kubeconfig:           "",
+		Context:              "",
+		EnvoyXDSPort:         18000,
+		EnvoyListenerPort:    80,
+		EnvoyTLSListenerPort: 443,
+		Domain:               "kcp-apps.127.0.0.1.nip.io",
+		GatewayAPI:           false,
+		Logs:                 logs,
 	}
 }
 
@@ -150,7 +215,11 @@ func (o *Options) BindFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Context, "context", o.Context, "Context to use in the kubeconfig file, instead of the current context")
 	fs.UintVar(&o.EnvoyXDSPort, "envoy-xds-port", o.EnvoyXDSPort, "Envoy control plane port. Set to 0 to disable")
 	fs.UintVar(&o.EnvoyListenerPort, "envoy-listener-port", o.EnvoyListenerPort, "Envoy listener port")
+	fs.UintVar(&o.EnvoyTLSListenerPort, "envoy-tls-listener-port", o.EnvoyTLSListenerPort, "Envoy listener port for the TLS hosts of ingresses. Set to 0 to disable TLS")
+	fs.StringVar(&o.EnvoyUpstreamCAFile, "envoy-upstream-ca-file", o.EnvoyUpstreamCAFile, "CA bundle to verify the certificates of the leaves of ingresses reached via TLS with. The certificates must be valid for the host of the request")
+	fs.BoolVar(&o.EnvoyUpstreamInsecureSkipVerify, "envoy-upstream-insecure-skip-verify", o.EnvoyUpstreamInsecureSkipVerify, "Do not verify the certificates of the leaves of ingresses reached via TLS if there is no --envoy-upstream-ca-file. Insecure, the traffic can be intercepted")
 	fs.StringVar(&o.Domain, "domain", o.Domain, "The domain to use to expose ingresses")
+	fs.BoolVar(&o.GatewayAPI, "gateway-api", o.GatewayAPI, "Split the Gateway API HTTPRoutes and Gateways, and serve the HTTPRoutes with Envoy. Requires the Gateway API CRDs")
 
 	o.Logs.AddFlags(fs)
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -26,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
 	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	apirequest "k8s.io/apiserver/pkg/endpoints/request"
@@ -46,31 +49,63 @@ func newRequestInfoFactory() *apirequest.RequestInfoFactory {
 	}
 }
 
-// withOptionalClientCert creates a handler that verifies a request's client
-// cert if one is presented but passes through to the next handler if one is
-// not.
-func withOptionalClientCert(handler, failed http.Handler, auth authenticator.Request) http.Handler {
+// withOptionalAuthentication creates a handler that authenticates a request's client
+// cert or bearer token if one is presented, but passes through to the next handler if
+// neither is. Requests with a client cert that cannot be authenticated are rejected.
+// Service account tokens, and bearer tokens that none of the authenticators know, are
+// passed through to the backend unchanged, which authenticates them itself.
+func withOptionalAuthentication(handler, failed http.Handler, auth authenticator.Request) http.Handler {
 	if auth == nil {
 		return handler
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		hasClientCert := req.TLS != nil && len(req.TLS.PeerCertificates) > 0
+		if !hasClientCert && (req.Header.Get("Authorization") == "" || isServiceAccountToken(req)) {
 			handler.ServeHTTP(w, req)
 			return
 		}
 		resp, ok, err := auth.AuthenticateRequest(req)
-		if err != nil || !ok {
+		if err != nil || (!ok && hasClientCert) {
 			if err != nil {
 				klog.ErrorS(err, "Unable to authenticate the request")
 			}
 			failed.ServeHTTP(w, req)
 			return
 		}
+		if !ok {
+			handler.ServeHTTP(w, req)
+			return
+		}
 		req = req.WithContext(request.WithUser(req.Context(), resp.User))
 		handler.ServeHTTP(w, req)
 	})
 }
 
+// isServiceAccountToken returns whether the request's bearer token is a JWT claiming to be
+// for a service account. The token is not verified, which is left to the backend.
+func isServiceAccountToken(req *http.Request) bool {
+	auth := strings.TrimSpace(req.Header.Get("Authorization"))
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return false
+	}
+	segments := strings.Split(strings.TrimSpace(parts[1]), ".")
+	if len(segments) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return false
+	}
+	return strings.HasPrefix(claims.Subject, serviceaccount.ServiceAccountUsernamePrefix)
+}
+
 func newUnauthorizedHandler() http.Handler {
 	scheme := runtime.NewScheme()
 	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Group: "", Version: "v1"})
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/kubernetes/pkg/serviceaccount"
+)
+
+func TestWithOptionalAuthentication(t *testing.T) {
+	keyPEM, err := keyutil.MakeEllipticPrivateKeyPEM()
+	require.NoError(t, err)
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	require.NoError(t, err)
+	generator, err := serviceaccount.JWTTokenGenerator(serviceaccount.LegacyIssuer, key)
+	require.NoError(t, err)
+	serviceAccountToken, err := generator.GenerateToken(serviceaccount.LegacyClaims(
+		corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "sa", Namespace: "default", UID: "sa-uid", ClusterName: "root:org"}},
+		corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sa-token", Namespace: "default", ClusterName: "root:org"}},
+	))
+	require.NoError(t, err)
+
+	// a token authenticator failing like an unreachable webhook
+	auth := authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		if req.Header.Get("Authorization") == "Bearer valid" {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "user"}}, true, nil
+		}
+		return nil, false, errors.New("webhook unavailable")
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantCode      int
+		wantUser      string
+	}{
+		{name: "anonymous", wantCode: http.StatusOK},
+		{name: "authenticated token", authorization: "Bearer valid", wantCode: http.StatusOK, wantUser: "user"},
+		{name: "service account token passed through", authorization: "Bearer " + serviceAccountToken, wantCode: http.StatusOK},
+		{name: "failing token", authorization: "Bearer other", wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if u, ok := request.UserFrom(req.Context()); ok {
+					gotUser = u.GetName()
+				}
+				require.Equal(t, tt.authorization, req.Header.Get("Authorization"), "the authorization header must be passed to the backend")
+			})
+			failed := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "https://proxy/clusters/root:org/api", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			withOptionalAuthentication(handler, failed, auth).ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantCode, w.Code)
+			require.Equal(t, tt.wantUser, gotUser)
+		})
+	}
+}
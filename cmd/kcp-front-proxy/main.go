@@ -21,6 +21,7 @@ import (
 	goflags "flag"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -35,7 +36,9 @@ import (
 	restclient "k8s.io/client-go/rest"
 	utilflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/logs"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
 
 	frontproxyoptions "github.com/kcp-dev/kcp/cmd/kcp-front-proxy/options"
 	"github.com/kcp-dev/kcp/pkg/proxy"
@@ -63,9 +66,10 @@ func NewProxyCommand(ctx context.Context) *cobra.Command {
 	options := frontproxyoptions.NewOptions()
 	cmd := &cobra.Command{
 		Use:   "kcp-front-proxy",
-		Short: "Terminate TLS and handles client cert auth for backend API servers",
+		Short: "Terminate TLS and handles client cert and token auth for backend API servers",
 		Long: `kcp-front-proxy is a reverse proxy that accepts client certificates and
-forwards Common Name and Organizations to backend API servers in HTTP headers.
+bearer tokens, and forwards the authenticated user, groups and extras to backend
+API servers in HTTP headers.
 The proxy terminates TLS and communicates with API servers via mTLS. Traffic is
 routed based on paths.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -80,7 +84,7 @@ routed based on paths.`,
 			}
 
 			var handler http.Handler
-			handler, err := proxy.NewHandler(&options.Proxy)
+			handler, err := proxy.NewHandler(ctx, &options.Proxy, options.WorkspaceMetrics.NewRecorder())
 			if err != nil {
 				return err
 			}
@@ -97,12 +101,16 @@ routed based on paths.`,
 			}
 
 			failedHandler := newUnauthorizedHandler()
-			handler = withOptionalClientCert(handler, failedHandler, authenticationInfo.Authenticator)
+			handler = withOptionalAuthentication(handler, failedHandler, authenticationInfo.Authenticator)
 
 			requestInfoFactory := newRequestInfoFactory()
 			handler = genericapifilters.WithRequestInfo(handler, requestInfoFactory)
 			handler = genericfilters.WithPanicRecovery(handler, requestInfoFactory)
 
+			if err := serveMetrics(ctx, options.MetricsBindAddress); err != nil {
+				return err
+			}
+
 			doneCh, err := servingInfo.Serve(handler, time.Second*60, ctx.Done())
 			if err != nil {
 				return err
@@ -123,3 +131,31 @@ routed based on paths.`,
 
 	return cmd
 }
+
+// serveMetrics serves the metrics of the proxy itself on /metrics of a separate, plain
+// HTTP listener until the context is done, such that /metrics on the secure port is
+// proxied to the backend like any other path. An empty address disables it.
+func serveMetrics(ctx context.Context, address string) error {
+	if address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on the metrics address %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", legacyregistry.Handler())
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close() // nolint:errcheck
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.ErrorS(err, "Failed to serve the front-proxy metrics", "address", address)
+		}
+	}()
+	klog.Infof("Serving the front-proxy metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
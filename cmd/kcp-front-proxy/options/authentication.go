@@ -21,43 +21,58 @@ import (
 
 	"github.com/spf13/pflag"
 
-	"k8s.io/apiserver/pkg/authentication/request/x509"
 	genericapiserver "k8s.io/apiserver/pkg/server"
-	apiserveroptions "k8s.io/apiserver/pkg/server/options"
+	kubeoptions "k8s.io/kubernetes/pkg/kubeapiserver/options"
 )
 
-// Authentication wraps ClientCertAuthenticationOptions so we don't pull in
-// more auth machinery than we need with DelegatingAuthenticationOptions
+// Authentication wraps BuiltInAuthenticationOptions, restricted to the authenticators that
+// work without access to a kube API: client certificates, OIDC, webhook token review and
+// static token files. Service account tokens are not authenticated, because they can only be
+// verified by the shard owning the service accounts, pods and secrets they belong to. They are
+// passed through to the shards instead.
 type Authentication struct {
-	ClientCert apiserveroptions.ClientCertAuthenticationOptions
+	BuiltInOptions *kubeoptions.BuiltInAuthenticationOptions
 }
 
 // NewAuthentication creates a default Authentication
 func NewAuthentication() *Authentication {
-	return &Authentication{}
+	return &Authentication{
+		BuiltInOptions: kubeoptions.NewBuiltInAuthenticationOptions().
+			WithClientCert().
+			WithOIDC().
+			WithTokenFile().
+			WithWebHook(),
+	}
 }
 
-// ApplyTo sets up the x509 Authenticator if the client-ca-file option was passed
+// ApplyTo sets up the x509 and token authenticators that are configured. The client CA is
+// also passed to the serving info, such that client certificates are requested.
 func (c *Authentication) ApplyTo(authenticationInfo *genericapiserver.AuthenticationInfo, servingInfo *genericapiserver.SecureServingInfo) error {
-	clientCAProvider, err := c.ClientCert.GetClientCAContentProvider()
+	authenticatorConfig, err := c.BuiltInOptions.ToAuthenticationConfig()
 	if err != nil {
-		return fmt.Errorf("unable to load client CA provider: %w", err)
+		return err
 	}
-	if clientCAProvider != nil {
-		if err = authenticationInfo.ApplyClientCert(clientCAProvider, servingInfo); err != nil {
+
+	if authenticatorConfig.ClientCAContentProvider != nil {
+		if err = authenticationInfo.ApplyClientCert(authenticatorConfig.ClientCAContentProvider, servingInfo); err != nil {
 			return fmt.Errorf("unable to assign client CA provider: %w", err)
 		}
-		authenticationInfo.Authenticator = x509.NewDynamic(clientCAProvider.VerifyOptions, x509.CommonNameUserConversion)
+	}
+
+	authenticationInfo.APIAudiences = authenticatorConfig.APIAudiences
+	authenticationInfo.Authenticator, _, err = authenticatorConfig.New()
+	if err != nil {
+		return fmt.Errorf("unable to create authenticator: %w", err)
 	}
 	return nil
 }
 
-// AddFlags delegates to ClientCertAuthenticationOptions
+// AddFlags delegates to BuiltInAuthenticationOptions
 func (c *Authentication) AddFlags(fs *pflag.FlagSet) {
-	c.ClientCert.AddFlags(fs)
+	c.BuiltInOptions.AddFlags(fs)
 }
 
-// Validate just completes the options pattern. Returns nil.
+// Validate validates the configured authenticators.
 func (c *Authentication) Validate() []error {
-	return nil
+	return c.BuiltInOptions.Validate()
 }
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/kubernetes/pkg/serviceaccount"
+)
+
+func TestServiceAccountFlagsNotSupported(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	NewAuthentication().AddFlags(fs)
+	for _, name := range []string{"service-account-key-file", "service-account-lookup", "service-account-issuer"} {
+		require.Nil(t, fs.Lookup(name), "--%s must not be offered by the proxy", name)
+	}
+	require.NotNil(t, fs.Lookup("token-auth-file"))
+}
+
+func TestServiceAccountTokensNotAuthenticated(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens.csv")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("static-token,user,uid,group\n"), 0600))
+
+	auth := NewAuthentication()
+	auth.BuiltInOptions.TokenFile.TokenFile = tokenFile
+	require.Empty(t, auth.Validate())
+	authenticationInfo := &genericapiserver.AuthenticationInfo{}
+	require.NoError(t, auth.ApplyTo(authenticationInfo, &genericapiserver.SecureServingInfo{}))
+
+	authenticate := func(token string) (string, bool) {
+		req, err := http.NewRequest(http.MethodGet, "https://proxy/clusters/root:org/api", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, ok, _ := authenticationInfo.Authenticator.AuthenticateRequest(req)
+		if !ok {
+			return "", false
+		}
+		return resp.User.GetName(), true
+	}
+
+	name, ok := authenticate("static-token")
+	require.True(t, ok)
+	require.Equal(t, "user", name)
+
+	keyPEM, err := keyutil.MakeEllipticPrivateKeyPEM()
+	require.NoError(t, err)
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	require.NoError(t, err)
+	generator, err := serviceaccount.JWTTokenGenerator(serviceaccount.LegacyIssuer, key)
+	require.NoError(t, err)
+	token, err := generator.GenerateToken(serviceaccount.LegacyClaims(
+		corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "sa", Namespace: "default", UID: "sa-uid", ClusterName: "root:org"}},
+		corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sa-token", Namespace: "default", ClusterName: "root:org"}},
+	))
+	require.NoError(t, err)
+	_, ok = authenticate(token)
+	require.False(t, ok, "service account tokens are left to the shards")
+}
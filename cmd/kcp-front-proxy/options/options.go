@@ -17,6 +17,8 @@ limitations under the License.
 package options
 
 import (
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 
@@ -27,25 +29,30 @@ import (
 	"k8s.io/component-base/logs"
 
 	proxyoptions "github.com/kcp-dev/kcp/pkg/proxy/options"
+	workspacemetricsoptions "github.com/kcp-dev/kcp/pkg/workspacemetrics/options"
 )
 
 type Options struct {
-	SecureServing  apiserveroptions.SecureServingOptionsWithLoopback
-	Authentication Authentication
-	Proxy          proxyoptions.Options
-	Logs           *logs.Options
-
-	RootDirectory string
+	SecureServing    apiserveroptions.SecureServingOptionsWithLoopback
+	Authentication   Authentication
+	Proxy            proxyoptions.Options
+	WorkspaceMetrics workspacemetricsoptions.WorkspaceMetrics
+	Logs             *logs.Options
+
+	RootDirectory      string
+	MetricsBindAddress string
 }
 
 func NewOptions() *Options {
 	o := &Options{
-		SecureServing:  *apiserveroptions.NewSecureServingOptions().WithLoopback(),
-		Authentication: *NewAuthentication(),
-		Proxy:          *proxyoptions.NewOptions(),
-		Logs:           logs.NewOptions(),
-
-		RootDirectory: ".kcp",
+		SecureServing:    *apiserveroptions.NewSecureServingOptions().WithLoopback(),
+		Authentication:   *NewAuthentication(),
+		Proxy:            *proxyoptions.NewOptions(),
+		WorkspaceMetrics: *workspacemetricsoptions.NewWorkspaceMetrics(),
+		Logs:             logs.NewOptions(),
+
+		RootDirectory:      ".kcp",
+		MetricsBindAddress: "127.0.0.1:8085",
 	}
 
 	// Default to -v=2
@@ -62,10 +69,12 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	o.SecureServing.AddFlags(fs)
 	o.Authentication.AddFlags(fs)
 	o.Proxy.AddFlags(fs)
+	o.WorkspaceMetrics.AddFlags(fs)
 
 	o.Logs.AddFlags(fs)
 
 	fs.StringVar(&o.RootDirectory, "root-directory", o.RootDirectory, "Root directory.")
+	fs.StringVar(&o.MetricsBindAddress, "metrics-bind-address", o.MetricsBindAddress, "The host:port to serve the metrics of the proxy itself on via plain HTTP, on /metrics. /metrics on the secure port is forwarded to the backend. Empty to disable.")
 }
 
 func (o *Options) Complete() error {
@@ -101,6 +110,13 @@ func (o *Options) Validate() []error {
 	errs = append(errs, o.SecureServing.Validate()...)
 	errs = append(errs, o.Authentication.Validate()...)
 	errs = append(errs, o.Proxy.Validate()...)
+	errs = append(errs, o.WorkspaceMetrics.Validate()...)
+
+	if o.MetricsBindAddress != "" {
+		if _, _, err := net.SplitHostPort(o.MetricsBindAddress); err != nil {
+			errs = append(errs, fmt.Errorf("--metrics-bind-address %q must be of the form host:port: %w", o.MetricsBindAddress, err))
+		}
+	}
 
 	return errs
 }
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetricsBindAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: ""},
+		{address: "127.0.0.1:8085"},
+		{address: ":8085"},
+		{address: "127.0.0.1", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			o := NewOptions()
+			o.MetricsBindAddress = tc.address
+			var found bool
+			for _, err := range o.Validate() {
+				if strings.HasPrefix(err.Error(), "--metrics-bind-address") {
+					found = true
+				}
+			}
+			require.Equal(t, tc.wantErr, found)
+		})
+	}
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	goflags "flag"
 	"fmt"
 	"os"
+	"os/exec"
 
 	"github.com/spf13/pflag"
 
@@ -45,6 +47,10 @@ func main() {
 	workspaceCmd.PersistentFlags().AddGoFlagSet(fs)
 
 	if err := workspaceCmd.Execute(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
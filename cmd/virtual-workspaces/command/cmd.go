@@ -132,6 +132,10 @@ func Run(o *options.Options, stopCh <-chan struct{}) error {
 	if err != nil {
 		return err
 	}
+	rootAPIServerConfig.ExtraConfig.WorkspaceFairness, err = o.WorkspaceFairness.NewController(wildcardKcpInformers.Tenancy().V1alpha1().ClusterWorkspaces().Lister())
+	if err != nil {
+		return err
+	}
 
 	completedRootAPIServerConfig := rootAPIServerConfig.Complete()
 	rootAPIServer, err := completedRootAPIServerConfig.New(genericapiserver.NewEmptyDelegate())
@@ -29,6 +29,7 @@ import (
 	genericapiserveroptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/component-base/logs"
 
+	flowcontroloptions "github.com/kcp-dev/kcp/pkg/flowcontrol/options"
 	virtualworkspacesoptions "github.com/kcp-dev/kcp/pkg/virtual/options"
 )
 
@@ -48,6 +49,7 @@ type Options struct {
 	Logs           logs.Options
 
 	VirtualWorkspaces virtualworkspacesoptions.Options
+	WorkspaceFairness flowcontroloptions.WorkspaceFairness
 }
 
 func NewOptions() *Options {
@@ -61,6 +63,7 @@ func NewOptions() *Options {
 		Logs:           *logs.NewOptions(),
 
 		VirtualWorkspaces: *virtualworkspacesoptions.NewOptions(),
+		WorkspaceFairness: *flowcontroloptions.NewWorkspaceFairness(),
 	}
 
 	opts.SecureServing.ServerCert.CertKey.CertFile = filepath.Join(".", ".kcp", "apiserver.crt")
@@ -75,6 +78,7 @@ func (o *Options) AddFlags(flags *pflag.FlagSet) {
 	o.Authentication.AddFlags(flags)
 	o.Logs.AddFlags(flags)
 	o.VirtualWorkspaces.AddFlags(flags)
+	o.WorkspaceFairness.AddFlags(flags)
 
 	flags.StringVar(&o.KubeconfigFile, "kubeconfig", o.KubeconfigFile, ""+
 		"The kubeconfig file of the KCP instance that hosts workspaces.")
@@ -86,6 +90,7 @@ func (o *Options) Validate() error {
 	errs = append(errs, o.SecureServing.Validate()...)
 	errs = append(errs, o.Authentication.Validate()...)
 	errs = append(errs, o.VirtualWorkspaces.Validate()...)
+	errs = append(errs, o.WorkspaceFairness.Validate()...)
 
 	if len(o.KubeconfigFile) == 0 {
 		errs = append(errs, fmt.Errorf("--kubeconfig is required for this command"))
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apiserver/pkg/admission"
 
+	"github.com/kcp-dev/kcp/pkg/admission/initializers"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 )
 
@@ -38,6 +40,8 @@ import (
 // - immutability of fields like type
 // - valid phase transitions fulfilling pre-conditions
 // - status.location.current and status.baseURL cannot be unset.
+// - deletion protection.
+// - soft-deletion before deletion, if deleted workspaces are retained.
 
 // Mutate ClusterWorkspace creation and updates for
 // - initializers are short enough to be put into a label
@@ -51,35 +55,55 @@ func Register(plugins *admission.Plugins) {
 	plugins.Register(PluginName,
 		func(_ io.Reader) (admission.Interface, error) {
 			return &clusterWorkspace{
-				Handler: admission.NewHandler(admission.Create, admission.Update),
+				Handler: admission.NewHandler(admission.Create, admission.Update, admission.Delete),
 			}, nil
 		})
 }
 
 type clusterWorkspace struct {
 	*admission.Handler
+
+	// deletionRetention is the time deleted workspaces are retained. Zero means
+	// workspaces can be deleted immediately.
+	deletionRetention time.Duration
 }
 
 // Ensure that the required admission interfaces are implemented.
 var _ admission.ValidationInterface = &clusterWorkspace{}
 var _ admission.MutationInterface = &clusterWorkspace{}
+var _ = initializers.WantsWorkspaceDeletionRetention(&clusterWorkspace{})
 
 var phaseOrdinal = map[tenancyv1alpha1.ClusterWorkspacePhaseType]int{
 	tenancyv1alpha1.ClusterWorkspacePhaseType(""):     1,
 	tenancyv1alpha1.ClusterWorkspacePhaseScheduling:   2,
 	tenancyv1alpha1.ClusterWorkspacePhaseInitializing: 3,
 	tenancyv1alpha1.ClusterWorkspacePhaseReady:        4,
+	tenancyv1alpha1.ClusterWorkspacePhaseDeleting:     5,
 }
 
 // Validate ensures that
 // - the workspace only does a valid phase transition
 // - has a valid type
 // - has valid initializers when transitioning to initializing
+// - is not deleted or soft-deleted if deletion protected
+// - is soft-deleted and its retention period is over before it is deleted, if deleted
+//   workspaces are retained
 func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) (err error) {
 	if a.GetResource().GroupResource() != tenancyv1alpha1.Resource("clusterworkspaces") {
 		return nil
 	}
 
+	if a.GetOperation() == admission.Delete {
+		u, ok := a.GetOldObject().(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", a.GetOldObject())
+		}
+		if u.GetAnnotations()[tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey] == "true" {
+			return admission.NewForbidden(a, fmt.Errorf("workspace is protected from deletion by the %s annotation", tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey))
+		}
+		return o.validateRetention(a, u)
+	}
+
 	u, ok := a.GetObject().(*unstructured.Unstructured)
 	if !ok {
 		return fmt.Errorf("unexpected type %T", a.GetObject())
@@ -114,11 +138,27 @@ func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes,
 			return admission.NewForbidden(a, errors.New("status.baseURL cannot be unset"))
 		}
 
-		if phaseOrdinal[old.Status.Phase] > phaseOrdinal[cw.Status.Phase] {
+		restored := old.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseDeleting && cw.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseReady
+		if phaseOrdinal[old.Status.Phase] > phaseOrdinal[cw.Status.Phase] && !restored {
+			return admission.NewForbidden(a, fmt.Errorf("cannot transition from %q to %q", old.Status.Phase, cw.Status.Phase))
+		}
+		if restored && cw.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey] != "" {
+			return admission.NewForbidden(a, fmt.Errorf("cannot transition from %q to %q while the %s annotation is set", old.Status.Phase, cw.Status.Phase, tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey))
+		}
+		if old.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseDeleting && cw.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseDeleting && old.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
 			return admission.NewForbidden(a, fmt.Errorf("cannot transition from %q to %q", old.Status.Phase, cw.Status.Phase))
 		}
 	}
 
+	if retainedUntil, found := cw.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]; found {
+		if _, err := time.Parse(time.RFC3339, retainedUntil); err != nil {
+			return admission.NewForbidden(a, fmt.Errorf("invalid %s annotation: %w", tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey, err))
+		}
+		if cw.Annotations[tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey] == "true" {
+			return admission.NewForbidden(a, fmt.Errorf("workspace is protected from deletion by the %s annotation", tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey))
+		}
+	}
+
 	if phaseOrdinal[cw.Status.Phase] > phaseOrdinal[tenancyv1alpha1.ClusterWorkspacePhaseInitializing] && len(cw.Status.Initializers) > 0 {
 		return admission.NewForbidden(a, fmt.Errorf("spec.initializers must be empty for phase %s", cw.Status.Phase))
 	}
@@ -135,10 +175,44 @@ func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes,
 	return nil
 }
 
+// validateRetention ensures that a workspace with content is only deleted after its retention
+// period, i.e. once it has been soft-deleted by setting the retained-until annotation, and that
+// point in time has passed. Workspaces which are not ready yet have no content worth retaining.
+func (o *clusterWorkspace) validateRetention(a admission.Attributes, u *unstructured.Unstructured) error {
+	if o.deletionRetention == 0 {
+		return nil
+	}
+
+	phase, _, err := unstructured.NestedString(u.Object, "status", "phase")
+	if err != nil {
+		return err
+	}
+	if tenancyv1alpha1.ClusterWorkspacePhaseType(phase) != tenancyv1alpha1.ClusterWorkspacePhaseReady && tenancyv1alpha1.ClusterWorkspacePhaseType(phase) != tenancyv1alpha1.ClusterWorkspacePhaseDeleting {
+		return nil
+	}
+
+	value, found := u.GetAnnotations()[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]
+	if !found {
+		return admission.NewForbidden(a, fmt.Errorf("workspace must be soft-deleted first by setting the %s annotation, e.g. by deleting the workspace through the workspaces virtual workspace", tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey))
+	}
+	retainedUntil, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return admission.NewForbidden(a, fmt.Errorf("invalid %s annotation: %w", tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey, err))
+	}
+	if time.Now().Before(retainedUntil) {
+		return admission.NewForbidden(a, fmt.Errorf("workspace is soft-deleted and retained until %s, it can be restored by removing the %s annotation", value, tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey))
+	}
+
+	return nil
+}
+
 func (o *clusterWorkspace) Admit(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) (err error) {
 	if a.GetResource().GroupResource() != tenancyv1alpha1.Resource("clusterworkspaces") {
 		return nil
 	}
+	if a.GetOperation() == admission.Delete {
+		return nil
+	}
 
 	u, ok := a.GetObject().(*unstructured.Unstructured)
 	if !ok {
@@ -179,3 +253,8 @@ func (o *clusterWorkspace) Admit(ctx context.Context, a admission.Attributes, _
 	u.Object = raw
 	return nil
 }
+
+// SetWorkspaceDeletionRetention implements the WantsWorkspaceDeletionRetention interface.
+func (o *clusterWorkspace) SetWorkspaceDeletionRetention(deletionRetention time.Duration) {
+	o.deletionRetention = deletionRetention
+}
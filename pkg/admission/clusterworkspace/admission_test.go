@@ -19,6 +19,7 @@ package clusterworkspace
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kcp-dev/logicalcluster"
@@ -67,11 +68,48 @@ func updateAttr(ws, old *tenancyv1alpha1.ClusterWorkspace) admission.Attributes
 	)
 }
 
+func deleteAttr(ws *tenancyv1alpha1.ClusterWorkspace) admission.Attributes {
+	return admission.NewAttributesRecord(
+		nil,
+		helpers.ToUnstructuredOrDie(ws),
+		tenancyv1alpha1.Kind("ClusterWorkspace").WithVersion("v1alpha1"),
+		"",
+		ws.Name,
+		tenancyv1alpha1.Resource("clusterworkspaces").WithVersion("v1alpha1"),
+		"",
+		admission.Delete,
+		&metav1.DeleteOptions{},
+		false,
+		&user.DefaultInfo{},
+	)
+}
+
+func readyWorkspace(phase tenancyv1alpha1.ClusterWorkspacePhaseType, annotations map[string]string) *tenancyv1alpha1.ClusterWorkspace {
+	return &tenancyv1alpha1.ClusterWorkspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: annotations,
+		},
+		Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+			Type: "Foo",
+		},
+		Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+			Phase:    phase,
+			Location: tenancyv1alpha1.ClusterWorkspaceLocation{Current: "somewhere"},
+			BaseURL:  "https://kcp.bigcorp.com/clusters/org:test",
+		},
+	}
+}
+
 func TestValidate(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
 	tests := []struct {
-		name    string
-		a       admission.Attributes
-		wantErr bool
+		name              string
+		a                 admission.Attributes
+		deletionRetention time.Duration
+		wantErr           bool
 	}{
 		{
 			name: "rejects type mutations",
@@ -314,6 +352,86 @@ func TestValidate(t *testing.T) {
 				}),
 			wantErr: true,
 		},
+		{
+			name: "allows transition from Ready to Deleting",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: "2022-06-01T00:00:00Z"}),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: "2022-06-01T00:00:00Z"}),
+			),
+		},
+		{
+			name: "rejects transition from Initializing to Deleting",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, nil),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseInitializing, nil),
+			),
+			wantErr: true,
+		},
+		{
+			name: "allows restoring from Deleting to Ready without retained-until annotation",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, nil),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, nil),
+			),
+		},
+		{
+			name: "rejects restoring from Deleting to Ready with retained-until annotation",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: "2022-06-01T00:00:00Z"}),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: "2022-06-01T00:00:00Z"}),
+			),
+			wantErr: true,
+		},
+		{
+			name: "rejects invalid retained-until annotation",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: "tomorrow"}),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, nil),
+			),
+			wantErr: true,
+		},
+		{
+			name: "rejects soft-deletion of protected workspace",
+			a: updateAttr(
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{
+					tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey:      "2022-06-01T00:00:00Z",
+					tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey: "true",
+				}),
+				readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey: "true"}),
+			),
+			wantErr: true,
+		},
+		{
+			name:    "rejects deletion of protected workspace",
+			a:       deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey: "true"})),
+			wantErr: true,
+		},
+		{
+			name: "allows deletion of unprotected workspace",
+			a:    deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, map[string]string{tenancyv1alpha1.ClusterWorkspaceDeletionProtectionAnnotationKey: "false"})),
+		},
+		{
+			name:              "rejects deletion of ready workspace which is not soft-deleted if retained",
+			a:                 deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseReady, nil)),
+			deletionRetention: time.Hour,
+			wantErr:           true,
+		},
+		{
+			name:              "rejects deletion of soft-deleted workspace during its retention period",
+			a:                 deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: future})),
+			deletionRetention: time.Hour,
+			wantErr:           true,
+		},
+		{
+			name:              "allows deletion of soft-deleted workspace after its retention period",
+			a:                 deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseDeleting, map[string]string{tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: past})),
+			deletionRetention: time.Hour,
+		},
+		{
+			name:              "allows deletion of initializing workspace if retained",
+			a:                 deleteAttr(readyWorkspace(tenancyv1alpha1.ClusterWorkspacePhaseInitializing, nil)),
+			deletionRetention: time.Hour,
+		},
 		{
 			name: "ignores different resources",
 			a: admission.NewAttributesRecord(
@@ -338,7 +456,8 @@ func TestValidate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			o := &clusterWorkspace{
-				Handler: admission.NewHandler(admission.Create, admission.Update),
+				Handler:           admission.NewHandler(admission.Create, admission.Update, admission.Delete),
+				deletionRetention: tt.deletionRetention,
 			}
 			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New("root:org")})
 			if err := o.Validate(ctx, tt.a, nil); (err != nil) != tt.wantErr {
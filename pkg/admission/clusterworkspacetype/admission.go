@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -33,6 +34,8 @@ import (
 
 // Validate ClusterWorkspaceTypes creation and updates for
 //  - "organization" type is only created in root workspace.
+//  - the type does not extend itself.
+//  - the default child type is one of the allowed child types.
 
 const (
 	PluginName = "tenancy.kcp.dev/ClusterWorkspaceType"
@@ -76,5 +79,24 @@ func (o *clusterWorkspaceType) Validate(ctx context.Context, a admission.Attribu
 		return errors.New("organization type can only be created in root workspace")
 	}
 
+	for _, t := range cwt.Spec.Extend {
+		if strings.ToLower(string(t)) == cwt.Name {
+			return admission.NewForbidden(a, fmt.Errorf("spec.extend must not contain the type itself"))
+		}
+	}
+
+	if cwt.Spec.DefaultChildWorkspaceType != "" && len(cwt.Spec.AllowedChildWorkspaceTypes) > 0 {
+		found := false
+		for _, t := range cwt.Spec.AllowedChildWorkspaceTypes {
+			if t == cwt.Spec.DefaultChildWorkspaceType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return admission.NewForbidden(a, fmt.Errorf("spec.defaultChildWorkspaceType %q must be one of spec.allowedChildWorkspaceTypes", cwt.Spec.DefaultChildWorkspaceType))
+		}
+	}
+
 	return nil
 }
@@ -101,6 +101,47 @@ func TestValidate(t *testing.T) {
 			clusterName: logicalcluster.New("foo:bar"),
 			wantErr:     true,
 		},
+		{
+			name: "deny type extending itself",
+			a: createAttr(&tenancyv1alpha1.ClusterWorkspaceType{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+					Extend: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Universal", "Foo"},
+				},
+			}),
+			clusterName: logicalcluster.New("foo:bar"),
+			wantErr:     true,
+		},
+		{
+			name: "allow default child type that is allowed",
+			a: createAttr(&tenancyv1alpha1.ClusterWorkspaceType{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+					DefaultChildWorkspaceType:  "Universal",
+					AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Team", "Universal"},
+				},
+			}),
+			clusterName: logicalcluster.New("foo:bar"),
+			wantErr:     false,
+		},
+		{
+			name: "deny default child type that is not allowed",
+			a: createAttr(&tenancyv1alpha1.ClusterWorkspaceType{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+					DefaultChildWorkspaceType:  "Universal",
+					AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Team"},
+				},
+			}),
+			clusterName: logicalcluster.New("foo:bar"),
+			wantErr:     true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -58,11 +58,13 @@ func Register(plugins *admission.Plugins) {
 
 // clusterWorkspaceTypeExists  does the following
 // - it checks existence of ClusterWorkspaceType in the same workspace,
-// - it applies the ClusterWorkspaceType initializers to the ClusterWorkspace when it
-//   transitions to the Initializing state.
+// - it checks the allowed parent and child types of the workspace and its parent,
+// - it applies the ClusterWorkspaceType initializers, including those of extended types,
+//   to the ClusterWorkspace when it transitions to the Initializing state.
 type clusterWorkspaceTypeExists struct {
 	*admission.Handler
 	typeLister        tenancyv1alpha1lister.ClusterWorkspaceTypeLister
+	workspaceLister   tenancyv1alpha1lister.ClusterWorkspaceLister
 	kubeClusterClient *kubernetes.Cluster
 
 	createAuthorizer delegated.DelegatedAuthorizerFactory
@@ -102,7 +104,7 @@ func (o *clusterWorkspaceTypeExists) Admit(ctx context.Context, a admission.Attr
 		return apierrors.NewInternalError(err)
 	}
 
-	cwt, err := o.typeLister.Get(clusters.ToClusterAwareKey(clusterName, strings.ToLower(cw.Spec.Type)))
+	cwt, _, err := o.resolveType(clusterName, cw.Spec.Type)
 	if err != nil && apierrors.IsNotFound(err) {
 		if cw.Spec.Type == "Universal" {
 			return nil // Universal is always valid
@@ -158,6 +160,7 @@ func (o *clusterWorkspaceTypeExists) Admit(ctx context.Context, a admission.Attr
 
 // Validate ensures that
 // - has a valid type
+// - has a type allowed by the parent workspace type, and vice versa, on creation
 // - has valid initializers when transitioning to initializing
 func (o *clusterWorkspaceTypeExists) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) (err error) {
 	if a.GetResource().GroupResource() != tenancyv1alpha1.Resource("clusterworkspaces") {
@@ -219,15 +222,28 @@ func (o *clusterWorkspaceTypeExists) Validate(ctx context.Context, a admission.A
 			return apierrors.NewInternalError(err)
 		}
 
-		cwt, err = o.typeLister.Get(clusters.ToClusterAwareKey(clusterName, strings.ToLower(cw.Spec.Type)))
+		var aliases sets.String
+		cwt, aliases, err = o.resolveType(clusterName, cw.Spec.Type)
 		if err != nil && apierrors.IsNotFound(err) {
-			if cw.Spec.Type == "Universal" {
-				return nil // Universal is always valid
+			if cw.Spec.Type != "Universal" {
+				return admission.NewForbidden(a, fmt.Errorf("spec.type %q does not exist", cw.Spec.Type))
 			}
-			return admission.NewForbidden(a, fmt.Errorf("spec.type %q does not exist", cw.Spec.Type))
+			// Universal is always valid, but must still be an allowed child type of the parent
+			if a.GetOperation() == admission.Create {
+				if err := o.validateHierarchy(clusterName, nil, sets.NewString(strings.ToLower(cw.Spec.Type))); err != nil {
+					return admission.NewForbidden(a, err)
+				}
+			}
+			return nil
 		} else if err != nil {
 			return admission.NewForbidden(a, err)
 		}
+
+		if a.GetOperation() == admission.Create {
+			if err := o.validateHierarchy(clusterName, cwt, aliases); err != nil {
+				return admission.NewForbidden(a, err)
+			}
+		}
 	}
 
 	// add initializers from type to workspace
@@ -271,16 +287,135 @@ func (o *clusterWorkspaceTypeExists) Validate(ctx context.Context, a admission.A
 	return nil
 }
 
+// resolveType returns the ClusterWorkspaceType of the given type name in the given workspace,
+// with the initializers and additional workspace labels of all transitively extended types
+// merged in. The returned set holds the lower-cased names of the type itself and of all
+// types it extends, i.e. all types it can act as in parent and child type constraints.
+func (o *clusterWorkspaceTypeExists) resolveType(clusterName logicalcluster.Name, typeName string) (*tenancyv1alpha1.ClusterWorkspaceType, sets.String, error) {
+	cwt, err := o.typeLister.Get(clusters.ToClusterAwareKey(clusterName, strings.ToLower(typeName)))
+	if err != nil {
+		return nil, nil, err
+	}
+	cwt = cwt.DeepCopy()
+
+	aliases := sets.NewString(strings.ToLower(typeName))
+	queue := append([]tenancyv1alpha1.ClusterWorkspaceTypeName{}, cwt.Spec.Extend...)
+	for len(queue) > 0 {
+		name := strings.ToLower(string(queue[0]))
+		queue = queue[1:]
+		if aliases.Has(name) {
+			continue
+		}
+		aliases.Insert(name)
+
+		extended, err := o.typeLister.Get(clusters.ToClusterAwareKey(clusterName, name))
+		if err != nil && apierrors.IsNotFound(err) {
+			if name == "universal" {
+				continue // Universal is always valid
+			}
+			return nil, nil, fmt.Errorf("type %q extended by %q does not exist", name, typeName)
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		existing := sets.NewString()
+		for _, i := range cwt.Spec.Initializers {
+			existing.Insert(string(i))
+		}
+		for _, i := range extended.Spec.Initializers {
+			if !existing.Has(string(i)) {
+				cwt.Spec.Initializers = append(cwt.Spec.Initializers, i)
+			}
+		}
+		for key, value := range extended.Spec.AdditionalWorkspaceLabels {
+			if cwt.Spec.AdditionalWorkspaceLabels == nil {
+				cwt.Spec.AdditionalWorkspaceLabels = map[string]string{}
+			}
+			if _, ok := cwt.Spec.AdditionalWorkspaceLabels[key]; !ok {
+				cwt.Spec.AdditionalWorkspaceLabels[key] = value
+			}
+		}
+
+		queue = append(queue, extended.Spec.Extend...)
+	}
+
+	return cwt, aliases, nil
+}
+
+// validateHierarchy checks that a workspace of the given type (nil if it has no
+// ClusterWorkspaceType object), acting as any of the given aliases, can be created
+// in the given workspace, and that the given workspace allows it as a child.
+func (o *clusterWorkspaceTypeExists) validateHierarchy(clusterName logicalcluster.Name, cwt *tenancyv1alpha1.ClusterWorkspaceType, aliases sets.String) error {
+	grandParentClusterName, parentName := clusterName.Split()
+
+	var parentType string
+	var parentAliases sets.String
+	var parentCwt *tenancyv1alpha1.ClusterWorkspaceType
+	if clusterName == tenancyv1alpha1.RootCluster {
+		parentType = string(tenancyv1alpha1.RootWorkspaceType)
+		parentAliases = sets.NewString(strings.ToLower(parentType))
+	} else if !grandParentClusterName.Empty() {
+		parent, err := o.workspaceLister.Get(clusters.ToClusterAwareKey(grandParentClusterName, parentName))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			parentType = parent.Spec.Type
+			parentCwt, parentAliases, err = o.resolveType(grandParentClusterName, parentType)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			if parentAliases == nil {
+				parentAliases = sets.NewString(strings.ToLower(parentType))
+			}
+		}
+	}
+
+	if cwt != nil && len(cwt.Spec.AllowedParentWorkspaceTypes) > 0 {
+		if parentAliases == nil {
+			return fmt.Errorf("workspace type %q can only be created in workspaces of type %v", cwt.Name, cwt.Spec.AllowedParentWorkspaceTypes)
+		}
+		if !parentAliases.HasAny(typeNames(cwt.Spec.AllowedParentWorkspaceTypes)...) {
+			return fmt.Errorf("workspace type %q cannot be created in a workspace of type %q, only in %v", cwt.Name, parentType, cwt.Spec.AllowedParentWorkspaceTypes)
+		}
+	}
+
+	if parentCwt != nil && len(parentCwt.Spec.AllowedChildWorkspaceTypes) > 0 {
+		if !aliases.HasAny(typeNames(parentCwt.Spec.AllowedChildWorkspaceTypes)...) {
+			return fmt.Errorf("workspace of type %q only allows child workspaces of type %v", parentType, parentCwt.Spec.AllowedChildWorkspaceTypes)
+		}
+	}
+
+	return nil
+}
+
+// typeNames returns the lower-cased names of the given types.
+func typeNames(types []tenancyv1alpha1.ClusterWorkspaceTypeName) []string {
+	ret := make([]string, 0, len(types))
+	for _, t := range types {
+		ret = append(ret, strings.ToLower(string(t)))
+	}
+	return ret
+}
+
 func (o *clusterWorkspaceTypeExists) ValidateInitialization() error {
 	if o.typeLister == nil {
 		return fmt.Errorf(PluginName + " plugin needs an ClusterWorkspaceType lister")
 	}
+	if o.workspaceLister == nil {
+		return fmt.Errorf(PluginName + " plugin needs an ClusterWorkspace lister")
+	}
 	return nil
 }
 
 func (o *clusterWorkspaceTypeExists) SetKcpInformers(informers kcpinformers.SharedInformerFactory) {
-	o.SetReadyFunc(informers.Tenancy().V1alpha1().ClusterWorkspaceTypes().Informer().HasSynced)
+	typesReady := informers.Tenancy().V1alpha1().ClusterWorkspaceTypes().Informer().HasSynced
+	workspacesReady := informers.Tenancy().V1alpha1().ClusterWorkspaces().Informer().HasSynced
+	o.SetReadyFunc(func() bool {
+		return typesReady() && workspacesReady()
+	})
 	o.typeLister = informers.Tenancy().V1alpha1().ClusterWorkspaceTypes().Lister()
+	o.workspaceLister = informers.Tenancy().V1alpha1().ClusterWorkspaces().Lister()
 }
 
 func (o *clusterWorkspaceTypeExists) SetKubeClusterClient(kubeClusterClient *kubernetes.Cluster) {
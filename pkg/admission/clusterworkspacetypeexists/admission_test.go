@@ -77,6 +77,7 @@ func TestAdmit(t *testing.T) {
 	tests := []struct {
 		name        string
 		types       []*tenancyv1alpha1.ClusterWorkspaceType
+		workspaces  []*tenancyv1alpha1.ClusterWorkspace
 		a           admission.Attributes
 		expectedObj runtime.Object
 		wantErr     bool
@@ -305,12 +306,143 @@ func TestAdmit(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "adds initializers of extended types during transition to initializing",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{"a"},
+						Extend:       []tenancyv1alpha1.ClusterWorkspaceTypeName{"Bar"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#bar",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{"a", "b"},
+						Extend:       []tenancyv1alpha1.ClusterWorkspaceTypeName{"Foo"},
+					},
+				},
+			},
+			a: updateAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+				Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+					Phase:    tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+					Location: tenancyv1alpha1.ClusterWorkspaceLocation{Current: "somewhere"},
+					BaseURL:  "https://kcp.bigcorp.com/clusters/org:test",
+				},
+			},
+				&tenancyv1alpha1.ClusterWorkspace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Foo",
+					},
+					Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+						Phase: tenancyv1alpha1.ClusterWorkspacePhaseScheduling,
+					},
+				}),
+			expectedObj: &tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+				Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+					Phase:        tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+					Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{"a", "b"},
+					Location:     tenancyv1alpha1.ClusterWorkspaceLocation{Current: "somewhere"},
+					BaseURL:      "https://kcp.bigcorp.com/clusters/org:test",
+				},
+			},
+		},
+		{
+			name: "adds additional workspace labels of extended types",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AdditionalWorkspaceLabels: map[string]string{
+							"own-label": "foo",
+						},
+						Extend: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Bar"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#bar",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AdditionalWorkspaceLabels: map[string]string{
+							"own-label":       "bar",
+							"inherited-label": "bar",
+						},
+					},
+				},
+			},
+			a: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			expectedObj: &tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Labels: map[string]string{
+						"own-label":       "foo",
+						"inherited-label": "bar",
+					},
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			},
+		},
+		{
+			name: "fails if extended type does not exist",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						Extend: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Bar"},
+					},
+				},
+			},
+			a: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			o := &clusterWorkspaceTypeExists{
-				Handler:    admission.NewHandler(admission.Create, admission.Update),
-				typeLister: fakeClusterWorkspaceTypeLister(tt.types),
+				Handler:         admission.NewHandler(admission.Create, admission.Update),
+				typeLister:      fakeClusterWorkspaceTypeLister(tt.types),
+				workspaceLister: fakeClusterWorkspaceLister(tt.workspaces),
 			}
 			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New("root:org")})
 			if err := o.Admit(ctx, tt.a, nil); (err != nil) != tt.wantErr {
@@ -329,9 +461,10 @@ func TestAdmit(t *testing.T) {
 
 func TestValidate(t *testing.T) {
 	tests := []struct {
-		name  string
-		types []*tenancyv1alpha1.ClusterWorkspaceType
-		attr  admission.Attributes
+		name       string
+		types      []*tenancyv1alpha1.ClusterWorkspaceType
+		workspaces []*tenancyv1alpha1.ClusterWorkspace
+		attr       admission.Attributes
 
 		authzDecision authorizer.Decision
 		authzError    error
@@ -567,12 +700,220 @@ func TestValidate(t *testing.T) {
 				&user.DefaultInfo{},
 			),
 		},
+		{
+			name: "passes create if parent type allows the type",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#organization",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Foo"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedParentWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Organization"},
+					},
+				},
+			},
+			workspaces: []*tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#org",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Organization",
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+		},
+		{
+			name: "fails create if parent type does not allow the type",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#organization",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Team"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+				},
+			},
+			workspaces: []*tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#org",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Organization",
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+			wantErr:       true,
+		},
+		{
+			name: "fails create of universal type if parent type does not allow it",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#organization",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Team"},
+					},
+				},
+			},
+			workspaces: []*tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#org",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Organization",
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Universal",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+			wantErr:       true,
+		},
+		{
+			name: "passes create if the type extends an allowed child type",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#organization",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedChildWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Universal"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						Extend: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Universal"},
+					},
+				},
+			},
+			workspaces: []*tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#org",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Organization",
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+		},
+		{
+			name: "fails create if the type does not allow the parent type",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedParentWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Root"},
+					},
+				},
+			},
+			workspaces: []*tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root#$#org",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+						Type: "Organization",
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+			wantErr:       true,
+		},
+		{
+			name: "fails create if the type requires a parent type, but the parent is unknown",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root:org#$#foo",
+					},
+					Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+						AllowedParentWorkspaceTypes: []tenancyv1alpha1.ClusterWorkspaceTypeName{"Organization"},
+					},
+				},
+			},
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: "Foo",
+				},
+			}),
+			authzDecision: authorizer.DecisionAllow,
+			wantErr:       true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			o := &clusterWorkspaceTypeExists{
-				Handler:    admission.NewHandler(admission.Create, admission.Update),
-				typeLister: fakeClusterWorkspaceTypeLister(tt.types),
+				Handler:         admission.NewHandler(admission.Create, admission.Update),
+				typeLister:      fakeClusterWorkspaceTypeLister(tt.types),
+				workspaceLister: fakeClusterWorkspaceLister(tt.workspaces),
 				createAuthorizer: func(clusterName logicalcluster.Name, client kubernetes.ClusterInterface) (authorizer.Authorizer, error) {
 					return &fakeAuthorizer{
 						tt.authzDecision,
@@ -611,6 +952,29 @@ func (l fakeClusterWorkspaceTypeLister) GetWithContext(ctx context.Context, name
 	return nil, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspacetype"), name)
 }
 
+type fakeClusterWorkspaceLister []*tenancyv1alpha1.ClusterWorkspace
+
+func (l fakeClusterWorkspaceLister) List(selector labels.Selector) (ret []*tenancyv1alpha1.ClusterWorkspace, err error) {
+	return l.ListWithContext(context.Background(), selector)
+}
+
+func (l fakeClusterWorkspaceLister) ListWithContext(ctx context.Context, selector labels.Selector) (ret []*tenancyv1alpha1.ClusterWorkspace, err error) {
+	return l, nil
+}
+
+func (l fakeClusterWorkspaceLister) Get(name string) (*tenancyv1alpha1.ClusterWorkspace, error) {
+	return l.GetWithContext(context.Background(), name)
+}
+
+func (l fakeClusterWorkspaceLister) GetWithContext(ctx context.Context, name string) (*tenancyv1alpha1.ClusterWorkspace, error) {
+	for _, t := range l {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspace"), name)
+}
+
 type fakeAuthorizer struct {
 	authorized authorizer.Decision
 	err        error
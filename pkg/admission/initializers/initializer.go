@@ -17,6 +17,8 @@ limitations under the License.
 package initializers
 
 import (
+	"time"
+
 	"k8s.io/apiserver/pkg/admission"
 	"k8s.io/client-go/kubernetes"
 
@@ -103,3 +105,23 @@ func (i *externalAddressInitializer) Initialize(plugin admission.Interface) {
 		wants.SetExternalAddressProvider(i.externalAddressProvider)
 	}
 }
+
+// NewWorkspaceDeletionRetentionInitializer returns an admission plugin initializer that injects
+// the time deleted workspaces are retained into the admission plugin.
+func NewWorkspaceDeletionRetentionInitializer(
+	deletionRetention time.Duration,
+) *workspaceDeletionRetentionInitializer {
+	return &workspaceDeletionRetentionInitializer{
+		deletionRetention: deletionRetention,
+	}
+}
+
+type workspaceDeletionRetentionInitializer struct {
+	deletionRetention time.Duration
+}
+
+func (i *workspaceDeletionRetentionInitializer) Initialize(plugin admission.Interface) {
+	if wants, ok := plugin.(WantsWorkspaceDeletionRetention); ok {
+		wants.SetWorkspaceDeletionRetention(i.deletionRetention)
+	}
+}
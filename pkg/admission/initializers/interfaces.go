@@ -17,6 +17,8 @@ limitations under the License.
 package initializers
 
 import (
+	"time"
+
 	"k8s.io/client-go/kubernetes"
 
 	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
@@ -46,3 +48,9 @@ type WantsKcpClusterClient interface {
 type WantsExternalAddressProvider interface {
 	SetExternalAddressProvider(externalAddressProvider func() string)
 }
+
+// WantsWorkspaceDeletionRetention interface should be implemented by admission plugins
+// that want to have the time deleted workspaces are retained injected.
+type WantsWorkspaceDeletionRetention interface {
+	SetWorkspaceDeletionRetention(deletionRetention time.Duration)
+}
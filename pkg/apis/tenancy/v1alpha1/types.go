@@ -117,8 +117,49 @@ type ClusterWorkspaceTypeSpec struct {
 	//
 	// +optional
 	AdditionalWorkspaceLabels map[string]string `json:"additionalWorkspaceLabels,omitempty"`
+
+	// extend is a list of other ClusterWorkspaceTypes in the same workspace whose
+	// initializers and additionalWorkspaceLabels are inherited by this type. Labels
+	// defined by this type take precedence over inherited ones. A type extending
+	// another type is also accepted wherever the extended type is allowed as a
+	// parent or child type.
+	//
+	// +optional
+	Extend []ClusterWorkspaceTypeName `json:"extend,omitempty"`
+
+	// defaultChildWorkspaceType is the type used by clients like the kubectl
+	// workspace plugin for nested workspaces that are created without an explicit
+	// type. If set, it must be one of allowedChildWorkspaceTypes if those are given.
+	//
+	// +optional
+	DefaultChildWorkspaceType ClusterWorkspaceTypeName `json:"defaultChildWorkspaceType,omitempty"`
+
+	// allowedChildWorkspaceTypes is a list of ClusterWorkspaceTypes that can be
+	// created in a workspace of this type. If empty, all types are allowed.
+	//
+	// +optional
+	AllowedChildWorkspaceTypes []ClusterWorkspaceTypeName `json:"allowedChildWorkspaceTypes,omitempty"`
+
+	// allowedParentWorkspaceTypes is a list of ClusterWorkspaceTypes that a
+	// workspace of this type can be created in. The root workspace has the
+	// type "Root". If empty, all parent types are allowed.
+	//
+	// +optional
+	AllowedParentWorkspaceTypes []ClusterWorkspaceTypeName `json:"allowedParentWorkspaceTypes,omitempty"`
 }
 
+// ClusterWorkspaceTypeName is the name of a ClusterWorkspaceType as used in
+// ClusterWorkspace.Spec.Type, i.e. the capitalized form of the ClusterWorkspaceType
+// object name.
+//
+// +kubebuilder:validation:Pattern=`^[A-Z][a-zA-Z0-9]+$`
+type ClusterWorkspaceTypeName string
+
+// RootWorkspaceType is the type of the root workspace. There is no
+// ClusterWorkspaceType object for it, but it can be referenced in
+// allowedParentWorkspaceTypes.
+const RootWorkspaceType ClusterWorkspaceTypeName = "Root"
+
 // ClusterWorkspaceTypeList is a list of cluster workspace types
 //
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -140,11 +181,14 @@ const (
 	ClusterWorkspacePhaseScheduling   ClusterWorkspacePhaseType = "Scheduling"
 	ClusterWorkspacePhaseInitializing ClusterWorkspacePhaseType = "Initializing"
 	ClusterWorkspacePhaseReady        ClusterWorkspacePhaseType = "Ready"
+	// ClusterWorkspacePhaseDeleting is the phase of a soft-deleted workspace. Its content
+	// is not accessible anymore, but kept until the retention period is over.
+	ClusterWorkspacePhaseDeleting ClusterWorkspacePhaseType = "Deleting"
 )
 
 // ClusterWorkspaceStatus communicates the observed state of the ClusterWorkspace.
 type ClusterWorkspaceStatus struct {
-	// Phase of the workspace  (Scheduling / Initializing / Ready)
+	// Phase of the workspace  (Scheduling / Initializing / Ready / Deleting)
 	Phase ClusterWorkspacePhaseType `json:"phase,omitempty"`
 
 	// Current processing state of the ClusterWorkspace.
@@ -290,6 +334,13 @@ type ClusterWorkspaceShardSpec struct {
 	// +kubebuilder:Required
 	// +required
 	ExternalURL string `json:"externalURL"`
+
+	// credentials is a reference to a secret in the root workspace holding a kubeconfig
+	// in the 'kubeconfig' key with administrative credentials for this shard. Peer shards
+	// use it to fan out wildcard requests, connecting to baseURL.
+	//
+	// +optional
+	Credentials *corev1.SecretReference `json:"credentials,omitempty"`
 }
 
 // ClusterWorkspaceShardStatus communicates the observed state of the ClusterWorkspaceShard.
@@ -321,4 +372,12 @@ const (
 	// and the set of labels with this prefix is enforced to match the set of initializers by a mutating admission
 	// webhook.
 	ClusterWorkspaceInitializerLabelPrefix = "internal.kcp.dev/initializer."
+
+	// ClusterWorkspaceDeletionProtectionAnnotationKey on a ClusterWorkspace with value "true" blocks its
+	// deletion, including soft-deletion.
+	ClusterWorkspaceDeletionProtectionAnnotationKey = "tenancy.kcp.dev/deletion-protection"
+	// ClusterWorkspaceRetainedUntilAnnotationKey marks a ClusterWorkspace as soft-deleted. The value is
+	// a RFC3339 timestamp after which the workspace is deleted for real, including its content. Until
+	// then the workspace is in the Deleting phase and can be restored by removing the annotation.
+	ClusterWorkspaceRetainedUntilAnnotationKey = "tenancy.kcp.dev/retained-until"
 )
@@ -110,7 +110,7 @@ func (in *ClusterWorkspaceShard) DeepCopyInto(out *ClusterWorkspaceShard) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -169,6 +169,11 @@ func (in *ClusterWorkspaceShardList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterWorkspaceShardSpec) DeepCopyInto(out *ClusterWorkspaceShardSpec) {
 	*out = *in
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
 	return
 }
 
@@ -332,6 +337,21 @@ func (in *ClusterWorkspaceTypeSpec) DeepCopyInto(out *ClusterWorkspaceTypeSpec)
 			(*out)[key] = val
 		}
 	}
+	if in.Extend != nil {
+		in, out := &in.Extend, &out.Extend
+		*out = make([]ClusterWorkspaceTypeName, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedChildWorkspaceTypes != nil {
+		in, out := &in.AllowedChildWorkspaceTypes, &out.AllowedChildWorkspaceTypes
+		*out = make([]ClusterWorkspaceTypeName, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedParentWorkspaceTypes != nil {
+		in, out := &in.AllowedParentWorkspaceTypes, &out.AllowedParentWorkspaceTypes
+		*out = make([]ClusterWorkspaceTypeName, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
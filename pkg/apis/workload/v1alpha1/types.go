@@ -91,6 +91,16 @@ const (
 	// The format is JSON.
 	InternalClusterStatusAnnotationPrefix = "experimental.status.workloads.kcp.dev/"
 
+	// InternalClusterSyncedAnnotationPrefix is the prefix of the annotation
+	//
+	//   synced.internal.workloads.kcp.dev/<workload-cluster-name>
+	//
+	// on upstream resources. It is set by the syncer of <workload-cluster-name> once the
+	// resource has been applied successfully to the downstream cluster for the first time,
+	// and removed together with the state label. Drain uses it to know when a new
+	// placement has caught up before the old one is released.
+	InternalClusterSyncedAnnotationPrefix = "synced.internal.workloads.kcp.dev/"
+
 	// ClusterSpecDiffAnnotationPrefix is the prefix of the annotation
 	//
 	//   experimental.spec-diff.workloads.kcp.dev/<workload-cluster-name>
@@ -112,6 +122,16 @@ const (
 	// SchedulingDisabledLabel on a namespace disables workload placement and scheduling.
 	SchedulingDisabledLabel = "experimental.workloads.kcp.dev/scheduling-disabled"
 
+	// DrainMaxUnavailableAnnotation on an unschedulable WorkloadCluster requests it to be drained:
+	// its namespaces are moved onto other ready WorkloadClusters, no more than the given number
+	// at the same time. A namespace is released from the drained WorkloadCluster once all of its
+	// resources have been synced to the new one.
+	DrainMaxUnavailableAnnotation = "experimental.workloads.kcp.dev/drain-max-unavailable"
+
+	// DrainBlockedAnnotation on a WorkloadCluster being drained is set by kcp to the namespaces that
+	// cannot be moved off it, as a JSON object mapping their names to the reason why.
+	DrainBlockedAnnotation = "experimental.workloads.kcp.dev/drain-blocked"
+
 	// WorkspaceSchedulableLabel on a workspace enables scheduling for the contents
 	// of the workspace. It is applied by default to workspaces of type `Universal`.
 	WorkspaceSchedulableLabel = "workloads.kcp.dev/schedulable"
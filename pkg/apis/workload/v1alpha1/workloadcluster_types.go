@@ -124,6 +124,10 @@ const (
 	// HeartbeatHealthy means the HeartbeatManager has seen a heartbeat for the WorkloadCluster within the expected interval.
 	HeartbeatHealthy conditionsv1alpha1.ConditionType = "HeartbeatHealthy"
 
+	// WorkloadClusterDrained means the WorkloadCluster is unschedulable and no namespace is assigned to it
+	// anymore. The condition is only present while the WorkloadCluster is unschedulable.
+	WorkloadClusterDrained conditionsv1alpha1.ConditionType = "Drained"
+
 	// WorkloadClusterUnknownReason documents a WorkloadCluster which readiness is unknown.
 	WorkloadClusterUnknownReason = "WorkloadClusterStatusUnknown"
 
@@ -153,6 +157,13 @@ const (
 
 	// ErrorHeartbeatMissedReason indicates that a heartbeat update was not received within the configured threshold.
 	ErrorHeartbeatMissedReason = "ErrorHeartbeat"
+
+	// NamespacesRemainingReason indicates that namespaces are still assigned to an unschedulable WorkloadCluster.
+	NamespacesRemainingReason = "NamespacesRemaining"
+
+	// NamespacesBlockedReason indicates that none of the namespaces still assigned to an unschedulable
+	// WorkloadCluster being drained can be moved off it.
+	NamespacesBlockedReason = "NamespacesBlocked"
 )
 
 func (in *WorkloadCluster) SetConditions(conditions conditionsv1alpha1.Conditions) {
@@ -110,6 +110,9 @@ func (a *workspaceContentAuthorizer) Authorize(ctx context.Context, attr authori
 				return authorizer.DecisionDeny, fmt.Sprintf("%q workspace access not permitted", cluster.Name), nil
 			}
 			return authorizer.DecisionNoOpinion, "", err
+		} else if ws.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseDeleting {
+			// soft-deleted workspaces are kept intact, but are not accessible until restored
+			return authorizer.DecisionDeny, fmt.Sprintf("%q workspace access not permitted, workspace is being deleted", cluster.Name), nil
 		} else if len(ws.Status.Initializers) > 0 {
 			workspaceAttr := authorizer.AttributesRecord{
 				User:            attr.GetUser(),
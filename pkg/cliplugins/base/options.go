@@ -14,7 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package plugin
+package base
 
 import (
 	"github.com/spf13/cobra"
@@ -23,7 +23,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// Options for the workload commands.
+// Options contains the options common to the kubectl kcp plugin commands.
 type Options struct {
 	KubectlOverrides *clientcmd.ConfigOverrides
 
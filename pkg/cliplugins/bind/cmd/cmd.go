@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/bind/plugin"
+)
+
+var (
+	bindExample = `
+	# Bind the APIExport "widgets" of the sibling workspace "api-provider" into the current
+	# workspace, and wait for its resources to be available.
+	%[1]s bind api-provider:widgets
+
+	# Same, naming the APIBinding and giving up after a minute.
+	%[1]s bind root:my-org:api-provider:widgets --name my-widgets --timeout 1m
+`
+	unbindExample = `
+	# Delete the APIBinding "widgets" of the current workspace.
+	%[1]s unbind widgets
+
+	# Delete all APIBindings of the current workspace for the given APIExport.
+	%[1]s unbind api-provider:widgets
+`
+	bindingsListExample = `
+	# List the APIBindings of the current workspace with their bound resources.
+	%[1]s bindings list
+`
+)
+
+// New provides the cobra commands for binding APIExports: bind, unbind and bindings.
+func New(streams genericclioptions.IOStreams) ([]*cobra.Command, error) {
+	opts := base.NewOptions(streams)
+
+	// bind
+	var bindingName string
+	var noWait bool
+	var bindTimeout = 30 * time.Second
+	bindCmd := &cobra.Command{
+		Use:          "bind <workspace>:<export> [--name <binding-name>]",
+		Short:        "Bind an APIExport into the current workspace",
+		Example:      fmt.Sprintf(bindExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return c.Help()
+			}
+
+			return kubeconfig.Bind(c.Context(), args[0], bindingName, !noWait, bindTimeout)
+		},
+	}
+	opts.BindFlags(bindCmd)
+	bindCmd.Flags().StringVar(&bindingName, "name", bindingName, "Name of the APIBinding. Defaults to the name of the APIExport.")
+	bindCmd.Flags().BoolVar(&noWait, "no-wait", noWait, "Do not wait for the APIExport to be bound.")
+	bindCmd.Flags().DurationVar(&bindTimeout, "timeout", bindTimeout, "Maximum time to wait for the APIExport to be bound. Zero means no limit.")
+
+	// unbind
+	unbindCmd := &cobra.Command{
+		Use:          "unbind <binding-name>|<workspace>:<export>",
+		Short:        "Delete an APIBinding of the current workspace",
+		Example:      fmt.Sprintf(unbindExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return c.Help()
+			}
+
+			return kubeconfig.Unbind(c.Context(), args[0])
+		},
+	}
+	opts.BindFlags(unbindCmd)
+
+	// bindings
+	bindingsCmd := &cobra.Command{
+		Aliases:          []string{"binding"},
+		Use:              "bindings",
+		Short:            "Manages the APIBindings of the current workspace",
+		SilenceUsage:     true,
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	opts.BindFlags(bindingsCmd)
+
+	var listOutput string
+	listCmd := &cobra.Command{
+		Use:          "list [-o json|yaml]",
+		Short:        "List the APIBindings of the current workspace",
+		Example:      fmt.Sprintf(bindingsListExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 0 {
+				return c.Help()
+			}
+
+			return kubeconfig.ListBindings(c.Context(), listOutput)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", listOutput, "Output format, one of json, yaml. Defaults to a table")
+
+	bindingsCmd.AddCommand(listCmd)
+
+	return []*cobra.Command{bindCmd, unbindCmd, bindingsCmd}, nil
+}
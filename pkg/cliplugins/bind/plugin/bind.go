@@ -0,0 +1,387 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/printers"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/third_party/conditions/util/conditions"
+)
+
+// bindPollInterval is how often the APIBinding is checked while waiting for it to be bound.
+var bindPollInterval = time.Second
+
+// Bind creates an APIBinding in the current workspace for the APIExport referenced
+// as <workspace>:<export>, and waits for the export to be bound. The bound resources
+// are printed, or the naming conflicts if the resources cannot be bound.
+//
+// If an APIBinding with the given name already exists for the same export, it is
+// waited for instead.
+func (c *Config) Bind(ctx context.Context, exportRef, bindingName string, waitForBound bool, timeout time.Duration) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	reference, err := parseExportReference(currentClusterName, exportRef)
+	if err != nil {
+		return err
+	}
+	if bindingName == "" {
+		bindingName = reference.Workspace.ExportName
+	}
+
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	binding, created, err := ensureAPIBinding(ctx, kcpClient, bindingName, reference)
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Fprintf(c.Out, "APIBinding %q for export %s created.\n", binding.Name, exportReferenceString(reference))
+	} else {
+		fmt.Fprintf(c.Out, "APIBinding %q for export %s already exists.\n", binding.Name, exportReferenceString(reference))
+	}
+	if !waitForBound {
+		return nil
+	}
+
+	binding, err = waitForAPIBinding(ctx, kcpClient, bindingName, timeout)
+	if binding != nil {
+		if printErr := printBindingResult(c.Out, binding); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// Unbind deletes the APIBinding with the given name. If a <workspace>:<export> reference is
+// given instead, all APIBindings of the current workspace for that export are deleted.
+func (c *Config) Unbind(ctx context.Context, nameOrExportRef string) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	names, err := bindingsToDelete(ctx, kcpClient, currentClusterName, nameOrExportRef)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := kcpClient.ApisV1alpha1().APIBindings().Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to delete APIBinding %s: %w", name, err)
+		}
+		fmt.Fprintf(c.Out, "APIBinding %q deleted.\n", name)
+	}
+	return nil
+}
+
+// ListBindings prints the APIBindings of the current workspace with the export they
+// reference, their phase and their bound resources.
+func (c *Config) ListBindings(ctx context.Context, output string) error {
+	if output != "" && output != "json" && output != "yaml" {
+		return fmt.Errorf("unsupported output format %q, must be one of json, yaml", output)
+	}
+
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	bindings, err := kcpClient.ApisV1alpha1().APIBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list APIBindings: %w", err)
+	}
+	sort.Slice(bindings.Items, func(i, j int) bool {
+		return bindings.Items[i].Name < bindings.Items[j].Name
+	})
+
+	switch output {
+	case "json":
+		bs, err := json.MarshalIndent(bindings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = c.Out.Write(append(bs, '\n'))
+		return err
+	case "yaml":
+		bs, err := yaml.Marshal(bindings)
+		if err != nil {
+			return err
+		}
+		_, err = c.Out.Write(bs)
+		return err
+	}
+	return printBindings(c.Out, bindings.Items)
+}
+
+// parseExportReference parses a <workspace>:<export> reference to an APIExport. The
+// workspace is either a sibling of the current workspace, or an absolute workspace with
+// the same parent as the current workspace, as APIBindings can only reference exports in
+// the same organization.
+func parseExportReference(currentClusterName logicalcluster.Name, exportRef string) (*apisv1alpha1.ExportReference, error) {
+	i := strings.LastIndex(exportRef, ":")
+	if i <= 0 || i == len(exportRef)-1 {
+		return nil, fmt.Errorf("invalid export reference %q, must be <workspace>:<export>", exportRef)
+	}
+	workspace, exportName := exportRef[:i], exportRef[i+1:]
+
+	if strings.Contains(workspace, ":") {
+		parent, workspaceName := logicalcluster.New(workspace).Split()
+		currentParent, _ := currentClusterName.Parent()
+		if parent != currentParent {
+			return nil, fmt.Errorf("workspace %q is not in the same organization as the current workspace %q", workspace, currentClusterName)
+		}
+		workspace = workspaceName
+	}
+
+	return &apisv1alpha1.ExportReference{
+		Workspace: &apisv1alpha1.WorkspaceExportReference{
+			WorkspaceName: workspace,
+			ExportName:    exportName,
+		},
+	}, nil
+}
+
+func exportReferenceString(reference *apisv1alpha1.ExportReference) string {
+	if reference == nil || reference.Workspace == nil {
+		return "<unknown>"
+	}
+	return reference.Workspace.WorkspaceName + ":" + reference.Workspace.ExportName
+}
+
+// ensureAPIBinding creates the APIBinding, or returns the existing one if it references
+// the same export. It also returns whether the APIBinding was created.
+func ensureAPIBinding(ctx context.Context, kcpClient kcpclientset.Interface, name string, reference *apisv1alpha1.ExportReference) (*apisv1alpha1.APIBinding, bool, error) {
+	binding, err := kcpClient.ApisV1alpha1().APIBindings().Create(ctx, &apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apisv1alpha1.APIBindingSpec{
+			Reference: *reference,
+		},
+	}, metav1.CreateOptions{})
+	if err == nil {
+		return binding, true, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, false, fmt.Errorf("failed to create APIBinding %s: %w", name, err)
+	}
+
+	binding, err = kcpClient.ApisV1alpha1().APIBindings().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get APIBinding %s: %w", name, err)
+	}
+	if existing := exportReferenceString(&binding.Spec.Reference); existing != exportReferenceString(reference) {
+		return nil, false, fmt.Errorf("APIBinding %s already exists for export %s", name, existing)
+	}
+	return binding, false, nil
+}
+
+// waitForAPIBinding waits until the APIBinding has completed its initial binding, or
+// failed in a way that will not resolve by waiting. The last seen APIBinding is returned
+// together with the error.
+func waitForAPIBinding(ctx context.Context, kcpClient kcpclientset.Interface, name string, timeout time.Duration) (*apisv1alpha1.APIBinding, error) {
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var binding *apisv1alpha1.APIBinding
+	var bindErr error
+	err := wait.PollImmediateUntilWithContext(waitCtx, bindPollInterval, func(ctx context.Context) (bool, error) {
+		current, err := kcpClient.ApisV1alpha1().APIBindings().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get APIBinding %s: %w", name, err)
+		}
+		binding = current
+		var done bool
+		done, bindErr = bindingDone(binding)
+		return done, nil
+	})
+	if err != nil {
+		if binding == nil || err != wait.ErrWaitTimeout {
+			return binding, err
+		}
+		return binding, fmt.Errorf("timed out waiting for APIBinding %s to be bound: %s", name, bindingConditionsString(binding))
+	}
+	return binding, bindErr
+}
+
+// bindingDone returns whether the APIBinding has completed its initial binding, or has
+// failed for a reason that requires user action, in which case the error describes it.
+func bindingDone(binding *apisv1alpha1.APIBinding) (bool, error) {
+	if conditions.IsTrue(binding, apisv1alpha1.InitialBindingCompleted) {
+		return true, nil
+	}
+	if conditions.IsFalse(binding, apisv1alpha1.APIExportValid) && conditions.GetReason(binding, apisv1alpha1.APIExportValid) == apisv1alpha1.APIExportInvalidReferenceReason {
+		return true, fmt.Errorf("APIBinding %s references an invalid export: %s", binding.Name, conditions.GetMessage(binding, apisv1alpha1.APIExportValid))
+	}
+	if conditions.GetReason(binding, apisv1alpha1.InitialBindingCompleted) == apisv1alpha1.NamingConflictsReason {
+		return true, fmt.Errorf("APIBinding %s has naming conflicts", binding.Name)
+	}
+	return false, nil
+}
+
+// namingConflicts returns the messages of the conditions of the APIBinding that report
+// naming conflicts.
+func namingConflicts(binding *apisv1alpha1.APIBinding) []string {
+	var messages []string
+	for _, t := range []conditionsapi.ConditionType{apisv1alpha1.InitialBindingCompleted, apisv1alpha1.BindingUpToDate} {
+		condition := conditions.Get(binding, t)
+		if condition == nil || condition.Reason != apisv1alpha1.NamingConflictsReason {
+			continue
+		}
+		if len(messages) == 0 || messages[len(messages)-1] != condition.Message {
+			messages = append(messages, condition.Message)
+		}
+	}
+	return messages
+}
+
+func bindingConditionsString(binding *apisv1alpha1.APIBinding) string {
+	var parts []string
+	for _, condition := range binding.Status.Conditions {
+		part := fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+		if condition.Reason != "" {
+			part += fmt.Sprintf(" (%s: %s)", condition.Reason, condition.Message)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "no conditions reported"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printBindingResult prints the bound resources of the APIBinding, and its naming
+// conflicts if any.
+func printBindingResult(out io.Writer, binding *apisv1alpha1.APIBinding) error {
+	if conditions.IsTrue(binding, apisv1alpha1.InitialBindingCompleted) {
+		fmt.Fprintf(out, "APIBinding %q is bound.\n", binding.Name)
+	}
+	if len(binding.Status.BoundResources) > 0 {
+		w := printers.GetNewTabWriter(out)
+		fmt.Fprintln(w, "RESOURCE\tSCHEMA\tSTORAGE VERSIONS")
+		for _, r := range binding.Status.BoundResources {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", groupResourceString(r.Group, r.Resource), r.Schema.Name, helpers.JoinOrNone(r.StorageVersions))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if conflicts := namingConflicts(binding); len(conflicts) > 0 {
+		fmt.Fprintln(out, "Naming conflicts:")
+		for _, conflict := range conflicts {
+			fmt.Fprintf(out, "  %s\n", conflict)
+		}
+	}
+	return nil
+}
+
+func printBindings(out io.Writer, bindings []apisv1alpha1.APIBinding) error {
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, "NAME\tEXPORT\tPHASE\tRESOURCES\tCONFLICTS")
+	for i := range bindings {
+		binding := &bindings[i]
+		var resources []string
+		for _, r := range binding.Status.BoundResources {
+			resources = append(resources, groupResourceString(r.Group, r.Resource))
+		}
+		conflicts := "<none>"
+		if len(namingConflicts(binding)) > 0 {
+			conflicts = "NamingConflicts"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", binding.Name, exportReferenceString(&binding.Spec.Reference), helpers.ValueOrNone(string(binding.Status.Phase)), helpers.JoinOrNone(resources), conflicts)
+	}
+	return w.Flush()
+}
+
+// bindingsToDelete resolves the argument of unbind to the names of APIBindings.
+func bindingsToDelete(ctx context.Context, kcpClient kcpclientset.Interface, currentClusterName logicalcluster.Name, nameOrExportRef string) ([]string, error) {
+	if !strings.Contains(nameOrExportRef, ":") {
+		return []string{nameOrExportRef}, nil
+	}
+
+	reference, err := parseExportReference(currentClusterName, nameOrExportRef)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := kcpClient.ApisV1alpha1().APIBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list APIBindings: %w", err)
+	}
+	var names []string
+	for _, binding := range bindings.Items {
+		if exportReferenceString(&binding.Spec.Reference) == exportReferenceString(reference) {
+			names = append(names, binding.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no APIBinding found for export %s", exportReferenceString(reference))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func groupResourceString(group, resource string) string {
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
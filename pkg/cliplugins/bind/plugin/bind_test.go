@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+)
+
+func TestParseExportReference(t *testing.T) {
+	current := logicalcluster.New("root:my-org:consumer")
+	tests := []struct {
+		exportRef string
+		want      *apisv1alpha1.WorkspaceExportReference
+		wantErr   bool
+	}{
+		{exportRef: "provider:widgets", want: &apisv1alpha1.WorkspaceExportReference{WorkspaceName: "provider", ExportName: "widgets"}},
+		{exportRef: "root:my-org:provider:widgets", want: &apisv1alpha1.WorkspaceExportReference{WorkspaceName: "provider", ExportName: "widgets"}},
+		{exportRef: "root:other-org:provider:widgets", wantErr: true},
+		{exportRef: "widgets", wantErr: true},
+		{exportRef: ":widgets", wantErr: true},
+		{exportRef: "provider:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.exportRef, func(t *testing.T) {
+			got, err := parseExportReference(current, tt.exportRef)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Empty(t, cmp.Diff(tt.want, got.Workspace))
+		})
+	}
+}
+
+func TestEnsureAPIBinding(t *testing.T) {
+	reference := func(workspace, export string) *apisv1alpha1.ExportReference {
+		return &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{WorkspaceName: workspace, ExportName: export}}
+	}
+	kcpClient := kcpfake.NewSimpleClientset(&apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing"},
+		Spec:       apisv1alpha1.APIBindingSpec{Reference: *reference("provider", "widgets")},
+	})
+
+	binding, created, err := ensureAPIBinding(context.Background(), kcpClient, "gadgets", reference("provider", "gadgets"))
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "provider:gadgets", exportReferenceString(&binding.Spec.Reference))
+
+	_, created, err = ensureAPIBinding(context.Background(), kcpClient, "existing", reference("provider", "widgets"))
+	require.NoError(t, err)
+	require.False(t, created)
+
+	_, _, err = ensureAPIBinding(context.Background(), kcpClient, "existing", reference("provider", "gadgets"))
+	require.Error(t, err)
+}
+
+func TestBindingDone(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions conditionsapi.Conditions
+		wantDone   bool
+		wantErr    bool
+	}{
+		{
+			name: "no conditions yet",
+		},
+		{
+			name:       "bound",
+			conditions: conditionsapi.Conditions{{Type: apisv1alpha1.InitialBindingCompleted, Status: corev1.ConditionTrue}},
+			wantDone:   true,
+		},
+		{
+			name: "waiting for established",
+			conditions: conditionsapi.Conditions{
+				{Type: apisv1alpha1.APIExportValid, Status: corev1.ConditionTrue},
+				{Type: apisv1alpha1.InitialBindingCompleted, Status: corev1.ConditionFalse, Reason: apisv1alpha1.WaitingForEstablishedReason},
+			},
+		},
+		{
+			name:       "export not found yet",
+			conditions: conditionsapi.Conditions{{Type: apisv1alpha1.APIExportValid, Status: corev1.ConditionFalse, Reason: apisv1alpha1.APIExportNotFoundReason}},
+		},
+		{
+			name:       "invalid reference",
+			conditions: conditionsapi.Conditions{{Type: apisv1alpha1.APIExportValid, Status: corev1.ConditionFalse, Reason: apisv1alpha1.APIExportInvalidReferenceReason}},
+			wantDone:   true,
+			wantErr:    true,
+		},
+		{
+			name:       "naming conflicts",
+			conditions: conditionsapi.Conditions{{Type: apisv1alpha1.InitialBindingCompleted, Status: corev1.ConditionFalse, Reason: apisv1alpha1.NamingConflictsReason}},
+			wantDone:   true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binding := &apisv1alpha1.APIBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "widgets"},
+				Status:     apisv1alpha1.APIBindingStatus{Conditions: tt.conditions},
+			}
+			done, err := bindingDone(binding)
+			require.Equal(t, tt.wantDone, done)
+			require.Equal(t, tt.wantErr, err != nil, "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestPrintBindingResult(t *testing.T) {
+	binding := &apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets"},
+		Status: apisv1alpha1.APIBindingStatus{
+			BoundResources: []apisv1alpha1.BoundAPIResource{
+				{Group: "example.io", Resource: "widgets", Schema: apisv1alpha1.BoundAPIResourceSchema{Name: "v1.widgets.example.io"}, StorageVersions: []string{"v1"}},
+			},
+			Conditions: conditionsapi.Conditions{
+				{Type: apisv1alpha1.InitialBindingCompleted, Status: corev1.ConditionTrue},
+				{Type: apisv1alpha1.BindingUpToDate, Status: corev1.ConditionFalse, Reason: apisv1alpha1.NamingConflictsReason, Message: "Unable to bind APIs: gadgets.example.io conflicts"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printBindingResult(&out, binding))
+	require.Empty(t, cmp.Diff(`APIBinding "widgets" is bound.
+RESOURCE             SCHEMA                  STORAGE VERSIONS
+widgets.example.io   v1.widgets.example.io   v1
+Naming conflicts:
+  Unable to bind APIs: gadgets.example.io conflicts
+`, out.String()))
+}
+
+func TestBindingsToDelete(t *testing.T) {
+	binding := func(name, workspace, export string) *apisv1alpha1.APIBinding {
+		return &apisv1alpha1.APIBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: apisv1alpha1.APIBindingSpec{Reference: apisv1alpha1.ExportReference{
+				Workspace: &apisv1alpha1.WorkspaceExportReference{WorkspaceName: workspace, ExportName: export},
+			}},
+		}
+	}
+	kcpClient := kcpfake.NewSimpleClientset(
+		binding("widgets", "provider", "widgets"),
+		binding("widgets-2", "provider", "widgets"),
+		binding("gadgets", "provider", "gadgets"),
+	)
+	current := logicalcluster.New("root:my-org:consumer")
+
+	names, err := bindingsToDelete(context.Background(), kcpClient, current, "gadgets")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gadgets"}, names)
+
+	names, err = bindingsToDelete(context.Background(), kcpClient, current, "provider:widgets")
+	require.NoError(t, err)
+	require.Equal(t, []string{"widgets", "widgets-2"}, names)
+
+	_, err = bindingsToDelete(context.Background(), kcpClient, current, "provider:unknown")
+	require.Error(t, err)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+)
+
+// Config is the configuration of the bind commands.
+type Config struct {
+	*base.Config
+}
+
+// NewConfig load a kubeconfig with default config access
+func NewConfig(opts *base.Options) (*Config, error) {
+	config, err := base.NewConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{Config: config}, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/crd/plugin"
+)
+
+var (
+	snapshotExample = `
+	# Create APIResourceSchemas in the current workspace for the CRDs in a directory, and make
+	# them the latest schemas of the APIExport "widgets".
+	%[1]s crd snapshot -f config/crds --prefix v1 --apiexport widgets
+
+	# Print the APIResourceSchemas for deployments and ingresses of a physical cluster.
+	%[1]s crd snapshot --from-kubeconfig <pcluster-config> deployments.apps ingresses.networking.k8s.io -o yaml
+`
+)
+
+// New provides a cobra command for CRD operations.
+func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
+	opts := base.NewOptions(streams)
+
+	cmd := &cobra.Command{
+		Aliases:          []string{"crds"},
+		Use:              "crd",
+		Short:            "Manages CustomResourceDefinitions as APIResourceSchemas",
+		SilenceUsage:     true,
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	opts.BindFlags(cmd)
+
+	var snapshotOpts plugin.SnapshotOptions
+	snapshotCmd := &cobra.Command{
+		Use:          "snapshot (-f <file|directory> | --from-kubeconfig <config> [<resource>...]) [--prefix <prefix>] [--apiexport <name>] [-o json|yaml]",
+		Short:        "Convert CustomResourceDefinitions to immutable APIResourceSchemas",
+		Example:      fmt.Sprintf(snapshotExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			snapshotOpts.Resources = args
+			return kubeconfig.Snapshot(c.Context(), snapshotOpts)
+		},
+	}
+	snapshotCmd.Flags().StringSliceVarP(&snapshotOpts.Filenames, "filename", "f", snapshotOpts.Filenames, "Files or directories containing CustomResourceDefinitions, or - for stdin.")
+	snapshotCmd.Flags().StringVar(&snapshotOpts.FromKubeconfig, "from-kubeconfig", snapshotOpts.FromKubeconfig, "Kubeconfig of a cluster to pull the given resources from, as CustomResourceDefinitions or synthesized from OpenAPI for built-in types.")
+	snapshotCmd.Flags().StringVar(&snapshotOpts.Prefix, "prefix", snapshotOpts.Prefix, "Prefix of the APIResourceSchema names, e.g. a version or a date. Defaults to a hash of the schema.")
+	snapshotCmd.Flags().StringVar(&snapshotOpts.APIExport, "apiexport", snapshotOpts.APIExport, "APIExport to point to the new APIResourceSchemas. It is created if it does not exist.")
+	snapshotCmd.Flags().StringVarP(&snapshotOpts.Output, "output", "o", snapshotOpts.Output, "Print the APIResourceSchemas in the given format, one of json, yaml, instead of creating them.")
+
+	cmd.AddCommand(snapshotCmd)
+
+	return cmd, nil
+}
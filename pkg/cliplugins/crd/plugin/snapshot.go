@@ -0,0 +1,477 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/crdpuller"
+)
+
+// prefixRE matches the part of an APIResourceSchema name in front of .<plural>.<group>.
+var prefixRE = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// SnapshotOptions select the CRDs to snapshot and what to do with the resulting
+// APIResourceSchemas.
+type SnapshotOptions struct {
+	// Filenames are files or directories with CRD manifests. "-" reads from stdin.
+	Filenames []string
+	// FromKubeconfig is the kubeconfig of a cluster to pull the CRDs from, instead of files.
+	FromKubeconfig string
+	// Resources are the resources to pull from the cluster. All resources are pulled if empty.
+	Resources []string
+
+	// Prefix is put in front of .<plural>.<group> in the APIResourceSchema names. If empty,
+	// a hash of the schema is used, so that the same schema always gets the same name.
+	Prefix string
+	// APIExport is the name of an APIExport whose LatestResourceSchemas are updated
+	// to the new schemas. It is created if it does not exist.
+	APIExport string
+	// Output prints the APIResourceSchemas in the given format instead of creating them.
+	Output string
+}
+
+// Snapshot converts CRDs to APIResourceSchemas and creates them in the current workspace,
+// or prints them. APIResourceSchemas are immutable: an existing schema with the same name
+// and the same content is left alone, one with different content is an error.
+func (c *Config) Snapshot(ctx context.Context, opts SnapshotOptions) error {
+	if opts.Output != "" && opts.Output != "yaml" && opts.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of json, yaml", opts.Output)
+	}
+	if opts.Output != "" && opts.APIExport != "" {
+		return errors.New("--apiexport cannot be used together with --output")
+	}
+	if opts.Prefix != "" && !prefixRE.MatchString(opts.Prefix) {
+		return fmt.Errorf("invalid prefix %q, must match %s", opts.Prefix, prefixRE)
+	}
+
+	crds, err := c.loadCRDs(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(crds) == 0 {
+		return errors.New("no CustomResourceDefinitions found")
+	}
+
+	schemas := make([]*apisv1alpha1.APIResourceSchema, 0, len(crds))
+	for _, crd := range crds {
+		s, err := CRDToAPIResourceSchema(crd, opts.Prefix)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+
+	if opts.Output != "" {
+		return printSchemas(c.Out, schemas, opts.Output)
+	}
+
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	names := make([]string, 0, len(schemas))
+	for _, s := range schemas {
+		created, err := createSchema(ctx, kcpClient, s)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Fprintf(c.Out, "APIResourceSchema %q created.\n", s.Name)
+		} else {
+			fmt.Fprintf(c.Out, "APIResourceSchema %q unchanged.\n", s.Name)
+		}
+		names = append(names, s.Name)
+	}
+
+	if opts.APIExport == "" {
+		return nil
+	}
+	return c.updateAPIExport(ctx, kcpClient, opts.APIExport, names)
+}
+
+// CRDToAPIResourceSchema converts a CRD to an APIResourceSchema named
+// <prefix>.<plural>.<group>. If the prefix is empty, a hash of the schema spec is used.
+func CRDToAPIResourceSchema(crd *apiextensionsv1.CustomResourceDefinition, prefix string) (*apisv1alpha1.APIResourceSchema, error) {
+	if crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == apiextensionsv1.WebhookConverter {
+		return nil, fmt.Errorf("CustomResourceDefinition %s uses a conversion webhook, which is not supported by APIResourceSchemas", crd.Name)
+	}
+
+	s := &apisv1alpha1.APIResourceSchema{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apisv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "APIResourceSchema",
+		},
+		Spec: apisv1alpha1.APIResourceSchemaSpec{
+			Group: crd.Spec.Group,
+			Names: crd.Spec.Names,
+			Scope: crd.Spec.Scope,
+		},
+	}
+	for _, v := range crd.Spec.Versions {
+		version := apisv1alpha1.APIResourceVersion{
+			Name:                     v.Name,
+			Served:                   v.Served,
+			Storage:                  v.Storage,
+			Deprecated:               v.Deprecated,
+			DeprecationWarning:       v.DeprecationWarning,
+			AdditionalPrinterColumns: v.AdditionalPrinterColumns,
+		}
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return nil, fmt.Errorf("version %s of CustomResourceDefinition %s has no schema", v.Name, crd.Name)
+		}
+		raw, err := json.Marshal(v.Schema.OpenAPIV3Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the schema of version %s of CustomResourceDefinition %s: %w", v.Name, crd.Name, err)
+		}
+		version.Schema = runtime.RawExtension{Raw: raw}
+		if v.Subresources != nil {
+			version.Subresources = *v.Subresources
+		}
+		s.Spec.Versions = append(s.Spec.Versions, version)
+	}
+
+	if prefix == "" {
+		hash, err := specHash(&s.Spec)
+		if err != nil {
+			return nil, err
+		}
+		prefix = "v" + hash
+	}
+	group := s.Spec.Group
+	if group == "" {
+		group = "core"
+	}
+	s.Name = fmt.Sprintf("%s.%s.%s", prefix, s.Spec.Names.Plural, group)
+
+	return s, nil
+}
+
+// specHash returns a short hash of the normalized schema spec.
+func specHash(spec *apisv1alpha1.APIResourceSchemaSpec) (string, error) {
+	normalized, err := normalizedSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	bs, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])[:10], nil
+}
+
+// normalizedSpec returns the spec as generic JSON, so that specs can be compared
+// independently of how the raw schemas are formatted.
+func normalizedSpec(spec *apisv1alpha1.APIResourceSchemaSpec) (interface{}, error) {
+	bs, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(bs, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func specsEqual(a, b *apisv1alpha1.APIResourceSchemaSpec) (bool, error) {
+	na, err := normalizedSpec(a)
+	if err != nil {
+		return false, err
+	}
+	nb, err := normalizedSpec(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(na, nb), nil
+}
+
+// createSchema creates the APIResourceSchema. It returns false if it already exists with the
+// same spec, and an error if it exists with a different one.
+func createSchema(ctx context.Context, kcpClient kcpclientset.Interface, s *apisv1alpha1.APIResourceSchema) (bool, error) {
+	_, err := kcpClient.ApisV1alpha1().APIResourceSchemas().Create(ctx, s, metav1.CreateOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("failed to create APIResourceSchema %s: %w", s.Name, err)
+	}
+
+	existing, err := kcpClient.ApisV1alpha1().APIResourceSchemas().Get(ctx, s.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get APIResourceSchema %s: %w", s.Name, err)
+	}
+	equal, err := specsEqual(&existing.Spec, &s.Spec)
+	if err != nil {
+		return false, err
+	}
+	if !equal {
+		return false, fmt.Errorf("APIResourceSchema %s already exists with different content, choose another --prefix", s.Name)
+	}
+	return false, nil
+}
+
+// updateAPIExport points the LatestResourceSchemas of the APIExport to the given schemas,
+// replacing the schemas for the same resources. The APIExport is created if it does not exist.
+func (c *Config) updateAPIExport(ctx context.Context, kcpClient kcpclientset.Interface, name string, schemaNames []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		export, err := kcpClient.ApisV1alpha1().APIExports().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err := kcpClient.ApisV1alpha1().APIExports().Create(ctx, &apisv1alpha1.APIExport{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       apisv1alpha1.APIExportSpec{LatestResourceSchemas: schemaNames},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to create APIExport %s: %w", name, err)
+			}
+			fmt.Fprintf(c.Out, "APIExport %q created.\n", name)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get APIExport %s: %w", name, err)
+		}
+
+		latest := mergeLatestResourceSchemas(export.Spec.LatestResourceSchemas, schemaNames)
+		if reflect.DeepEqual(latest, export.Spec.LatestResourceSchemas) {
+			fmt.Fprintf(c.Out, "APIExport %q unchanged.\n", name)
+			return nil
+		}
+		export.Spec.LatestResourceSchemas = latest
+		if _, err := kcpClient.ApisV1alpha1().APIExports().Update(ctx, export, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.Out, "APIExport %q updated.\n", name)
+		return nil
+	})
+}
+
+// mergeLatestResourceSchemas replaces the schemas in latest that are for the same resource
+// as one of the given schemas, and appends the schemas for new resources.
+func mergeLatestResourceSchemas(latest, schemaNames []string) []string {
+	byResource := map[string]string{}
+	for _, name := range schemaNames {
+		byResource[schemaResource(name)] = name
+	}
+
+	merged := make([]string, 0, len(latest)+len(schemaNames))
+	seen := map[string]bool{}
+	for _, name := range latest {
+		resource := schemaResource(name)
+		if replacement, ok := byResource[resource]; ok {
+			name = replacement
+		}
+		if !seen[resource] {
+			merged = append(merged, name)
+			seen[resource] = true
+		}
+	}
+	for _, name := range schemaNames {
+		if resource := schemaResource(name); !seen[resource] {
+			merged = append(merged, name)
+			seen[resource] = true
+		}
+	}
+	return merged
+}
+
+// schemaResource returns the <plural>.<group> part of an APIResourceSchema name.
+func schemaResource(schemaName string) string {
+	if i := strings.Index(schemaName, "."); i >= 0 {
+		return schemaName[i+1:]
+	}
+	return schemaName
+}
+
+func (c *Config) loadCRDs(ctx context.Context, opts SnapshotOptions) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	if opts.FromKubeconfig != "" {
+		if len(opts.Filenames) > 0 {
+			return nil, errors.New("--filename cannot be used together with --from-kubeconfig")
+		}
+		config, err := clientcmd.BuildConfigFromFlags("", opts.FromKubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		puller, err := crdpuller.NewSchemaPuller(config)
+		if err != nil {
+			return nil, err
+		}
+		pulled, err := puller.PullCRDs(ctx, opts.Resources...)
+		if err != nil {
+			return nil, err
+		}
+		grs := make([]schema.GroupResource, 0, len(pulled))
+		for gr := range pulled {
+			grs = append(grs, gr)
+		}
+		sort.Slice(grs, func(i, j int) bool {
+			return grs[i].String() < grs[j].String()
+		})
+		crds := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(pulled))
+		for _, gr := range grs {
+			crds = append(crds, pulled[gr])
+		}
+		return crds, nil
+	}
+
+	if len(opts.Resources) > 0 {
+		return nil, errors.New("resources can only be given together with --from-kubeconfig")
+	}
+	if len(opts.Filenames) == 0 {
+		return nil, errors.New("one of --filename or --from-kubeconfig must be specified")
+	}
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, filename := range opts.Filenames {
+		fromFile, err := c.readCRDs(filename)
+		if err != nil {
+			return nil, err
+		}
+		crds = append(crds, fromFile...)
+	}
+	return crds, nil
+}
+
+// readCRDs reads the CRDs from a file, all the YAML and JSON files of a directory, or
+// stdin if the filename is "-". Other kinds of objects are skipped.
+func (c *Config) readCRDs(filename string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	if filename == "-" {
+		return decodeCRDs(c.In, "stdin")
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return decodeCRDs(f, filename)
+	}
+
+	entries, err := os.ReadDir(filename)
+	if err != nil {
+		return nil, err
+	}
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		fromFile, err := c.readCRDs(filepath.Join(filename, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		crds = append(crds, fromFile...)
+	}
+	return crds, nil
+}
+
+func decodeCRDs(r io.Reader, source string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return crds, nil
+			}
+			return nil, fmt.Errorf("failed to decode %s: %w", source, err)
+		}
+		raw.Raw = bytes.TrimSpace(raw.Raw)
+		if len(raw.Raw) == 0 || bytes.Equal(raw.Raw, []byte("null")) {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(raw.Raw, &typeMeta); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", source, err)
+		}
+		if typeMeta.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		if typeMeta.APIVersion != apiextensionsv1.SchemeGroupVersion.String() {
+			return nil, fmt.Errorf("unsupported CustomResourceDefinition version %q in %s, must be %s", typeMeta.APIVersion, source, apiextensionsv1.SchemeGroupVersion)
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := json.Unmarshal(raw.Raw, crd); err != nil {
+			return nil, fmt.Errorf("failed to decode CustomResourceDefinition in %s: %w", source, err)
+		}
+		crds = append(crds, crd)
+	}
+}
+
+func printSchemas(out io.Writer, schemas []*apisv1alpha1.APIResourceSchema, output string) error {
+	for _, s := range schemas {
+		var bs []byte
+		var err error
+		if output == "json" {
+			bs, err = json.MarshalIndent(s, "", "  ")
+			bs = append(bs, '\n')
+		} else {
+			bs, err = yaml.Marshal(s)
+			bs = append([]byte("---\n"), bs...)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(bs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
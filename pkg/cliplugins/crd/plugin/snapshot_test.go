@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kcp-dev/kcp/pkg/admission/apiresourceschema"
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+)
+
+const widgetsCRD = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-a-crd
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.io
+spec:
+  group: example.io
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              replicas:
+                type: integer
+    subresources:
+      status: {}
+`
+
+func TestCRDToAPIResourceSchema(t *testing.T) {
+	streams, in, _, _ := genericclioptions.NewTestIOStreams()
+	in.WriteString(widgetsCRD)
+	c := &Config{Config: &base.Config{IOStreams: streams}}
+
+	crds, err := c.readCRDs("-")
+	require.NoError(t, err)
+	require.Len(t, crds, 1)
+
+	s, err := CRDToAPIResourceSchema(crds[0], "v1")
+	require.NoError(t, err)
+	require.Equal(t, "v1.widgets.example.io", s.Name)
+	require.Empty(t, apiresourceschema.ValidateAPIResourceSchema(s))
+	require.Len(t, s.Spec.Versions, 1)
+	require.NotNil(t, s.Spec.Versions[0].Subresources.Status)
+
+	hashed, err := CRDToAPIResourceSchema(crds[0], "")
+	require.NoError(t, err)
+	require.Regexp(t, `^v[0-9a-f]{10}\.widgets\.example\.io$`, hashed.Name)
+	require.Empty(t, apiresourceschema.ValidateAPIResourceSchema(hashed))
+
+	again, err := CRDToAPIResourceSchema(crds[0], "")
+	require.NoError(t, err)
+	require.Equal(t, hashed.Name, again.Name, "the hash prefix must be stable")
+
+	changed := crds[0].DeepCopy()
+	changed.Spec.Versions[0].Schema.OpenAPIV3Schema.Description = "changed"
+	changedSchema, err := CRDToAPIResourceSchema(changed, "")
+	require.NoError(t, err)
+	require.NotEqual(t, hashed.Name, changedSchema.Name)
+
+	core := crds[0].DeepCopy()
+	core.Spec.Group = ""
+	coreSchema, err := CRDToAPIResourceSchema(core, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "v1.widgets.core", coreSchema.Name)
+
+	webhook := crds[0].DeepCopy()
+	webhook.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.WebhookConverter}
+	_, err = CRDToAPIResourceSchema(webhook, "v1")
+	require.Error(t, err)
+}
+
+func TestCreateSchema(t *testing.T) {
+	schema := func(raw string) *apisv1alpha1.APIResourceSchema {
+		return &apisv1alpha1.APIResourceSchema{
+			ObjectMeta: metav1.ObjectMeta{Name: "v1.widgets.example.io"},
+			Spec: apisv1alpha1.APIResourceSchemaSpec{
+				Group:    "example.io",
+				Versions: []apisv1alpha1.APIResourceVersion{{Name: "v1", Schema: runtime.RawExtension{Raw: []byte(raw)}}},
+			},
+		}
+	}
+	kcpClient := kcpfake.NewSimpleClientset()
+
+	created, err := createSchema(context.Background(), kcpClient, schema(`{"type":"object"}`))
+	require.NoError(t, err)
+	require.True(t, created)
+
+	created, err = createSchema(context.Background(), kcpClient, schema(`{ "type": "object" }`))
+	require.NoError(t, err, "differently formatted raw schemas are equal")
+	require.False(t, created)
+
+	_, err = createSchema(context.Background(), kcpClient, schema(`{"type":"string"}`))
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "different content"), err.Error())
+}
+
+func TestMergeLatestResourceSchemas(t *testing.T) {
+	require.Equal(t,
+		[]string{"v2.widgets.example.io", "v1.gadgets.example.io", "v2.gizmos.example.io"},
+		mergeLatestResourceSchemas(
+			[]string{"v1.widgets.example.io", "v1.gadgets.example.io"},
+			[]string{"v2.widgets.example.io", "v2.gizmos.example.io"},
+		),
+	)
+	require.Equal(t,
+		[]string{"v1.widgets.example.io"},
+		mergeLatestResourceSchemas(nil, []string{"v1.widgets.example.io"}),
+	)
+}
+
+func TestUpdateAPIExport(t *testing.T) {
+	kcpClient := kcpfake.NewSimpleClientset(&apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets"},
+		Spec:       apisv1alpha1.APIExportSpec{LatestResourceSchemas: []string{"v1.widgets.example.io"}},
+	})
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	c := &Config{Config: &base.Config{IOStreams: streams}}
+
+	require.NoError(t, c.updateAPIExport(context.Background(), kcpClient, "widgets", []string{"v2.widgets.example.io"}))
+	export, err := kcpClient.ApisV1alpha1().APIExports().Get(context.Background(), "widgets", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"v2.widgets.example.io"}, export.Spec.LatestResourceSchemas)
+
+	require.NoError(t, c.updateAPIExport(context.Background(), kcpClient, "gadgets", []string{"v1.gadgets.example.io"}))
+	export, err = kcpClient.ApisV1alpha1().APIExports().Get(context.Background(), "gadgets", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.gadgets.example.io"}, export.Spec.LatestResourceSchemas)
+}
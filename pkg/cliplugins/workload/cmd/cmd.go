@@ -19,12 +19,14 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
 	"github.com/kcp-dev/kcp/pkg/cliplugins/workload/plugin"
 )
 
@@ -32,6 +34,23 @@ var (
 	syncExample = `
 	# Ensure a syncer is running on the specified workload cluster.
 	%[1]s workload sync <workload-cluster-name> --syncer-image <kcp-syncer-image>
+`
+	unsyncExample = `
+	# Drain a workload cluster, remove it and its syncer's access from kcp, and print the
+	# manifests to remove the syncer and the synced namespaces from the physical cluster.
+	%[1]s workload unsync <workload-cluster-name> | kubectl --kubeconfig <pcluster-config> delete --ignore-not-found -f -
+
+	# Same, deleting the syncer and the synced namespaces from the physical cluster directly.
+	%[1]s workload unsync <workload-cluster-name> --downstream-kubeconfig <pcluster-config>
+`
+	rotateCredentialsExample = `
+	# Mint a new token for the syncer of a workload cluster, replace it in the physical cluster,
+	# and revoke the old token once the syncer has been seen using the new one.
+	%[1]s workload rotate-credentials <workload-cluster-name> --downstream-kubeconfig <pcluster-config>
+
+	# Same, applying the new secret to the physical cluster manually.
+	%[1]s workload rotate-credentials <workload-cluster-name> | kubectl --kubeconfig <pcluster-config> apply -f -
+	%[1]s workload rotate-credentials <workload-cluster-name> --revoke-stale
 `
 	cordonExample = `
 	# Mark a workload cluster as unschedulable.
@@ -44,12 +63,23 @@ var (
 	drainExample = `
 	# Start draining a workload cluster in preparation for maintenance.
 	%[1]s workload drain <workload-cluster-name>
+
+	# Drain a workload cluster, moving two namespaces at a time, and wait until it is drained.
+	%[1]s workload drain <workload-cluster-name> --max-unavailable=2 --wait --timeout=10m
+`
+	describeExample = `
+	# Show the syncer health, the locations and the placed namespaces of a workload cluster.
+	%[1]s workload describe <workload-cluster-name>
+`
+	listExample = `
+	# List the workload clusters of the current workspace with their syncer health.
+	%[1]s workload list
 `
 )
 
 // New provides a cobra command for workload operations.
 func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
-	opts := plugin.NewOptions(streams)
+	opts := base.NewOptions(streams)
 
 	cmd := &cobra.Command{
 		Aliases:          []string{"workloads"},
@@ -125,6 +155,92 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 
 	cmd.AddCommand(enableSyncerCmd)
 
+	// unsync
+	var unsyncKCPNamespaceName = "default"
+	var unsyncDownstreamKubeconfig string
+	var unsyncMaxUnavailable = 1
+	var unsyncTimeout = 5 * time.Minute
+	unsyncCmd := &cobra.Command{
+		Use:          "unsync <workload-cluster-name> [--downstream-kubeconfig <pcluster-config>]",
+		Short:        "Drain and deregister the given workload cluster, and remove its syncer",
+		Example:      fmt.Sprintf(unsyncExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			if len(unsyncKCPNamespaceName) == 0 {
+				return errors.New("a value must be specified for --kcp-namespace")
+			}
+
+			workloadClusterName := args[0]
+
+			return kubeconfig.Unsync(c.Context(), workloadClusterName, unsyncKCPNamespaceName, unsyncDownstreamKubeconfig, unsyncMaxUnavailable, unsyncTimeout)
+		},
+	}
+	unsyncCmd.Flags().StringVar(&unsyncKCPNamespaceName, "kcp-namespace", unsyncKCPNamespaceName, "The name of the kcp namespace the syncer's service account was created in.")
+	unsyncCmd.Flags().StringVar(&unsyncDownstreamKubeconfig, "downstream-kubeconfig", unsyncDownstreamKubeconfig, "Kubeconfig of the physical cluster to remove the syncer and the synced namespaces from. If empty, the manifests to delete are printed.")
+	unsyncCmd.Flags().IntVar(&unsyncMaxUnavailable, "max-unavailable", unsyncMaxUnavailable, "Maximum number of namespaces that are moved to other workload clusters at the same time.")
+	unsyncCmd.Flags().DurationVar(&unsyncTimeout, "timeout", unsyncTimeout, "Maximum time to wait for the workload cluster to be drained. Zero means no limit, blocked namespaces still fail the unsync.")
+
+	cmd.AddCommand(unsyncCmd)
+
+	// rotate-credentials
+	var rotateKCPNamespaceName = "default"
+	var rotateDownstreamKubeconfig string
+	var rotateRevokeStale bool
+	var rotateGracePeriod = 3 * time.Minute
+	var rotateTimeout = 10 * time.Minute
+	rotateCredentialsCmd := &cobra.Command{
+		Use:          "rotate-credentials <workload-cluster-name> [--downstream-kubeconfig <pcluster-config> | --revoke-stale]",
+		Short:        "Replace the token the syncer of the given workload cluster uses to connect to kcp",
+		Example:      fmt.Sprintf(rotateCredentialsExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			if len(rotateKCPNamespaceName) == 0 {
+				return errors.New("a value must be specified for --kcp-namespace")
+			}
+			if rotateRevokeStale && rotateDownstreamKubeconfig != "" {
+				return errors.New("--revoke-stale and --downstream-kubeconfig are mutually exclusive")
+			}
+
+			workloadClusterName := args[0]
+
+			if rotateRevokeStale {
+				return kubeconfig.RevokeStaleCredentials(c.Context(), workloadClusterName, rotateKCPNamespaceName, rotateGracePeriod)
+			}
+			return kubeconfig.RotateCredentials(c.Context(), workloadClusterName, rotateKCPNamespaceName, rotateDownstreamKubeconfig, rotateGracePeriod, rotateTimeout)
+		},
+	}
+	rotateCredentialsCmd.Flags().StringVar(&rotateKCPNamespaceName, "kcp-namespace", rotateKCPNamespaceName, "The name of the kcp namespace the syncer's service account was created in.")
+	rotateCredentialsCmd.Flags().StringVar(&rotateDownstreamKubeconfig, "downstream-kubeconfig", rotateDownstreamKubeconfig, "Kubeconfig of the physical cluster to update the syncer's secret in. If empty, the secret is printed.")
+	rotateCredentialsCmd.Flags().BoolVar(&rotateRevokeStale, "revoke-stale", rotateRevokeStale, "Revoke all but the newest token, once the syncer has been seen using it.")
+	rotateCredentialsCmd.Flags().DurationVar(&rotateGracePeriod, "grace-period", rotateGracePeriod, "Time for the physical cluster and the syncer to pick up an updated secret. Only heartbeats after it count as using the new token.")
+	rotateCredentialsCmd.Flags().DurationVar(&rotateTimeout, "timeout", rotateTimeout, "Maximum time to wait for a heartbeat with the new token. Zero means no limit.")
+
+	cmd.AddCommand(rotateCredentialsCmd)
+
 	// cordon
 	cordonCmd := &cobra.Command{
 		Use:          "cordon <workload-cluster-name>",
@@ -180,9 +296,12 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 	cmd.AddCommand(uncordonCmd)
 
 	// drain
+	var drainMaxUnavailable int
+	var drainWait bool
+	var drainTimeout time.Duration
 	drainCmd := &cobra.Command{
-		Use:          "drain <workload-cluster-name>",
-		Short:        "Start draining workload cluster in preparation for maintenance",
+		Use:          "drain <workload-cluster-name> [--max-unavailable <n>] [--wait [--timeout <duration>]]",
+		Short:        "Move the namespaces of a workload cluster to other workload clusters in preparation for maintenance",
 		Example:      fmt.Sprintf(drainExample, "kubectl kcp"),
 		SilenceUsage: true,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -200,11 +319,70 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 
 			workloadClusterName := args[0]
 
-			return kubeconfig.Drain(c.Context(), workloadClusterName)
+			return kubeconfig.Drain(c.Context(), workloadClusterName, drainMaxUnavailable, drainWait, drainTimeout)
 		},
 	}
+	drainCmd.Flags().IntVar(&drainMaxUnavailable, "max-unavailable", 1, "Maximum number of namespaces that are moved at the same time.")
+	drainCmd.Flags().BoolVar(&drainWait, "wait", false, "Wait until all namespaces have been moved to other workload clusters.")
+	drainCmd.Flags().DurationVar(&drainTimeout, "timeout", 0, "Maximum time to wait with --wait. Zero means no limit.")
 
 	cmd.AddCommand(drainCmd)
 
+	// describe
+	var describeOutput string
+	describeCmd := &cobra.Command{
+		Use:          "describe <workload-cluster-name> [-o json|yaml]",
+		Short:        "Show syncer health, locations and placed namespaces of a workload cluster",
+		Example:      fmt.Sprintf(describeExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			workloadClusterName := args[0]
+
+			return kubeconfig.Describe(c.Context(), workloadClusterName, describeOutput)
+		},
+	}
+	describeCmd.Flags().StringVarP(&describeOutput, "output", "o", describeOutput, "Output format, one of json, yaml. Defaults to a human-readable report")
+
+	cmd.AddCommand(describeCmd)
+
+	// list
+	var listOutput string
+	listCmd := &cobra.Command{
+		Use:          "list [-o json|yaml]",
+		Short:        "List workload clusters with their syncer health",
+		Example:      fmt.Sprintf(listExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 0 {
+				return cmd.Help()
+			}
+
+			return kubeconfig.List(c.Context(), listOutput)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", listOutput, "Output format, one of json, yaml. Defaults to a table")
+
+	cmd.AddCommand(listCmd)
+
 	return cmd, nil
 }
@@ -17,30 +17,19 @@ limitations under the License.
 package plugin
 
 import (
-	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/tools/clientcmd"
-	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
 )
 
+// Config is the configuration of the workload commands.
 type Config struct {
-	startingConfig *clientcmdapi.Config
-	overrides      *clientcmd.ConfigOverrides
-
-	genericclioptions.IOStreams
+	*base.Config
 }
 
 // NewConfig load a kubeconfig with default config access
-func NewConfig(opts *Options) (*Config, error) {
-	configAccess := clientcmd.NewDefaultClientConfigLoadingRules()
-	startingConfig, err := configAccess.GetStartingConfig()
+func NewConfig(opts *base.Options) (*Config, error) {
+	config, err := base.NewConfig(opts)
 	if err != nil {
 		return nil, err
 	}
-
-	return &Config{
-		startingConfig: startingConfig,
-		overrides:      opts.KubectlOverrides,
-
-		IOStreams: opts.IOStreams,
-	}, nil
+	return &Config{Config: config}, nil
 }
@@ -0,0 +1,433 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	kubernetesclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/third_party/conditions/util/conditions"
+)
+
+// workloadClusterReport is what "describe" and "list" show about a WorkloadCluster.
+type workloadClusterReport struct {
+	Name              string                `json:"name"`
+	Workspace         string                `json:"workspace"`
+	Ready             conditionReport       `json:"ready"`
+	Scheduling        string                `json:"scheduling"`
+	LastHeartbeatTime *metav1.Time          `json:"lastHeartbeatTime,omitempty"`
+	HeartbeatAge      string                `json:"heartbeatAge,omitempty"`
+	HeartbeatHealthy  conditionReport       `json:"heartbeatHealthy"`
+	APIImporter       conditionReport       `json:"apiImporter"`
+	SyncedResources   []string              `json:"syncedResources,omitempty"`
+	VirtualWorkspaces []string              `json:"virtualWorkspaces,omitempty"`
+	Locations         []string              `json:"locations,omitempty"`
+	Placements        []*namespacePlacement `json:"placements,omitempty"`
+	Errors            []string              `json:"errors,omitempty"`
+}
+
+type conditionReport struct {
+	Status  corev1.ConditionStatus `json:"status"`
+	Reason  string                 `json:"reason,omitempty"`
+	Message string                 `json:"message,omitempty"`
+}
+
+func (r conditionReport) String() string {
+	if r.Reason == "" {
+		return string(r.Status)
+	}
+	if r.Message == "" {
+		return fmt.Sprintf("%s (%s)", r.Status, r.Reason)
+	}
+	return fmt.Sprintf("%s (%s: %s)", r.Status, r.Reason, r.Message)
+}
+
+// namespacePlacement describes the objects of a namespace (or the cluster-scoped
+// objects of a workspace if Namespace is empty) that are assigned to a WorkloadCluster.
+type namespacePlacement struct {
+	Workspace string                            `json:"workspace"`
+	Namespace string                            `json:"namespace,omitempty"`
+	Location  string                            `json:"location,omitempty"`
+	State     schedulingv1alpha1.PlacementState `json:"state,omitempty"`
+	Objects   int                               `json:"objects"`
+	Syncing   int                               `json:"syncing"`
+}
+
+const (
+	schedulingStateSchedulable = "Schedulable"
+	schedulingStateCordoned    = "Cordoned"
+	schedulingStateDraining    = "Draining"
+)
+
+// Describe prints the health, the locations and the placed namespaces of a workload cluster.
+func (c *Config) Describe(ctx context.Context, workloadClusterName string, output string) error {
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	workloadCluster, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get WorkloadCluster %s: %w", workloadClusterName, err)
+	}
+	locations, err := kcpClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list Locations: %w", err)
+	}
+
+	report := newWorkloadClusterReport(workloadCluster, currentClusterName, locations.Items, time.Now())
+	c.addPlacements(ctx, config, workloadCluster, report)
+
+	switch output {
+	case "json", "yaml":
+		return printStructured(c.Out, report, output)
+	}
+	return printWorkloadClusterReport(c.Out, report)
+}
+
+// List prints a summary of the health and the locations of all workload clusters
+// in the current workspace.
+func (c *Config) List(ctx context.Context, output string) error {
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	workloadClusters, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list WorkloadClusters: %w", err)
+	}
+	locations, err := kcpClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list Locations: %w", err)
+	}
+
+	now := time.Now()
+	reports := make([]*workloadClusterReport, 0, len(workloadClusters.Items))
+	for i := range workloadClusters.Items {
+		reports = append(reports, newWorkloadClusterReport(&workloadClusters.Items[i], currentClusterName, locations.Items, now))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+
+	switch output {
+	case "json", "yaml":
+		return printStructured(c.Out, reports, output)
+	}
+	return printWorkloadClusterReports(c.Out, reports)
+}
+
+func validateOutput(output string) error {
+	if output != "" && output != "json" && output != "yaml" {
+		return fmt.Errorf("unsupported output format %q, must be one of json, yaml", output)
+	}
+	return nil
+}
+
+func newWorkloadClusterReport(workloadCluster *workloadv1alpha1.WorkloadCluster, clusterName logicalcluster.Name, locations []schedulingv1alpha1.Location, now time.Time) *workloadClusterReport {
+	report := &workloadClusterReport{
+		Name:              workloadCluster.Name,
+		Workspace:         clusterName.String(),
+		Ready:             newConditionReport(workloadCluster, conditionsapi.ReadyCondition),
+		Scheduling:        schedulingStateSchedulable,
+		LastHeartbeatTime: workloadCluster.Status.LastSyncerHeartbeatTime,
+		HeartbeatHealthy:  newConditionReport(workloadCluster, workloadv1alpha1.HeartbeatHealthy),
+		APIImporter:       newConditionReport(workloadCluster, workloadv1alpha1.APIImporterReady),
+		SyncedResources:   workloadCluster.Status.SyncedResources,
+	}
+
+	if workloadCluster.Spec.EvictAfter != nil {
+		report.Scheduling = schedulingStateDraining
+	} else if workloadCluster.Spec.Unschedulable {
+		report.Scheduling = schedulingStateCordoned
+	}
+	if t := workloadCluster.Status.LastSyncerHeartbeatTime; t != nil {
+		report.HeartbeatAge = duration.HumanDuration(now.Sub(t.Time))
+	}
+	for _, vw := range workloadCluster.Status.VirtualWorkspaces {
+		report.VirtualWorkspaces = append(report.VirtualWorkspaces, vw.URL)
+	}
+
+	for i := range locations {
+		location := &locations[i]
+		members, err := locationreconciler.LocationWorkloadClusters([]*workloadv1alpha1.WorkloadCluster{workloadCluster}, location)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if len(members) > 0 {
+			report.Locations = append(report.Locations, location.Name)
+		}
+	}
+	sort.Strings(report.Locations)
+
+	return report
+}
+
+func newConditionReport(workloadCluster *workloadv1alpha1.WorkloadCluster, t conditionsapi.ConditionType) conditionReport {
+	c := conditions.Get(workloadCluster, t)
+	if c == nil {
+		return conditionReport{Status: corev1.ConditionUnknown}
+	}
+	return conditionReport{Status: c.Status, Reason: c.Reason, Message: c.Message}
+}
+
+// addPlacements lists the objects assigned to the workload cluster through its syncer
+// virtual workspace, across all workspaces, and looks up the placement state of their
+// namespaces. Failures are recorded in the report, not returned: the rest of the
+// report is useful for debugging without them.
+func (c *Config) addPlacements(ctx context.Context, config *rest.Config, workloadCluster *workloadv1alpha1.WorkloadCluster, report *workloadClusterReport) {
+	if len(workloadCluster.Status.VirtualWorkspaces) == 0 {
+		return
+	}
+
+	vwConfig := rest.CopyConfig(config)
+	vwConfig.Host = strings.TrimSuffix(workloadCluster.Status.VirtualWorkspaces[0].URL, "/") + logicalcluster.Wildcard.Path()
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(vwConfig)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+	dynamicClient, err := dynamic.NewForConfig(vwConfig)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	var objs []unstructured.Unstructured
+	for _, resource := range workloadCluster.Status.SyncedResources {
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to find resource %q in the syncer virtual workspace: %v", resource, err))
+			continue
+		}
+		list, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to list %s in the syncer virtual workspace: %v", resource, err))
+			continue
+		}
+		objs = append(objs, list.Items...)
+	}
+	report.Placements = placementsOf(objs, workloadCluster.Name)
+
+	u, _, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+	clusterConfig := rest.CopyConfig(config)
+	clusterConfig.Host = u.Scheme + "://" + u.Host
+	kubeClusterClient, err := kubernetesclientset.NewClusterForConfig(clusterConfig)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+	for _, p := range report.Placements {
+		if p.Namespace == "" {
+			continue
+		}
+		ns, err := kubeClusterClient.Cluster(logicalcluster.New(p.Workspace)).CoreV1().Namespaces().Get(ctx, p.Namespace, metav1.GetOptions{})
+		if err != nil {
+			// the user might not have access to the namespace. The object counts are still helpful.
+			continue
+		}
+		p.setPlacementState(ns, workloadCluster.UID)
+	}
+}
+
+// placementsOf groups the objects assigned to the given workload cluster by workspace
+// and namespace, counting how many of them are in the Sync state.
+func placementsOf(objs []unstructured.Unstructured, workloadClusterName string) []*namespacePlacement {
+	byNamespace := map[string]*namespacePlacement{}
+	for i := range objs {
+		obj := &objs[i]
+		state, found := obj.GetLabels()[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+workloadClusterName]
+		if !found {
+			continue
+		}
+		key := obj.GetClusterName() + "|" + obj.GetNamespace()
+		p, found := byNamespace[key]
+		if !found {
+			p = &namespacePlacement{Workspace: obj.GetClusterName(), Namespace: obj.GetNamespace()}
+			byNamespace[key] = p
+		}
+		p.Objects++
+		if workloadv1alpha1.ResourceState(state) == workloadv1alpha1.ResourceStateSync {
+			p.Syncing++
+		}
+	}
+
+	placements := make([]*namespacePlacement, 0, len(byNamespace))
+	for _, p := range byNamespace {
+		placements = append(placements, p)
+	}
+	sort.Slice(placements, func(i, j int) bool {
+		if placements[i].Workspace != placements[j].Workspace {
+			return placements[i].Workspace < placements[j].Workspace
+		}
+		return placements[i].Namespace < placements[j].Namespace
+	})
+	return placements
+}
+
+// setPlacementState sets location and state from the placement annotation of the
+// namespace, whose keys have the format <location>+<workload-cluster-uid>.
+func (p *namespacePlacement) setPlacementState(ns *corev1.Namespace, uid types.UID) {
+	value, found := ns.Annotations[schedulingv1alpha1.PlacementAnnotationKey]
+	if !found {
+		return
+	}
+	var placement schedulingv1alpha1.PlacementAnnotation
+	if err := json.Unmarshal([]byte(value), &placement); err != nil {
+		return
+	}
+	for key, state := range placement {
+		if location := strings.TrimSuffix(key, "+"+string(uid)); location != key {
+			p.Location = location
+			p.State = state
+			return
+		}
+	}
+}
+
+func printStructured(out io.Writer, obj interface{}, output string) error {
+	var bs []byte
+	var err error
+	if output == "json" {
+		bs, err = json.MarshalIndent(obj, "", "  ")
+		bs = append(bs, '\n')
+	} else {
+		bs, err = yaml.Marshal(obj)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(bs)
+	return err
+}
+
+func printWorkloadClusterReport(out io.Writer, report *workloadClusterReport) error {
+	w := printers.GetNewTabWriter(out)
+
+	heartbeat := "<never>"
+	if report.LastHeartbeatTime != nil {
+		heartbeat = fmt.Sprintf("%s (%s ago)", report.LastHeartbeatTime.UTC().Format(time.RFC3339), report.HeartbeatAge)
+	}
+
+	fmt.Fprintf(w, "Name:\t%s\n", report.Name)
+	fmt.Fprintf(w, "Workspace:\t%s\n", report.Workspace)
+	fmt.Fprintf(w, "Ready:\t%s\n", report.Ready)
+	fmt.Fprintf(w, "Scheduling:\t%s\n", report.Scheduling)
+	fmt.Fprintf(w, "Last Heartbeat:\t%s\n", heartbeat)
+	fmt.Fprintf(w, "Heartbeat Healthy:\t%s\n", report.HeartbeatHealthy)
+	fmt.Fprintf(w, "API Importer:\t%s\n", report.APIImporter)
+	fmt.Fprintf(w, "Synced Resources:\t%s\n", helpers.JoinOrNone(report.SyncedResources))
+	fmt.Fprintf(w, "Virtual Workspaces:\t%s\n", helpers.JoinOrNone(report.VirtualWorkspaces))
+	fmt.Fprintf(w, "Locations:\t%s\n", helpers.JoinOrNone(report.Locations))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Placements:")
+	if len(report.Placements) == 0 {
+		fmt.Fprintln(out, "  <none>")
+	} else {
+		w = printers.GetNewTabWriter(out)
+		fmt.Fprintln(w, "  WORKSPACE\tNAMESPACE\tLOCATION\tSTATE\tOBJECTS\tSYNCING")
+		for _, p := range report.Placements {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%d\t%d\n", p.Workspace, helpers.ValueOrNone(p.Namespace), helpers.ValueOrNone(p.Location), helpers.ValueOrNone(string(p.State)), p.Objects, p.Syncing)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		fmt.Fprintln(out, "Errors:")
+		for _, e := range report.Errors {
+			fmt.Fprintf(out, "  %s\n", e)
+		}
+	}
+	return nil
+}
+
+func printWorkloadClusterReports(out io.Writer, reports []*workloadClusterReport) error {
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, "NAME\tREADY\tSCHEDULING\tHEARTBEAT\tHEARTBEAT HEALTHY\tAPI IMPORTER\tLOCATIONS")
+	for _, r := range reports {
+		heartbeat := "<never>"
+		if r.LastHeartbeatTime != nil {
+			heartbeat = r.HeartbeatAge
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Ready.Status, r.Scheduling, heartbeat, r.HeartbeatHealthy.Status, r.APIImporter.Status, helpers.JoinOrNone(r.Locations))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+)
+
+func TestWorkloadClusterReport(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	workloadCluster := &workloadv1alpha1.WorkloadCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "us-east1",
+			UID:    "uid-1",
+			Labels: map[string]string{"region": "us-east"},
+		},
+		Spec: workloadv1alpha1.WorkloadClusterSpec{
+			Unschedulable: true,
+		},
+		Status: workloadv1alpha1.WorkloadClusterStatus{
+			Conditions: conditionsapi.Conditions{
+				{Type: conditionsapi.ReadyCondition, Status: corev1.ConditionTrue},
+				{Type: workloadv1alpha1.HeartbeatHealthy, Status: corev1.ConditionFalse, Reason: workloadv1alpha1.ErrorHeartbeatMissedReason, Message: "No heartbeat since 2m"},
+			},
+			SyncedResources:         []string{"deployments.apps", "services"},
+			LastSyncerHeartbeatTime: &metav1.Time{Time: now.Add(-2 * time.Minute)},
+			VirtualWorkspaces:       []workloadv1alpha1.VirtualWorkspace{{URL: "https://kcp/services/syncer/root:org:compute/us-east1"}},
+		},
+	}
+	locations := []schedulingv1alpha1.Location{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "us"},
+			Spec:       schedulingv1alpha1.LocationSpec{InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us-east"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "eu"},
+			Spec:       schedulingv1alpha1.LocationSpec{InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu-west"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "all"},
+			Spec:       schedulingv1alpha1.LocationSpec{InstanceSelector: &metav1.LabelSelector{}},
+		},
+	}
+
+	report := newWorkloadClusterReport(workloadCluster, logicalcluster.New("root:org:compute"), locations, now)
+	require.Equal(t, schedulingStateCordoned, report.Scheduling)
+	require.Equal(t, "2m", report.HeartbeatAge)
+	require.Equal(t, corev1.ConditionTrue, report.Ready.Status)
+	require.Equal(t, conditionReport{Status: corev1.ConditionFalse, Reason: workloadv1alpha1.ErrorHeartbeatMissedReason, Message: "No heartbeat since 2m"}, report.HeartbeatHealthy)
+	require.Equal(t, conditionReport{Status: corev1.ConditionUnknown}, report.APIImporter)
+	require.Equal(t, []string{"all", "us"}, report.Locations)
+	require.Equal(t, []string{"https://kcp/services/syncer/root:org:compute/us-east1"}, report.VirtualWorkspaces)
+
+	newObject := func(clusterName, namespace, name string, labels map[string]string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetClusterName(clusterName)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		return obj
+	}
+	stateLabel := workloadv1alpha1.InternalClusterResourceStateLabelPrefix + "us-east1"
+	report.Placements = placementsOf([]unstructured.Unstructured{
+		newObject("root:org:ws2", "default", "a", map[string]string{stateLabel: "Sync"}),
+		newObject("root:org:ws1", "app", "a", map[string]string{stateLabel: "Sync"}),
+		newObject("root:org:ws1", "app", "b", map[string]string{stateLabel: ""}),
+		newObject("root:org:ws1", "app", "c", map[string]string{workloadv1alpha1.InternalClusterResourceStateLabelPrefix + "other": "Sync"}),
+	}, "us-east1")
+	require.Equal(t, []*namespacePlacement{
+		{Workspace: "root:org:ws1", Namespace: "app", Objects: 2, Syncing: 1},
+		{Workspace: "root:org:ws2", Namespace: "default", Objects: 1, Syncing: 1},
+	}, report.Placements)
+
+	report.Placements[0].setPlacementState(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "app",
+			Annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: `{"eu+uid-2":"Removing","us+uid-1":"Bound"}`,
+			},
+		},
+	}, workloadCluster.UID)
+	require.Equal(t, "us", report.Placements[0].Location)
+	require.Equal(t, schedulingv1alpha1.PlacementStateBound, report.Placements[0].State)
+
+	var out bytes.Buffer
+	require.NoError(t, printWorkloadClusterReport(&out, report))
+	require.Equal(t, `Name:                 us-east1
+Workspace:            root:org:compute
+Ready:                True
+Scheduling:           Cordoned
+Last Heartbeat:       2022-06-01T11:58:00Z (2m ago)
+Heartbeat Healthy:    False (ErrorHeartbeat: No heartbeat since 2m)
+API Importer:         Unknown
+Synced Resources:     deployments.apps, services
+Virtual Workspaces:   https://kcp/services/syncer/root:org:compute/us-east1
+Locations:            all, us
+Placements:
+  WORKSPACE      NAMESPACE   LOCATION   STATE    OBJECTS   SYNCING
+  root:org:ws1   app         us         Bound    2         1
+  root:org:ws2   default     <none>     <none>   1         1
+`, out.String())
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/third_party/conditions/util/conditions"
+)
+
+const drainPollInterval = 2 * time.Second
+
+// Drain cordons the workload cluster and requests kcp to move every namespace assigned
+// to it onto another ready workload cluster in the same location, no more than
+// maxUnavailable at the same time. With wait, it waits until the workload cluster
+// reports to be drained.
+func (c *Config) Drain(ctx context.Context, workloadClusterName string, maxUnavailable int, waitForDrain bool, timeout time.Duration) error {
+	if maxUnavailable < 1 {
+		return fmt.Errorf("max-unavailable must be at least 1")
+	}
+
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	// Cordon such that no new namespaces are assigned, and request the drain. Setting
+	// evictAfter is left to the user: it makes the scheduler move namespaces without
+	// waiting for the new placement to be synced.
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				workloadv1alpha1.DrainMaxUnavailableAnnotation: strconv.Itoa(maxUnavailable),
+			},
+		},
+		"spec": map[string]interface{}{
+			"unschedulable": true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Patch(ctx, workloadClusterName, types.MergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update WorkloadCluster %s: %w", workloadClusterName, err)
+	}
+
+	if !waitForDrain {
+		fmt.Fprintln(c.Out, workloadClusterName, "draining")
+		return nil
+	}
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	fmt.Fprintln(c.Out, "waiting for", workloadClusterName, "to be drained")
+	var reported string
+	err = wait.PollImmediateUntilWithContext(waitCtx, drainPollInterval, func(ctx context.Context) (bool, error) {
+		workloadCluster, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get WorkloadCluster %s: %w", workloadClusterName, err)
+		}
+		if conditions.IsTrue(workloadCluster, workloadv1alpha1.WorkloadClusterDrained) {
+			return true, nil
+		}
+		// Blocked namespaces stay until the user intervenes, do not wait for them.
+		if conditions.GetReason(workloadCluster, workloadv1alpha1.WorkloadClusterDrained) == workloadv1alpha1.NamespacesBlockedReason {
+			return false, fmt.Errorf("%s cannot be drained: %s", workloadClusterName, conditions.GetMessage(workloadCluster, workloadv1alpha1.WorkloadClusterDrained))
+		}
+		if message := conditions.GetMessage(workloadCluster, workloadv1alpha1.WorkloadClusterDrained); message != "" && message != reported {
+			reported = message
+			fmt.Fprintf(c.Out, "%s draining: %s\n", workloadClusterName, message)
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout || waitCtx.Err() != nil {
+		return fmt.Errorf("timed out waiting for %s to be drained", workloadClusterName)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.Out, workloadClusterName, "drained")
+	return nil
+}
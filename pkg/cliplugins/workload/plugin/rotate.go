@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubernetesclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// RotateCredentials mints a new token for the syncer of the given workload cluster and
+// replaces the syncer's secret in the pcluster with it, using the given downstream
+// kubeconfig. The syncer reads the token from the mounted secret, and picks it up without
+// a restart. Once a heartbeat of the syncer has been seen after the grace period, the old
+// tokens are revoked.
+//
+// Without a downstream kubeconfig the new secret is printed instead. The old tokens are
+// then revoked by calling RevokeStaleCredentials after the secret has been applied.
+func (c *Config) RotateCredentials(ctx context.Context, workloadClusterName, kcpNamespaceName, downstreamKubeconfig string, gracePeriod, timeout time.Duration) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	configURL, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+	kubeClient, err := kubernetesclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	if _, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get WorkloadCluster %s: %w", workloadClusterName, err)
+	}
+
+	tokenSecret, err := mintSyncerToken(ctx, kubeClient, workloadClusterName, kcpNamespaceName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.ErrOut, "minted token %s/%s\n", kcpNamespaceName, tokenSecret.Name)
+
+	input := templateInput{
+		ServerURL:       configURL.Scheme + "://" + configURL.Host,
+		CAData:          base64.StdEncoding.EncodeToString(config.CAData),
+		Token:           string(tokenSecret.Data["token"]),
+		KCPNamespace:    kcpNamespaceName,
+		LogicalCluster:  currentClusterName.String(),
+		WorkloadCluster: workloadClusterName,
+	}
+	secretYAML, err := renderSyncerSecret(input)
+	if err != nil {
+		return err
+	}
+
+	if downstreamKubeconfig == "" {
+		if _, err := c.Out.Write(secretYAML); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.ErrOut, "apply the secret to the pcluster, then revoke the old tokens with --revoke-stale\n")
+		return nil
+	}
+
+	downstreamConfig, err := clientcmd.BuildConfigFromFlags("", downstreamKubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load downstream kubeconfig: %w", err)
+	}
+	downstreamClient, err := kubernetesclientset.NewForConfig(downstreamConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create downstream kubernetes client: %w", err)
+	}
+	var secret corev1.Secret
+	if err := yaml.Unmarshal(secretYAML, &secret); err != nil {
+		return err
+	}
+	if _, err := downstreamClient.CoreV1().Secrets(secret.Namespace).Update(ctx, &secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update downstream Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	fmt.Fprintf(c.ErrOut, "updated downstream secret %s/%s\n", secret.Namespace, secret.Name)
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	since := time.Now().Add(gracePeriod)
+	fmt.Fprintf(c.ErrOut, "waiting for a heartbeat of %s after %s\n", workloadClusterName, since.UTC().Format(time.RFC3339))
+	if err := wait.PollImmediateUntilWithContext(waitCtx, drainPollInterval, func(ctx context.Context) (bool, error) {
+		workloadCluster, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return heartbeatSince(workloadCluster, since), nil
+	}); err != nil {
+		return fmt.Errorf("no heartbeat of %s seen with the new token, the old tokens were not revoked: %w", workloadClusterName, err)
+	}
+
+	return c.revokeStaleSyncerTokens(ctx, kubeClient, workloadClusterName, kcpNamespaceName, tokenSecret.Name)
+}
+
+// RevokeStaleCredentials revokes all tokens of the syncer of the given workload cluster
+// but the newest, provided a heartbeat of the syncer has been seen after the grace period
+// following the creation of the newest token.
+func (c *Config) RevokeStaleCredentials(ctx context.Context, workloadClusterName, kcpNamespaceName string, gracePeriod time.Duration) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+	kubeClient, err := kubernetesclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	tokens, err := syncerTokens(ctx, kubeClient, workloadClusterName, kcpNamespaceName)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("no tokens found for the syncer of %s", workloadClusterName)
+	}
+	newest := tokens[len(tokens)-1]
+
+	workloadCluster, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get WorkloadCluster %s: %w", workloadClusterName, err)
+	}
+	since := newest.CreationTimestamp.Add(gracePeriod)
+	if !heartbeatSince(workloadCluster, since) {
+		return fmt.Errorf("no heartbeat of %s seen since %s, make sure the new secret has been applied", workloadClusterName, since.UTC().Format(time.RFC3339))
+	}
+
+	return c.revokeStaleSyncerTokens(ctx, kubeClient, workloadClusterName, kcpNamespaceName, newest.Name)
+}
+
+// mintSyncerToken creates a new token secret for the service account of the syncer and
+// waits for the token to be populated.
+func mintSyncerToken(ctx context.Context, kubeClient kubernetesclientset.Interface, workloadClusterName, namespace string) (*corev1.Secret, error) {
+	authResourceName := SyncerAuthResourcePrefix + workloadClusterName
+	if _, err := kubeClient.CoreV1().ServiceAccounts(namespace).Get(ctx, authResourceName, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get ServiceAccount %s/%s: %w", namespace, authResourceName, err)
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: authResourceName + "-token-",
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: authResourceName,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token Secret for ServiceAccount %s/%s: %w", namespace, authResourceName, err)
+	}
+
+	// Wait for the token controller to populate the token
+	err = wait.PollImmediateWithContext(ctx, 100*time.Millisecond, 20*time.Second, func(ctx context.Context) (bool, error) {
+		secret, err = kubeClient.CoreV1().Secrets(namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return len(secret.Data["token"]) > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out waiting for the token to be set on Secret %s/%s", namespace, secret.Name)
+	}
+	return secret, nil
+}
+
+// syncerTokens returns the token secrets of the service account of the syncer, oldest first.
+func syncerTokens(ctx context.Context, kubeClient kubernetesclientset.Interface, workloadClusterName, namespace string) ([]corev1.Secret, error) {
+	authResourceName := SyncerAuthResourcePrefix + workloadClusterName
+	secrets, err := kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Secrets in namespace %s: %w", namespace, err)
+	}
+	var tokens []corev1.Secret
+	for _, secret := range secrets.Items {
+		if secret.Type == corev1.SecretTypeServiceAccountToken && secret.Annotations[corev1.ServiceAccountNameKey] == authResourceName {
+			tokens = append(tokens, secret)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if !tokens[i].CreationTimestamp.Equal(&tokens[j].CreationTimestamp) {
+			return tokens[i].CreationTimestamp.Before(&tokens[j].CreationTimestamp)
+		}
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
+}
+
+// revokeStaleSyncerTokens deletes all token secrets of the service account of the syncer
+// but the given one.
+func (c *Config) revokeStaleSyncerTokens(ctx context.Context, kubeClient kubernetesclientset.Interface, workloadClusterName, namespace, keep string) error {
+	tokens, err := syncerTokens(ctx, kubeClient, workloadClusterName, namespace)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.Name == keep {
+			continue
+		}
+		if err := kubeClient.CoreV1().Secrets(namespace).Delete(ctx, token.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to revoke token %s/%s: %w", namespace, token.Name, err)
+		}
+		fmt.Fprintf(c.ErrOut, "revoked token %s/%s\n", namespace, token.Name)
+	}
+	return nil
+}
+
+func heartbeatSince(workloadCluster *workloadv1alpha1.WorkloadCluster, since time.Time) bool {
+	return workloadCluster.Status.LastSyncerHeartbeatTime != nil && workloadCluster.Status.LastSyncerHeartbeatTime.Time.After(since)
+}
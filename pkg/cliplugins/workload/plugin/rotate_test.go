@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+)
+
+func TestNewSyncerSecretYAML(t *testing.T) {
+	expectedYAML := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: kcp-syncer-config
+  namespace:  kcpsync25e6e3ce5be10b16411448aec95b6b6d695a1daa5120732019531d8d
+stringData:
+  kubeconfig: |
+    apiVersion: v1
+    kind: Config
+    clusters:
+    - name: default-cluster
+      cluster:
+        certificate-authority-data: ca-data
+        server: server-url
+    contexts:
+    - name: default-context
+      context:
+        cluster: default-cluster
+        namespace: kcp-namespace
+        user: default-user
+    current-context: default-context
+    users:
+    - name: default-user
+      user:
+        tokenFile: /kcp/token
+  token: new-token
+`
+	actualYAML, err := renderSyncerSecret(templateInput{
+		ServerURL:       "server-url",
+		CAData:          "ca-data",
+		Token:           "new-token",
+		KCPNamespace:    "kcp-namespace",
+		LogicalCluster:  "root:default:foo",
+		WorkloadCluster: "workload-cluster-name",
+	})
+	require.NoError(t, err)
+	require.Empty(t, cmp.Diff(expectedYAML, string(actualYAML)))
+}
+
+func TestRevokeStaleSyncerTokens(t *testing.T) {
+	now := time.Now()
+	token := func(name, serviceAccount string, created time.Time) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		}
+	}
+	kubeClient := kubefake.NewSimpleClientset(
+		token("syncer-us-east1-token-old", "syncer-us-east1", now.Add(-time.Hour)),
+		token("syncer-us-east1-token-new", "syncer-us-east1", now),
+		token("syncer-us-west1-token-old", "syncer-us-west1", now.Add(-time.Hour)),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}},
+	)
+
+	tokens, err := syncerTokens(context.Background(), kubeClient, "us-east1", "default")
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "syncer-us-east1-token-new", tokens[1].Name)
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	c := &Config{Config: &base.Config{IOStreams: streams}}
+	require.NoError(t, c.revokeStaleSyncerTokens(context.Background(), kubeClient, "us-east1", "default", "syncer-us-east1-token-new"))
+
+	secrets, err := kubeClient.CoreV1().Secrets("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, secret := range secrets.Items {
+		names = append(names, secret.Name)
+	}
+	require.ElementsMatch(t, []string{"syncer-us-east1-token-new", "syncer-us-west1-token-old", "unrelated"}, names)
+}
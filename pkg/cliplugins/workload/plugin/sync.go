@@ -39,7 +39,6 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubernetesclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
@@ -58,6 +57,11 @@ const (
 	// The name of the key for the upstream config in the pcluster secret.
 	SyncerSecretConfigKey = "kubeconfig"
 
+	// The name of the key for the upstream token in the pcluster secret. The upstream
+	// config refers to it as a token file, such that the syncer picks up a rotated
+	// token without a restart.
+	SyncerSecretTokenKey = "token"
+
 	// The prefix for syncer-supporting auth resources in kcp.
 	SyncerAuthResourcePrefix = "syncer-"
 
@@ -76,7 +80,7 @@ const (
 // Sync prepares a kcp workspace for use with a syncer and outputs the
 // configuration required to deploy a syncer to the pcluster to stdout.
 func (c *Config) Sync(ctx context.Context, workloadClusterName, kcpNamespaceName, image string, resourcesToSync []string, replicas int) error {
-	config, err := clientcmd.NewDefaultClientConfig(*c.startingConfig, c.overrides).ClientConfig()
+	config, err := c.ClientConfig()
 	if err != nil {
 		return err
 	}
@@ -393,6 +397,8 @@ type templateArgs struct {
 	Secret string
 	// Key in the syncer secret for the kcp logical cluster kubconfig.
 	SecretConfigKey string
+	// Key in the syncer secret for the token the kubeconfig refers to.
+	SecretTokenKey string
 	// Deployment is the name of the deployment that will run the syncer in the
 	// pcluster.
 	Deployment string
@@ -407,6 +413,16 @@ type templateArgs struct {
 // cluster role and role binding would be owned by the namespace to ensure cleanup on deletion
 // of the namespace.
 func renderSyncerResources(input templateInput) ([]byte, error) {
+	return renderSyncerTemplate("syncer.yaml", input)
+}
+
+// renderSyncerSecret renders the secret holding the syncer's kcp kubeconfig and token, e.g. to
+// replace it in a pcluster after rotating the token.
+func renderSyncerSecret(input templateInput) ([]byte, error) {
+	return renderSyncerTemplate("syncer-secret.yaml", input)
+}
+
+func renderSyncerTemplate(name string, input templateInput) ([]byte, error) {
 	syncerID := GetSyncerID(input.LogicalCluster, input.WorkloadCluster)
 
 	tmplArgs := templateArgs{
@@ -419,20 +435,17 @@ func renderSyncerResources(input templateInput) ([]byte, error) {
 		GroupMappings:           getGroupMappings(input.ResourcesToSync),
 		Secret:                  SyncerSecretName,
 		SecretConfigKey:         SyncerSecretConfigKey,
+		SecretTokenKey:          SyncerSecretTokenKey,
 		Deployment:              SyncerResourceName,
 		DeploymentApp:           syncerID,
 	}
 
-	syncerTemplate, err := embeddedResources.ReadFile("syncer.yaml")
-	if err != nil {
-		return nil, err
-	}
-	tmpl, err := template.New("syncerTemplate").Parse(string(syncerTemplate))
+	tmpl, err := template.ParseFS(embeddedResources, "syncer.yaml", "syncer-secret.yaml")
 	if err != nil {
 		return nil, err
 	}
 	buffer := bytes.NewBuffer([]byte{})
-	err = tmpl.Execute(buffer, tmplArgs)
+	err = tmpl.ExecuteTemplate(buffer, name, tmplArgs)
 	if err != nil {
 		return nil, err
 	}
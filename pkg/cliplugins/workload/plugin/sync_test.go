@@ -105,7 +105,8 @@ stringData:
     users:
     - name: default-user
       user:
-        token: token
+        tokenFile: /kcp/token
+  token: token
 ---
 apiVersion: apps/v1
 kind: Deployment
@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+// Unsync is the reverse of Sync: it drains the workload cluster, revokes the syncer's
+// service account token and RBAC in kcp, and deletes the workload cluster. The resources
+// deployed to the pcluster by Sync and the namespaces synced there are then deleted with
+// the given downstream kubeconfig, or, if none is given, printed to stdout for the user to
+// delete them.
+func (c *Config) Unsync(ctx context.Context, workloadClusterName, kcpNamespaceName, downstreamKubeconfig string, maxUnavailable int, timeout time.Duration) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	kcpClient, err := kcpclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+	kubeClient, err := kubernetesclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	// Remember the namespaces of the workspace before draining. Their names in the
+	// pcluster are derived from the upstream names, such that they can be cleaned
+	// up without access to the pcluster.
+	namespaces, err := kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var syncedNamespaces []syncedNamespace
+	for _, ns := range namespaces.Items {
+		downstream, err := shared.PhysicalClusterNamespaceName(shared.NamespaceLocator{LogicalCluster: currentClusterName, Namespace: ns.Name})
+		if err != nil {
+			return err
+		}
+		syncedNamespaces = append(syncedNamespaces, syncedNamespace{Upstream: ns.Name, Downstream: downstream})
+	}
+
+	_, err = kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx, workloadClusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		fmt.Fprintln(c.ErrOut, workloadClusterName, "already deleted")
+	case err != nil:
+		return fmt.Errorf("failed to get WorkloadCluster %s: %w", workloadClusterName, err)
+	default:
+		// progress goes to stderr such that stdout only carries the cleanup manifests
+		drainer := *c
+		drainer.Out = c.ErrOut
+		if err := drainer.Drain(ctx, workloadClusterName, maxUnavailable, true, timeout); err != nil {
+			return err
+		}
+	}
+
+	if err := revokeSyncerForWorkspace(ctx, kubeClient, workloadClusterName, kcpNamespaceName); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.ErrOut, "syncer access revoked")
+
+	if err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Delete(ctx, workloadClusterName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete WorkloadCluster %s: %w", workloadClusterName, err)
+	}
+	fmt.Fprintln(c.ErrOut, workloadClusterName, "deleted")
+
+	if downstreamKubeconfig == "" {
+		resources, err := renderSyncerCleanupResources(currentClusterName, workloadClusterName, syncedNamespaces)
+		if err != nil {
+			return err
+		}
+		_, err = c.Out.Write(resources)
+		return err
+	}
+
+	downstreamConfig, err := clientcmd.BuildConfigFromFlags("", downstreamKubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load downstream kubeconfig: %w", err)
+	}
+	downstreamClient, err := kubernetesclientset.NewForConfig(downstreamConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create downstream kubernetes client: %w", err)
+	}
+	return c.deleteSyncerResources(ctx, downstreamClient, currentClusterName, workloadClusterName)
+}
+
+// revokeSyncerForWorkspace deletes the service account of the syncer, its token secrets
+// and its cluster role binding. These are owned by the workload cluster, but deleting them
+// explicitly makes sure the syncer loses access immediately.
+func revokeSyncerForWorkspace(ctx context.Context, kubeClient kubernetesclientset.Interface, workloadClusterName, namespace string) error {
+	authResourceName := SyncerAuthResourcePrefix + workloadClusterName
+
+	if err := kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, authResourceName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ClusterRoleBinding %s: %w", authResourceName, err)
+	}
+
+	sa, err := kubeClient.CoreV1().ServiceAccounts(namespace).Get(ctx, authResourceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get ServiceAccount %s/%s: %w", namespace, authResourceName, err)
+	}
+	for _, secret := range sa.Secrets {
+		if err := kubeClient.CoreV1().Secrets(namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Secret %s/%s: %w", namespace, secret.Name, err)
+		}
+	}
+	if err := kubeClient.CoreV1().ServiceAccounts(namespace).Delete(ctx, authResourceName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ServiceAccount %s/%s: %w", namespace, authResourceName, err)
+	}
+	return nil
+}
+
+// deleteSyncerResources deletes the resources deployed to the pcluster by Sync, and the
+// namespaces the syncer created for the given logical cluster.
+func (c *Config) deleteSyncerResources(ctx context.Context, downstreamClient kubernetesclientset.Interface, logicalClusterName logicalcluster.Name, workloadClusterName string) error {
+	syncerID := GetSyncerID(logicalClusterName.String(), workloadClusterName)
+
+	if err := downstreamClient.CoreV1().Namespaces().Delete(ctx, syncerID, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete downstream Namespace %s: %w", syncerID, err)
+	}
+	if err := downstreamClient.RbacV1().ClusterRoleBindings().Delete(ctx, syncerID, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete downstream ClusterRoleBinding %s: %w", syncerID, err)
+	}
+	if err := downstreamClient.RbacV1().ClusterRoles().Delete(ctx, syncerID, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete downstream ClusterRole %s: %w", syncerID, err)
+	}
+	fmt.Fprintf(c.ErrOut, "syncer %s removed from the pcluster\n", syncerID)
+
+	namespaces, err := downstreamClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: workloadv1alpha1.InternalDownstreamClusterLabel + "=" + workloadClusterName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list downstream namespaces: %w", err)
+	}
+	sort.Slice(namespaces.Items, func(i, j int) bool {
+		return namespaces.Items[i].Name < namespaces.Items[j].Name
+	})
+	for _, ns := range namespaces.Items {
+		locator, err := shared.LocatorFromAnnotations(ns.Annotations)
+		if err != nil || locator == nil || locator.LogicalCluster != logicalClusterName {
+			// synced from another workspace with a workload cluster of the same name
+			continue
+		}
+		if err := downstreamClient.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete downstream Namespace %s: %w", ns.Name, err)
+		}
+		fmt.Fprintf(c.ErrOut, "namespace %s synced from %s deleted from the pcluster\n", ns.Name, locator.Namespace)
+	}
+	return nil
+}
+
+// syncedNamespace is a namespace of a workspace and its name in a pcluster.
+type syncedNamespace struct {
+	Upstream   string
+	Downstream string
+}
+
+// cleanupTemplateArgs represents the arguments required to render the resources to
+// delete from a pcluster when a syncer is removed.
+type cleanupTemplateArgs struct {
+	LogicalCluster     string
+	WorkloadCluster    string
+	Namespace          string
+	ClusterRole        string
+	ClusterRoleBinding string
+	SyncedNamespaces   []syncedNamespace
+}
+
+// renderSyncerCleanupResources renders the resources deployed to a pcluster by Sync, and
+// the namespaces the syncer might have created for the given namespaces of the logical
+// cluster, such that they can be deleted with kubectl.
+func renderSyncerCleanupResources(logicalClusterName logicalcluster.Name, workloadClusterName string, syncedNamespaces []syncedNamespace) ([]byte, error) {
+	syncerID := GetSyncerID(logicalClusterName.String(), workloadClusterName)
+
+	sort.Slice(syncedNamespaces, func(i, j int) bool {
+		return syncedNamespaces[i].Upstream < syncedNamespaces[j].Upstream
+	})
+	tmplArgs := cleanupTemplateArgs{
+		LogicalCluster:     logicalClusterName.String(),
+		WorkloadCluster:    workloadClusterName,
+		Namespace:          syncerID,
+		ClusterRole:        syncerID,
+		ClusterRoleBinding: syncerID,
+		SyncedNamespaces:   syncedNamespaces,
+	}
+
+	unsyncerTemplate, err := embeddedResources.ReadFile("unsyncer.yaml")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("unsyncerTemplate").Parse(string(unsyncerTemplate))
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer([]byte{})
+	if err := tmpl.Execute(buffer, tmplArgs); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+)
+
+func TestUnsyncerYAML(t *testing.T) {
+	expectedYAML := `# Remove the syncer of workload cluster workload-cluster-name for logical cluster root:default:foo
+# and the namespaces it synced with:
+#
+#   kubectl delete --ignore-not-found -f <this file>
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kcpsync25e6e3ce5be10b16411448aec95b6b6d695a1daa5120732019531d8d
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: kcpsync25e6e3ce5be10b16411448aec95b6b6d695a1daa5120732019531d8d
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kcpsync25e6e3ce5be10b16411448aec95b6b6d695a1daa5120732019531d8d
+---
+# synced from namespace app
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kcp-app
+---
+# synced from namespace default
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kcp-default
+`
+	actualYAML, err := renderSyncerCleanupResources(logicalcluster.New("root:default:foo"), "workload-cluster-name", []syncedNamespace{
+		{Upstream: "default", Downstream: "kcp-default"},
+		{Upstream: "app", Downstream: "kcp-app"},
+	})
+	require.NoError(t, err)
+	require.Empty(t, cmp.Diff(expectedYAML, string(actualYAML)))
+}
+
+func TestRevokeSyncerForWorkspace(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "syncer-us-east1", Namespace: "default"},
+			Secrets:    []corev1.ObjectReference{{Name: "syncer-us-east1-token-abcde"}},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "syncer-us-east1-token-abcde", Namespace: "default"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "syncer-us-east1"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+
+	require.NoError(t, revokeSyncerForWorkspace(context.Background(), kubeClient, "us-east1", "default"))
+
+	sas, err := kubeClient.CoreV1().ServiceAccounts("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, sas.Items)
+	secrets, err := kubeClient.CoreV1().Secrets("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, secrets.Items)
+	crbs, err := kubeClient.RbacV1().ClusterRoleBindings().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, crbs.Items, 1)
+	require.Equal(t, "other", crbs.Items[0].Name)
+
+	// revoking again is a no-op
+	require.NoError(t, revokeSyncerForWorkspace(context.Background(), kubeClient, "us-east1", "default"))
+}
+
+func TestDeleteSyncerResources(t *testing.T) {
+	syncerID := GetSyncerID("root:org:ws", "us-east1")
+	syncedNamespace := func(name, logicalCluster, workloadCluster string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"internal.workloads.kcp.dev/cluster": workloadCluster},
+			Annotations: map[string]string{"kcp.dev/namespace-locator": `{"logical-cluster":"` + logicalCluster + `","namespace":"app"}`},
+		}}
+	}
+	downstreamClient := kubefake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: syncerID}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: syncerID}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: syncerID}},
+		syncedNamespace("kcp-synced", "root:org:ws", "us-east1"),
+		syncedNamespace("kcp-other-workspace", "root:org:other", "us-east1"),
+		syncedNamespace("kcp-other-cluster", "root:org:ws", "us-west1"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	)
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	c := &Config{Config: &base.Config{IOStreams: streams}}
+	require.NoError(t, c.deleteSyncerResources(context.Background(), downstreamClient, logicalcluster.New("root:org:ws"), "us-east1"))
+
+	namespaces, err := downstreamClient.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	require.ElementsMatch(t, []string{"kcp-other-workspace", "kcp-other-cluster", "kube-system"}, names)
+	crs, err := downstreamClient.RbacV1().ClusterRoles().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, crs.Items)
+	crbs, err := downstreamClient.RbacV1().ClusterRoleBindings().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, crbs.Items)
+}
@@ -19,19 +19,19 @@ package plugin
 import (
 	"context"
 	"fmt"
-	"time"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 )
 
 // Cordon the workload cluster and mark it as unschedulable
 func (c *Config) Cordon(ctx context.Context, workloadClusterName string) error {
-	config, err := clientcmd.NewDefaultClientConfig(*c.startingConfig, c.overrides).ClientConfig()
+	config, err := c.ClientConfig()
 	if err != nil {
 		return err
 	}
@@ -46,7 +46,7 @@ func (c *Config) Cordon(ctx context.Context, workloadClusterName string) error {
 
 // Uncordon the workload cluster and mark it as schedulable
 func (c *Config) Uncordon(ctx context.Context, workloadClusterName string) error {
-	config, err := clientcmd.NewDefaultClientConfig(*c.startingConfig, c.overrides).ClientConfig()
+	config, err := c.ClientConfig()
 	if err != nil {
 		return err
 	}
@@ -92,6 +92,10 @@ func modifyCordon(ctx context.Context, config *rest.Config, workloadClusterName
 		if workloadCluster.Spec.EvictAfter != nil {
 			evict = `,{"op":"remove","path":"/spec/evictAfter"}`
 		}
+		if _, found := workloadCluster.Annotations[workloadv1alpha1.DrainMaxUnavailableAnnotation]; found {
+			// stop draining
+			evict += `,{"op":"remove","path":"/metadata/annotations/` + strings.ReplaceAll(workloadv1alpha1.DrainMaxUnavailableAnnotation, "/", "~1") + `"}`
+		}
 
 		patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":false}` + evict + `]`)
 	}
@@ -111,44 +115,3 @@ func modifyCordon(ctx context.Context, config *rest.Config, workloadClusterName
 
 	return nil
 }
-
-// Start draining the workload cluster and mark it as unschedulable
-func (c *Config) Drain(ctx context.Context, workloadClusterName string) error {
-	config, err := clientcmd.NewDefaultClientConfig(*c.startingConfig, c.overrides).ClientConfig()
-	if err != nil {
-		return err
-	}
-
-	kcpClient, err := kcpclientset.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create kcp client: %w", err)
-	}
-
-	workloadCluster, err := kcpClient.WorkloadV1alpha1().WorkloadClusters().Get(ctx,
-		workloadClusterName,
-		metav1.GetOptions{},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to get workloadcluster %s: %w", workloadClusterName, err)
-	}
-
-	// See if there is nothing to do
-	if workloadCluster.Spec.EvictAfter != nil && workloadCluster.Spec.Unschedulable {
-		fmt.Println(workloadClusterName, "already draining")
-		return nil
-	}
-
-	nowTime := time.Now().UTC()
-	var patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":true},{"op":"replace","path":"/spec/evictAfter","value":"` + nowTime.Format(time.RFC3339) + `"}]`)
-
-	_, err = kcpClient.WorkloadV1alpha1().WorkloadClusters().Patch(ctx, workloadClusterName, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
-
-	if err != nil {
-		return fmt.Errorf("failed to update WorkloadCluster %s: %w", workloadClusterName, err)
-	}
-
-	fmt.Println(workloadClusterName, "draining")
-
-	return nil
-
-}
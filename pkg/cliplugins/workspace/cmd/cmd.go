@@ -17,7 +17,11 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -38,6 +42,9 @@ var (
 	# list all your personal workspaces
 	%[1]s workspace list
 
+	# show the hierarchy of workspaces below the current workspace, two levels deep
+	%[1]s workspace tree --max-depth 2
+
 	# enter a given absolute workspace
 	%[1]s workspace root:default:my-workspace
 
@@ -55,6 +62,18 @@ var (
 
 	# create a context with the current workspace, named context-name
 	%[1]s workspace create-context context-name
+
+	# export the contents of a workspace as manifests into a directory
+	%[1]s workspace export my-workspace -o my-workspace/
+
+	# re-create exported manifests in another workspace
+	%[1]s workspace import root:default:other-workspace -f my-workspace/
+
+	# run a command in a given workspace, without changing the current workspace
+	%[1]s workspace exec my-workspace -- kubectl get configmaps
+
+	# print a standalone kubeconfig for a given workspace
+	%[1]s workspace kubeconfig root:default:my-workspace > my-workspace.kubeconfig
 `
 )
 
@@ -81,21 +100,47 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 		}
 		return kubeconfig.UseWorkspace(cmd.Context(), arg)
 	}
+	// completeWorkspace completes the first argument with the workspaces at the current or
+	// the given absolute level.
+	completeWorkspace := func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		kubeconfig, err := plugin.NewKubeConfig(opts)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		workspaces, err := kubeconfig.CompleteWorkspaces(context.Background(), opts.Scope, toComplete)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		directive := cobra.ShellCompDirectiveNoFileComp
+		if strings.Contains(toComplete, ":") {
+			// allow continuing with the next level
+			directive |= cobra.ShellCompDirectiveNoSpace
+		}
+		return workspaces, directive
+	}
+
 	cmd := &cobra.Command{
-		Aliases:          []string{"ws", "workspaces"},
-		Use:              "workspace [list|create|create-context|<workspace>|..|-|<root:absolute:workspace>]",
-		Short:            "Manages KCP workspaces",
-		Example:          fmt.Sprintf(workspaceExample, "kubectl kcp"),
-		SilenceUsage:     true,
-		TraverseChildren: true,
-		RunE:             useRunE,
+		Aliases:           []string{"ws", "workspaces"},
+		Use:               "workspace [list|create|create-context|<workspace>|..|-|<root:absolute:workspace>]",
+		Short:             "Manages KCP workspaces",
+		Example:           fmt.Sprintf(workspaceExample, "kubectl kcp"),
+		ValidArgsFunction: completeWorkspace,
+		SilenceUsage:      true,
+		TraverseChildren:  true,
+		RunE:              useRunE,
 	}
 	opts.BindFlags(cmd)
 
 	useCmd := &cobra.Command{
-		Use:          "use <workspace>|..|-|<root:absolute:workspace>",
-		Short:        "Uses the given workspace as the current workspace. Using - means previous workspace, .. means parent workspace",
-		SilenceUsage: true,
+		Use:               "use <workspace>|..|-|<root:absolute:workspace>",
+		ValidArgsFunction: completeWorkspace,
+		Short:             "Uses the given workspace as the current workspace. Using - means previous workspace, .. means parent workspace",
+		SilenceUsage:      true,
 		RunE: func(c *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return c.Help()
@@ -147,6 +192,37 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 		},
 	}
 
+	var treeMaxDepth int
+	var treeOutput string
+	treeCmd := &cobra.Command{
+		Use:               "tree [<workspace>|<root:absolute:workspace>] [--max-depth <n>] [-o json|yaml]",
+		ValidArgsFunction: completeWorkspace,
+		Short:             "Shows the hierarchy of workspaces below a workspace",
+		Example:           "kcp workspace tree --max-depth 2",
+		SilenceUsage:      true,
+		Args:              cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			if treeMaxDepth < 0 {
+				return fmt.Errorf("--max-depth must not be negative")
+			}
+			kubeconfig, err := plugin.NewKubeConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			arg := ""
+			if len(args) == 1 {
+				arg = args[0]
+			}
+			return kubeconfig.TreeWorkspaces(c.Context(), opts, arg, treeMaxDepth, treeOutput)
+		},
+	}
+	treeCmd.Flags().IntVar(&treeMaxDepth, "max-depth", treeMaxDepth, "The number of levels to show below the workspace, 0 for no limit")
+	treeCmd.Flags().StringVarP(&treeOutput, "output", "o", treeOutput, "Output format, one of json, yaml. Defaults to a human-readable tree")
+
 	var workspaceType string
 	var enterAfterCreation bool
 	var ignoreExisting bool
@@ -167,7 +243,22 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 			return kubeconfig.CreateWorkspace(cmd.Context(), args[0], workspaceType, ignoreExisting, enterAfterCreation, time.Minute)
 		},
 	}
-	createCmd.Flags().StringVar(&workspaceType, "type", "", "A workspace type (default: Universal)")
+	createCmd.Flags().StringVar(&workspaceType, "type", "", "A workspace type (default: the default child type of the current workspace type, or Universal)")
+	if err := createCmd.RegisterFlagCompletionFunc("type", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		kubeconfig, err := plugin.NewKubeConfig(opts)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		types, err := kubeconfig.CompleteWorkspaceTypes(context.Background(), toComplete)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return types, cobra.ShellCompDirectiveNoFileComp
+	}); err != nil {
+		return nil, err
+	}
 	createCmd.Flags().BoolVar(&enterAfterCreation, "enter", enterAfterCreation, "Immediately enter the created workspace")
 	createCmd.Flags().BoolVar(&ignoreExisting, "ignore-existing", ignoreExisting, "Ignore if the workspace already exists")
 	createCmd.Flags().BoolVar(&enterAfterCreation, "use", enterAfterCreation, "Use the new workspace after a successful creation")
@@ -215,11 +306,128 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 		},
 	}
 
+	var exportDir string
+	exportCmd := &cobra.Command{
+		Use:               "export [<workspace>|<root:absolute:workspace>] -o <directory>",
+		ValidArgsFunction: completeWorkspace,
+		Short:             "Exports the contents of a workspace as manifests into a directory",
+		Example:           "kcp workspace export my-workspace -o my-workspace/",
+		SilenceUsage:      true,
+		Args:              cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			if exportDir == "" {
+				return fmt.Errorf("--output-dir is required")
+			}
+			kubeconfig, err := plugin.NewKubeConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			arg := ""
+			if len(args) == 1 {
+				arg = args[0]
+			}
+			return kubeconfig.ExportWorkspace(c.Context(), arg, exportDir)
+		},
+	}
+	exportCmd.Flags().StringVarP(&exportDir, "output-dir", "o", exportDir, "The directory to write the manifests to")
+
+	var importDir string
+	var importTimeout = time.Minute
+	importCmd := &cobra.Command{
+		Use:               "import [<workspace>|<root:absolute:workspace>] -f <directory>",
+		ValidArgsFunction: completeWorkspace,
+		Short:             "Re-creates manifests exported with \"export\" in a workspace",
+		Example:           "kcp workspace import my-other-workspace -f my-workspace/",
+		SilenceUsage:      true,
+		Args:              cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			if importDir == "" {
+				return fmt.Errorf("--input-dir is required")
+			}
+			kubeconfig, err := plugin.NewKubeConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			arg := ""
+			if len(args) == 1 {
+				arg = args[0]
+			}
+			return kubeconfig.ImportWorkspace(c.Context(), arg, importDir, importTimeout)
+		},
+	}
+	importCmd.Flags().StringVarP(&importDir, "input-dir", "f", importDir, "The directory to read the manifests from")
+	importCmd.Flags().DurationVar(&importTimeout, "timeout", importTimeout, "How long to wait for CRDs to be established and APIBindings to be bound")
+
 	cmd.AddCommand(useCmd)
 	cmd.AddCommand(currentCmd)
 	cmd.AddCommand(listCmd)
+	cmd.AddCommand(treeCmd)
 	cmd.AddCommand(createCmd)
 	cmd.AddCommand(createContextCmd)
 	cmd.AddCommand(deleteCmd)
+	cmd.AddCommand(exportCmd)
+	execCmd := &cobra.Command{
+		Use: "exec <workspace>|..|-|<root:absolute:workspace> -- <command> [<args>...]",
+		ValidArgsFunction: func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completeWorkspace(c, args, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveDefault
+		},
+		Short:        "Runs a command with KUBECONFIG pointing to the given workspace, without changing the current workspace",
+		Example:      "kcp workspace exec my-workspace -- kubectl get configmaps",
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			if c.ArgsLenAtDash() != 1 || len(args) < 2 {
+				return c.Help()
+			}
+			kubeconfig, err := plugin.NewKubeConfig(opts)
+			if err != nil {
+				return err
+			}
+
+			err = kubeconfig.Exec(c.Context(), args[0], args[1:])
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// the command has reported its failure itself, only its exit code is passed on
+				c.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	kubeconfigCmd := &cobra.Command{
+		Use:               "kubeconfig <workspace>|..|-|<root:absolute:workspace>",
+		ValidArgsFunction: completeWorkspace,
+		Short:             "Prints a standalone kubeconfig for the given workspace, without changing the current workspace",
+		Example:           "kcp workspace kubeconfig my-workspace > my-workspace.kubeconfig",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			kubeconfig, err := plugin.NewKubeConfig(opts)
+			if err != nil {
+				return err
+			}
+			return kubeconfig.PrintKubeConfig(c.Context(), args[0])
+		},
+	}
+
+	cmd.AddCommand(importCmd)
+	cmd.AddCommand(execCmd)
+	cmd.AddCommand(kubeconfigCmd)
 	return cmd, nil
 }
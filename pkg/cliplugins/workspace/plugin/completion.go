@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+const (
+	// completionTimeout bounds the requests made for shell completion, so that the
+	// shell does not hang if the server is slow or unreachable.
+	completionTimeout = 2 * time.Second
+	// completionCacheTTL is how long completion results are reused.
+	completionCacheTTL = 30 * time.Second
+)
+
+// CompleteWorkspaces returns the workspace arguments starting with toComplete: the
+// workspaces in the current workspace, "..", "-" and "root" for relative input, and
+// the workspaces at the given level for absolute input like "root:org:". In the
+// personal scope, relative workspaces are completed with the names the user gave them,
+// while absolute paths always use the logical cluster names.
+func (kc *KubeConfig) CompleteWorkspaces(ctx context.Context, scope, toComplete string) ([]string, error) {
+	config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	_, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	if i := strings.LastIndex(toComplete, ":"); i >= 0 {
+		parent := logicalcluster.New(toComplete[:i])
+		if !pluginhelpers.IsValid(parent) {
+			return nil, nil
+		}
+		names, err := kc.cachedCompletion(ctx, config.Host, "workspaces", scope, parent.String(), func(ctx context.Context) ([]string, error) {
+			return kc.listChildWorkspaces(ctx, scope, parent, true)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			candidates = append(candidates, parent.Join(name).String())
+		}
+	} else {
+		// listing is best-effort here, the other candidates are valid regardless
+		names, _ := kc.cachedCompletion(ctx, config.Host, "workspaces", scope, currentClusterName.String()+"/relative", func(ctx context.Context) ([]string, error) {
+			return kc.listChildWorkspaces(ctx, scope, currentClusterName, false)
+		})
+		candidates = append(candidates, names...)
+		if _, hasParent := currentClusterName.Parent(); hasParent {
+			candidates = append(candidates, "..")
+		}
+		if _, found := kc.startingConfig.Contexts[kcpPreviousWorkspaceContextKey]; found {
+			candidates = append(candidates, "-")
+		}
+		candidates = append(candidates, tenancyv1alpha1.RootCluster.String())
+	}
+
+	return withPrefix(candidates, toComplete), nil
+}
+
+// CompleteWorkspaceTypes returns the names of the workspace types that can be used to
+// create workspaces in the current workspace, starting with toComplete.
+func (kc *KubeConfig) CompleteWorkspaceTypes(ctx context.Context, toComplete string) ([]string, error) {
+	config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	_, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	types, err := kc.cachedCompletion(ctx, config.Host, "types", "", currentClusterName.String(), func(ctx context.Context) ([]string, error) {
+		list, err := kc.clusterClient.Cluster(currentClusterName).TenancyV1alpha1().ClusterWorkspaceTypes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		types := []string{"Universal"}
+		for _, cwt := range list.Items {
+			// types are referenced by the capitalized object name
+			name := strings.ToUpper(cwt.Name[:1]) + cwt.Name[1:]
+			if name != "Universal" {
+				types = append(types, name)
+			}
+		}
+		return types, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withPrefix(types, toComplete), nil
+}
+
+// listChildWorkspaces returns the names of the workspaces in the given workspace. With
+// logicalClusterNames, the logical cluster names of the ready workspaces are returned
+// instead of the names of the workspace objects, which differ in the personal scope.
+func (kc *KubeConfig) listChildWorkspaces(ctx context.Context, scope string, clusterName logicalcluster.Name, logicalClusterNames bool) ([]string, error) {
+	client := kc.clusterClient
+	if scope == "personal" {
+		client = kc.personalClient
+	}
+	list, err := client.Cluster(clusterName).TenancyV1beta1().Workspaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, ws := range list.Items {
+		if !logicalClusterNames {
+			names = append(names, ws.Name)
+			continue
+		}
+		if ws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+			continue
+		}
+		if _, childClusterName, err := pluginhelpers.ParseClusterURL(ws.Status.URL); err == nil {
+			names = append(names, childClusterName.Base())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type completionCacheEntry struct {
+	Time   time.Time `json:"time"`
+	Values []string  `json:"values"`
+}
+
+// cachedCompletion returns the values computed by fn, reusing the values computed for the
+// same server, user and key within completionCacheTTL. fn is called with a context
+// bounded by completionTimeout. Caching is best-effort and skipped if there is no cache
+// directory.
+func (kc *KubeConfig) cachedCompletion(ctx context.Context, host, kind, scope, key string, fn func(ctx context.Context) ([]string, error)) ([]string, error) {
+	var authInfo string
+	if currentContext, found := kc.startingConfig.Contexts[kc.currentContext]; found {
+		authInfo = currentContext.AuthInfo
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{host, authInfo, kind, scope, key}, "\x00")))
+
+	var path string
+	if kc.completionCacheDir != "" {
+		path = filepath.Join(kc.completionCacheDir, hex.EncodeToString(sum[:16])+".json")
+		if bs, err := os.ReadFile(path); err == nil {
+			var entry completionCacheEntry
+			if err := json.Unmarshal(bs, &entry); err == nil && time.Since(entry.Time) < completionCacheTTL {
+				return entry.Values, nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+	values, err := fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		if bs, err := json.Marshal(completionCacheEntry{Time: time.Now(), Values: values}); err == nil {
+			if err := os.MkdirAll(kc.completionCacheDir, 0700); err == nil {
+				_ = os.WriteFile(path, bs, 0600)
+			}
+		}
+	}
+	return values, nil
+}
+
+// defaultCompletionCacheDir returns the directory to cache completion results in, next
+// to kubectl's discovery cache.
+func defaultCompletionCacheDir() string {
+	home := homedir.HomeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "cache", "kcp-completion")
+}
+
+func withPrefix(values []string, prefix string) []string {
+	var result []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
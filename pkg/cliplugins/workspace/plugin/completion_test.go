@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1beta1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1beta1"
+	tenancyfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+func newCompletionTestKubeConfig(t *testing.T, withPrevious bool) *KubeConfig {
+	workspace := func(name, cluster string) *tenancyv1beta1.Workspace {
+		return &tenancyv1beta1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     tenancyv1beta1.WorkspaceStatus{Phase: tenancyv1alpha1.ClusterWorkspacePhaseReady, URL: "https://test/clusters/" + cluster},
+		}
+	}
+	unready := workspace("unready", "")
+	unready.Status = tenancyv1beta1.WorkspaceStatus{Phase: tenancyv1alpha1.ClusterWorkspacePhaseInitializing}
+
+	config := clientcmdapi.Config{CurrentContext: "workspace.kcp.dev/current",
+		Contexts:  map[string]*clientcmdapi.Context{"workspace.kcp.dev/current": {Cluster: "workspace.kcp.dev/current", AuthInfo: "test"}},
+		Clusters:  map[string]*clientcmdapi.Cluster{"workspace.kcp.dev/current": {Server: "https://test/clusters/root:org"}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
+	}
+	if withPrevious {
+		config.Contexts["workspace.kcp.dev/previous"] = &clientcmdapi.Context{Cluster: "workspace.kcp.dev/current", AuthInfo: "test"}
+	}
+
+	return &KubeConfig{
+		startingConfig: &config,
+		currentContext: config.CurrentContext,
+
+		clusterClient: fakeTenancyClient{
+			t: t,
+			clients: map[logicalcluster.Name]*tenancyfake.Clientset{
+				logicalcluster.New("root:org"): tenancyfake.NewSimpleClientset(
+					workspace("team-a", "root:org:team-a"),
+					workspace("team-b", "root:org:team-b"),
+					&tenancyv1alpha1.ClusterWorkspaceType{ObjectMeta: metav1.ObjectMeta{Name: "team"}},
+					&tenancyv1alpha1.ClusterWorkspaceType{ObjectMeta: metav1.ObjectMeta{Name: "universal"}},
+				),
+			},
+		},
+		personalClient: fakeTenancyClient{
+			t: t,
+			clients: map[logicalcluster.Name]*tenancyfake.Clientset{
+				logicalcluster.New("root:org"): tenancyfake.NewSimpleClientset(
+					workspace("my-team", "root:org:team-a"),
+					unready,
+				),
+			},
+		},
+		IOStreams: genericclioptions.NewTestIOStreamsDiscard(),
+	}
+}
+
+func TestCompleteWorkspaces(t *testing.T) {
+	tests := []struct {
+		name         string
+		scope        string
+		toComplete   string
+		withPrevious bool
+		want         []string
+	}{
+		{name: "relative personal", scope: "personal", toComplete: "", want: []string{"my-team", "unready", "..", "root"}},
+		{name: "relative all", scope: "all", toComplete: "team", want: []string{"team-a", "team-b"}},
+		{name: "relative with previous", scope: "all", toComplete: "-", withPrevious: true, want: []string{"-"}},
+		{name: "root", scope: "all", toComplete: "ro", want: []string{"root"}},
+		{name: "absolute personal", scope: "personal", toComplete: "root:org:", want: []string{"root:org:team-a"}},
+		{name: "absolute all with prefix", scope: "all", toComplete: "root:org:team-b", want: []string{"root:org:team-b"}},
+		{name: "absolute invalid parent", scope: "all", toComplete: "Root::", want: nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			kc := newCompletionTestKubeConfig(t, tt.withPrevious)
+			got, err := kc.CompleteWorkspaces(context.Background(), tt.scope, tt.toComplete)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompleteWorkspaceTypes(t *testing.T) {
+	kc := newCompletionTestKubeConfig(t, false)
+
+	got, err := kc.CompleteWorkspaceTypes(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Universal", "Team"}, got)
+
+	got, err = kc.CompleteWorkspaceTypes(context.Background(), "T")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Team"}, got)
+}
+
+func TestCachedCompletion(t *testing.T) {
+	kc := newCompletionTestKubeConfig(t, false)
+	kc.completionCacheDir = t.TempDir()
+
+	calls := 0
+	fn := func(ctx context.Context) ([]string, error) {
+		calls++
+		_, hasDeadline := ctx.Deadline()
+		require.True(t, hasDeadline, "completion requests must have a timeout")
+		return []string{"a", "b"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := kc.cachedCompletion(context.Background(), "https://test", "workspaces", "all", "root", fn)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b"}, got)
+	}
+	require.Equal(t, 1, calls, "the second call must be served from the cache")
+
+	_, err := kc.cachedCompletion(context.Background(), "https://test", "workspaces", "personal", "root", fn)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls, "a different scope must not be served from the cache")
+
+	_, err = kc.cachedCompletion(context.Background(), "https://test", "workspaces", "all", "other", func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("failed")
+	})
+	require.Error(t, err)
+}
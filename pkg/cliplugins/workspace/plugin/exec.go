@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// PrintKubeConfig prints a standalone kubeconfig for the given workspace, using the
+// credentials of the current context. The user's kubeconfig is not modified.
+func (kc *KubeConfig) PrintKubeConfig(ctx context.Context, name string) error {
+	config, err := kc.workspaceKubeConfig(ctx, name)
+	if err != nil {
+		return err
+	}
+	bs, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+	_, err = kc.Out.Write(bs)
+	return err
+}
+
+// Exec runs the given command with KUBECONFIG pointing to a temporary standalone kubeconfig
+// for the given workspace, using the credentials of the current context. The user's
+// kubeconfig is not modified, so that commands for different workspaces can run in
+// parallel. The temporary kubeconfig is removed when the command exits.
+func (kc *KubeConfig) Exec(ctx context.Context, name string, command []string) error {
+	if len(command) == 0 {
+		return errors.New("a command must be given")
+	}
+
+	config, err := kc.workspaceKubeConfig(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "kcp-workspace-*.kubeconfig")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := clientcmd.WriteToFile(*config, f.Name()); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) // nolint:gosec
+	cmd.Env = append(os.Environ(), clientcmd.RecommendedConfigPathEnvVar+"="+f.Name())
+	cmd.Stdin = kc.In
+	cmd.Stdout = kc.Out
+	cmd.Stderr = kc.ErrOut
+	return cmd.Run()
+}
+
+// workspaceKubeConfig returns a kubeconfig with a single context for the given workspace,
+// named like the context "kubectl ws use" creates, with the cluster and the credentials of
+// the current context. The workspace is resolved like in workspaceConfig, or is the previous
+// workspace for "-", and is checked to be ready.
+func (kc *KubeConfig) workspaceKubeConfig(ctx context.Context, name string) (*clientcmdapi.Config, error) {
+	currentContext, found := kc.startingConfig.Contexts[kc.currentContext]
+	if !found {
+		return nil, fmt.Errorf("current %q context not found", kc.currentContext)
+	}
+	currentCluster, found := kc.startingConfig.Clusters[currentContext.Cluster]
+	if !found {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", currentContext.Cluster)
+	}
+
+	var host string
+	checked := false
+	if name == "-" {
+		prev, exists := kc.startingConfig.Contexts[kcpPreviousWorkspaceContextKey]
+		if !exists {
+			return nil, errors.New("no previous workspace found in kubeconfig")
+		}
+		prevCluster, found := kc.startingConfig.Clusters[prev.Cluster]
+		if !found {
+			return nil, fmt.Errorf("cluster %q not found in kubeconfig", prev.Cluster)
+		}
+		host = prevCluster.Server
+	} else {
+		config, _, err := kc.workspaceConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		host = config.Host
+		// workspaceHost already checked the phase of workspaces relative to the current one.
+		checked = isRelativeWorkspace(name)
+	}
+	if !checked {
+		if err := kc.ensureWorkspaceReady(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+
+	newCluster := *currentCluster
+	newCluster.Server = host
+	newContext := *currentContext
+	newContext.Cluster = kcpCurrentWorkspaceContextKey
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[kcpCurrentWorkspaceContextKey] = &newCluster
+	config.Contexts[kcpCurrentWorkspaceContextKey] = &newContext
+	if authInfo, found := kc.startingConfig.AuthInfos[currentContext.AuthInfo]; found {
+		config.AuthInfos[currentContext.AuthInfo] = authInfo
+	}
+	config.CurrentContext = kcpCurrentWorkspaceContextKey
+	return config, nil
+}
+
+// isRelativeWorkspace returns whether workspaceHost resolves the given name as a workspace
+// in the current workspace.
+func isRelativeWorkspace(name string) bool {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return false
+	case strings.Contains(name, ":") || name == tenancyv1alpha1.RootCluster.String():
+		return false
+	default:
+		return true
+	}
+}
+
+// ensureWorkspaceReady checks that the workspace behind the given workspace URL is ready,
+// as seen by the user in its parent. The check is skipped for the root workspace, and for
+// workspaces the user cannot see in their parent, e.g. because the user may only access the
+// workspace itself, leaving it to the server to reject requests to them.
+func (kc *KubeConfig) ensureWorkspaceReady(ctx context.Context, host string) error {
+	_, clusterName, err := pluginhelpers.ParseClusterURL(host)
+	if err != nil {
+		return fmt.Errorf("URL %q does not point to cluster workspace", host)
+	}
+	parentClusterName, name := clusterName.Split()
+	if parentClusterName.Empty() {
+		return nil
+	}
+
+	ws, err := kc.personalClient.Cluster(parentClusterName).TenancyV1beta1().Workspaces().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+		return fmt.Errorf("workspace %q is not ready", clusterName)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1beta1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1beta1"
+	tenancyfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+func newExecTestKubeConfig(t *testing.T, streams genericclioptions.IOStreams) *KubeConfig {
+	workspace := func(name, url string, phase tenancyv1alpha1.ClusterWorkspacePhaseType) *tenancyv1beta1.Workspace {
+		return &tenancyv1beta1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     tenancyv1beta1.WorkspaceStatus{Phase: phase, URL: url},
+		}
+	}
+
+	config := clientcmdapi.Config{CurrentContext: "workspace.kcp.dev/current",
+		Contexts: map[string]*clientcmdapi.Context{
+			"workspace.kcp.dev/current":  {Cluster: "workspace.kcp.dev/current", AuthInfo: "test", Namespace: "ns"},
+			"workspace.kcp.dev/previous": {Cluster: "workspace.kcp.dev/previous", AuthInfo: "test"},
+		},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"workspace.kcp.dev/current":  {Server: "https://test/clusters/root:foo", CertificateAuthorityData: []byte("ca")},
+			"workspace.kcp.dev/previous": {Server: "https://test/clusters/root:bar"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"test":  {Token: "test"},
+			"other": {Token: "other"},
+		},
+	}
+
+	forbidden := tenancyfake.NewSimpleClientset()
+	forbidden.PrependReactor("get", "workspaces", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(tenancyv1beta1.Resource("workspaces"), action.(clientgotesting.GetAction).GetName(), errors.New("not allowed"))
+	})
+
+	return &KubeConfig{
+		startingConfig: &config,
+		currentContext: config.CurrentContext,
+
+		personalClient: fakeTenancyClient{
+			t: t,
+			clients: map[logicalcluster.Name]*tenancyfake.Clientset{
+				logicalcluster.New("root"): tenancyfake.NewSimpleClientset(
+					workspace("foo", "https://test/clusters/root:foo", tenancyv1alpha1.ClusterWorkspacePhaseReady),
+					workspace("bar", "https://test/clusters/root:bar", tenancyv1alpha1.ClusterWorkspacePhaseReady),
+				),
+				logicalcluster.New("root:foo"): tenancyfake.NewSimpleClientset(
+					workspace("pretty", "https://test/clusters/root:foo:ready", tenancyv1alpha1.ClusterWorkspacePhaseReady),
+					workspace("initializing", "https://test/clusters/root:foo:initializing", tenancyv1alpha1.ClusterWorkspacePhaseInitializing),
+				),
+				logicalcluster.New("root:bar"): forbidden,
+			},
+		},
+		modifyConfig: func(config *clientcmdapi.Config) error {
+			t.Errorf("unexpected kubeconfig write")
+			return nil
+		},
+		IOStreams: streams,
+	}
+}
+
+func TestWorkspaceKubeConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      string
+		wantServer string
+		wantErr    string
+	}{
+		{name: "relative", param: "pretty", wantServer: "https://test/clusters/root:foo:ready"},
+		{name: "absolute", param: "root:foo:ready", wantServer: "https://test/clusters/root:foo:ready"},
+		{name: "current", param: ".", wantServer: "https://test/clusters/root:foo"},
+		{name: "parent", param: "..", wantServer: "https://test/clusters/root"},
+		{name: "previous", param: "-", wantServer: "https://test/clusters/root:bar"},
+		{name: "relative not ready", param: "initializing", wantErr: "is not ready"},
+		{name: "relative not found", param: "missing", wantErr: "not found"},
+		{name: "absolute not ready", param: "root:foo:initializing", wantErr: "is not ready"},
+		{name: "absolute not visible", param: "root:foo:missing", wantServer: "https://test/clusters/root:foo:missing"},
+		{name: "absolute forbidden", param: "root:bar:secret", wantServer: "https://test/clusters/root:bar:secret"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			kc := newExecTestKubeConfig(t, genericclioptions.NewTestIOStreamsDiscard())
+
+			got, err := kc.workspaceKubeConfig(context.Background(), tt.param)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, "workspace.kcp.dev/current", got.CurrentContext)
+			require.Len(t, got.Contexts, 1)
+			require.Equal(t, &clientcmdapi.Context{Cluster: "workspace.kcp.dev/current", AuthInfo: "test", Namespace: "ns"}, got.Contexts["workspace.kcp.dev/current"])
+			require.Len(t, got.Clusters, 1)
+			require.Equal(t, tt.wantServer, got.Clusters["workspace.kcp.dev/current"].Server)
+			require.Equal(t, []byte("ca"), got.Clusters["workspace.kcp.dev/current"].CertificateAuthorityData)
+			require.Equal(t, map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}}, got.AuthInfos)
+
+			require.Equal(t, "https://test/clusters/root:foo", kc.startingConfig.Clusters["workspace.kcp.dev/current"].Server, "the starting config must not be modified")
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	streams, _, stdout, _ := genericclioptions.NewTestIOStreams()
+	kc := newExecTestKubeConfig(t, streams)
+
+	require.NoError(t, kc.Exec(context.Background(), "pretty", []string{"sh", "-c", `echo "$KUBECONFIG"; cat "$KUBECONFIG"`}))
+
+	lines := strings.SplitN(stdout.String(), "\n", 2)
+	require.Len(t, lines, 2)
+	config, err := clientcmd.Load([]byte(lines[1]))
+	require.NoError(t, err)
+	require.Equal(t, "https://test/clusters/root:foo:ready", config.Clusters[config.Contexts[config.CurrentContext].Cluster].Server)
+
+	_, err = os.Stat(lines[0])
+	require.True(t, os.IsNotExist(err), "the temporary kubeconfig %q must be removed", lines[0])
+
+	err = kc.Exec(context.Background(), "pretty", []string{"sh", "-c", "exit 3"})
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "expected an exit error, got %v", err)
+	require.Equal(t, 3, exitErr.ExitCode())
+}
@@ -0,0 +1,446 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// exportTier defines the order in which exported objects are written, and in which
+// they are re-created on import. It is used as prefix of the exported file names.
+type exportTier int
+
+const (
+	tierCustomResourceDefinitions exportTier = iota
+	tierAPIResourceSchemas
+	tierNamespaces
+	tierAPIExportIdentitySecrets
+	tierAPIExports
+	tierAPIBindings
+	tierClusterScoped
+	tierNamespaced
+)
+
+var (
+	crdsGR         = schema.GroupResource{Group: apiextensionsv1.GroupName, Resource: "customresourcedefinitions"}
+	namespacesGR   = schema.GroupResource{Resource: "namespaces"}
+	secretsGR      = schema.GroupResource{Resource: "secrets"}
+	servicesGR     = schema.GroupResource{Resource: "services"}
+	configMapsGR   = schema.GroupResource{Resource: "configmaps"}
+	apiSchemasGR   = apisv1alpha1.Resource("apiresourceschemas")
+	apiExportsGR   = apisv1alpha1.Resource("apiexports")
+	apiBindingsGR  = apisv1alpha1.Resource("apibindings")
+	notExportedGRs = sets.NewString(
+		// transient or system-managed
+		"events",
+		"events.events.k8s.io",
+		"leases.coordination.k8s.io",
+		"apiresourceimports.apiresource.kcp.dev",
+		"negotiatedapiresources.apiresource.kcp.dev",
+		// nested workspaces are logical clusters of their own
+		"clusterworkspaces.tenancy.kcp.dev",
+		"workspaces.tenancy.kcp.dev",
+		"clusterworkspaceshards.tenancy.kcp.dev",
+	)
+)
+
+// exportedObject is an object of the exported workspace, together with its resource.
+type exportedObject struct {
+	gr  schema.GroupResource
+	obj *unstructured.Unstructured
+}
+
+// ExportWorkspace writes the contents of the given workspace as YAML manifests into the given
+// directory. The manifests are stripped of server-populated fields, and the lexical order of the
+// file names is the order in which ImportWorkspace re-creates the objects: CRDs and APIResourceSchemas
+// first, then namespaces, APIExports and APIBindings, and the remaining content last.
+func (kc *KubeConfig) ExportWorkspace(ctx context.Context, workspace string, dir string) error {
+	config, clusterName, err := kc.workspaceConfig(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	n, err := exportWorkspace(ctx, discoveryClient, dynamicClient, dir)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(kc.Out, "Exported %d objects of workspace %q to %q.\n", n, clusterName, dir)
+	return err
+}
+
+// ImportWorkspace re-creates the objects in the given directory, as written by ExportWorkspace,
+// in the given workspace. Objects that already exist are left untouched. CRDs and APIBindings
+// are waited for to be established and bound before the objects depending on them are created.
+func (kc *KubeConfig) ImportWorkspace(ctx context.Context, workspace string, dir string, timeout time.Duration) error {
+	config, clusterName, err := kc.workspaceConfig(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	n, err := importWorkspace(ctx, discoveryClient, dynamicClient, dir, timeout, kc.ErrOut)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(kc.Out, "Imported %d objects from %q into workspace %q.\n", n, dir, clusterName)
+	return err
+}
+
+// workspaceConfig returns a client config for the given workspace, which is either
+// the current workspace (empty or "."), its parent (".."), an absolute logical cluster
+// name, or the name of a workspace in the current workspace.
+func (kc *KubeConfig) workspaceConfig(ctx context.Context, workspace string) (*rest.Config, logicalcluster.Name, error) {
+	config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
+	if err != nil {
+		return nil, logicalcluster.Name{}, err
+	}
+	u, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return nil, logicalcluster.Name{}, fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+
+	clusterName := currentClusterName
+	if workspace != "" && workspace != "." {
+		host, _, err := kc.workspaceHost(ctx, workspace)
+		if err != nil {
+			return nil, logicalcluster.Name{}, err
+		}
+		if _, clusterName, err = pluginhelpers.ParseClusterURL(host); err != nil {
+			return nil, logicalcluster.Name{}, err
+		}
+	}
+	if !pluginhelpers.IsValid(clusterName) {
+		return nil, logicalcluster.Name{}, fmt.Errorf("invalid workspace %q", workspace)
+	}
+
+	u.Path = clusterName.Path()
+	config = rest.CopyConfig(config)
+	config.Host = u.String()
+	return config, clusterName, nil
+}
+
+func exportWorkspace(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, dir string) (int, error) {
+	resourceLists, err := discovery.ServerPreferredResources(discoveryClient)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return 0, err
+	}
+
+	var objs []exportedObject
+	seen := sets.NewString()
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range list.APIResources {
+			gvr := gv.WithResource(r.Name)
+			if strings.Contains(r.Name, "/") || seen.Has(gvr.GroupResource().String()) || notExportedGRs.Has(gvr.GroupResource().String()) {
+				continue
+			}
+			if verbs := sets.NewString(r.Verbs...); !verbs.HasAll("list", "create") {
+				continue
+			}
+			seen.Insert(gvr.GroupResource().String())
+
+			list, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+					continue
+				}
+				return 0, fmt.Errorf("failed to list %s: %w", gvr.GroupResource(), err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if skipExport(gvr.GroupResource(), obj) {
+					continue
+				}
+				stripForExport(gvr.GroupResource(), obj)
+				objs = append(objs, exportedObject{gr: gvr.GroupResource(), obj: obj})
+			}
+		}
+	}
+
+	// identity secrets have to exist before the APIExports referencing them,
+	// otherwise new identities would be generated.
+	identitySecrets := sets.NewString()
+	for _, o := range objs {
+		if o.gr != apiExportsGR {
+			continue
+		}
+		namespace, _, _ := unstructured.NestedString(o.obj.Object, "spec", "identity", "secretRef", "namespace")
+		name, _, _ := unstructured.NestedString(o.obj.Object, "spec", "identity", "secretRef", "name")
+		if name != "" {
+			identitySecrets.Insert(namespace + "/" + name)
+		}
+	}
+
+	files := map[string][]*unstructured.Unstructured{}
+	for _, o := range objs {
+		filename := fmt.Sprintf("%02d-%s.yaml", exportTierOf(o.gr, o.obj, identitySecrets), o.gr.String())
+		files[filename] = append(files[filename], o.obj)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	for filename, objs := range files {
+		sort.Slice(objs, func(i, j int) bool {
+			if objs[i].GetNamespace() != objs[j].GetNamespace() {
+				return objs[i].GetNamespace() < objs[j].GetNamespace()
+			}
+			return objs[i].GetName() < objs[j].GetName()
+		})
+
+		var buf bytes.Buffer
+		for _, obj := range objs {
+			bs, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				return 0, err
+			}
+			buf.WriteString("---\n")
+			buf.Write(bs)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filename), buf.Bytes(), 0644); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(objs), nil
+}
+
+// exportTierOf returns the tier of the given object.
+func exportTierOf(gr schema.GroupResource, obj *unstructured.Unstructured, identitySecrets sets.String) exportTier {
+	switch {
+	case gr == crdsGR:
+		return tierCustomResourceDefinitions
+	case gr == apiSchemasGR:
+		return tierAPIResourceSchemas
+	case gr == namespacesGR:
+		return tierNamespaces
+	case gr == secretsGR && identitySecrets.Has(obj.GetNamespace()+"/"+obj.GetName()):
+		return tierAPIExportIdentitySecrets
+	case gr == apiExportsGR:
+		return tierAPIExports
+	case gr == apiBindingsGR:
+		return tierAPIBindings
+	case obj.GetNamespace() == "":
+		return tierClusterScoped
+	default:
+		return tierNamespaced
+	}
+}
+
+// skipExport returns true for objects that are re-created by controllers,
+// either from their owner or as defaults of every workspace or namespace.
+func skipExport(gr schema.GroupResource, obj *unstructured.Unstructured) bool {
+	if metav1.GetControllerOf(obj) != nil {
+		return true
+	}
+	switch gr {
+	case configMapsGR:
+		return obj.GetName() == "kube-root-ca.crt"
+	case secretsGR:
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		return secretType == string(corev1.SecretTypeServiceAccountToken)
+	}
+	return false
+}
+
+// stripForExport removes all fields from the object that are populated by the server
+// or specific to the exported logical cluster.
+func stripForExport(gr schema.GroupResource, obj *unstructured.Unstructured) {
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds", "ownerReferences", "clusterName"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	if gr == servicesGR {
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	}
+}
+
+func importWorkspace(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, dir string, timeout time.Duration, out io.Writer) (int, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var filenames []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".yaml") {
+			filenames = append(filenames, info.Name())
+		}
+	}
+	sort.Strings(filenames)
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	created := 0
+	for _, filename := range filenames {
+		objs, err := readManifests(filepath.Join(dir, filename))
+		if err != nil {
+			return created, fmt.Errorf("failed to read %q: %w", filename, err)
+		}
+
+		// CRDs and APIBindings from earlier files might have added resources
+		mapper.Reset()
+
+		var toWaitFor []*unstructured.Unstructured
+		for _, obj := range objs {
+			gvk := obj.GroupVersionKind()
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				return created, fmt.Errorf("failed to map %s %q: %w", gvk.Kind, obj.GetName(), err)
+			}
+
+			var client dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+			if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+				client = dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+			}
+			if _, err := client.Create(ctx, obj, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return created, fmt.Errorf("failed to create %s %q: %w", mapping.Resource.GroupResource(), qualifiedName(obj), err)
+			} else if err != nil {
+				fmt.Fprintf(out, "%s %q already exists, skipping.\n", mapping.Resource.GroupResource(), qualifiedName(obj)) // nolint: errcheck
+			} else {
+				created++
+			}
+
+			if gr := mapping.Resource.GroupResource(); gr == crdsGR || gr == apiBindingsGR {
+				toWaitFor = append(toWaitFor, obj)
+			}
+		}
+
+		for _, obj := range toWaitFor {
+			if err := waitForImported(ctx, dynamicClient, obj, timeout); err != nil {
+				return created, err
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// waitForImported waits for CRDs to be established and for APIBindings to be bound.
+func waitForImported(ctx context.Context, dynamicClient dynamic.Interface, obj *unstructured.Unstructured, timeout time.Duration) error {
+	gvr := apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions")
+	if obj.GroupVersionKind().Group == apisv1alpha1.SchemeGroupVersion.Group {
+		gvr = apisv1alpha1.SchemeGroupVersion.WithResource("apibindings")
+	}
+
+	return wait.PollImmediate(time.Millisecond*500, timeout, func() (bool, error) {
+		current, err := dynamicClient.Resource(gvr).Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if gvr.Resource == "apibindings" {
+			phase, _, _ := unstructured.NestedString(current.Object, "status", "phase")
+			return phase == string(apisv1alpha1.APIBindingPhaseBound), nil
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(current.Object, "status", "conditions")
+		for _, c := range conditions {
+			c, ok := c.(map[string]interface{})
+			if ok && c["type"] == string(apiextensionsv1.Established) && c["status"] == string(apiextensionsv1.ConditionTrue) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// readManifests reads all objects of a multi-document YAML file.
+func readManifests(filename string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+}
+
+func qualifiedName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
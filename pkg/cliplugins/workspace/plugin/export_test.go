@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clientgotesting "k8s.io/client-go/testing"
+)
+
+var exportTestResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace", Verbs: []string{"list", "create", "get"}},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "create", "get"}},
+			{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: []string{"list", "create", "get"}},
+			{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"list", "create", "get"}},
+			{Name: "namespaces/status", Kind: "Namespace", Verbs: []string{"get", "update"}},
+		},
+	},
+	{
+		GroupVersion: "apis.kcp.dev/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "apiexports", Kind: "APIExport", Verbs: []string{"list", "create", "get"}},
+		},
+	},
+}
+
+func newExportTestClients(objs ...runtime.Object) (*fakediscovery.FakeDiscovery, *fakedynamic.FakeDynamicClient) {
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clientgotesting.Fake{Resources: exportTestResources}}
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "namespaces"}:                              "NamespaceList",
+		{Version: "v1", Resource: "configmaps"}:                              "ConfigMapList",
+		{Version: "v1", Resource: "secrets"}:                                 "SecretList",
+		{Version: "v1", Resource: "events"}:                                  "EventList",
+		{Group: "apis.kcp.dev", Version: "v1alpha1", Resource: "apiexports"}: "APIExportList",
+	}, objs...)
+	return discoveryClient, dynamicClient
+}
+
+func newUnstructured(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestExportImportWorkspace(t *testing.T) {
+	ns := newUnstructured("v1", "Namespace", "", "default", map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Active"},
+	})
+	ns.SetUID("1234")
+	ns.SetResourceVersion("42")
+	ns.SetClusterName("root:org:ws")
+
+	cm := newUnstructured("v1", "ConfigMap", "default", "cm", map[string]interface{}{
+		"data": map[string]interface{}{"foo": "bar"},
+	})
+	rootCA := newUnstructured("v1", "ConfigMap", "default", "kube-root-ca.crt", nil)
+	owned := newUnstructured("v1", "ConfigMap", "default", "owned", nil)
+	controller := true
+	owned.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", UID: "5678", Controller: &controller}})
+	token := newUnstructured("v1", "Secret", "default", "token", map[string]interface{}{
+		"type": "kubernetes.io/service-account-token",
+	})
+	identity := newUnstructured("v1", "Secret", "kcp-system", "identity", map[string]interface{}{
+		"data": map[string]interface{}{"key": "c2VjcmV0"},
+	})
+	export := newUnstructured("apis.kcp.dev/v1alpha1", "APIExport", "", "export", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"secretRef": map[string]interface{}{"namespace": "kcp-system", "name": "identity"},
+			},
+		},
+	})
+	event := newUnstructured("v1", "Event", "default", "event", nil)
+
+	discoveryClient, dynamicClient := newExportTestClients(ns, cm, rootCA, owned, token, identity, export, event)
+
+	dir := t.TempDir()
+	n, err := exportWorkspace(context.Background(), discoveryClient, dynamicClient, dir)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var filenames []string
+	for _, info := range infos {
+		filenames = append(filenames, info.Name())
+	}
+	require.Equal(t, []string{
+		"02-namespaces.yaml",
+		"03-secrets.yaml",
+		"04-apiexports.apis.kcp.dev.yaml",
+		"07-configmaps.yaml",
+	}, filenames)
+
+	objs, err := readManifests(filepath.Join(dir, "02-namespaces.yaml"))
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	require.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": "default"},
+	}, objs[0].Object, "expected server-populated fields to be stripped")
+
+	objs, err = readManifests(filepath.Join(dir, "04-apiexports.apis.kcp.dev.yaml"))
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	require.Equal(t, export.Object["spec"], objs[0].Object["spec"], "expected identity reference to be kept")
+
+	// import into a workspace which already has the namespace
+	existing := newUnstructured("v1", "Namespace", "", "default", nil)
+	discoveryClient, dynamicClient = newExportTestClients(existing)
+	var out bytes.Buffer
+	n, err = importWorkspace(context.Background(), discoveryClient, dynamicClient, dir, time.Second, &out)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Contains(t, out.String(), `namespaces "default" already exists`)
+
+	var created []string
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "create" {
+			created = append(created, action.GetResource().Resource)
+		}
+	}
+	require.Equal(t, []string{"namespaces", "secrets", "apiexports", "configmaps"}, created, "expected objects to be created in dependency order")
+
+	_, err = dynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}).Namespace("kcp-system").Get(context.Background(), "identity", metav1.GetOptions{})
+	require.NoError(t, err)
+}
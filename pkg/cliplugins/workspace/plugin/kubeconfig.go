@@ -61,6 +61,9 @@ type KubeConfig struct {
 	personalClient tenancyclient.ClusterInterface
 	modifyConfig   func(newConfig *clientcmdapi.Config) error
 
+	// completionCacheDir is where shell completion results are cached. Empty disables caching.
+	completionCacheDir string
+
 	genericclioptions.IOStreams
 }
 
@@ -106,6 +109,7 @@ func NewKubeConfig(opts *Options) (*KubeConfig, error) {
 		modifyConfig: func(newConfig *clientcmdapi.Config) error {
 			return clientcmd.ModifyConfig(configAccess, *newConfig, true)
 		},
+		completionCacheDir: defaultCompletionCacheDir(),
 
 		IOStreams: opts.IOStreams,
 	}, nil
@@ -161,55 +165,15 @@ func (kc *KubeConfig) UseWorkspace(ctx context.Context, name string) error {
 
 		return kc.currentWorkspace(ctx, newKubeConfig.Clusters[newKubeConfig.Contexts[kcpCurrentWorkspaceContextKey].Cluster].Server, "", false)
 
-	case "..":
-		config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
-		if err != nil {
-			return err
-		}
-		u, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
-		if err != nil {
-			return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
-		}
-		parentClusterName, hasParent := currentClusterName.Parent()
-		if !hasParent {
-			if currentClusterName == tenancyv1alpha1.RootCluster {
-				return fmt.Errorf("current workspace is %q", currentClusterName)
-			}
-			return fmt.Errorf("current workspace %q has no parent", currentClusterName)
-		}
-		u.Path = path.Join(u.Path, parentClusterName.Path())
-		newServerHost = u.String()
-
 	case "":
 		return kc.CurrentWorkspace(ctx, false)
 
 	default:
-		config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
+		var err error
+		newServerHost, workspaceType, err = kc.workspaceHost(ctx, name)
 		if err != nil {
 			return err
 		}
-		u, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
-		if err != nil {
-			return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
-		}
-
-		if strings.Contains(name, ":") || name == tenancyv1alpha1.RootCluster.String() {
-			// absolute logical cluster
-			u.Path = path.Join(u.Path, logicalcluster.New(name).Path())
-			newServerHost = u.String()
-		} else {
-			// relative logical cluster, get URL from workspace object in current context
-			ws, err := kc.personalClient.Cluster(currentClusterName).TenancyV1beta1().Workspaces().Get(ctx, name, metav1.GetOptions{})
-			if err != nil {
-				return err
-			}
-			if ws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
-				return fmt.Errorf("workspace %q is not ready", name)
-			}
-
-			newServerHost = ws.Status.URL
-			workspaceType = ws.Spec.Type
-		}
 	}
 
 	// modify kubeconfig, using the "workspace" context and cluster
@@ -242,6 +206,49 @@ func (kc *KubeConfig) UseWorkspace(ctx context.Context, name string) error {
 	return kc.currentWorkspace(ctx, newServerHost, workspaceType, false)
 }
 
+// workspaceHost resolves "..", an absolute workspace, or a workspace relative to the current
+// workspace to the server URL of the workspace. Relative workspaces are looked up and
+// checked to be ready, and their type is returned.
+func (kc *KubeConfig) workspaceHost(ctx context.Context, name string) (string, string, error) {
+	config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
+	if err != nil {
+		return "", "", err
+	}
+	u, currentClusterName, err := pluginhelpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return "", "", fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+
+	switch {
+	case name == "..":
+		parentClusterName, hasParent := currentClusterName.Parent()
+		if !hasParent {
+			if currentClusterName == tenancyv1alpha1.RootCluster {
+				return "", "", fmt.Errorf("current workspace is %q", currentClusterName)
+			}
+			return "", "", fmt.Errorf("current workspace %q has no parent", currentClusterName)
+		}
+		u.Path = path.Join(u.Path, parentClusterName.Path())
+		return u.String(), "", nil
+
+	case strings.Contains(name, ":") || name == tenancyv1alpha1.RootCluster.String():
+		// absolute logical cluster
+		u.Path = path.Join(u.Path, logicalcluster.New(name).Path())
+		return u.String(), "", nil
+
+	default:
+		// relative logical cluster, get URL from workspace object in current context
+		ws, err := kc.personalClient.Cluster(currentClusterName).TenancyV1beta1().Workspaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", err
+		}
+		if ws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+			return "", "", fmt.Errorf("workspace %q is not ready", name)
+		}
+		return ws.Status.URL, ws.Spec.Type, nil
+	}
+}
+
 // CurrentWorkspace outputs the current workspace.
 func (kc *KubeConfig) CurrentWorkspace(ctx context.Context, shortWorkspaceOutput bool) error {
 	config, err := clientcmd.NewDefaultClientConfig(*kc.startingConfig, kc.overrides).ClientConfig()
@@ -300,13 +307,18 @@ func (kc *KubeConfig) CreateWorkspace(ctx context.Context, workspaceName string,
 		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
 	}
 
+	newWorkspaceType := workspaceType
+	if newWorkspaceType == "" {
+		newWorkspaceType = kc.defaultChildWorkspaceType(ctx, currentClusterName)
+	}
+
 	preExisting := false
 	ws, err := kc.personalClient.Cluster(currentClusterName).TenancyV1beta1().Workspaces().Create(ctx, &tenancyv1beta1.Workspace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: workspaceName,
 		},
 		Spec: tenancyv1beta1.WorkspaceSpec{
-			Type: workspaceType,
+			Type: newWorkspaceType,
 		},
 	}, metav1.CreateOptions{})
 	if apierrors.IsAlreadyExists(err) && ignoreExisting {
@@ -374,6 +386,28 @@ func (kc *KubeConfig) CreateWorkspace(ctx context.Context, workspaceName string,
 	return nil
 }
 
+// defaultChildWorkspaceType returns the default type of workspaces created in the given
+// workspace, as defined by the ClusterWorkspaceType of that workspace. If it cannot be
+// determined, e.g. due to missing permissions, the empty string is returned and the
+// server-side default applies.
+func (kc *KubeConfig) defaultChildWorkspaceType(ctx context.Context, clusterName logicalcluster.Name) string {
+	parentClusterName, workspaceName := clusterName.Split()
+	if parentClusterName.Empty() {
+		return ""
+	}
+
+	ws, err := getWorkspaceFromInternalName(ctx, workspaceName, kc.clusterClient.Cluster(parentClusterName))
+	if err != nil {
+		return ""
+	}
+	cwt, err := kc.clusterClient.Cluster(parentClusterName).TenancyV1alpha1().ClusterWorkspaceTypes().Get(ctx, strings.ToLower(ws.Spec.Type), metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+
+	return string(cwt.Spec.DefaultChildWorkspaceType)
+}
+
 // ListWorkspaces outputs the list of workspaces of the current user
 // (kubeconfig user possibly overridden by CLI options).
 func (kc *KubeConfig) ListWorkspaces(ctx context.Context, opts *Options) error {
@@ -50,12 +50,16 @@ func TestCreate(t *testing.T) {
 		existingWorkspaces []string // existing cluster workspaces
 		markReady          bool
 
+		currentWorkspaceType string
+		types                []*tenancyv1alpha1.ClusterWorkspaceType // types in the parent of the current workspace
+
 		newWorkspaceName                 string
 		newWorkspaceType                 string
 		useAfterCreation, ignoreExisting bool
 
-		expected *clientcmdapi.Config
-		wantErr  bool
+		expected     *clientcmdapi.Config
+		expectedType string
+		wantErr      bool
 	}{
 		{
 			name: "happy case, no use",
@@ -65,6 +69,56 @@ func TestCreate(t *testing.T) {
 				AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
 			},
 			newWorkspaceName: "bar",
+			markReady:        true,
+		},
+		{
+			name: "default child type of the current workspace type",
+			config: clientcmdapi.Config{CurrentContext: "test",
+				Contexts:  map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+				Clusters:  map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:foo"}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
+			},
+			currentWorkspaceType: "Organization",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "organization"},
+					Spec:       tenancyv1alpha1.ClusterWorkspaceTypeSpec{DefaultChildWorkspaceType: "Team"},
+				},
+			},
+			newWorkspaceName: "bar",
+			markReady:        true,
+			expectedType:     "Team",
+		},
+		{
+			name: "explicit type overrides the default child type",
+			config: clientcmdapi.Config{CurrentContext: "test",
+				Contexts:  map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+				Clusters:  map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:foo"}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
+			},
+			currentWorkspaceType: "Organization",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "organization"},
+					Spec:       tenancyv1alpha1.ClusterWorkspaceTypeSpec{DefaultChildWorkspaceType: "Team"},
+				},
+			},
+			newWorkspaceName: "bar",
+			markReady:        true,
+			newWorkspaceType: "Universal",
+			expectedType:     "Universal",
+		},
+		{
+			name: "no default child type without type object",
+			config: clientcmdapi.Config{CurrentContext: "test",
+				Contexts:  map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+				Clusters:  map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:foo"}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
+			},
+			currentWorkspaceType: "Organization",
+			newWorkspaceName:     "bar",
+			markReady:            true,
+			expectedType:         "",
 		},
 		{
 			name: "create, use after creation, but not ready",
@@ -139,6 +193,7 @@ func TestCreate(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -166,6 +221,7 @@ func TestCreate(t *testing.T) {
 			}
 			client := tenancyfake.NewSimpleClientset(objects...)
 
+			var createdType string
 			if tt.markReady {
 				client.PrependReactor("create", "workspaces", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
 					obj := action.(clientgotesting.CreateAction).GetObject().(*tenancyv1beta1.Workspace)
@@ -173,7 +229,6 @@ func TestCreate(t *testing.T) {
 					u := parseURLOrDie(u.String())
 					u.Path = currentClusterName.Join(obj.Name).Path()
 					obj.Status.URL = u.String()
-					obj.Spec.Type = tt.newWorkspaceType
 					if obj.Spec.Type == "" {
 						obj.Spec.Type = "Universal"
 					}
@@ -184,6 +239,32 @@ func TestCreate(t *testing.T) {
 				})
 			}
 
+			// Record the type the workspace is created with, before the server defaults it.
+			client.PrependReactor("create", "workspaces", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				createdType = action.(clientgotesting.CreateAction).GetObject().(*tenancyv1beta1.Workspace).Spec.Type
+				return false, nil, nil
+			})
+
+			currentWorkspaceType := tt.currentWorkspaceType
+			if currentWorkspaceType == "" {
+				currentWorkspaceType = "Universal"
+			}
+			parentClusterName, currentWorkspaceName := currentClusterName.Split()
+			parentObjects := []runtime.Object{
+				&tenancyv1beta1.Workspace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: currentWorkspaceName,
+					},
+					Spec: tenancyv1beta1.WorkspaceSpec{
+						Type: currentWorkspaceType,
+					},
+				},
+			}
+			for _, cwt := range tt.types {
+				parentObjects = append(parentObjects, cwt)
+			}
+			parentClient := tenancyfake.NewSimpleClientset(parentObjects...)
+
 			kc := &KubeConfig{
 				startingConfig: tt.config.DeepCopy(),
 				currentContext: tt.config.CurrentContext,
@@ -192,6 +273,7 @@ func TestCreate(t *testing.T) {
 					t: t,
 					clients: map[logicalcluster.Name]*tenancyfake.Clientset{
 						currentClusterName: client,
+						parentClusterName:  parentClient,
 					},
 				},
 				personalClient: fakeTenancyClient{
@@ -213,6 +295,10 @@ func TestCreate(t *testing.T) {
 				require.NoError(t, err)
 			}
 
+			if !tt.wantErr && !tt.ignoreExisting {
+				require.Equal(t, tt.expectedType, createdType)
+			}
+
 			if got != nil && tt.expected == nil {
 				t.Errorf("unexpected kubeconfig write")
 			} else if got == nil && tt.expected != nil {
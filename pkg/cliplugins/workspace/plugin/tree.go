@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// workspaceTreeNode is a workspace in the hierarchy printed by "tree".
+type workspaceTreeNode struct {
+	Name     string                                    `json:"name"`
+	Cluster  string                                    `json:"cluster,omitempty"`
+	Type     string                                    `json:"type,omitempty"`
+	Phase    tenancyv1alpha1.ClusterWorkspacePhaseType `json:"phase,omitempty"`
+	Error    string                                    `json:"error,omitempty"`
+	Children []*workspaceTreeNode                      `json:"children,omitempty"`
+}
+
+// TreeWorkspaces prints the hierarchy of workspaces below the given workspace
+// (or the current one), as far as the user is allowed to see it through the
+// workspaces virtual workspace. A maxDepth of 0 means no limit. The output
+// format is either empty for a human-readable tree, "json" or "yaml".
+func (kc *KubeConfig) TreeWorkspaces(ctx context.Context, opts *Options, workspace string, maxDepth int, output string) error {
+	if output != "" && output != "json" && output != "yaml" {
+		return fmt.Errorf("unsupported output format %q, must be one of json, yaml", output)
+	}
+
+	_, clusterName, err := kc.workspaceConfig(ctx, workspace)
+	if err != nil {
+		return err
+	}
+
+	root := &workspaceTreeNode{
+		Name:    clusterName.String(),
+		Cluster: clusterName.String(),
+	}
+	if err := kc.walkWorkspaces(ctx, opts.Scope, root, clusterName, 1, maxDepth); err != nil {
+		return err
+	}
+
+	switch output {
+	case "json":
+		bs, err := json.MarshalIndent(root, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(kc.Out, string(bs))
+		return err
+	case "yaml":
+		bs, err := yaml.Marshal(root)
+		if err != nil {
+			return err
+		}
+		_, err = kc.Out.Write(bs)
+		return err
+	}
+
+	w := tabwriter.NewWriter(kc.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tPHASE")
+	printWorkspaceTree(w, root, "", "")
+	return w.Flush()
+}
+
+// walkWorkspaces lists the children of the given node in the given logical cluster,
+// and descends into those that are ready. Listing is authorized per level: if the
+// user is not allowed to list workspaces in a workspace, the error is recorded on
+// the node and the walk continues with its siblings.
+func (kc *KubeConfig) walkWorkspaces(ctx context.Context, scope string, node *workspaceTreeNode, clusterName logicalcluster.Name, depth, maxDepth int) error {
+	if maxDepth > 0 && depth > maxDepth {
+		return nil
+	}
+
+	client := kc.clusterClient
+	if scope == "personal" {
+		client = kc.personalClient
+	}
+	list, err := client.Cluster(clusterName).TenancyV1beta1().Workspaces().List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		node.Error = "forbidden"
+		return nil
+	case apierrors.IsNotFound(err):
+		// workspaces of this type cannot have children
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to list workspaces in %q: %w", clusterName, err)
+	}
+
+	workspaces := list.Items
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	for _, ws := range workspaces {
+		child := &workspaceTreeNode{
+			Name:  ws.Name,
+			Type:  ws.Spec.Type,
+			Phase: ws.Status.Phase,
+		}
+		node.Children = append(node.Children, child)
+
+		if ws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+			continue
+		}
+		_, childClusterName, err := pluginhelpers.ParseClusterURL(ws.Status.URL)
+		if err != nil {
+			child.Error = fmt.Sprintf("invalid URL %q", ws.Status.URL)
+			continue
+		}
+		child.Cluster = childClusterName.String()
+		if err := kc.walkWorkspaces(ctx, scope, child, childClusterName, depth+1, maxDepth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printWorkspaceTree(w io.Writer, node *workspaceTreeNode, prefix, childPrefix string) {
+	name := node.Name
+	if node.Error != "" {
+		name += fmt.Sprintf(" (%s)", node.Error)
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%s\n", prefix, name, node.Type, node.Phase)
+
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			printWorkspaceTree(w, child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printWorkspaceTree(w, child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientgotesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1beta1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1beta1"
+	tenancyfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+func TestTreeWorkspaces(t *testing.T) {
+	newWorkspace := func(parent logicalcluster.Name, name, typ string, phase tenancyv1alpha1.ClusterWorkspacePhaseType) runtime.Object {
+		ws := &tenancyv1beta1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       tenancyv1beta1.WorkspaceSpec{Type: typ},
+			Status:     tenancyv1beta1.WorkspaceStatus{Phase: phase},
+		}
+		if phase == tenancyv1alpha1.ClusterWorkspacePhaseReady {
+			ws.Status.URL = "https://test" + parent.Join(name).Path()
+		}
+		return ws
+	}
+
+	org := logicalcluster.New("root:org")
+	newClients := func() map[logicalcluster.Name]*tenancyfake.Clientset {
+		forbidden := tenancyfake.NewSimpleClientset()
+		forbidden.PrependReactor("list", "workspaces", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "tenancy.kcp.dev", Resource: "workspaces"}, "", nil)
+		})
+		return map[logicalcluster.Name]*tenancyfake.Clientset{
+			org: tenancyfake.NewSimpleClientset(
+				newWorkspace(org, "team", "Team", tenancyv1alpha1.ClusterWorkspacePhaseReady),
+				newWorkspace(org, "other", "Universal", tenancyv1alpha1.ClusterWorkspacePhaseInitializing),
+			),
+			org.Join("team"): tenancyfake.NewSimpleClientset(
+				newWorkspace(org.Join("team"), "project", "Universal", tenancyv1alpha1.ClusterWorkspacePhaseReady),
+			),
+			org.Join("team").Join("project"): forbidden,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		output   string
+
+		wantStdout string
+		wantErr    bool
+	}{
+		{
+			name: "full tree",
+			wantStdout: `NAME                         TYPE       PHASE
+root:org
+├── other                    Universal  Initializing
+└── team                     Team       Ready
+    └── project (forbidden)  Universal  Ready
+`,
+		},
+		{
+			name:     "limited depth",
+			maxDepth: 1,
+			wantStdout: `NAME       TYPE       PHASE
+root:org
+├── other  Universal  Initializing
+└── team   Team       Ready
+`,
+		},
+		{
+			name:     "yaml",
+			maxDepth: 1,
+			output:   "yaml",
+			wantStdout: `children:
+- name: other
+  phase: Initializing
+  type: Universal
+- cluster: root:org:team
+  name: team
+  phase: Ready
+  type: Team
+cluster: root:org
+name: root:org
+`,
+		},
+		{
+			name:    "invalid output",
+			output:  "wide",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := clientcmdapi.Config{CurrentContext: "test",
+				Contexts:  map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+				Clusters:  map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:org"}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "test"}},
+			}
+
+			streams, _, stdout, _ := genericclioptions.NewTestIOStreams()
+			kc := &KubeConfig{
+				startingConfig: &config,
+				currentContext: config.CurrentContext,
+
+				clusterClient:  fakeTenancyClient{t: t, clients: newClients()},
+				personalClient: fakeTenancyClient{t: t, clients: newClients()},
+				IOStreams:      streams,
+			}
+
+			opts := NewOptions(streams)
+			opts.Scope = "all"
+			err := kc.TreeWorkspaces(context.Background(), opts, "", tt.maxDepth, tt.output)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var lines []string
+			for _, line := range strings.Split(stdout.String(), "\n") {
+				lines = append(lines, strings.TrimRight(line, " "))
+			}
+			require.Equal(t, tt.wantStdout, strings.Join(lines, "\n"))
+		})
+	}
+}
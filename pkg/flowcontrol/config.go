@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flowcontrol implements priority and fairness between logical clusters.
+//
+// Upstream API Priority and Fairness distinguishes flows only by user or
+// namespace. As all workspaces of a shard are served by one apiserver, a single
+// tenant can starve all others. This package classifies requests by their
+// logical cluster, the labels of its ClusterWorkspace, and the requesting user,
+// and dispatches them fairly from per-flow queues, by default one flow per
+// logical cluster.
+package flowcontrol
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/yaml"
+)
+
+// DistinguisherMethod defines how requests of a flow schema are divided into flows.
+type DistinguisherMethod string
+
+const (
+	// ByLogicalCluster puts the requests of each logical cluster into their own flow.
+	// Requests without a logical cluster are distinguished by user.
+	ByLogicalCluster DistinguisherMethod = "ByLogicalCluster"
+	// ByUser puts the requests of each user into their own flow.
+	ByUser DistinguisherMethod = "ByUser"
+)
+
+// Configuration holds the priority levels and flow schemas requests are classified into.
+type Configuration struct {
+	PriorityLevels []PriorityLevel `json:"priorityLevels"`
+	FlowSchemas    []FlowSchema    `json:"flowSchemas"`
+}
+
+// PriorityLevel is a share of the server's concurrency limit. Its requests are queued
+// per flow and dispatched fairly between the flows.
+type PriorityLevel struct {
+	Name string `json:"name"`
+
+	// Exempt requests are never queued nor limited.
+	Exempt bool `json:"exempt,omitempty"`
+
+	// ConcurrencyShares is the level's share of the concurrency limit, relative to the
+	// shares of all non-exempt levels.
+	ConcurrencyShares int `json:"concurrencyShares,omitempty"`
+	// Queues is the number of queues. With zero queues, requests exceeding the level's
+	// concurrency are rejected immediately.
+	Queues int `json:"queues,omitempty"`
+	// HandSize is the number of queues a flow is shuffle-sharded into.
+	HandSize int `json:"handSize,omitempty"`
+	// QueueLengthLimit is the maximum number of requests waiting in one queue.
+	QueueLengthLimit int `json:"queueLengthLimit,omitempty"`
+}
+
+// FlowSchema matches requests and assigns them to a priority level. A request matches
+// if every non-empty criterion does. The matching schema with the lowest precedence wins.
+// Requests matching no schema are not subject to flow control.
+type FlowSchema struct {
+	Name               string `json:"name"`
+	PriorityLevel      string `json:"priorityLevel"`
+	MatchingPrecedence int    `json:"matchingPrecedence"`
+
+	// DistinguisherMethod defaults to ByLogicalCluster.
+	DistinguisherMethod DistinguisherMethod `json:"distinguisherMethod,omitempty"`
+
+	// LogicalClusters are logical cluster names. "*" matches wildcard requests across
+	// all logical clusters, and a trailing ":*" matches all descendants, e.g. "root:org:*".
+	LogicalClusters []string `json:"logicalClusters,omitempty"`
+	// WorkspaceSelector matches the labels of the ClusterWorkspace of the request's
+	// logical cluster. Use the additionalWorkspaceLabels of a ClusterWorkspaceType
+	// to match all workspaces of a type.
+	WorkspaceSelector *metav1.LabelSelector `json:"workspaceSelector,omitempty"`
+	// Users are user names.
+	Users []string `json:"users,omitempty"`
+	// Groups are group names.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// DefaultConfiguration returns the bootstrap configuration: system:masters are exempt,
+// wildcard requests get a small share distinguished by user, requests to workspaces
+// are queued by logical cluster, and everything else shares the rest by user.
+func DefaultConfiguration() *Configuration {
+	return &Configuration{
+		PriorityLevels: []PriorityLevel{
+			{Name: "exempt", Exempt: true},
+			{Name: "workspaces", ConcurrencyShares: 100, Queues: 128, HandSize: 6, QueueLengthLimit: 50},
+			{Name: "wildcard", ConcurrencyShares: 20, Queues: 16, HandSize: 4, QueueLengthLimit: 50},
+			{Name: "other", ConcurrencyShares: 20, Queues: 16, HandSize: 4, QueueLengthLimit: 50},
+		},
+		FlowSchemas: []FlowSchema{
+			{Name: "exempt", PriorityLevel: "exempt", MatchingPrecedence: 1, Groups: []string{user.SystemPrivilegedGroup}},
+			{Name: "wildcard", PriorityLevel: "wildcard", MatchingPrecedence: 500, DistinguisherMethod: ByUser, LogicalClusters: []string{"*"}},
+			{Name: "workspaces", PriorityLevel: "workspaces", MatchingPrecedence: 1000, LogicalClusters: []string{"root", "root:*"}},
+			{Name: "other", PriorityLevel: "other", MatchingPrecedence: 10000, DistinguisherMethod: ByUser},
+		},
+	}
+}
+
+// LoadConfiguration reads a configuration from a YAML or JSON file.
+func LoadConfiguration(file string) (*Configuration, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config Configuration
+	if err := yaml.UnmarshalStrict(bs, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", file, err)
+	}
+	return &config, nil
+}
+
+// Validate checks the configuration for consistency.
+func (c *Configuration) Validate() error {
+	levels := sets.NewString()
+	for _, pl := range c.PriorityLevels {
+		switch {
+		case pl.Name == "":
+			return fmt.Errorf("priority level name must not be empty")
+		case levels.Has(pl.Name):
+			return fmt.Errorf("duplicate priority level %q", pl.Name)
+		case pl.Exempt:
+		case pl.ConcurrencyShares <= 0:
+			return fmt.Errorf("priority level %q: concurrencyShares must be positive", pl.Name)
+		case pl.Queues < 0 || pl.QueueLengthLimit < 0:
+			return fmt.Errorf("priority level %q: queues and queueLengthLimit must not be negative", pl.Name)
+		case pl.Queues > 0 && (pl.HandSize <= 0 || pl.HandSize > pl.Queues):
+			return fmt.Errorf("priority level %q: handSize must be between 1 and the number of queues", pl.Name)
+		}
+		levels.Insert(pl.Name)
+	}
+
+	schemas := sets.NewString()
+	for _, fs := range c.FlowSchemas {
+		switch {
+		case fs.Name == "":
+			return fmt.Errorf("flow schema name must not be empty")
+		case schemas.Has(fs.Name):
+			return fmt.Errorf("duplicate flow schema %q", fs.Name)
+		case !levels.Has(fs.PriorityLevel):
+			return fmt.Errorf("flow schema %q: unknown priority level %q", fs.Name, fs.PriorityLevel)
+		case fs.DistinguisherMethod != "" && fs.DistinguisherMethod != ByLogicalCluster && fs.DistinguisherMethod != ByUser:
+			return fmt.Errorf("flow schema %q: unknown distinguisherMethod %q", fs.Name, fs.DistinguisherMethod)
+		}
+		if fs.WorkspaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(fs.WorkspaceSelector); err != nil {
+				return fmt.Errorf("flow schema %q: invalid workspaceSelector: %w", fs.Name, err)
+			}
+		}
+		schemas.Insert(fs.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	fq "k8s.io/apiserver/pkg/util/flowcontrol/fairqueuing"
+	"k8s.io/apiserver/pkg/util/flowcontrol/fairqueuing/eventclock"
+	"k8s.io/apiserver/pkg/util/flowcontrol/fairqueuing/queueset"
+	fcmetrics "k8s.io/apiserver/pkg/util/flowcontrol/metrics"
+	fcrequest "k8s.io/apiserver/pkg/util/flowcontrol/request"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+// requestWaitLimit is the maximum time a request waits in a queue, like the upstream
+// default of a quarter of the request timeout.
+const requestWaitLimit = 15 * time.Second
+
+// Controller classifies requests into flow schemas and dispatches them through the
+// queues of their priority levels.
+type Controller struct {
+	schemas         []*flowSchema
+	workspaceLister tenancylister.ClusterWorkspaceLister
+}
+
+type priorityLevel struct {
+	name   string
+	exempt bool
+	queues fq.QueueSet
+}
+
+type flowSchema struct {
+	FlowSchema
+
+	level             *priorityLevel
+	logicalClusters   sets.String
+	clusterPrefixes   []string
+	workspaceSelector labels.Selector
+	users             sets.String
+	groups            sets.String
+}
+
+// NewController creates a controller for the given configuration, dividing concurrencyLimit
+// between the non-exempt priority levels by their shares. The workspace lister is used to
+// match workspace selectors.
+func NewController(config *Configuration, concurrencyLimit int, workspaceLister tenancylister.ClusterWorkspaceLister) (*Controller, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if concurrencyLimit <= 0 {
+		return nil, fmt.Errorf("concurrency limit must be positive")
+	}
+
+	fcmetrics.Register()
+
+	totalShares := 0
+	for _, pl := range config.PriorityLevels {
+		if !pl.Exempt {
+			totalShares += pl.ConcurrencyShares
+		}
+	}
+
+	factory := queueset.NewQueueSetFactory(eventclock.Real{})
+	levels := map[string]*priorityLevel{}
+	for _, pl := range config.PriorityLevels {
+		level := &priorityLevel{name: pl.Name, exempt: pl.Exempt}
+		levels[pl.Name] = level
+		if pl.Exempt {
+			continue
+		}
+
+		labelValues := []string{pl.Name}
+		completer, err := factory.BeginConstruction(fq.QueuingConfig{
+			Name:             pl.Name,
+			DesiredNumQueues: pl.Queues,
+			QueueLengthLimit: pl.QueueLengthLimit,
+			HandSize:         pl.HandSize,
+			RequestWaitLimit: requestWaitLimit,
+		}, fcmetrics.PriorityLevelConcurrencyObserverPairGenerator.Generate(1, 1, labelValues), fcmetrics.PriorityLevelExecutionSeatsObserverGenerator.Generate(1, 1, labelValues))
+		if err != nil {
+			return nil, fmt.Errorf("priority level %q: %w", pl.Name, err)
+		}
+		// round up, such that every level can execute at least one request
+		limit := (concurrencyLimit*pl.ConcurrencyShares + totalShares - 1) / totalShares
+		level.queues = completer.Complete(fq.DispatchingConfig{ConcurrencyLimit: limit})
+		fcmetrics.UpdateSharedConcurrencyLimit(pl.Name, limit)
+	}
+
+	c := &Controller{workspaceLister: workspaceLister}
+	for _, fs := range config.FlowSchemas {
+		schema := &flowSchema{
+			FlowSchema:      fs,
+			level:           levels[fs.PriorityLevel],
+			logicalClusters: sets.NewString(),
+			users:           sets.NewString(fs.Users...),
+			groups:          sets.NewString(fs.Groups...),
+		}
+		if schema.DistinguisherMethod == "" {
+			schema.DistinguisherMethod = ByLogicalCluster
+		}
+		for _, name := range fs.LogicalClusters {
+			if name != logicalcluster.Wildcard.String() && strings.HasSuffix(name, ":*") {
+				schema.clusterPrefixes = append(schema.clusterPrefixes, strings.TrimSuffix(name, "*"))
+			} else {
+				schema.logicalClusters.Insert(name)
+			}
+		}
+		if fs.WorkspaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(fs.WorkspaceSelector)
+			if err != nil {
+				return nil, err // validated above
+			}
+			schema.workspaceSelector = selector
+		}
+		c.schemas = append(c.schemas, schema)
+	}
+	sort.SliceStable(c.schemas, func(i, j int) bool {
+		return c.schemas[i].MatchingPrecedence < c.schemas[j].MatchingPrecedence
+	})
+
+	return c, nil
+}
+
+// match returns the flow schema with the lowest precedence matching the request, or nil.
+func (c *Controller) match(clusterName logicalcluster.Name, u user.Info) *flowSchema {
+	var workspaceLabels labels.Set
+	workspaceFound, workspaceLoaded := false, false
+
+	for _, schema := range c.schemas {
+		if len(schema.LogicalClusters) > 0 && !schema.matchesCluster(clusterName) {
+			continue
+		}
+		if len(schema.users) > 0 && !schema.users.Has(u.GetName()) {
+			continue
+		}
+		if len(schema.groups) > 0 && !schema.groups.HasAny(u.GetGroups()...) {
+			continue
+		}
+		if schema.workspaceSelector != nil {
+			if !workspaceLoaded {
+				workspaceLabels, workspaceFound = c.workspaceLabels(clusterName)
+				workspaceLoaded = true
+			}
+			if !workspaceFound || !schema.workspaceSelector.Matches(workspaceLabels) {
+				continue
+			}
+		}
+		return schema
+	}
+	return nil
+}
+
+func (s *flowSchema) matchesCluster(clusterName logicalcluster.Name) bool {
+	if clusterName.Empty() {
+		return false
+	}
+	if s.logicalClusters.Has(clusterName.String()) {
+		return true
+	}
+	for _, prefix := range s.clusterPrefixes {
+		if strings.HasPrefix(clusterName.String(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// workspaceLabels returns the labels of the ClusterWorkspace of the given logical cluster,
+// and false if there is none.
+func (c *Controller) workspaceLabels(clusterName logicalcluster.Name) (labels.Set, bool) {
+	if c.workspaceLister == nil || clusterName.Empty() || clusterName == logicalcluster.Wildcard || clusterName == tenancyv1alpha1.RootCluster {
+		return nil, false
+	}
+	parent, name := clusterName.Split()
+	if parent.Empty() {
+		return nil, false
+	}
+	workspace, err := c.workspaceLister.Get(clusters.ToClusterAwareKey(parent, name))
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to get ClusterWorkspace for %q: %v", clusterName, err)
+		}
+		return nil, false
+	}
+	return workspace.Labels, true
+}
+
+// distinguisher returns the flow of a request within the schema.
+func (s *flowSchema) distinguisher(clusterName logicalcluster.Name, u user.Info) string {
+	if s.DistinguisherMethod == ByLogicalCluster && !clusterName.Empty() {
+		return clusterName.String()
+	}
+	return u.GetName()
+}
+
+// startRequest classifies the request and, unless exempt, enqueues it. It returns whether
+// the request is exempt, and otherwise the request to finish, or nil if it was rejected.
+func (c *Controller) startRequest(ctx context.Context, clusterName logicalcluster.Name, u user.Info, descr interface{}) (exempt bool, req fq.Request) {
+	schema := c.match(clusterName, u)
+	if schema == nil || schema.level.exempt {
+		return true, nil
+	}
+
+	distinguisher := schema.distinguisher(clusterName, u)
+	req, _ = schema.level.queues.StartRequest(ctx, &fcrequest.WorkEstimate{InitialSeats: 1}, hashFlowID(schema.Name, distinguisher), distinguisher, schema.Name, descr, u, func(bool) {})
+	return false, req
+}
+
+// hashFlowID hashes the flow schema and distinguisher for shuffle sharding, like upstream.
+func hashFlowID(schemaName, distinguisher string) uint64 {
+	hash := sha256.New()
+	hash.Write([]byte(schemaName))
+	hash.Write([]byte{0})
+	hash.Write([]byte(distinguisher))
+	var sum [32]byte
+	hash.Sum(sum[:0])
+	return binary.LittleEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+func newWorkspaceLister(t *testing.T, workspaces ...*tenancyv1alpha1.ClusterWorkspace) tenancylister.ClusterWorkspaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ws := range workspaces {
+		require.NoError(t, indexer.Add(ws))
+	}
+	return tenancylister.NewClusterWorkspaceLister(indexer)
+}
+
+func TestMatch(t *testing.T) {
+	config := DefaultConfiguration()
+	config.PriorityLevels = append(config.PriorityLevels, PriorityLevel{Name: "premium", ConcurrencyShares: 10})
+	config.FlowSchemas = append(config.FlowSchemas, FlowSchema{
+		Name:               "premium",
+		PriorityLevel:      "premium",
+		MatchingPrecedence: 100,
+		WorkspaceSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "premium"}},
+	})
+
+	c, err := NewController(config, 100, newWorkspaceLister(t,
+		&tenancyv1alpha1.ClusterWorkspace{ObjectMeta: metav1.ObjectMeta{Name: "org", ClusterName: "root"}},
+		&tenancyv1alpha1.ClusterWorkspace{ObjectMeta: metav1.ObjectMeta{Name: "gold", ClusterName: "root:org", Labels: map[string]string{"tier": "premium"}}},
+	))
+	require.NoError(t, err)
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}}
+	admin := &user.DefaultInfo{Name: "admin", Groups: []string{user.SystemPrivilegedGroup}}
+
+	tests := []struct {
+		name              string
+		cluster           string
+		user              user.Info
+		wantSchema        string
+		wantDistinguisher string
+	}{
+		{name: "admin is exempt", cluster: "root:org", user: admin, wantSchema: "exempt", wantDistinguisher: "root:org"},
+		{name: "root", cluster: "root", user: alice, wantSchema: "workspaces", wantDistinguisher: "root"},
+		{name: "workspace", cluster: "root:org", user: alice, wantSchema: "workspaces", wantDistinguisher: "root:org"},
+		{name: "unknown workspace", cluster: "root:org:unknown", user: alice, wantSchema: "workspaces", wantDistinguisher: "root:org:unknown"},
+		{name: "labelled workspace", cluster: "root:org:gold", user: alice, wantSchema: "premium", wantDistinguisher: "root:org:gold"},
+		{name: "wildcard", cluster: "*", user: alice, wantSchema: "wildcard", wantDistinguisher: "alice"},
+		{name: "system cluster", cluster: "system:admin", user: alice, wantSchema: "other", wantDistinguisher: "alice"},
+		{name: "no cluster", user: alice, wantSchema: "other", wantDistinguisher: "alice"},
+		{name: "not root", cluster: "rootless", user: alice, wantSchema: "other", wantDistinguisher: "alice"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var clusterName logicalcluster.Name
+			if tt.cluster != "" {
+				clusterName = logicalcluster.New(tt.cluster)
+			}
+			schema := c.match(clusterName, tt.user)
+			require.NotNil(t, schema)
+			require.Equal(t, tt.wantSchema, schema.Name)
+			require.Equal(t, tt.wantDistinguisher, schema.distinguisher(clusterName, tt.user))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Configuration)
+		wantErr bool
+	}{
+		{name: "default", modify: func(*Configuration) {}},
+		{name: "unknown level", modify: func(c *Configuration) { c.FlowSchemas[0].PriorityLevel = "unknown" }, wantErr: true},
+		{name: "duplicate level", modify: func(c *Configuration) { c.PriorityLevels[1].Name = "exempt" }, wantErr: true},
+		{name: "duplicate schema", modify: func(c *Configuration) { c.FlowSchemas[1].Name = "exempt" }, wantErr: true},
+		{name: "no shares", modify: func(c *Configuration) { c.PriorityLevels[1].ConcurrencyShares = 0 }, wantErr: true},
+		{name: "hand larger than queues", modify: func(c *Configuration) { c.PriorityLevels[1].HandSize = 1000 }, wantErr: true},
+		{name: "unknown distinguisher", modify: func(c *Configuration) { c.FlowSchemas[1].DistinguisherMethod = "ByNamespace" }, wantErr: true},
+		{name: "invalid selector", modify: func(c *Configuration) {
+			c.FlowSchemas[1].WorkspaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"in valid": "x"}}
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfiguration()
+			tt.modify(config)
+			err := config.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+priorityLevels:
+- name: workspaces
+  concurrencyShares: 1
+flowSchemas:
+- name: workspaces
+  priorityLevel: workspaces
+  logicalClusters: ["root:*"]
+`), 0600))
+	config, err := LoadConfiguration(file)
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:*"}, config.FlowSchemas[0].LogicalClusters)
+
+	require.NoError(t, os.WriteFile(file, []byte("priorityLevels: []\nunknown: true\n"), 0600))
+	_, err = LoadConfiguration(file)
+	require.Error(t, err, "unknown fields must be rejected")
+}
+
+func TestWithPriorityAndFairness(t *testing.T) {
+	c, err := NewController(&Configuration{
+		PriorityLevels: []PriorityLevel{{Name: "exempt", Exempt: true}, {Name: "limited", ConcurrencyShares: 1}},
+		FlowSchemas: []FlowSchema{
+			{Name: "exempt", PriorityLevel: "exempt", MatchingPrecedence: 1, Users: []string{"admin"}},
+			{Name: "limited", PriorityLevel: "limited", MatchingPrecedence: 2, LogicalClusters: []string{"root:*"}},
+		},
+	}, 1, nil)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := WithPriorityAndFairness(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/block" {
+			close(started)
+			<-release
+		}
+	}), c, func(req *http.Request, requestInfo *request.RequestInfo) bool {
+		return requestInfo.Verb == "watch"
+	})
+
+	serve := func(path, userName, cluster, verb string) *httptest.ResponseRecorder {
+		ctx := request.WithUser(request.NewContext(), &user.DefaultInfo{Name: userName})
+		ctx = request.WithRequestInfo(ctx, &request.RequestInfo{IsResourceRequest: true, Verb: verb})
+		ctx = request.WithCluster(ctx, request.Cluster{Name: logicalcluster.New(cluster)})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		return w
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve("/block", "alice", "root:org", "list")
+	}()
+	<-started
+
+	w := serve("/", "bob", "root:other", "list")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, serve("/", "bob", "root:other", "watch").Code, "long-running requests are not limited")
+	require.Equal(t, http.StatusOK, serve("/", "admin", "root:other", "list").Code, "exempt requests are not limited")
+	require.Equal(t, http.StatusOK, serve("/", "bob", "system:admin", "list").Code, "unmatched requests are not limited")
+
+	close(release)
+	<-done
+	require.Equal(t, http.StatusOK, serve("/", "bob", "root:other", "list").Code)
+}
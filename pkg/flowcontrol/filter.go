@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// WithPriorityAndFairness limits the concurrency of requests by the flow schemas and
+// priority levels of the controller. It must run after authentication and after the
+// request info and logical cluster are set in the context. Long-running requests like
+// watches are not limited. Rejected requests get a 429 with a Retry-After header.
+func WithPriorityAndFairness(handler http.Handler, c *Controller, longRunningRequestCheck request.LongRunningRequestCheck) http.Handler {
+	if c == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		requestInfo, ok := request.RequestInfoFrom(ctx)
+		if !ok {
+			responsewriters.InternalError(w, req, errors.New("no RequestInfo found in the context"))
+			return
+		}
+		if longRunningRequestCheck != nil && longRunningRequestCheck(req, requestInfo) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		u, ok := request.UserFrom(ctx)
+		if !ok {
+			responsewriters.InternalError(w, req, errors.New("no user found in the context"))
+			return
+		}
+		var clusterName logicalcluster.Name
+		if cluster := request.ClusterFrom(ctx); cluster != nil {
+			clusterName = cluster.Name
+		}
+
+		exempt, r := c.startRequest(ctx, clusterName, u, requestInfo)
+		if exempt {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		served := false
+		if r != nil {
+			r.Finish(func() {
+				served = true
+				handler.ServeHTTP(w, req)
+			})
+		}
+		if !served {
+			tooManyRequests(w)
+		}
+	})
+}
+
+func tooManyRequests(w http.ResponseWriter) {
+	// Return a 429 status indicating "Too Many Requests"
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "Too many requests, please try again later.", http.StatusTooManyRequests)
+}
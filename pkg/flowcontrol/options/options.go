@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/flowcontrol"
+)
+
+type WorkspaceFairness struct {
+	Enabled          bool
+	ConfigFile       string
+	ConcurrencyLimit int
+}
+
+func NewWorkspaceFairness() *WorkspaceFairness {
+	return &WorkspaceFairness{
+		Enabled: true,
+		// the sum of the upstream --max-requests-inflight and --max-mutating-requests-inflight defaults
+		ConcurrencyLimit: 600,
+	}
+}
+
+func (o *WorkspaceFairness) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "workspace-fairness", o.Enabled, "Queue requests by logical cluster and dispatch them fairly between workspaces, instead of the upstream priority and fairness and max-in-flight limits.")
+	fs.StringVar(&o.ConfigFile, "workspace-fairness-config-file", o.ConfigFile, "File with the priority levels and flow schemas for workspace fairness. If empty, a default configuration is used.")
+	fs.IntVar(&o.ConcurrencyLimit, "workspace-fairness-concurrency-limit", o.ConcurrencyLimit, "Maximum number of requests executing concurrently, divided between the priority levels by their shares.")
+}
+
+func (o *WorkspaceFairness) Validate() []error {
+	var errs []error
+
+	if o.Enabled && o.ConcurrencyLimit <= 0 {
+		errs = append(errs, fmt.Errorf("--workspace-fairness-concurrency-limit must be positive"))
+	}
+	if !o.Enabled && o.ConfigFile != "" {
+		errs = append(errs, fmt.Errorf("--workspace-fairness-config-file requires --workspace-fairness"))
+	}
+
+	return errs
+}
+
+// ApplyTo disables the upstream priority and fairness and max-in-flight limits of the given
+// config if workspace fairness is enabled, such that requests are only queued once.
+func (o *WorkspaceFairness) ApplyTo(config *genericapiserver.Config) {
+	if !o.Enabled {
+		return
+	}
+	config.FlowControl = nil
+	config.MaxRequestsInFlight = 0
+	config.MaxMutatingRequestsInFlight = 0
+}
+
+// NewController returns the workspace fairness controller, or nil if disabled.
+func (o *WorkspaceFairness) NewController(workspaceLister tenancylister.ClusterWorkspaceLister) (*flowcontrol.Controller, error) {
+	if !o.Enabled {
+		return nil, nil
+	}
+
+	config := flowcontrol.DefaultConfiguration()
+	if o.ConfigFile != "" {
+		var err error
+		if config, err = flowcontrol.LoadConfiguration(o.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return flowcontrol.NewController(config, o.ConcurrencyLimit, workspaceLister)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	genericfilters "k8s.io/apiserver/pkg/server/filters"
+	utilflowcontrol "k8s.io/apiserver/pkg/util/flowcontrol"
+)
+
+type upstreamFlowControl struct {
+	utilflowcontrol.Interface
+}
+
+func TestApplyTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		wantCode int
+	}{
+		{name: "workspace fairness enabled", enabled: true, wantCode: http.StatusOK},
+		{name: "workspace fairness disabled", enabled: false, wantCode: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := genericapiserver.NewConfig(serializer.NewCodecFactory(runtime.NewScheme()))
+			config.MaxRequestsInFlight = 1
+			config.MaxMutatingRequestsInFlight = 1
+			if tt.enabled {
+				config.FlowControl = upstreamFlowControl{}
+			}
+
+			o := NewWorkspaceFairness()
+			o.Enabled = tt.enabled
+			o.ApplyTo(config)
+			if tt.enabled {
+				require.Nil(t, config.FlowControl, "the upstream priority and fairness must be disabled")
+			}
+
+			// Without FlowControl, DefaultBuildHandlerChain limits requests by the max-in-flight
+			// limits. Only with workspace fairness disabled, they are still in place.
+			release := make(chan struct{})
+			started := make(chan struct{})
+			handler := genericfilters.WithMaxInFlightLimit(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/block" {
+					close(started)
+					<-release
+				}
+			}), config.MaxRequestsInFlight, config.MaxMutatingRequestsInFlight, config.LongRunningFunc)
+
+			serve := func(path string) *httptest.ResponseRecorder {
+				ctx := request.WithUser(request.NewContext(), &user.DefaultInfo{Name: "alice"})
+				ctx = request.WithRequestInfo(ctx, &request.RequestInfo{IsResourceRequest: true, Verb: "list"})
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+				return w
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				serve("/block")
+			}()
+			<-started
+
+			require.Equal(t, tt.wantCode, serve("/").Code)
+
+			close(release)
+			<-done
+		})
+	}
+}
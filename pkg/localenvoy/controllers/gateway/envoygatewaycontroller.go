@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	envoycontrolplane "github.com/kcp-dev/kcp/pkg/localenvoy/controlplane"
+)
+
+const (
+	controllerName = "kcp-envoy-gateway"
+
+	// envoyConfigKey is the single key of the queue: any change to the HTTPRoutes or Gateways
+	// updates the whole Envoy configuration.
+	envoyConfigKey = "envoy-config"
+)
+
+// NewController returns a new Controller which updates the envoy controlplane on the
+// changes of the Gateway API HTTPRoutes and Gateways.
+func NewController(
+	gatewayInformer informers.GenericInformer,
+	httpRouteInformer informers.GenericInformer,
+	ecp *envoycontrolplane.EnvoyControlPlane) *Controller {
+
+	c := &Controller{
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		ecp:   ecp,
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.queue.Add(envoyConfigKey) },
+		UpdateFunc: func(_, _ interface{}) { c.queue.Add(envoyConfigKey) },
+		DeleteFunc: func(interface{}) { c.queue.Add(envoyConfigKey) },
+	}
+
+	// Watch for events related to Gateways and HTTPRoutes
+	gatewayInformer.Informer().AddEventHandler(handler)
+	httpRouteInformer.Informer().AddEventHandler(handler)
+
+	return c
+}
+
+// The Controller struct represents a Gateway API controller instance, which translates
+// the leaf HTTPRoutes into Envoy configuration via the envoycontrolplane.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	ecp *envoycontrolplane.EnvoyControlPlane
+}
+
+// Start starts the controller worker. The Envoy configuration is updated as a whole,
+// hence a single worker.
+func (c *Controller) Start(ctx context.Context) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting workers", "controller", controllerName)
+	defer klog.InfoS("Stopping workers", "controller", controllerName)
+
+	go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.ecp.UpdateEnvoyConfig(ctx); err != nil {
+		runtime.HandleError(fmt.Errorf("failed setting Envoy snapshot: %w", err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
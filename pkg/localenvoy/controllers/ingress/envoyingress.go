@@ -18,6 +18,7 @@ package ingress
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"strings"
@@ -39,14 +40,23 @@ func (c *Controller) reconcile(ctx context.Context, ingress *networkingv1.Ingres
 	//nolint:staticcheck
 	if shared.DeprecatedGetAssignedWorkloadCluster(ingress.Labels) == "" {
 		// Root
+		lbIngress := corev1.LoadBalancerIngress{}
 		if len(ingress.Status.LoadBalancer.Ingress) > 0 && ingress.Status.LoadBalancer.Ingress[0].Hostname != "" {
-			// Already set - never changes - do nothing
-			return nil
+			// Already set - never changes
+			lbIngress.Hostname = ingress.Status.LoadBalancer.Ingress[0].Hostname
+		} else {
+			lbIngress.Hostname = generateStatusHost(c.domain, ingress)
 		}
 
-		ingress.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
-			Hostname: generateStatusHost(c.domain, ingress),
-		}}
+		portStatus, err := c.tlsPortStatus(ingress)
+		if err != nil {
+			return err
+		}
+		if portStatus != nil {
+			lbIngress.Ports = []corev1.PortStatus{*portStatus}
+		}
+
+		ingress.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{lbIngress}
 
 		return nil
 	}
@@ -67,6 +77,29 @@ func (c *Controller) reconcile(ctx context.Context, ingress *networkingv1.Ingres
 	return nil
 }
 
+// tlsPortStatus returns the status of the TLS port of a root ingress, with the reason why
+// its certificates cannot be served as error. It returns nil if the ingress has no TLS
+// configuration or TLS is not served.
+func (c *Controller) tlsPortStatus(ingress *networkingv1.Ingress) (*corev1.PortStatus, error) {
+	if len(ingress.Spec.TLS) == 0 || c.ecp.TLSListenPort() == 0 {
+		return nil, nil
+	}
+
+	status := &corev1.PortStatus{
+		Port:     int32(c.ecp.TLSListenPort()),
+		Protocol: corev1.ProtocolTCP,
+	}
+	var tlsErr *envoycontrolplane.TLSError
+	if err := c.ecp.ValidateTLS(ingress); errors.As(err, &tlsErr) {
+		klog.Infof("Ingress %s - %s/%s cannot be served with TLS: %v", logicalcluster.From(ingress), ingress.Namespace, ingress.Name, tlsErr)
+		status.Error = &tlsErr.Reason
+	} else if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
 // TODO(jmprusi): Review the hash algorithm.
 func domainHashString(s string) string {
 	h := fnv.New32a()
@@ -23,11 +23,14 @@ import (
 
 	"github.com/kcp-dev/logicalcluster"
 
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
 	"k8s.io/client-go/kubernetes"
 	networkinglisters "k8s.io/client-go/listers/networking/v1"
@@ -48,6 +51,7 @@ const controllerName = "kcp-envoy-ingress-status-aggregator"
 func NewController(
 	kubeClient kubernetes.ClusterInterface,
 	ingressInformer networkinginformers.IngressInformer,
+	secretInformer coreinformers.SecretInformer,
 	ecp *envoycontrolplane.EnvoyControlPlane, domain string) *Controller {
 
 	c := &Controller{
@@ -67,6 +71,13 @@ func NewController(
 		DeleteFunc: func(obj interface{}) { c.enqueue(obj) },
 	})
 
+	// Watch for events related to Secrets, which the TLS hosts of ingresses are served with
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueSecret(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueSecret(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueSecret(obj) },
+	})
+
 	return c
 }
 
@@ -117,6 +128,36 @@ func (c *Controller) enqueue(obj interface{}) {
 	c.queue.Add(key)
 }
 
+// enqueueSecret enqueues the root ingresses referencing the secret in their TLS
+// configuration.
+func (c *Controller) enqueueSecret(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected object type: %T", obj))
+		return
+	}
+
+	ingresses, err := c.ingressLister.Ingresses(secret.Namespace).List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, ingress := range ingresses {
+		if logicalcluster.From(ingress) != logicalcluster.From(secret) || rootIngressKeyFor(ingress) != "" {
+			continue
+		}
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName == secret.Name {
+				c.enqueue(ingress)
+				break
+			}
+		}
+	}
+}
+
 // Start starts the controller workers.
 func (c *Controller) Start(ctx context.Context, numThreads int) {
 	defer runtime.HandleCrash()
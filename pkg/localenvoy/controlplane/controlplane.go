@@ -20,26 +20,34 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
 	"time"
 
-	envoyroutev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoylistenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	secret "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
 	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
-	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	envoycachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	xds "github.com/envoyproxy/go-control-plane/pkg/server/v3"
 	"github.com/google/uuid"
+	"github.com/kcp-dev/logicalcluster"
 	"google.golang.org/grpc"
 	health "google.golang.org/grpc/health/grpc_health_v1"
 
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	v1 "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/gatewaysplitter"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
 )
 
 const (
@@ -65,23 +73,32 @@ var envoyReadySelector labels.Selector
 // EnvoyControlPlane is an envoy control plane that handles configuration update
 // and the management of the xDS server.
 type EnvoyControlPlane struct {
-	ingressLister  v1.IngressLister
-	translator     *translator
-	managementPort uint
-	snapshotCache  cache.SnapshotCache
-	callbacks      xds.Callbacks
+	ingressLister   v1.IngressLister
+	secretLister    corelisters.SecretLister
+	httpRouteLister cache.GenericLister
+	gatewayLister   cache.GenericLister
+	translator      *translator
+	managementPort  uint
+	snapshotCache   envoycachev3.SnapshotCache
+	callbacks       xds.Callbacks
 }
 
-// NewEnvoyControlPlane creates a new EnvoyControlPlane instance.
-func NewEnvoyControlPlane(managementPort, envoyListenPort uint, ingressLister v1.IngressLister, callbacks xds.Callbacks) *EnvoyControlPlane {
-	snapshotCache := cache.NewSnapshotCache(true, cache.IDHash{}, nil)
+// NewEnvoyControlPlane creates a new EnvoyControlPlane instance. The TLS hosts of the ingresses
+// are served on envoyTLSListenPort with the certificates of their secrets, unless it is 0.
+// The Gateway API HTTPRoutes are served unless httpRouteLister and gatewayLister are nil.
+// The leaves of ingresses reached via TLS are verified according to upstreamTLS.
+func NewEnvoyControlPlane(managementPort, envoyListenPort, envoyTLSListenPort uint, upstreamTLS UpstreamTLSOptions, ingressLister v1.IngressLister, secretLister corelisters.SecretLister, httpRouteLister, gatewayLister cache.GenericLister, callbacks xds.Callbacks) *EnvoyControlPlane {
+	snapshotCache := envoycachev3.NewSnapshotCache(true, envoycachev3.IDHash{}, nil)
 
 	ecp := EnvoyControlPlane{
-		managementPort: managementPort,
-		ingressLister:  ingressLister,
-		translator:     newTranslator(envoyListenPort),
-		snapshotCache:  snapshotCache,
-		callbacks:      callbacks,
+		managementPort:  managementPort,
+		ingressLister:   ingressLister,
+		secretLister:    secretLister,
+		httpRouteLister: httpRouteLister,
+		gatewayLister:   gatewayLister,
+		translator:      newTranslator(envoyListenPort, envoyTLSListenPort, upstreamTLS),
+		snapshotCache:   snapshotCache,
+		callbacks:       callbacks,
 	}
 
 	return &ecp
@@ -105,6 +122,7 @@ func (ecp *EnvoyControlPlane) Start(ctx context.Context) error {
 	cluster.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
 	listener.RegisterListenerDiscoveryServiceServer(grpcServer, xdsServer)
 	route.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+	secret.RegisterSecretDiscoveryServiceServer(grpcServer, xdsServer)
 
 	// Goroutine to gracefully shutdown the grpc server
 	go func() {
@@ -129,30 +147,55 @@ func (ecp *EnvoyControlPlane) Start(ctx context.Context) error {
 
 // UpdateEnvoyConfig creates a new envoy config snapshot and updates the xDS server
 // using the information from the ingresses that are labeled with the ToEnvoyLabel.
+// The TLS hosts with a valid certificate are served on the TLS listener, the certificates
+// are pushed via SDS.
 func (ecp *EnvoyControlPlane) UpdateEnvoyConfig(ctx context.Context) error {
-	clustersResources := make([]cachetypes.Resource, 0)
-	virtualhosts := make([]*envoyroutev3.VirtualHost, 0)
-
 	ingresses, err := ecp.ingressLister.List(envoyReadySelector)
 	if err != nil {
 		return err
 	}
-
-	for _, ingress := range ingresses {
-		ingclusters, ingvhosts := ecp.translator.translateIngress(ingress)
-		clustersResources = append(clustersResources, ingclusters...)
-		virtualhosts = append(virtualhosts, ingvhosts...)
+	sort.Slice(ingresses, func(i, j int) bool {
+		return ingressSortKey(ingresses[i]) < ingressSortKey(ingresses[j])
+	})
+
+	var httpRoutes []*gatewayv1alpha2.HTTPRoute
+	var gateways []*gatewayv1alpha2.Gateway
+	if ecp.httpRouteLister != nil && ecp.gatewayLister != nil {
+		objs, err := ecp.httpRouteLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		if httpRoutes, err = gatewaysplitter.ToHTTPRoutes(objs); err != nil {
+			return err
+		}
+		if objs, err = ecp.gatewayLister.List(labels.Everything()); err != nil {
+			return err
+		}
+		if gateways, err = gatewaysplitter.ToGateways(objs); err != nil {
+			return err
+		}
 	}
 
-	routeConfig := ecp.translator.newRouteConfig("defaultroute", virtualhosts)
+	clustersResources, routeConfig := ecp.translator.translate("defaultroute", ingresses, httpRoutes, gateways)
+
 	hcm := ecp.translator.newHTTPConnectionManager(routeConfig.Name)
 	listener, _ := ecp.translator.newHTTPListener(hcm)
+	listeners := []cachetypes.Resource{listener}
+
+	tlsListener, secrets, err := ecp.translateTLS(routeConfig.Name, ingresses)
+	if err != nil {
+		return err
+	}
+	if tlsListener != nil {
+		listeners = append(listeners, tlsListener)
+	}
 
 	res := make(map[resource.Type][]cachetypes.Resource)
 
 	res[resource.RouteType] = []cachetypes.Resource{routeConfig}
-	res[resource.ListenerType] = []cachetypes.Resource{listener}
+	res[resource.ListenerType] = listeners
 	res[resource.ClusterType] = clustersResources
+	res[resource.SecretType] = secrets
 
 	newSnapshot, err := envoycachev3.NewSnapshot(
 		uuid.New().String(),
@@ -165,6 +208,95 @@ func (ecp *EnvoyControlPlane) UpdateEnvoyConfig(ctx context.Context) error {
 	return ecp.snapshotCache.SetSnapshot(ctx, NodeID, newSnapshot)
 }
 
+// translateTLS returns the TLS listener, selecting the filter chain of the TLS hosts of the
+// ingresses by SNI, and the Envoy secrets with their certificates, sorted by name. Hosts whose
+// secret cannot be served are left out, and the listener is nil if no host is left. The
+// first ingress in order wins a host.
+func (ecp *EnvoyControlPlane) translateTLS(routeConfigName string, ingresses []*networkingv1.Ingress) (*envoylistenerv3.Listener, []cachetypes.Resource, error) {
+	if ecp.translator.envoyTLSListenPort == 0 {
+		return nil, nil, nil
+	}
+
+	secretResources := make(map[string]cachetypes.Resource)
+	secretForHost := make(map[string]string)
+	for _, ingress := range ingresses {
+		clusterName := logicalcluster.From(ingress)
+		for _, h := range tlsHostsFor(ingress) {
+			if _, found := secretForHost[h.host]; found {
+				continue
+			}
+			name := secretResourceName(clusterName, ingress.Namespace, h.secretName)
+			if _, found := secretResources[name]; !found {
+				certPEM, keyPEM, err := certificateFor(ecp.secretLister, clusterName, ingress.Namespace, h.secretName)
+				if err != nil {
+					// this is reported on the status of the root ingress
+					klog.V(2).Infof("Not serving host %q of ingress %s|%s/%s with TLS: %v", h.host, clusterName, ingress.Namespace, ingress.Name, err)
+					continue
+				}
+				secretResources[name] = ecp.translator.newSecret(name, certPEM, keyPEM)
+			}
+			secretForHost[h.host] = name
+		}
+	}
+	if len(secretForHost) == 0 {
+		return nil, nil, nil
+	}
+
+	hosts := make([]string, 0, len(secretForHost))
+	for host := range secretForHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	filterChains := make([]*envoylistenerv3.FilterChain, 0, len(hosts))
+	for _, host := range hosts {
+		tlsHCM := ecp.translator.newHTTPConnectionManager(routeConfigName)
+		tlsHCM.StatPrefix = "ingress_https"
+		filterChain, err := ecp.translator.newTLSFilterChain(host, secretForHost[host], tlsHCM)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create filter chain for host %q: %w", host, err)
+		}
+		filterChains = append(filterChains, filterChain)
+	}
+	tlsListener, err := ecp.translator.newHTTPSListener(filterChains)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create TLS listener: %w", err)
+	}
+
+	names := make([]string, 0, len(secretResources))
+	for name := range secretResources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	secrets := make([]cachetypes.Resource, 0, len(names))
+	for _, name := range names {
+		secrets = append(secrets, secretResources[name])
+	}
+
+	return tlsListener, secrets, nil
+}
+
+// TLSListenPort returns the port the TLS hosts of the ingresses are served on, or 0 if TLS
+// is not served.
+func (ecp *EnvoyControlPlane) TLSListenPort() uint {
+	return ecp.translator.envoyTLSListenPort
+}
+
+// ValidateTLS returns a *TLSError for the first TLS secret of the ingress that cannot be served.
+func (ecp *EnvoyControlPlane) ValidateTLS(ingress *networkingv1.Ingress) error {
+	for _, entry := range ingress.Spec.TLS {
+		if _, _, err := certificateFor(ecp.secretLister, logicalcluster.From(ingress), ingress.Namespace, entry.SecretName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingressSortKey orders ingresses by logical cluster, namespace and name.
+func ingressSortKey(ingress *networkingv1.Ingress) string {
+	return logicalcluster.From(ingress).String() + "/" + ingress.Namespace + "/" + ingress.Name
+}
+
 type healthServer struct {
 	health.UnimplementedHealthServer
 }
@@ -0,0 +1,370 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	envoyroutev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoymatcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/gatewaysplitter"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+// translateHTTPRoutes translates the leaves of the HTTPRoutes into Envoy resources. The leaves
+// of a root HTTPRoute are translated together, the addresses of the leaf Gateways they are
+// attached to being the endpoints of the same cluster.
+func (t *translator) translateHTTPRoutes(httpRoutes []*gatewayv1alpha2.HTTPRoute, gateways []*gatewayv1alpha2.Gateway) ([]cachetypes.Resource, []hostRoute) {
+	gatewaysByKey := make(map[string]*gatewayv1alpha2.Gateway, len(gateways))
+	for _, gateway := range gateways {
+		gatewaysByKey[gateway.Namespace+"/"+clusters.ToClusterAwareKey(logicalcluster.From(gateway), gateway.Name)] = gateway
+	}
+
+	leavesByKey := make(map[string][]*gatewayv1alpha2.HTTPRoute)
+	for _, route := range httpRoutes {
+		// The roots are served through their leaves.
+		if key := gatewaysplitter.RootKeyFor(route); key != "" {
+			leavesByKey[key] = append(leavesByKey[key], route)
+		}
+	}
+	keys := make([]string, 0, len(leavesByKey))
+	for key := range leavesByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	clusters := make([]cachetypes.Resource, 0)
+	routes := make([]hostRoute, 0)
+	for _, key := range keys {
+		leaves := leavesByKey[key]
+		sort.Slice(leaves, func(i, j int) bool {
+			return leaves[i].Name < leaves[j].Name
+		})
+		routeClusters, routeRoutes := t.translateHTTPRoute(key, leaves, gatewaysByKey)
+		clusters = append(clusters, routeClusters...)
+		routes = append(routes, routeRoutes...)
+	}
+
+	return clusters, routes
+}
+
+// translateHTTPRoute translates the leaves of an HTTPRoute into Envoy resources, with a cluster
+// of the leaf Gateways the leaves are attached to and the routes of its rules. The leaf Gateways
+// route the requests to the backend services with the same rules, applying the weights of the
+// backends, so the rules all share the cluster of the leaf Gateways.
+func (t *translator) translateHTTPRoute(routeKey string, leaves []*gatewayv1alpha2.HTTPRoute, gatewaysByKey map[string]*gatewayv1alpha2.Gateway) ([]cachetypes.Resource, []hostRoute) {
+	// The leaves share the spec of their root HTTPRoute, but for the names of their parents.
+	httpRoute := leaves[0]
+
+	hosts := make([]string, 0, len(httpRoute.Spec.Hostnames))
+	var healthCheckHost string
+	for _, hostname := range httpRoute.Spec.Hostnames {
+		hosts = append(hosts, string(hostname))
+		if healthCheckHost == "" && !strings.HasPrefix(string(hostname), "*") {
+			healthCheckHost = string(hostname)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = append(hosts, anyHost)
+	}
+
+	var leafEndpoints []leafEndpoints
+	for _, leaf := range leaves {
+		leafEndpoints = append(leafEndpoints, gatewayLeafEndpoints(leaf, gatewaysByKey)...)
+	}
+
+	clusters := make([]cachetypes.Resource, 0)
+	// clusterFor returns the name of the cluster of the leaf Gateways, creating the cluster if needed.
+	clusterFor := func() (string, error) {
+		if len(clusters) == 0 {
+			cluster, err := t.newLeafCluster(routeKey, t.newLocalityEndpoints(leafEndpoints), healthCheckHost, false)
+			if err != nil {
+				return "", err
+			}
+			clusters = append(clusters, cluster)
+		}
+		return routeKey, nil
+	}
+
+	routes := make([]hostRoute, 0)
+	for i, rule := range httpRoute.Spec.Rules {
+		action := t.newHTTPRouteRuleAction(fmt.Sprintf("%s/%d", routeKey, i), rule, httpRoute.Namespace, clusterFor)
+
+		matches := rule.Matches
+		if len(matches) == 0 {
+			// A rule without matches matches all the requests.
+			matches = []gatewayv1alpha2.HTTPRouteMatch{{}}
+		}
+		for j, match := range matches {
+			for _, routeMatch := range t.newHTTPRouteMatches(match) {
+				for _, host := range hosts {
+					route := &envoyroutev3.Route{
+						Name:  fmt.Sprintf("%s/%d/%d", routeKey, i, j),
+						Match: routeMatch.match,
+					}
+					route.Action = action.Action
+					routes = append(routes, hostRoute{
+						host:    host,
+						route:   route,
+						path:    routeMatch.path,
+						exact:   routeMatch.exact,
+						matches: routeMatch.matches,
+					})
+				}
+			}
+		}
+	}
+
+	return clusters, routes
+}
+
+// gatewayLeafEndpoints returns the addresses of the leaf Gateways a leaf HTTPRoute is attached
+// to, on the port of their first HTTP listener. The Gateways without HTTP listener are skipped.
+func gatewayLeafEndpoints(leaf *gatewayv1alpha2.HTTPRoute, gatewaysByKey map[string]*gatewayv1alpha2.Gateway) []leafEndpoints {
+	var endpoints []leafEndpoints
+	for _, ref := range leaf.Spec.ParentRefs {
+		if !gatewaysplitter.IsGatewayRef(ref) {
+			continue
+		}
+		namespace := leaf.Namespace
+		if ref.Namespace != nil {
+			namespace = string(*ref.Namespace)
+		}
+		gateway, found := gatewaysByKey[namespace+"/"+clusters.ToClusterAwareKey(logicalcluster.From(leaf), string(ref.Name))]
+		if !found {
+			klog.V(4).Infof("Gateway %s/%s of HTTPRoute %s|%s/%s not found", namespace, ref.Name, logicalcluster.From(leaf), leaf.Namespace, leaf.Name)
+			continue
+		}
+
+		// TODO: serve the HTTPS listeners too.
+		var port uint32
+		for _, listener := range gateway.Spec.Listeners {
+			if listener.Protocol == gatewayv1alpha2.HTTPProtocolType {
+				port = uint32(listener.Port)
+				break
+			}
+		}
+		if port == 0 {
+			klog.Infof("Skipping Gateway %s/%s of HTTPRoute %s|%s/%s: no HTTP listener", namespace, ref.Name, logicalcluster.From(leaf), leaf.Namespace, leaf.Name)
+			continue
+		}
+
+		addresses := make([]string, 0, len(gateway.Status.Addresses))
+		for _, address := range gateway.Status.Addresses {
+			addresses = append(addresses, address.Value)
+		}
+
+		endpoints = append(endpoints, leafEndpoints{
+			//nolint:staticcheck
+			workloadCluster: shared.DeprecatedGetAssignedWorkloadCluster(leaf.Labels),
+			weight:          1,
+			addresses:       addresses,
+			port:            port,
+		})
+	}
+	return endpoints
+}
+
+// newHTTPRouteRuleAction returns a route with the action of an HTTPRoute rule:
+//  - a redirect, if the rule has a RequestRedirect filter,
+//  - the cluster of the leaf Gateways, if any of its backends can serve the requests,
+//  - a 500 response otherwise.
+// The weights of the backends are applied by the leaf Gateways.
+func (t *translator) newHTTPRouteRuleAction(name string, rule gatewayv1alpha2.HTTPRouteRule, namespace string, clusterFor func() (string, error)) *envoyroutev3.Route {
+	for _, filter := range rule.Filters {
+		if filter.Type == gatewayv1alpha2.HTTPRouteFilterRequestRedirect && filter.RequestRedirect != nil {
+			return &envoyroutev3.Route{Action: &envoyroutev3.Route_Redirect{Redirect: newRedirectAction(filter.RequestRedirect)}}
+		}
+	}
+
+	internalServerError := &envoyroutev3.Route{Action: &envoyroutev3.Route_DirectResponse{DirectResponse: &envoyroutev3.DirectResponseAction{Status: http.StatusInternalServerError}}}
+	served := false
+	for _, backendRef := range rule.BackendRefs {
+		if backendRef.Weight != nil && *backendRef.Weight == 0 {
+			continue
+		}
+		if err := validateBackendRef(backendRef.BackendObjectReference, namespace); err != nil {
+			klog.Errorf("Ignoring backend %q of HTTPRoute rule %s: %v", backendRef.Name, name, err)
+			continue
+		}
+		served = true
+	}
+	if !served {
+		return internalServerError
+	}
+
+	clusterName, err := clusterFor()
+	if err != nil {
+		klog.Errorf("Failed to create the cluster of HTTPRoute rule %s: %v", name, err)
+		return internalServerError
+	}
+	action := t.newRouteAction()
+	action.ClusterSpecifier = &envoyroutev3.RouteAction_Cluster{Cluster: clusterName}
+	return &envoyroutev3.Route{Action: &envoyroutev3.Route_Route{Route: action}}
+}
+
+// validateBackendRef returns an error if the leaf Gateways cannot route to the backend of an
+// HTTPRoute in the namespace.
+func validateBackendRef(ref gatewayv1alpha2.BackendObjectReference, namespace string) error {
+	if !gatewaysplitter.IsServiceRef(ref) {
+		return fmt.Errorf("only service backends are supported")
+	}
+	if ref.Namespace != nil && string(*ref.Namespace) != namespace {
+		return fmt.Errorf("only backends in the namespace of the HTTPRoute are supported")
+	}
+	if ref.Port == nil {
+		return fmt.Errorf("the port of service %q is required", ref.Name)
+	}
+	return nil
+}
+
+func newRedirectAction(filter *gatewayv1alpha2.HTTPRequestRedirectFilter) *envoyroutev3.RedirectAction {
+	redirect := &envoyroutev3.RedirectAction{
+		ResponseCode: envoyroutev3.RedirectAction_FOUND,
+	}
+	if filter.Scheme != nil {
+		redirect.SchemeRewriteSpecifier = &envoyroutev3.RedirectAction_SchemeRedirect{SchemeRedirect: *filter.Scheme}
+	}
+	if filter.Hostname != nil {
+		redirect.HostRedirect = string(*filter.Hostname)
+	}
+	if filter.Port != nil {
+		redirect.PortRedirect = uint32(*filter.Port)
+	}
+	if filter.StatusCode != nil && *filter.StatusCode == http.StatusMovedPermanently {
+		redirect.ResponseCode = envoyroutev3.RedirectAction_MOVED_PERMANENTLY
+	}
+	return redirect
+}
+
+// httpRouteMatch is an Envoy route match of an HTTPRoute match, with the precedence of the match.
+type httpRouteMatch struct {
+	match   *envoyroutev3.RouteMatch
+	path    string
+	exact   bool
+	matches int
+}
+
+// newHTTPRouteMatches returns the Envoy route matches of an HTTPRoute match. The paths are
+// matched like the paths of the ingresses:
+//  - an Exact path matches the request path exactly,
+//  - a PathPrefix path matches the request path element-wise,
+//  - a RegularExpression path matches the whole request path with RE2.
+// The headers, the query parameters and the method of the match are matched by all of them.
+func (t *translator) newHTTPRouteMatches(match gatewayv1alpha2.HTTPRouteMatch) []httpRouteMatch {
+	pathType := gatewayv1alpha2.PathMatchPathPrefix
+	path := "/"
+	if match.Path != nil {
+		if match.Path.Type != nil {
+			pathType = *match.Path.Type
+		}
+		if match.Path.Value != nil {
+			path = *match.Path.Value
+		}
+	}
+
+	var routeMatches []httpRouteMatch
+	switch pathType {
+	case gatewayv1alpha2.PathMatchExact:
+		routeMatches = []httpRouteMatch{{
+			match: &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Path{Path: path}},
+			path:  path,
+			exact: true,
+		}}
+	case gatewayv1alpha2.PathMatchRegularExpression:
+		routeMatches = []httpRouteMatch{{
+			match: &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_SafeRegex{SafeRegex: newRegexMatcher(path)}},
+			path:  path,
+		}}
+	default:
+		prefix := strings.TrimRight(path, "/")
+		if prefix == "" {
+			routeMatches = []httpRouteMatch{{
+				match: &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: "/"}},
+				path:  "/",
+			}}
+		} else {
+			routeMatches = []httpRouteMatch{{
+				match: &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Path{Path: prefix}},
+				path:  prefix,
+			}, {
+				match: &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: prefix + "/"}},
+				path:  prefix,
+			}}
+		}
+	}
+
+	headers := make([]*envoyroutev3.HeaderMatcher, 0, len(match.Headers)+1)
+	for _, header := range match.Headers {
+		regex := header.Type != nil && *header.Type == gatewayv1alpha2.HeaderMatchRegularExpression
+		headers = append(headers, &envoyroutev3.HeaderMatcher{
+			Name:                 string(header.Name),
+			HeaderMatchSpecifier: &envoyroutev3.HeaderMatcher_StringMatch{StringMatch: newStringMatcher(header.Value, regex)},
+		})
+	}
+	if match.Method != nil {
+		headers = append(headers, &envoyroutev3.HeaderMatcher{
+			Name:                 ":method",
+			HeaderMatchSpecifier: &envoyroutev3.HeaderMatcher_StringMatch{StringMatch: newStringMatcher(string(*match.Method), false)},
+		})
+	}
+
+	queryParameters := make([]*envoyroutev3.QueryParameterMatcher, 0, len(match.QueryParams))
+	for _, queryParam := range match.QueryParams {
+		regex := queryParam.Type != nil && *queryParam.Type == gatewayv1alpha2.QueryParamMatchRegularExpression
+		queryParameters = append(queryParameters, &envoyroutev3.QueryParameterMatcher{
+			Name:                         queryParam.Name,
+			QueryParameterMatchSpecifier: &envoyroutev3.QueryParameterMatcher_StringMatch{StringMatch: newStringMatcher(queryParam.Value, regex)},
+		})
+	}
+
+	for i := range routeMatches {
+		if len(headers) > 0 {
+			routeMatches[i].match.Headers = headers
+		}
+		if len(queryParameters) > 0 {
+			routeMatches[i].match.QueryParameters = queryParameters
+		}
+		routeMatches[i].matches = len(headers) + len(queryParameters)
+	}
+
+	return routeMatches
+}
+
+func newStringMatcher(value string, regex bool) *envoymatcherv3.StringMatcher {
+	if regex {
+		return &envoymatcherv3.StringMatcher{MatchPattern: &envoymatcherv3.StringMatcher_SafeRegex{SafeRegex: newRegexMatcher(value)}}
+	}
+	return &envoymatcherv3.StringMatcher{MatchPattern: &envoymatcherv3.StringMatcher_Exact{Exact: value}}
+}
+
+func newRegexMatcher(regex string) *envoymatcherv3.RegexMatcher {
+	return &envoymatcherv3.RegexMatcher{
+		EngineType: &envoymatcherv3.RegexMatcher_GoogleRe2{GoogleRe2: &envoymatcherv3.RegexMatcher_GoogleRE2{}},
+		Regex:      regex,
+	}
+}
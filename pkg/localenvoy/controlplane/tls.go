@@ -0,0 +1,255 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	envoycorev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoylistenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoytlsinspectorv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	envoyfilterhcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	envoytlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoymatcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/kcp-dev/logicalcluster"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clusters"
+)
+
+const (
+	// UpstreamProtocolAnnotation selects the protocol Envoy uses to reach the leaves of an
+	// ingress: "http", the default, on port 80, or "https" on port 443.
+	UpstreamProtocolAnnotation = "ingress.kcp.dev/upstream-protocol"
+
+	// CertificateNotFoundReason is the error of the TLS port status of a root ingress
+	// whose TLS secret does not exist.
+	CertificateNotFoundReason = "CertificateNotFound"
+	// InvalidCertificateReason is the error of the TLS port status of a root ingress
+	// whose TLS secret has no valid certificate and key.
+	InvalidCertificateReason = "InvalidCertificate"
+)
+
+// TLSError is the error of a TLS secret of an ingress that cannot be served.
+type TLSError struct {
+	Reason  string
+	Message string
+}
+
+func (e *TLSError) Error() string {
+	return e.Message
+}
+
+// tlsHost is a host of an ingress served with the certificate of a secret.
+type tlsHost struct {
+	host       string
+	secretName string
+}
+
+// tlsHostsFor returns the TLS hosts of the ingress. A TLS entry without hosts applies to
+// all the hosts of the ingress rules.
+func tlsHostsFor(ingress *networkingv1.Ingress) []tlsHost {
+	var hosts []tlsHost
+	for _, entry := range ingress.Spec.TLS {
+		entryHosts := entry.Hosts
+		if len(entryHosts) == 0 {
+			for _, rule := range ingress.Spec.Rules {
+				if rule.Host != "" {
+					entryHosts = append(entryHosts, rule.Host)
+				}
+			}
+		}
+		for _, host := range entryHosts {
+			hosts = append(hosts, tlsHost{host: host, secretName: entry.SecretName})
+		}
+	}
+	return hosts
+}
+
+// certificateFor returns the PEM encoded certificate and key of the TLS secret in the
+// logical cluster and namespace. A secret that cannot be served results in a *TLSError.
+func certificateFor(secretLister corelisters.SecretLister, clusterName logicalcluster.Name, namespace, name string) (certPEM, keyPEM []byte, err error) {
+	secret, err := secretLister.Secrets(namespace).Get(clusters.ToClusterAwareKey(clusterName, name))
+	if apierrors.IsNotFound(err) {
+		return nil, nil, &TLSError{Reason: CertificateNotFoundReason, Message: fmt.Sprintf("secret %s/%s not found", namespace, name)}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM, keyPEM = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, nil, &TLSError{Reason: InvalidCertificateReason, Message: fmt.Sprintf("secret %s/%s must have %q and %q keys", namespace, name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)}
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, &TLSError{Reason: InvalidCertificateReason, Message: fmt.Sprintf("secret %s/%s: %v", namespace, name, err)}
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, &TLSError{Reason: InvalidCertificateReason, Message: fmt.Sprintf("secret %s/%s: %v", namespace, name, err)}
+	}
+	if now := time.Now(); now.After(leaf.NotAfter) {
+		return nil, nil, &TLSError{Reason: InvalidCertificateReason, Message: fmt.Sprintf("secret %s/%s: certificate expired at %s", namespace, name, leaf.NotAfter.Format(time.RFC3339))}
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// secretResourceName returns the name of the Envoy secret of a TLS secret.
+func secretResourceName(clusterName logicalcluster.Name, namespace, name string) string {
+	return namespace + "/" + clusters.ToClusterAwareKey(clusterName, name)
+}
+
+func (t *translator) newSecret(name string, certPEM, keyPEM []byte) *envoytlsv3.Secret {
+	return &envoytlsv3.Secret{
+		Name: name,
+		Type: &envoytlsv3.Secret_TlsCertificate{
+			TlsCertificate: &envoytlsv3.TlsCertificate{
+				CertificateChain: &envoycorev3.DataSource{
+					Specifier: &envoycorev3.DataSource_InlineBytes{InlineBytes: certPEM},
+				},
+				PrivateKey: &envoycorev3.DataSource{
+					Specifier: &envoycorev3.DataSource_InlineBytes{InlineBytes: keyPEM},
+				},
+			},
+		},
+	}
+}
+
+// newTLSFilterChain returns a filter chain terminating TLS for the host with the certificate
+// of the Envoy secret, which Envoy fetches via SDS.
+func (t *translator) newTLSFilterChain(host, secretName string, manager *envoyfilterhcmv3.HttpConnectionManager) (*envoylistenerv3.FilterChain, error) {
+	managerAny, err := anypb.New(manager)
+	if err != nil {
+		return nil, err
+	}
+	transportSocket, err := newTLSTransportSocket(&envoytlsv3.DownstreamTlsContext{
+		CommonTlsContext: &envoytlsv3.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*envoytlsv3.SdsSecretConfig{{
+				Name: secretName,
+				SdsConfig: &envoycorev3.ConfigSource{
+					ResourceApiVersion: resource.DefaultAPIVersion,
+					ConfigSourceSpecifier: &envoycorev3.ConfigSource_Ads{
+						Ads: &envoycorev3.AggregatedConfigSource{},
+					},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoylistenerv3.FilterChain{
+		FilterChainMatch: &envoylistenerv3.FilterChainMatch{
+			ServerNames: []string{host},
+		},
+		Filters: []*envoylistenerv3.Filter{{
+			Name:       wellknown.HTTPConnectionManager,
+			ConfigType: &envoylistenerv3.Filter_TypedConfig{TypedConfig: managerAny},
+		}},
+		TransportSocket: transportSocket,
+	}, nil
+}
+
+// newHTTPSListener returns the TLS listener, selecting the filter chain by SNI.
+func (t *translator) newHTTPSListener(filterChains []*envoylistenerv3.FilterChain) (*envoylistenerv3.Listener, error) {
+	inspectorAny, err := anypb.New(&envoytlsinspectorv3.TlsInspector{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoylistenerv3.Listener{
+		Name:    fmt.Sprintf("listener_%d", t.envoyTLSListenPort),
+		Address: t.newListenerAddress(t.envoyTLSListenPort),
+		ListenerFilters: []*envoylistenerv3.ListenerFilter{{
+			Name:       wellknown.TlsInspector,
+			ConfigType: &envoylistenerv3.ListenerFilter_TypedConfig{TypedConfig: inspectorAny},
+		}},
+		FilterChains: filterChains,
+	}, nil
+}
+
+// upstreamTLS returns whether Envoy reaches the leaves of the ingress via TLS.
+func upstreamTLS(ingress *networkingv1.Ingress) bool {
+	return strings.EqualFold(ingress.Annotations[UpstreamProtocolAnnotation], "https")
+}
+
+// UpstreamTLSOptions configure how Envoy verifies the certificates of the leaves it reaches
+// via TLS. Without a CA, ingresses reaching their leaves via TLS are only served with
+// InsecureSkipVerify.
+type UpstreamTLSOptions struct {
+	// CAData is the PEM encoded bundle of the CAs the certificates of the leaves are verified
+	// with. The subject alternative names of the certificates must match the SNI host.
+	CAData []byte
+	// InsecureSkipVerify disables the verification of the certificates of the leaves, if
+	// there is no CA.
+	InsecureSkipVerify bool
+}
+
+// newUpstreamTLSTransportSocket returns the transport socket of a cluster reaching its
+// endpoints via TLS, with the given SNI. The certificates of the endpoints are verified
+// with the upstream CA and must be valid for the SNI host, unless the verification is
+// explicitly disabled.
+func (t *translator) newUpstreamTLSTransportSocket(sni string) (*envoycorev3.TransportSocket, error) {
+	context := &envoytlsv3.UpstreamTlsContext{
+		Sni: sni,
+	}
+	switch {
+	case len(t.upstreamTLS.CAData) > 0:
+		validationContext := &envoytlsv3.CertificateValidationContext{
+			TrustedCa: &envoycorev3.DataSource{
+				Specifier: &envoycorev3.DataSource_InlineBytes{InlineBytes: t.upstreamTLS.CAData},
+			},
+		}
+		if sni != "" {
+			validationContext.MatchSubjectAltNames = []*envoymatcherv3.StringMatcher{{
+				MatchPattern: &envoymatcherv3.StringMatcher_Exact{Exact: sni},
+			}}
+		}
+		context.CommonTlsContext = &envoytlsv3.CommonTlsContext{
+			ValidationContextType: &envoytlsv3.CommonTlsContext_ValidationContext{ValidationContext: validationContext},
+		}
+	case t.upstreamTLS.InsecureSkipVerify:
+		// the certificates of the leaves are not verified
+	default:
+		return nil, fmt.Errorf("no CA to verify the upstream certificates with, and the verification is not disabled")
+	}
+	return newTLSTransportSocket(context)
+}
+
+func newTLSTransportSocket(context proto.Message) (*envoycorev3.TransportSocket, error) {
+	contextAny, err := anypb.New(context)
+	if err != nil {
+		return nil, err
+	}
+	return &envoycorev3.TransportSocket{
+		Name:       wellknown.TransportSocketTls,
+		ConfigType: &envoycorev3.TransportSocket_TypedConfig{TypedConfig: contextAny},
+	}, nil
+}
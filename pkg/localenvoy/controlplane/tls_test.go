@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+func TestTranslateTLS(t *testing.T) {
+	prefix := networkingv1.PathTypePrefix
+	rule := func(host string) networkingv1.IngressRule {
+		return networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("web", 80)}},
+			}},
+		}
+	}
+	secretLister := newTestSecretLister(t,
+		tlsSecret(t, "web-tls", "tls"),
+		tlsSecret(t, "other-tls", "tls"),
+		tlsSecret(t, "expired-tls", "expired"),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "keyless-tls", Namespace: "default", ClusterName: "root:org:ws"}, Data: map[string][]byte{corev1.TLSCertKey: []byte("cert")}},
+	)
+
+	tests := []struct {
+		name      string
+		ingresses []*networkingv1.Ingress
+	}{
+		{
+			name: "tls hosts",
+			ingresses: []*networkingv1.Ingress{
+				ingress("first", withRules(rule("example.com"), rule("www.example.com")), withTLS(networkingv1.IngressTLS{Hosts: []string{"example.com", "www.example.com"}, SecretName: "web-tls"})),
+				// the host of the first ingress wins
+				ingress("second", withRules(rule("example.com")), withTLS(networkingv1.IngressTLS{Hosts: []string{"example.com"}, SecretName: "other-tls"})),
+				// without hosts, the TLS entry applies to the hosts of the rules
+				ingress("third", withRules(rule("api.example.com")), withTLS(networkingv1.IngressTLS{SecretName: "other-tls"})),
+			},
+		},
+		{
+			name: "tls secrets that cannot be served",
+			ingresses: []*networkingv1.Ingress{
+				ingress("missing", withRules(rule("missing.example.com")), withTLS(networkingv1.IngressTLS{SecretName: "missing-tls"})),
+				ingress("keyless", withRules(rule("keyless.example.com")), withTLS(networkingv1.IngressTLS{SecretName: "keyless-tls"})),
+				ingress("expired", withRules(rule("expired.example.com")), withTLS(networkingv1.IngressTLS{SecretName: "expired-tls"})),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ecp := NewEnvoyControlPlane(0, 80, 443, UpstreamTLSOptions{}, nil, secretLister, nil, nil, nil)
+			listener, secrets, err := ecp.translateTLS("defaultroute", tc.ingresses)
+			require.NoError(t, err)
+
+			out := map[string]interface{}{}
+			if listener != nil {
+				out["listener"] = protoValue(t, listener)
+			}
+			var secretValues []interface{}
+			for _, secret := range secrets {
+				secretValues = append(secretValues, protoValue(t, secret))
+			}
+			out["secrets"] = secretValues
+
+			actual, err := yaml.Marshal(out)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(tc.name, " ", "_")+".yaml")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, actual, 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err, "run the tests with -update to create the golden file")
+			require.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestCertificateFor(t *testing.T) {
+	secretLister := newTestSecretLister(t,
+		tlsSecret(t, "web-tls", "tls"),
+		tlsSecret(t, "expired-tls", "expired"),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "keyless-tls", Namespace: "default", ClusterName: "root:org:ws"}, Data: map[string][]byte{corev1.TLSCertKey: []byte("cert")}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "garbage-tls", Namespace: "default", ClusterName: "root:org:ws"}, Data: map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")}},
+	)
+
+	tests := map[string]struct {
+		secretName string
+		wantReason string
+	}{
+		"valid":          {secretName: "web-tls"},
+		"missing":        {secretName: "missing-tls", wantReason: CertificateNotFoundReason},
+		"without key":    {secretName: "keyless-tls", wantReason: InvalidCertificateReason},
+		"not a key pair": {secretName: "garbage-tls", wantReason: InvalidCertificateReason},
+		"expired":        {secretName: "expired-tls", wantReason: InvalidCertificateReason},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			certPEM, keyPEM, err := certificateFor(secretLister, logicalcluster.New("root:org:ws"), "default", tc.secretName)
+			if tc.wantReason == "" {
+				require.NoError(t, err)
+				require.NotEmpty(t, certPEM)
+				require.NotEmpty(t, keyPEM)
+				return
+			}
+			var tlsErr *TLSError
+			require.True(t, errors.As(err, &tlsErr), "expected a *TLSError, got %v", err)
+			require.Equal(t, tc.wantReason, tlsErr.Reason)
+		})
+	}
+}
+
+func newTestSecretLister(t *testing.T, secrets ...*corev1.Secret) corelisters.SecretLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, secret := range secrets {
+		require.NoError(t, indexer.Add(secret))
+	}
+	return corelisters.NewSecretLister(indexer)
+}
+
+// tlsSecret returns a TLS secret with the certificate and key of the given testdata files.
+func tlsSecret(t *testing.T, name, file string) *corev1.Secret {
+	certPEM, err := os.ReadFile(filepath.Join("testdata", file+".crt"))
+	require.NoError(t, err)
+	keyPEM, err := os.ReadFile(filepath.Join("testdata", file+".key"))
+	require.NoError(t, err)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ClusterName: "root:org:ws"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+	}
+}
+
+func withTLS(entries ...networkingv1.IngressTLS) ingressOption {
+	return func(ingress *networkingv1.Ingress) {
+		ingress.Spec.TLS = entries
+	}
+}
@@ -18,7 +18,9 @@ package controlplane
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	envoyclusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
@@ -27,100 +29,452 @@ import (
 	envoylistenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	envoyroutev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoyfilterhcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	envoytypev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/kcp-dev/logicalcluster"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
 	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/ingresssplitter"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
 )
 
 // translator takes care of translating the ingress objects into Envoy resources.
 type translator struct {
-	envoyListenPort uint
+	envoyListenPort    uint
+	envoyTLSListenPort uint
+	upstreamTLS        UpstreamTLSOptions
 }
 
 // newTranslator returns a new translator.
-func newTranslator(envoyListenPort uint) *translator {
+func newTranslator(envoyListenPort, envoyTLSListenPort uint, upstreamTLS UpstreamTLSOptions) *translator {
 	return &translator{
-		envoyListenPort: envoyListenPort,
+		envoyListenPort:    envoyListenPort,
+		envoyTLSListenPort: envoyTLSListenPort,
+		upstreamTLS:        upstreamTLS,
 	}
 }
 
-// translateIngress has a "simple" implementation of a networkingv1.Ingress parser to translation to Envoy resources.
-// It traverses the ingress spec object and creates a list of Envoy resources.
-func (t *translator) translateIngress(ingress *networkingv1.Ingress) ([]cachetypes.Resource, []*envoyroutev3.VirtualHost) {
-	// TODO(jmprusi): Hardcoded port, also, not TLS support. Review
-	endpoints := make([]*envoyendpointv3.LbEndpoint, 0)
-	for _, lb := range ingress.Status.LoadBalancer.Ingress {
-		endpoint := &envoyendpointv3.LbEndpoint{}
-		if lb.Hostname != "" {
-			endpoint = t.newLBEndpoint(lb.Hostname, 80)
-		} else if lb.IP != "" {
-			endpoint = t.newLBEndpoint(lb.IP, 80)
+// anyHost is the Envoy domain of the rules without host and of the default backends.
+const anyHost = "*"
+
+// hostRoute is an Envoy route of an ingress for the requests to a host.
+type hostRoute struct {
+	host  string
+	route *envoyroutev3.Route
+	// path, exact and matches order the routes of a host: the longest path matches first,
+	// with an Exact path before any other path of the same length, then the route with the
+	// most header, query parameter and method matches.
+	path    string
+	exact   bool
+	matches int
+	// defaultBackend marks the route of the default backend of an ingress, which only
+	// matches the requests not matched by any rule.
+	defaultBackend bool
+}
+
+// translate translates the ingresses, and the HTTPRoutes attached to the gateways, into the
+// Envoy clusters and the route configuration named routeConfigName. The routes of all the
+// ingresses and HTTPRoutes are merged by host.
+func (t *translator) translate(routeConfigName string, ingresses []*networkingv1.Ingress, httpRoutes []*gatewayv1alpha2.HTTPRoute, gateways []*gatewayv1alpha2.Gateway) ([]cachetypes.Resource, *envoyroutev3.RouteConfiguration) {
+	clusters, routes := t.translateIngresses(ingresses)
+	httpRouteClusters, httpRouteRoutes := t.translateHTTPRoutes(httpRoutes, gateways)
+	clusters = append(clusters, httpRouteClusters...)
+	routes = append(routes, httpRouteRoutes...)
+
+	return clusters, t.newRouteConfig(routeConfigName, t.newVirtualHosts(routes))
+}
+
+// translateIngresses translates the ingresses into the Envoy clusters and routes.
+func (t *translator) translateIngresses(ingresses []*networkingv1.Ingress) ([]cachetypes.Resource, []hostRoute) {
+	// The leaves of a root ingress are translated together, their load balancers being the
+	// endpoints of the same clusters.
+	keys := make([]string, 0)
+	leavesByKey := make(map[string][]*networkingv1.Ingress)
+	for _, ingress := range ingresses {
+		key := rootIngressKeyFor(ingress)
+		if key == "" {
+			var err error
+			if key, err = cache.MetaNamespaceKeyFunc(ingress); err != nil {
+				klog.Errorf("Error getting key for ingress %s: %v", ingress.Name, err)
+				continue
+			}
 		}
-		endpoints = append(endpoints, endpoint)
+		if _, found := leavesByKey[key]; !found {
+			keys = append(keys, key)
+		}
+		leavesByKey[key] = append(leavesByKey[key], ingress)
 	}
 
-	//TODO(jmprusi): HTTP2 is set to false always, also allow for configuration of the timeout
-	ingressKey, err := cache.MetaNamespaceKeyFunc(ingress)
-	if err != nil {
-		klog.Errorf("Error getting key for ingress %s: %v", ingress.Name, err)
-		return nil, nil
+	clusters := make([]cachetypes.Resource, 0)
+	routes := make([]hostRoute, 0)
+	for _, key := range keys {
+		ingclusters, ingroutes := t.translateIngress(key, leavesByKey[key])
+		clusters = append(clusters, ingclusters...)
+		routes = append(routes, ingroutes...)
 	}
-	cluster := t.newCluster(ingressKey, 2*time.Second, endpoints, envoyclusterv3.Cluster_STRICT_DNS)
-	cluster.DnsLookupFamily = envoyclusterv3.Cluster_V4_ONLY
 
-	virtualHosts := make([]*envoyroutev3.VirtualHost, 0)
-	routes := make([]*envoyroutev3.Route, 0)
-	domains := make([]string, 0)
+	return clusters, routes
+}
+
+// translateIngress translates the leaves of an ingress into Envoy resources, with a cluster per
+// backend service and the routes of its rules and default backend. The clusters all point to
+// the load balancers of the leaves, which route the requests to the services.
+func (t *translator) translateIngress(ingressKey string, leaves []*networkingv1.Ingress) ([]cachetypes.Resource, []hostRoute) {
+	// The leaves share the spec of their root ingress.
+	ingress := leaves[0]
+
+	// TODO(jmprusi): Hardcoded ports. Review
+	port := uint32(80)
+	if upstreamTLS(ingress) {
+		port = 443
+	}
+	leafEndpoints := make([]leafEndpoints, 0, len(leaves))
+	for _, leaf := range leaves {
+		leafEndpoints = append(leafEndpoints, ingressLeafEndpoints(leaf, port))
+	}
+	endpoints := t.newLocalityEndpoints(leafEndpoints)
 
-	//TODO(jmprusi): We are ignoring the path type, we need to review this.
+	clusters := make([]cachetypes.Resource, 0)
+	clusterNames := sets.NewString()
+	// clusterFor returns the name of the cluster of the backend, creating the cluster if needed.
+	clusterFor := func(backend networkingv1.IngressBackend) (string, error) {
+		if backend.Service == nil {
+			return "", fmt.Errorf("only service backends are supported")
+		}
+		name := ingressKey + "/" + backendServiceName(backend.Service)
+		if clusterNames.Has(name) {
+			return name, nil
+		}
+		cluster, err := t.newLeafCluster(name, endpoints, ingressHost(ingress), upstreamTLS(ingress))
+		if err != nil {
+			return "", err
+		}
+		clusterNames.Insert(name)
+		clusters = append(clusters, cluster)
+		return name, nil
+	}
+
+	routes := make([]hostRoute, 0)
+	hosts := make([]string, 0)
 	for i, rule := range ingress.Spec.Rules {
-		// TODO(jmprusi): If the host is empty we just ignore the rule, not ideal.
-		if rule.HTTP.Paths == nil || rule.Host == "" {
-			break
+		host := rule.Host
+		if host == "" {
+			host = anyHost
+		}
+		hosts = append(hosts, host)
+		if rule.HTTP == nil {
+			continue
 		}
 
-		for _, path := range rule.HTTP.Paths {
-			route := &envoyroutev3.Route{
-				Name: ingressKey + strconv.Itoa(i),
-				Match: &envoyroutev3.RouteMatch{
-					PathSpecifier: &envoyroutev3.RouteMatch_Prefix{
-						Prefix: path.Path,
-					},
-				},
-				Action: &envoyroutev3.Route_Route{
-					Route: &envoyroutev3.RouteAction{
-						ClusterSpecifier: &envoyroutev3.RouteAction_Cluster{
-							Cluster: ingressKey,
-						},
-						Timeout: &durationpb.Duration{Seconds: 0},
-						UpgradeConfigs: []*envoyroutev3.RouteAction_UpgradeConfig{{
-							UpgradeType: "websocket",
-							Enabled:     wrapperspb.Bool(true),
-						}},
-					},
-				},
+		for j, path := range rule.HTTP.Paths {
+			clusterName, err := clusterFor(path.Backend)
+			if err != nil {
+				klog.Errorf("Ignoring path %q of ingress %s: %v", path.Path, ingressKey, err)
+				continue
 			}
-			routes = append(routes, route)
+			routeName := fmt.Sprintf("%s/%d/%d", ingressKey, i, j)
+			routes = append(routes, t.newPathRoutes(host, routeName, path, clusterName)...)
+		}
+	}
+
+	if backend := ingress.Spec.DefaultBackend; backend != nil {
+		clusterName, err := clusterFor(*backend)
+		if err != nil {
+			klog.Errorf("Ignoring default backend of ingress %s: %v", ingressKey, err)
+		} else {
+			// The default backend takes the requests to the hosts of the ingress that no rule
+			// matches, as well as the requests to any other host.
+			for _, host := range sets.NewString(append(hosts, anyHost)...).List() {
+				routes = append(routes, hostRoute{
+					host:           host,
+					route:          t.newRoute(ingressKey+"/default", &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: "/"}}, clusterName),
+					path:           "/",
+					defaultBackend: true,
+				})
+			}
+		}
+	}
+
+	return clusters, routes
+}
+
+// newPathRoutes returns the routes matching the path of an ingress rule:
+//  - an Exact path matches the request path exactly,
+//  - a Prefix path matches the request path element-wise, i.e. "/foo" matches "/foo" and
+//    "/foo/bar" but not "/foobar". Trailing slashes are ignored,
+//  - an ImplementationSpecific path is a plain Envoy prefix.
+func (t *translator) newPathRoutes(host, name string, path networkingv1.HTTPIngressPath, clusterName string) []hostRoute {
+	pathType := networkingv1.PathTypeImplementationSpecific
+	if path.PathType != nil {
+		pathType = *path.PathType
+	}
+
+	switch pathType {
+	case networkingv1.PathTypeExact:
+		return []hostRoute{{
+			host:  host,
+			route: t.newRoute(name, &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Path{Path: path.Path}}, clusterName),
+			path:  path.Path,
+			exact: true,
+		}}
+	case networkingv1.PathTypePrefix:
+		prefix := strings.TrimRight(path.Path, "/")
+		if prefix == "" {
+			return []hostRoute{{
+				host:  host,
+				route: t.newRoute(name, &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: "/"}}, clusterName),
+				path:  "/",
+			}}
+		}
+		return []hostRoute{{
+			host:  host,
+			route: t.newRoute(name, &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Path{Path: prefix}}, clusterName),
+			path:  prefix,
+		}, {
+			host:  host,
+			route: t.newRoute(name, &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: prefix + "/"}}, clusterName),
+			path:  prefix,
+		}}
+	default:
+		prefix := path.Path
+		if prefix == "" {
+			prefix = "/"
+		}
+		return []hostRoute{{
+			host:  host,
+			route: t.newRoute(name, &envoyroutev3.RouteMatch{PathSpecifier: &envoyroutev3.RouteMatch_Prefix{Prefix: prefix}}, clusterName),
+			path:  prefix,
+		}}
+	}
+}
+
+// newVirtualHosts returns a virtual host per host of the routes, with its routes ordered by
+// precedence. Envoy prefers a host to a wildcard host, and a wildcard host to anyHost.
+func (t *translator) newVirtualHosts(routes []hostRoute) []*envoyroutev3.VirtualHost {
+	routesByHost := make(map[string][]hostRoute)
+	for _, route := range routes {
+		routesByHost[route.host] = append(routesByHost[route.host], route)
+	}
+
+	hosts := make([]string, 0, len(routesByHost))
+	for host := range routesByHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	virtualHosts := make([]*envoyroutev3.VirtualHost, 0, len(hosts))
+	for _, host := range hosts {
+		hostRoutes := routesByHost[host]
+		sort.SliceStable(hostRoutes, func(i, j int) bool {
+			a, b := hostRoutes[i], hostRoutes[j]
+			if a.defaultBackend != b.defaultBackend {
+				return !a.defaultBackend
+			}
+			if len(a.path) != len(b.path) {
+				return len(a.path) > len(b.path)
+			}
+			if a.exact != b.exact {
+				return a.exact
+			}
+			return a.matches > b.matches
+		})
+
+		envoyRoutes := make([]*envoyroutev3.Route, 0, len(hostRoutes))
+		for _, route := range hostRoutes {
+			envoyRoutes = append(envoyRoutes, route.route)
+		}
+		virtualHosts = append(virtualHosts, &envoyroutev3.VirtualHost{
+			Name:    host,
+			Domains: []string{host},
+			Routes:  envoyRoutes,
+		})
+	}
+
+	return virtualHosts
+}
+
+func (t *translator) newRoute(name string, match *envoyroutev3.RouteMatch, clusterName string) *envoyroutev3.Route {
+	action := t.newRouteAction()
+	action.ClusterSpecifier = &envoyroutev3.RouteAction_Cluster{
+		Cluster: clusterName,
+	}
+	return &envoyroutev3.Route{
+		Name:   name,
+		Match:  match,
+		Action: &envoyroutev3.Route_Route{Route: action},
+	}
+}
+
+// newRouteAction returns a route action without cluster.
+func (t *translator) newRouteAction() *envoyroutev3.RouteAction {
+	return &envoyroutev3.RouteAction{
+		Timeout: &durationpb.Duration{Seconds: 0},
+		UpgradeConfigs: []*envoyroutev3.RouteAction_UpgradeConfig{{
+			UpgradeType: "websocket",
+			Enabled:     wrapperspb.Bool(true),
+		}},
+	}
+}
+
+// ingressHost returns the first host of the ingress which is not a wildcard, or "".
+func ingressHost(ingress *networkingv1.Ingress) string {
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" && !strings.HasPrefix(rule.Host, "*") {
+			return rule.Host
 		}
-		domains = append(domains, rule.Host, rule.Host+":*")
+	}
+	return ""
+}
+
+// newLeafCluster returns a cluster reaching the endpoints of the leaves, via TLS if upstreamTLS
+// is set. The traffic is split between the localities of the endpoints by their weight, and the
+// unhealthy endpoints are ejected. The leaves route by host, hence the health checks and the
+// SNI use the given host.
+func (t *translator) newLeafCluster(name string, endpoints []*envoyendpointv3.LocalityLbEndpoints, host string, upstreamTLS bool) (*envoyclusterv3.Cluster, error) {
+	//TODO(jmprusi): HTTP2 is set to false always, also allow for configuration of the timeout
+	cluster := t.newCluster(name, 2*time.Second, endpoints, envoyclusterv3.Cluster_STRICT_DNS)
+	cluster.DnsLookupFamily = envoyclusterv3.Cluster_V4_ONLY
+	cluster.CommonLbConfig = &envoyclusterv3.Cluster_CommonLbConfig{
+		LocalityConfigSpecifier: &envoyclusterv3.Cluster_CommonLbConfig_LocalityWeightedLbConfig_{
+			LocalityWeightedLbConfig: &envoyclusterv3.Cluster_CommonLbConfig_LocalityWeightedLbConfig{},
+		},
+	}
+	cluster.HealthChecks = []*envoycorev3.HealthCheck{t.newHealthCheck(host)}
+	cluster.OutlierDetection = &envoyclusterv3.OutlierDetection{
+		Consecutive_5Xx:    wrapperspb.UInt32(5),
+		Interval:           durationpb.New(10 * time.Second),
+		BaseEjectionTime:   durationpb.New(30 * time.Second),
+		MaxEjectionPercent: wrapperspb.UInt32(100),
+	}
+	if !upstreamTLS {
+		return cluster, nil
 	}
 
-	vh := &envoyroutev3.VirtualHost{
-		Name:    ingressKey,
-		Domains: domains,
-		Routes:  routes,
+	// the SNI is taken from the host of each request, defaulting to the given host, and the
+	// certificates are verified against it.
+	transportSocket, err := t.newUpstreamTLSTransportSocket(host)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the upstream TLS context: %w", err)
+	}
+	cluster.TransportSocket = transportSocket
+	cluster.UpstreamHttpProtocolOptions = &envoycorev3.UpstreamHttpProtocolOptions{
+		AutoSni:           true,
+		AutoSanValidation: len(t.upstreamTLS.CAData) > 0,
 	}
 
-	virtualHosts = append(virtualHosts, vh)
+	return cluster, nil
+}
+
+// newHealthCheck returns an active HTTP health check of the load balancers of the leaves.
+// Any response below 500 means the load balancer, and the workload cluster behind, serves
+// the requests to the host.
+func (t *translator) newHealthCheck(host string) *envoycorev3.HealthCheck {
+	return &envoycorev3.HealthCheck{
+		Timeout:            durationpb.New(2 * time.Second),
+		Interval:           durationpb.New(10 * time.Second),
+		UnhealthyThreshold: wrapperspb.UInt32(3),
+		HealthyThreshold:   wrapperspb.UInt32(1),
+		HealthChecker: &envoycorev3.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: &envoycorev3.HealthCheck_HttpHealthCheck{
+				Host:             host,
+				Path:             "/",
+				ExpectedStatuses: []*envoytypev3.Int64Range{{Start: 200, End: 500}},
+			},
+		},
+	}
+}
+
+// rootIngressKeyFor returns the key of the root ingress of a leaf, or "" for an ingress
+// which is not a leaf.
+func rootIngressKeyFor(ingress metav1.Object) string {
+	if ingress.GetLabels()[ingresssplitter.OwnedByCluster] != "" && ingress.GetLabels()[ingresssplitter.OwnedByNamespace] != "" && ingress.GetLabels()[ingresssplitter.OwnedByIngress] != "" {
+		return ingress.GetLabels()[ingresssplitter.OwnedByNamespace] + "/" + clusters.ToClusterAwareKey(ingresssplitter.UnescapeClusterNameLabel(ingress.GetLabels()[ingresssplitter.OwnedByCluster]), ingress.GetLabels()[ingresssplitter.OwnedByIngress])
+	}
+
+	return ""
+}
+
+// backendServiceName returns the service and port of an ingress backend as name:port.
+func backendServiceName(service *networkingv1.IngressServiceBackend) string {
+	if service.Port.Name != "" {
+		return service.Name + ":" + service.Port.Name
+	}
+	return service.Name + ":" + strconv.Itoa(int(service.Port.Number))
+}
 
-	return []cachetypes.Resource{cluster}, virtualHosts
+// leafEndpoints are the load balancer addresses of a leaf in its workload cluster.
+type leafEndpoints struct {
+	workloadCluster string
+	weight          uint32
+	addresses       []string
+	port            uint32
+}
+
+// ingressLeafEndpoints returns the load balancers of a leaf ingress, weighted by its
+// WeightAnnotation.
+func ingressLeafEndpoints(leaf *networkingv1.Ingress, port uint32) leafEndpoints {
+	weight := uint32(1)
+	if value, found := leaf.Annotations[ingresssplitter.WeightAnnotation]; found {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			klog.Errorf("Ignoring invalid weight %q of ingress %s|%s/%s: %v", value, logicalcluster.From(leaf), leaf.Namespace, leaf.Name, err)
+		} else {
+			weight = uint32(parsed)
+		}
+	}
+
+	addresses := make([]string, 0, len(leaf.Status.LoadBalancer.Ingress))
+	for _, lb := range leaf.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		} else if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+	}
+
+	return leafEndpoints{
+		//nolint:staticcheck
+		workloadCluster: shared.DeprecatedGetAssignedWorkloadCluster(leaf.Labels),
+		weight:          weight,
+		addresses:       addresses,
+		port:            port,
+	}
+}
+
+// newLocalityEndpoints returns the endpoints of the load balancers of the leaves, with a
+// locality per leaf weighted by its weight. The leaves of weight 0 are dropped.
+func (t *translator) newLocalityEndpoints(leaves []leafEndpoints) []*envoyendpointv3.LocalityLbEndpoints {
+	localities := make([]*envoyendpointv3.LocalityLbEndpoints, 0, len(leaves))
+	for _, leaf := range leaves {
+		if leaf.weight == 0 || len(leaf.addresses) == 0 {
+			continue
+		}
+
+		endpoints := make([]*envoyendpointv3.LbEndpoint, 0, len(leaf.addresses))
+		for _, address := range leaf.addresses {
+			endpoints = append(endpoints, t.newLBEndpoint(address, leaf.port))
+		}
+
+		localities = append(localities, &envoyendpointv3.LocalityLbEndpoints{
+			Locality:            &envoycorev3.Locality{Zone: leaf.workloadCluster},
+			LbEndpoints:         endpoints,
+			LoadBalancingWeight: wrapperspb.UInt32(leaf.weight),
+		})
+	}
+
+	return localities
 }
 
 func (t *translator) newLBEndpoint(ip string, port uint32) *envoyendpointv3.LbEndpoint {
@@ -147,7 +501,7 @@ func (t *translator) newLBEndpoint(ip string, port uint32) *envoyendpointv3.LbEn
 func (t *translator) newCluster(
 	name string,
 	connectTimeout time.Duration,
-	endpoints []*envoyendpointv3.LbEndpoint,
+	endpoints []*envoyendpointv3.LocalityLbEndpoints,
 	discoveryType envoyclusterv3.Cluster_DiscoveryType) *envoyclusterv3.Cluster {
 
 	return &envoyclusterv3.Cluster{
@@ -158,9 +512,7 @@ func (t *translator) newCluster(
 		ConnectTimeout: durationpb.New(connectTimeout),
 		LoadAssignment: &envoyendpointv3.ClusterLoadAssignment{
 			ClusterName: name,
-			Endpoints: []*envoyendpointv3.LocalityLbEndpoints{{
-				LbEndpoints: endpoints,
-			}},
+			Endpoints:   endpoints,
 		},
 	}
 }
@@ -185,6 +537,8 @@ func (t *translator) newHTTPConnectionManager(routeConfigName string) *envoyfilt
 		CodecType:   envoyfilterhcmv3.HttpConnectionManager_AUTO,
 		StatPrefix:  "ingress_http",
 		HttpFilters: filters,
+		// The virtual hosts match the hosts of the ingresses, whatever the port.
+		StripPortMode: &envoyfilterhcmv3.HttpConnectionManager_StripAnyHostPort{StripAnyHostPort: true},
 		RouteSpecifier: &envoyfilterhcmv3.HttpConnectionManager_Rds{
 			Rds: &envoyfilterhcmv3.Rds{
 				ConfigSource: &envoycorev3.ConfigSource{
@@ -212,20 +566,24 @@ func (t *translator) newHTTPListener(manager *envoyfilterhcmv3.HttpConnectionMan
 	}}
 
 	return &envoylistenerv3.Listener{
-		Name: fmt.Sprintf("listener_%d", t.envoyListenPort),
-		Address: &envoycorev3.Address{
-			Address: &envoycorev3.Address_SocketAddress{
-				SocketAddress: &envoycorev3.SocketAddress{
-					Protocol: envoycorev3.SocketAddress_TCP,
-					Address:  "0.0.0.0",
-					PortSpecifier: &envoycorev3.SocketAddress_PortValue{
-						PortValue: uint32(t.envoyListenPort),
-					},
-				},
-			},
-		},
+		Name:    fmt.Sprintf("listener_%d", t.envoyListenPort),
+		Address: t.newListenerAddress(t.envoyListenPort),
 		FilterChains: []*envoylistenerv3.FilterChain{
 			{Filters: filters},
 		},
 	}, nil
 }
+
+func (t *translator) newListenerAddress(port uint) *envoycorev3.Address {
+	return &envoycorev3.Address{
+		Address: &envoycorev3.Address_SocketAddress{
+			SocketAddress: &envoycorev3.SocketAddress{
+				Protocol: envoycorev3.SocketAddress_TCP,
+				Address:  "0.0.0.0",
+				PortSpecifier: &envoycorev3.SocketAddress_PortValue{
+					PortValue: uint32(port),
+				},
+			},
+		},
+	}
+}
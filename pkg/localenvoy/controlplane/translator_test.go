@@ -0,0 +1,396 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/gatewaysplitter"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/ingresssplitter"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the translator tests")
+
+func TestTranslate(t *testing.T) {
+	exact := networkingv1.PathTypeExact
+	prefix := networkingv1.PathTypePrefix
+	implementationSpecific := networkingv1.PathTypeImplementationSpecific
+	webRule := networkingv1.IngressRule{
+		Host: "example.com",
+		IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("web", 80)}},
+		}},
+	}
+
+	pathPrefix := gatewayv1alpha2.PathMatchPathPrefix
+	pathExact := gatewayv1alpha2.PathMatchExact
+	pathRegex := gatewayv1alpha2.PathMatchRegularExpression
+	headerRegex := gatewayv1alpha2.HeaderMatchRegularExpression
+	post := gatewayv1alpha2.HTTPMethodPost
+	webGateways := []*gatewayv1alpha2.Gateway{
+		gateway("web-east", "east", "10.0.0.1"),
+		gateway("web-west", "west", "west.example.org"),
+	}
+	httpsGateway := gateway("web-south", "south", "10.0.0.3")
+	httpsGateway.Spec.Listeners = httpsGateway.Spec.Listeners[:1]
+
+	httpsIngress := ingress("https", withAnnotation(UpstreamProtocolAnnotation, "https"), withRules(networkingv1.IngressRule{
+		Host: "example.com",
+		IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("web", 443)}},
+		}},
+	}))
+
+	tests := []struct {
+		name        string
+		ingresses   []*networkingv1.Ingress
+		httpRoutes  []*gatewayv1alpha2.HTTPRoute
+		gateways    []*gatewayv1alpha2.Gateway
+		upstreamTLS UpstreamTLSOptions
+	}{
+		{
+			name: "path types",
+			ingresses: []*networkingv1.Ingress{
+				ingress("paths", withRules(networkingv1.IngressRule{
+					Host: "example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{Path: "/", PathType: &prefix, Backend: serviceBackend("root", 80)},
+							{Path: "/foo/", PathType: &prefix, Backend: serviceBackend("foo", 80)},
+							{Path: "/foo", PathType: &exact, Backend: serviceBackend("exact-foo", 80)},
+							{Path: "/foo/bar", PathType: &implementationSpecific, Backend: serviceBackend("bar", 8080)},
+						},
+					}},
+				})),
+			},
+		},
+		{
+			name: "host-less and wildcard rules",
+			ingresses: []*networkingv1.Ingress{
+				ingress("hosts", withRules(
+					networkingv1.IngressRule{
+						IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("any", 80)}},
+						}},
+					},
+					networkingv1.IngressRule{
+						Host: "*.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("wildcard", 80)}},
+						}},
+					},
+					networkingv1.IngressRule{
+						Host: "foo.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("foo", 80)}},
+						}},
+					},
+				)),
+			},
+		},
+		{
+			name: "default backend",
+			ingresses: []*networkingv1.Ingress{
+				ingress("default", withDefaultBackend(serviceBackend("default", 80)), withRules(networkingv1.IngressRule{
+					Host: "example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/api", PathType: &prefix, Backend: serviceBackend("api", 80)}},
+					}},
+				})),
+			},
+		},
+		{
+			name: "routes merged across ingresses",
+			ingresses: []*networkingv1.Ingress{
+				ingress("first", withRules(networkingv1.IngressRule{
+					Host: "example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix, Backend: serviceBackend("web", 80)}},
+					}},
+				})),
+				ingress("second", withRules(networkingv1.IngressRule{
+					Host: "example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/api", PathType: &prefix, Backend: serviceBackend("api", 80)}},
+					}},
+				})),
+			},
+		},
+		{
+			name:        "https upstream",
+			ingresses:   []*networkingv1.Ingress{httpsIngress},
+			upstreamTLS: UpstreamTLSOptions{CAData: []byte("-----BEGIN CERTIFICATE-----\ntest\n-----END CERTIFICATE-----\n")},
+		},
+		{
+			name:        "https upstream insecure",
+			ingresses:   []*networkingv1.Ingress{httpsIngress},
+			upstreamTLS: UpstreamTLSOptions{InsecureSkipVerify: true},
+		},
+		{
+			name:      "https upstream without CA",
+			ingresses: []*networkingv1.Ingress{httpsIngress},
+		},
+		{
+			name: "weighted leaves",
+			ingresses: []*networkingv1.Ingress{
+				ingress("web-east", withLeaf("web", "east", "3", "east.example.org"), withRules(webRule)),
+				ingress("web-west", withLeaf("web", "west", "1", "west.example.org"), withRules(webRule)),
+				ingress("web-broken", withLeaf("web", "broken", "0", "broken.example.org"), withRules(webRule)),
+			},
+		},
+		{
+			name: "http route matches",
+			httpRoutes: []*gatewayv1alpha2.HTTPRoute{
+				httpRoute("api", "east", "web", []gatewayv1alpha2.Hostname{"api.example.com", "*.example.org"}, []gatewayv1alpha2.HTTPRouteRule{{
+					Matches: []gatewayv1alpha2.HTTPRouteMatch{{
+						Path: &gatewayv1alpha2.HTTPPathMatch{Type: &pathPrefix, Value: pointer.String("/v1/")},
+					}, {
+						Path:    &gatewayv1alpha2.HTTPPathMatch{Type: &pathPrefix, Value: pointer.String("/v1")},
+						Headers: []gatewayv1alpha2.HTTPHeaderMatch{{Name: "x-canary", Value: "true"}},
+					}},
+					BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("v1", 80, nil)},
+				}, {
+					Matches: []gatewayv1alpha2.HTTPRouteMatch{{
+						Path:        &gatewayv1alpha2.HTTPPathMatch{Type: &pathExact, Value: pointer.String("/login")},
+						Method:      &post,
+						QueryParams: []gatewayv1alpha2.HTTPQueryParamMatch{{Name: "debug", Value: "1"}},
+					}, {
+						Path:    &gatewayv1alpha2.HTTPPathMatch{Type: &pathRegex, Value: pointer.String("/users/[0-9]+")},
+						Headers: []gatewayv1alpha2.HTTPHeaderMatch{{Type: &headerRegex, Name: "user-agent", Value: ".*Mobile.*"}},
+					}},
+					BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("auth", 8080, nil)},
+				}, {
+					BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("web", 80, nil)},
+				}}),
+			},
+			gateways: webGateways,
+		},
+		{
+			name: "http route weighted backends and redirects",
+			httpRoutes: []*gatewayv1alpha2.HTTPRoute{
+				httpRoute("shop", "east", "web", nil, []gatewayv1alpha2.HTTPRouteRule{{
+					Matches: []gatewayv1alpha2.HTTPRouteMatch{{
+						Path: &gatewayv1alpha2.HTTPPathMatch{Type: &pathPrefix, Value: pointer.String("/old")},
+					}},
+					Filters: []gatewayv1alpha2.HTTPRouteFilter{{
+						Type: gatewayv1alpha2.HTTPRouteFilterRequestRedirect,
+						RequestRedirect: &gatewayv1alpha2.HTTPRequestRedirectFilter{
+							Scheme:     pointer.String("https"),
+							Hostname:   (*gatewayv1alpha2.PreciseHostname)(pointer.String("shop.example.com")),
+							StatusCode: pointer.Int(301),
+						},
+					}},
+				}, {
+					Matches: []gatewayv1alpha2.HTTPRouteMatch{{
+						Path: &gatewayv1alpha2.HTTPPathMatch{Type: &pathPrefix, Value: pointer.String("/broken")},
+					}},
+					BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("unused", 80, pointer.Int32(0))},
+				}, {
+					BackendRefs: []gatewayv1alpha2.HTTPBackendRef{
+						backendRef("stable", 80, pointer.Int32(90)),
+						backendRef("canary", 80, pointer.Int32(10)),
+						backendRef("disabled", 80, pointer.Int32(0)),
+					},
+				}}),
+				httpRoute("shop", "west", "web", nil, nil),
+				// the leaf Gateways without HTTP listener are skipped
+				httpRoute("shop", "south", "web", nil, nil),
+				// the roots are served through their leaves
+				{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default", ClusterName: "root:org:ws"}},
+			},
+			gateways: append([]*gatewayv1alpha2.Gateway{httpsGateway}, webGateways...),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clusters, routeConfig := newTranslator(80, 443, tc.upstreamTLS).translate("defaultroute", tc.ingresses, tc.httpRoutes, tc.gateways)
+
+			out := map[string]interface{}{}
+			var clusterValues []interface{}
+			for _, cluster := range clusters {
+				clusterValues = append(clusterValues, protoValue(t, cluster))
+			}
+			out["clusters"] = clusterValues
+			out["routeConfig"] = protoValue(t, routeConfig)
+
+			actual, err := yaml.Marshal(out)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(tc.name, " ", "_")+".yaml")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, actual, 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err, "run the tests with -update to create the golden file")
+			require.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+// protoValue returns the message as a JSON value, protojson output being unstable.
+func protoValue(t *testing.T, message proto.Message) interface{} {
+	data, err := protojson.Marshal(message)
+	require.NoError(t, err)
+	var value interface{}
+	require.NoError(t, json.Unmarshal(data, &value))
+	return value
+}
+
+type ingressOption func(*networkingv1.Ingress)
+
+func ingress(name string, opts ...ingressOption) *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			ClusterName: "root:org:ws",
+			Annotations: map[string]string{},
+		},
+		Status: networkingv1.IngressStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{Hostname: "leaf.example.org"}},
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(ingress)
+	}
+	return ingress
+}
+
+func withRules(rules ...networkingv1.IngressRule) ingressOption {
+	return func(ingress *networkingv1.Ingress) {
+		ingress.Spec.Rules = rules
+	}
+}
+
+func withDefaultBackend(backend networkingv1.IngressBackend) ingressOption {
+	return func(ingress *networkingv1.Ingress) {
+		ingress.Spec.DefaultBackend = &backend
+	}
+}
+
+func withAnnotation(key, value string) ingressOption {
+	return func(ingress *networkingv1.Ingress) {
+		ingress.Annotations[key] = value
+	}
+}
+
+// withLeaf makes the ingress a leaf of the root ingress in the workload cluster, with the
+// given weight and load balancer.
+func withLeaf(root, workloadCluster, weight, hostname string) ingressOption {
+	return func(ingress *networkingv1.Ingress) {
+		ingress.Labels = map[string]string{
+			workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync),
+			ingresssplitter.OwnedByCluster:   ingresssplitter.LabelEscapeClusterName(logicalcluster.From(ingress)),
+			ingresssplitter.OwnedByIngress:   root,
+			ingresssplitter.OwnedByNamespace: ingress.Namespace,
+		}
+		ingress.Annotations[ingresssplitter.WeightAnnotation] = weight
+		ingress.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: hostname}}
+	}
+}
+
+func serviceBackend(name string, port int32) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: name,
+			Port: networkingv1.ServiceBackendPort{Number: port},
+		},
+	}
+}
+
+// httpRoute returns the leaf of the root HTTPRoute in the workload cluster, attached to the
+// leaf of the gateway. The leaves share the rules of their root.
+func httpRoute(root, workloadCluster, gateway string, hostnames []gatewayv1alpha2.Hostname, rules []gatewayv1alpha2.HTTPRouteRule) *gatewayv1alpha2.HTTPRoute {
+	return &gatewayv1alpha2.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        gatewaysplitter.LeafName(root, workloadCluster),
+			Namespace:   "default",
+			ClusterName: "root:org:ws",
+			Labels:      leafLabels(root, workloadCluster),
+		},
+		Spec: gatewayv1alpha2.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1alpha2.CommonRouteSpec{
+				ParentRefs: []gatewayv1alpha2.ParentRef{{Name: gatewayv1alpha2.ObjectName(gatewaysplitter.LeafName(gateway, workloadCluster))}},
+			},
+			Hostnames: hostnames,
+			Rules:     rules,
+		},
+	}
+}
+
+// gateway returns a leaf gateway with an HTTP listener on port 8080 and the given address.
+func gateway(name, workloadCluster, address string) *gatewayv1alpha2.Gateway {
+	return &gatewayv1alpha2.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			ClusterName: "root:org:ws",
+			Labels:      leafLabels(strings.TrimSuffix(name, "-"+workloadCluster), workloadCluster),
+		},
+		Spec: gatewayv1alpha2.GatewaySpec{
+			Listeners: []gatewayv1alpha2.Listener{
+				{Name: "https", Port: 8443, Protocol: gatewayv1alpha2.HTTPSProtocolType},
+				{Name: "http", Port: 8080, Protocol: gatewayv1alpha2.HTTPProtocolType},
+			},
+		},
+		Status: gatewayv1alpha2.GatewayStatus{
+			Addresses: []gatewayv1alpha2.GatewayAddress{{Value: address}},
+		},
+	}
+}
+
+func leafLabels(root, workloadCluster string) map[string]string {
+	return map[string]string{
+		workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync),
+		gatewaysplitter.OwnedByCluster:   ingresssplitter.LabelEscapeClusterName(logicalcluster.New("root:org:ws")),
+		gatewaysplitter.OwnedByName:      root,
+		gatewaysplitter.OwnedByNamespace: "default",
+	}
+}
+
+func backendRef(name string, port gatewayv1alpha2.PortNumber, weight *int32) gatewayv1alpha2.HTTPBackendRef {
+	return gatewayv1alpha2.HTTPBackendRef{
+		BackendRef: gatewayv1alpha2.BackendRef{
+			BackendObjectReference: gatewayv1alpha2.BackendObjectReference{
+				Name: gatewayv1alpha2.ObjectName(name),
+				Port: &port,
+			},
+			Weight: weight,
+		},
+	}
+}
@@ -2325,10 +2325,18 @@ func schema_pkg_apis_tenancy_v1alpha1_ClusterWorkspaceShardSpec(ref common.Refer
 							Format:      "",
 						},
 					},
+					"credentials": {
+						SchemaProps: spec.SchemaProps{
+							Description: "credentials is a reference to a secret in the root workspace holding a kubeconfig in the 'kubeconfig' key with administrative credentials for this shard. Peer shards use it to fan out wildcard requests, connecting to baseURL.",
+							Ref:         ref("k8s.io/api/core/v1.SecretReference"),
+						},
+					},
 				},
 				Required: []string{"externalURL"},
 			},
 		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.SecretReference"},
 	}
 }
 
@@ -2411,7 +2419,7 @@ func schema_pkg_apis_tenancy_v1alpha1_ClusterWorkspaceStatus(ref common.Referenc
 				Properties: map[string]spec.Schema{
 					"phase": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Phase of the workspace  (Scheduling / Initializing / Ready)",
+							Description: "Phase of the workspace  (Scheduling / Initializing / Ready / Deleting)",
 							Type:        []string{"string"},
 							Format:      "",
 						},
@@ -2594,6 +2602,58 @@ func schema_pkg_apis_tenancy_v1alpha1_ClusterWorkspaceTypeSpec(ref common.Refere
 							},
 						},
 					},
+					"extend": {
+						SchemaProps: spec.SchemaProps{
+							Description: "extend is a list of other ClusterWorkspaceTypes in the same workspace whose initializers and additionalWorkspaceLabels are inherited by this type. Labels defined by this type take precedence over inherited ones. A type extending another type is also accepted wherever the extended type is allowed as a parent or child type.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"defaultChildWorkspaceType": {
+						SchemaProps: spec.SchemaProps{
+							Description: "defaultChildWorkspaceType is the type used by clients like the kubectl workspace plugin for nested workspaces that are created without an explicit type. If set, it must be one of allowedChildWorkspaceTypes if those are given.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"allowedChildWorkspaceTypes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "allowedChildWorkspaceTypes is a list of ClusterWorkspaceTypes that can be created in a workspace of this type. If empty, all types are allowed.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"allowedParentWorkspaceTypes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "allowedParentWorkspaceTypes is a list of ClusterWorkspaceTypes that a workspace of this type can be created in. The root workspace has the type \"Root\". If empty, all parent types are allowed.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -14,10 +14,10 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package proxy provides a reverse proxy that accepts client certificates and
-// forwards Common Name and Organizations to backend API servers in HTTP
-// headers. The proxy terminates client TLS and communicates with API servers
-// via mTLS. Traffic is routed based on paths.
+// Package proxy provides a reverse proxy that authenticates client certificates
+// and bearer tokens, and forwards the user name, groups and extras to backend API
+// servers in HTTP headers. The proxy terminates client TLS and communicates with
+// API servers via mTLS. Traffic is routed based on paths.
 //
 // An example configuration:
 //
@@ -31,5 +31,24 @@ limitations under the License.
 //    backend_server_ca: certs/kcp-ca-cert.pem
 //    proxy_client_cert: certs/proxy-client-cert.pem
 //    proxy_client_key: certs/proxy-client-key.pem
+//
+// With --root-kubeconfig, the proxy watches the ClusterWorkspaces and
+// ClusterWorkspaceShards of the root shard, and routes requests to
+// /clusters/root:<workspace>/ to the base URL of the shard the workspace is
+// scheduled to, using the client certificate of the "/" mapping. Requests for
+// workspaces that do not exist fail with 404, those for workspaces that are not
+// scheduled or whose shard is unknown with 503.
+//
+// The mapping file and the certificates and keys it references are checked for
+// changes every --mapping-reload-interval. A changed configuration is applied
+// once it loads completely; requests in flight, including watches, finish on the
+// previous one. The kcp_front_proxy_config_generation metric counts the applied
+// configurations. The metrics of the proxy itself are served on the separate
+// --metrics-bind-address, /metrics on the secure port is forwarded to the backend.
+//
+// Requests are recorded in the per-workspace request metrics by the logical
+// cluster of their /clusters/<name>/ path, see docs/workspace-metrics.md. Only
+// authenticated requests to workspaces that resolve are attributed to their
+// workspace, others are recorded without one.
 
 package proxy
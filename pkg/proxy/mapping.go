@@ -17,57 +17,173 @@ limitations under the License.
 package proxy
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
 
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	proxyoptions "github.com/kcp-dev/kcp/pkg/proxy/options"
+	"github.com/kcp-dev/kcp/pkg/workspacemetrics"
 )
 
+const resyncPeriod = 10 * time.Hour
+
 // PathMapping describes how to route traffic from a path to a backend server.
 // Each Path is registered with the DefaultServeMux with a handler that
 // delegates to the specified backend.
 type PathMapping struct {
-	Path            string `json:"path"`
-	Backend         string `json:"backend"`
-	BackendServerCA string `json:"backend_server_ca"`
-	ProxyClientCert string `json:"proxy_client_cert"`
-	ProxyClientKey  string `json:"proxy_client_key"`
-	UserHeader      string `json:"user_header,omitempty"`
-	GroupHeader     string `json:"group_header,omitempty"`
+	Path              string `json:"path"`
+	Backend           string `json:"backend"`
+	BackendServerCA   string `json:"backend_server_ca"`
+	ProxyClientCert   string `json:"proxy_client_cert"`
+	ProxyClientKey    string `json:"proxy_client_key"`
+	UserHeader        string `json:"user_header,omitempty"`
+	GroupHeader       string `json:"group_header,omitempty"`
+	ExtraHeaderPrefix string `json:"extra_header_prefix,omitempty"`
 }
 
-func NewHandler(o *proxyoptions.Options) (http.Handler, error) {
-	mappingData, err := ioutil.ReadFile(o.MappingFile)
+// NewHandler returns a handler routing requests according to the mapping file. With a root
+// shard kubeconfig, requests to workspaces below root are routed to the shard hosting the
+// workspace instead, with the client certificate and headers of the / path mapping. With a
+// reload interval, the mapping file and the files it references are reloaded when they
+// change. The informers and the reloading run until ctx is done. Requests are recorded by
+// the recorder, if not nil, once they are authenticated and their workspace resolves.
+func NewHandler(ctx context.Context, o *proxyoptions.Options, recorder *workspacemetrics.Recorder) (http.Handler, error) {
+	var resolver *workspaceResolver
+	if o.RootKubeconfig != "" {
+		config, err := clientcmd.BuildConfigFromFlags("", o.RootKubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load root shard kubeconfig %q: %w", o.RootKubeconfig, err)
+		}
+		kcpClusterClient, err := kcpclient.NewClusterForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		informerFactory := kcpinformers.NewSharedInformerFactoryWithOptions(kcpClusterClient.Cluster(logicalcluster.Wildcard), resyncPeriod)
+		resolver = newWorkspaceResolver(
+			informerFactory.Tenancy().V1alpha1().ClusterWorkspaces(),
+			informerFactory.Tenancy().V1alpha1().ClusterWorkspaceShards(),
+		)
+		informerFactory.Start(ctx.Done())
+		for typ, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return nil, fmt.Errorf("failed to sync %v informer", typ)
+			}
+		}
+	}
+
+	handler := &reloadingHandler{mappingFile: o.MappingFile, resolver: resolver}
+	if err := handler.load(); err != nil {
+		return nil, err
+	}
+	if o.MappingReloadInterval > 0 {
+		go wait.UntilWithContext(ctx, handler.reloadIfChanged, o.MappingReloadInterval)
+	}
+	return workspacemetrics.WithRequestMetrics(handler, recorder, resolvedRequestAttributes(resolver), longRunningRequestCheck), nil
+}
+
+// loadMapping reads the mapping file and returns the mappings with a digest of the mapping
+// file and all the files it references.
+func loadMapping(mappingFile string) ([]PathMapping, []byte, error) {
+	mappingData, err := ioutil.ReadFile(mappingFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read mapping file %q: %w", o.MappingFile, err)
+		return nil, nil, fmt.Errorf("failed to read mapping file %q: %w", mappingFile, err)
 	}
 
 	var mapping []PathMapping
 	if err = yaml.Unmarshal(mappingData, &mapping); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal mapping file %q: %w", o.MappingFile, err)
+		return nil, nil, fmt.Errorf("failed to unmarshal mapping file %q: %w", mappingFile, err)
 	}
 
-	mux := http.NewServeMux()
+	digest := sha256.New()
+	digest.Write(mappingData)
 	for _, m := range mapping {
+		for _, file := range []string{m.BackendServerCA, m.ProxyClientCert, m.ProxyClientKey} {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read file %q of path mapping %q: %w", file, m.Path, err)
+			}
+			digest.Write(data)
+		}
+	}
+	return mapping, digest.Sum(nil), nil
+}
+
+// newProxyConfig creates the reverse proxies for the given mappings, and the handler routing
+// to them, and to the shards if resolver is not nil.
+func newProxyConfig(mapping []PathMapping, resolver *workspaceResolver) (*proxyConfig, error) {
+	config := &proxyConfig{}
+
+	mux := http.NewServeMux()
+	paths := map[string]bool{}
+	var rootMapping *PathMapping
+	for i, m := range mapping {
+		if m.Path == "" || m.Backend == "" {
+			return nil, fmt.Errorf("path mapping %d must have a path and a backend", i)
+		}
+		if paths[m.Path] {
+			return nil, fmt.Errorf("duplicate path mapping for path %q", m.Path)
+		}
+		paths[m.Path] = true
+
 		klog.V(2).Infof("Adding mapping %v", m)
 		proxy, err := NewReverseProxy(m.Backend, m.ProxyClientCert, m.ProxyClientKey, m.BackendServerCA)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create path mapping for path %q: %w", m.Path, err)
 		}
-		userHeader := "X-Remote-User"
-		groupHeader := "X-Remote-Group"
-		if m.UserHeader != "" {
-			userHeader = m.UserHeader
-		}
-		if m.GroupHeader != "" {
-			groupHeader = m.GroupHeader
+		config.addProxy(proxy)
+		userHeader, groupHeader, extraHeaderPrefix := m.headers()
+		mux.Handle(m.Path, http.HandlerFunc(ProxyHandler(proxy, userHeader, groupHeader, extraHeaderPrefix)))
+		if m.Path == "/" {
+			rootMapping = &mapping[i]
 		}
-		mux.Handle(m.Path, http.HandlerFunc(ProxyHandler(proxy, userHeader, groupHeader)))
 	}
 
-	return mux, nil
+	if resolver == nil {
+		config.handler = mux
+		return config, nil
+	}
+	if rootMapping == nil {
+		return nil, fmt.Errorf("a mapping for path \"/\" is required to route to workspaces")
+	}
+
+	userHeader, groupHeader, extraHeaderPrefix := rootMapping.headers()
+	proxies := &shardProxies{newProxy: func(shardURL string) (http.Handler, error) {
+		klog.V(2).Infof("Adding proxy for shard %q", shardURL)
+		proxy, err := NewReverseProxy(shardURL, rootMapping.ProxyClientCert, rootMapping.ProxyClientKey, rootMapping.BackendServerCA)
+		if err != nil {
+			return nil, err
+		}
+		config.addProxy(proxy)
+		return http.HandlerFunc(ProxyHandler(proxy, userHeader, groupHeader, extraHeaderPrefix)), nil
+	}}
+	config.handler = withWorkspaceRouting(mux, resolver, proxies.Get)
+	return config, nil
+}
+
+func (m *PathMapping) headers() (userHeader, groupHeader, extraHeaderPrefix string) {
+	userHeader = "X-Remote-User"
+	groupHeader = "X-Remote-Group"
+	extraHeaderPrefix = "X-Remote-Extra-"
+	if m.UserHeader != "" {
+		userHeader = m.UserHeader
+	}
+	if m.GroupHeader != "" {
+		groupHeader = m.GroupHeader
+	}
+	if m.ExtraHeaderPrefix != "" {
+		extraHeaderPrefix = m.ExtraHeaderPrefix
+	}
+	return userHeader, groupHeader, extraHeaderPrefix
 }
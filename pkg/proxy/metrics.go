@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	genericfilters "k8s.io/apiserver/pkg/server/filters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/workspacemetrics"
+)
+
+var requestInfoFactory = request.RequestInfoFactory{
+	APIPrefixes:          sets.NewString("api", "apis"),
+	GrouplessAPIPrefixes: sets.NewString("api"),
+}
+
+// longRunningRequestCheck matches the long-running requests of the kcp API servers, which
+// are not observed in the latency of the workspace request metrics.
+var longRunningRequestCheck = genericfilters.BasicLongRunningRequestCheck(
+	sets.NewString("watch", "proxy"),
+	sets.NewString("attach", "exec", "proxy", "log", "portforward"),
+)
+
+// requestAttributes returns the logical cluster of a /clusters/<name>/ request and the
+// request info of the path below it, for the per-workspace request metrics. Requests
+// outside of /clusters/ have no logical cluster.
+func requestAttributes(req *http.Request) (logicalcluster.Name, *request.RequestInfo) {
+	clusterName, ok := clusterFromPath(req.URL.Path)
+	if !ok {
+		requestInfo, _ := request.RequestInfoFrom(req.Context())
+		return logicalcluster.Name{}, requestInfo
+	}
+
+	// strip the /clusters/<name> prefix, such that API requests are recognized as such
+	path := strings.TrimPrefix(req.URL.Path, "/clusters/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[i:]
+	} else {
+		path = "/"
+	}
+	u := *req.URL
+	u.Path = path
+	u.RawPath = ""
+	stripped := req.WithContext(req.Context())
+	stripped.URL = &u
+
+	requestInfo, err := requestInfoFactory.NewRequestInfo(stripped)
+	if err != nil {
+		return clusterName, nil
+	}
+	return clusterName, requestInfo
+}
+
+// resolvedRequestAttributes returns the request attributes of authenticated requests whose
+// workspace resolves. Other requests have no logical cluster, such that anonymous clients
+// and requests to workspaces that do not exist cannot take up workspace label values.
+// Without a resolver, the workspaces below root are not checked.
+func resolvedRequestAttributes(resolver *workspaceResolver) workspacemetrics.AttributesFunc {
+	return func(req *http.Request) (logicalcluster.Name, *request.RequestInfo) {
+		clusterName, requestInfo := requestAttributes(req)
+		if clusterName.Empty() {
+			return clusterName, requestInfo
+		}
+		if _, ok := request.UserFrom(req.Context()); !ok {
+			return logicalcluster.Name{}, requestInfo
+		}
+		if resolver != nil && strings.HasPrefix(clusterName.String(), tenancyv1alpha1.RootCluster.String()+":") {
+			if _, err := resolver.Resolve(clusterName); err != nil {
+				return logicalcluster.Name{}, requestInfo
+			}
+		}
+		return clusterName, requestInfo
+	}
+}
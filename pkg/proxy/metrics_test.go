@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestRequestAttributes(t *testing.T) {
+	tests := map[string]struct {
+		path string
+
+		wantCluster     logicalcluster.Name
+		wantResource    string
+		wantVerb        string
+		wantNonResource bool
+	}{
+		"resource request": {
+			path:         "/clusters/root:org/api/v1/namespaces/default/configmaps/foo",
+			wantCluster:  logicalcluster.New("root:org"),
+			wantResource: "configmaps",
+			wantVerb:     "get",
+		},
+		"unprefixed workspace": {
+			path:         "/clusters/org/apis/apps/v1/deployments?watch=true",
+			wantCluster:  logicalcluster.New("root:org"),
+			wantResource: "deployments",
+			wantVerb:     "watch",
+		},
+		"non-resource request": {
+			path:            "/clusters/root:org/version",
+			wantCluster:     logicalcluster.New("root:org"),
+			wantNonResource: true,
+		},
+		"cluster only": {
+			path:            "/clusters/root:org",
+			wantCluster:     logicalcluster.New("root:org"),
+			wantNonResource: true,
+		},
+		"outside of clusters": {
+			path: "/services/foo",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clusterName, requestInfo := requestAttributes(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.wantCluster, clusterName)
+			if tt.wantCluster.Empty() {
+				require.Nil(t, requestInfo)
+				return
+			}
+			require.NotNil(t, requestInfo)
+			require.Equal(t, !tt.wantNonResource, requestInfo.IsResourceRequest)
+			if !tt.wantNonResource {
+				require.Equal(t, tt.wantResource, requestInfo.Resource)
+				require.Equal(t, tt.wantVerb, requestInfo.Verb)
+			}
+		})
+	}
+}
+
+func TestResolvedRequestAttributes(t *testing.T) {
+	resolver, _ := newTestResolver(t, clusterWorkspace("root", "org", "shard-1"))
+	attributes := resolvedRequestAttributes(resolver)
+
+	tests := map[string]struct {
+		path          string
+		authenticated bool
+		wantCluster   logicalcluster.Name
+	}{
+		"resolved workspace":      {path: "/clusters/root:org/api/v1/configmaps", authenticated: true, wantCluster: logicalcluster.New("root:org")},
+		"root":                    {path: "/clusters/root/api/v1/configmaps", authenticated: true, wantCluster: logicalcluster.New("root")},
+		"unknown workspace":       {path: "/clusters/root:missing/api/v1/configmaps", authenticated: true},
+		"unauthenticated":         {path: "/clusters/root:org/api/v1/configmaps"},
+		"without logical cluster": {path: "/api/v1/configmaps", authenticated: true},
+		"unauthenticated unknown": {path: "/clusters/root:missing/api/v1/configmaps"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authenticated {
+				req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "user"}))
+			}
+			clusterName, _ := attributes(req)
+			require.Equal(t, tt.wantCluster, clusterName)
+		})
+	}
+}
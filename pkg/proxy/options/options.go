@@ -18,21 +18,28 @@ package options
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
-	MappingFile string
+	MappingFile           string
+	MappingReloadInterval time.Duration
+	RootKubeconfig        string
 }
 
 func NewOptions() *Options {
-	o := &Options{}
+	o := &Options{
+		MappingReloadInterval: 10 * time.Second,
+	}
 	return o
 }
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.MappingFile, "mapping-file", o.MappingFile, "Config file mapping paths to backends")
+	fs.DurationVar(&o.MappingReloadInterval, "mapping-reload-interval", o.MappingReloadInterval, "Interval to check the mapping file and the certificates and keys it references for changes. Changed files are reloaded without dropping existing connections. 0 disables reloading")
+	fs.StringVar(&o.RootKubeconfig, "root-kubeconfig", o.RootKubeconfig, "Kubeconfig of the root shard. If set, requests to workspaces are routed to the shard hosting them, using the client certificate of the / path mapping")
 }
 
 func (o *Options) Complete() error {
@@ -45,6 +52,9 @@ func (o *Options) Validate() []error {
 	if o.MappingFile == "" {
 		errs = append(errs, fmt.Errorf("--mapping-file is required"))
 	}
+	if o.MappingReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("--mapping-reload-interval must not be negative"))
+	}
 
 	return errs
 }
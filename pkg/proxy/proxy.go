@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
 
 	userinfo "k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
@@ -31,8 +32,9 @@ import (
 
 // KCPProxy wraps the httputil.ReverseProxy and captures the backend name.
 type KCPProxy struct {
-	proxy   *httputil.ReverseProxy
-	backend string
+	proxy     *httputil.ReverseProxy
+	transport *http.Transport
+	backend   string
 }
 
 // NewReverseProxy returns a new reverse proxy where backend is the backend URL to
@@ -66,15 +68,18 @@ func NewReverseProxy(backend, clientCert, clientKeyFile, caFile string) (*KCPPro
 	proxy := httputil.NewSingleHostReverseProxy(target)
 	proxy.Transport = transport
 
-	return &KCPProxy{proxy: proxy, backend: backend}, nil
+	return &KCPProxy{proxy: proxy, transport: transport, backend: backend}, nil
 }
 
-// ProxyHandler extracts the CN as a user name and Organizations as groups from
-// the client cert and adds them as HTTP headers to backend request.
-func ProxyHandler(p *KCPProxy, UserHeader, GroupHeader string) func(wr http.ResponseWriter, req *http.Request) {
+// ProxyHandler forwards the authenticated user, its groups and extras to the backend in
+// HTTP headers. Identity headers sent by the client are always removed. If the proxy
+// authenticated the request, its credentials are not forwarded.
+func ProxyHandler(p *KCPProxy, userHeader, groupHeader, extraHeaderPrefix string) func(wr http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		removeAuthHeaders(r.Header, userHeader, groupHeader, extraHeaderPrefix)
 		if u, ok := request.UserFrom(r.Context()); ok {
-			appendClientCertAuthHeaders(r.Header, u, UserHeader, GroupHeader)
+			appendAuthHeaders(r.Header, u, userHeader, groupHeader, extraHeaderPrefix)
+			r.Header.Del("Authorization")
 		}
 		if klog.V(6).Enabled() {
 			klog.Infof("%s %s (%s -> %s) ", r.Method, r.RequestURI, r.RemoteAddr, p.backend)
@@ -83,10 +88,28 @@ func ProxyHandler(p *KCPProxy, UserHeader, GroupHeader string) func(wr http.Resp
 	}
 }
 
-func appendClientCertAuthHeaders(header http.Header, user userinfo.Info, UserHeader, GroupHeader string) {
-	header.Set(UserHeader, user.GetName())
+func removeAuthHeaders(header http.Header, userHeader, groupHeader, extraHeaderPrefix string) {
+	header.Del(userHeader)
+	header.Del(groupHeader)
+	for key := range header {
+		if strings.HasPrefix(strings.ToLower(key), strings.ToLower(extraHeaderPrefix)) {
+			header.Del(key)
+		}
+	}
+}
+
+func appendAuthHeaders(header http.Header, user userinfo.Info, userHeader, groupHeader, extraHeaderPrefix string) {
+	header.Set(userHeader, user.GetName())
 
 	for _, group := range user.GetGroups() {
-		header.Add(GroupHeader, group)
+		header.Add(groupHeader, group)
+	}
+
+	// extra keys are escaped like the requestheader authenticator of the backend expects,
+	// e.g. for the authentication.kubernetes.io/cluster-name extra of service accounts
+	for key, values := range user.GetExtra() {
+		for _, value := range values {
+			header.Add(extraHeaderPrefix+url.PathEscape(key), value)
+		}
 	}
 }
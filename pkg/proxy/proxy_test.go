@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	userinfo "k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestProxyHandler(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+	}))
+	defer backend.Close()
+	target, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	p := &KCPProxy{proxy: httputil.NewSingleHostReverseProxy(target), backend: backend.URL}
+	handler := ProxyHandler(p, "X-Remote-User", "X-Remote-Group", "X-Remote-Extra-")
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/clusters/root:org/api", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-Remote-User", "spoofed")
+		req.Header.Add("X-Remote-Group", "system:masters")
+		req.Header.Set("X-Remote-Extra-Scopes", "spoofed")
+		return req
+	}
+
+	t.Run("authenticated", func(t *testing.T) {
+		req := newRequest()
+		req = req.WithContext(request.WithUser(req.Context(), &userinfo.DefaultInfo{
+			Name:   "system:serviceaccount:default:default",
+			Groups: []string{"system:serviceaccounts", "system:authenticated"},
+			Extra:  map[string][]string{"authentication.kubernetes.io/cluster-name": {"root:org"}},
+		}))
+		handler(httptest.NewRecorder(), req)
+
+		require.Equal(t, "system:serviceaccount:default:default", got.Get("X-Remote-User"))
+		require.Equal(t, []string{"system:serviceaccounts", "system:authenticated"}, got.Values("X-Remote-Group"))
+		require.Equal(t, []string{"root:org"}, got.Values("X-Remote-Extra-Authentication.kubernetes.io%2fcluster-Name"))
+		require.Empty(t, got.Values("X-Remote-Extra-Scopes"))
+		require.Empty(t, got.Get("Authorization"), "credentials authenticated by the proxy must not be forwarded")
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		handler(httptest.NewRecorder(), newRequest())
+
+		require.Empty(t, got.Values("X-Remote-User"))
+		require.Empty(t, got.Values("X-Remote-Group"))
+		require.Empty(t, got.Values("X-Remote-Extra-Scopes"))
+		require.Equal(t, "Bearer token", got.Get("Authorization"))
+	})
+}
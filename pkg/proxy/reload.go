@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const subsystem = "kcp_front_proxy"
+
+var (
+	configGeneration = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "config_generation",
+			Help:           "Generation of the loaded mapping configuration, increased on every successful reload.",
+			StabilityLevel: metrics.ALPHA,
+		})
+	configReloadFailures = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "config_reload_failures_total",
+			Help:           "Counter of mapping configuration reloads that failed, keeping the previous configuration.",
+			StabilityLevel: metrics.ALPHA,
+		})
+)
+
+func init() {
+	legacyregistry.MustRegister(configGeneration)
+	legacyregistry.MustRegister(configReloadFailures)
+}
+
+// proxyConfig is a loaded mapping configuration with the reverse proxies it created.
+type proxyConfig struct {
+	handler http.Handler
+
+	lock    sync.Mutex
+	proxies []*KCPProxy
+}
+
+func (c *proxyConfig) addProxy(proxy *KCPProxy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.proxies = append(c.proxies, proxy)
+}
+
+// closeIdleConnections closes the idle backend connections of the configuration. Requests
+// in flight, including watches, keep their connections until they finish.
+func (c *proxyConfig) closeIdleConnections() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, proxy := range c.proxies {
+		proxy.transport.CloseIdleConnections()
+	}
+}
+
+// reloadingHandler serves requests with the last valid configuration loaded from the mapping
+// file. A changed configuration is only applied if it is valid, i.e. all of its backend URLs,
+// certificates and keys can be loaded.
+type reloadingHandler struct {
+	mappingFile string
+	resolver    *workspaceResolver
+
+	config     atomic.Value // *proxyConfig
+	digest     []byte
+	generation int64
+}
+
+func (h *reloadingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.config.Load().(*proxyConfig).handler.ServeHTTP(w, req)
+}
+
+// load loads and applies the configuration.
+func (h *reloadingHandler) load() error {
+	mapping, digest, err := loadMapping(h.mappingFile)
+	if err != nil {
+		return err
+	}
+	config, err := newProxyConfig(mapping, h.resolver)
+	if err != nil {
+		return err
+	}
+
+	old, _ := h.config.Load().(*proxyConfig)
+	h.config.Store(config)
+	h.digest = digest
+	h.generation++
+	configGeneration.Set(float64(h.generation))
+	klog.Infof("Loaded mapping file %q, generation %d", h.mappingFile, h.generation)
+
+	if old != nil {
+		old.closeIdleConnections()
+	}
+	return nil
+}
+
+// reloadIfChanged reloads the configuration if the mapping file or any file it references
+// changed. It is not safe for concurrent use.
+func (h *reloadingHandler) reloadIfChanged(ctx context.Context) {
+	_, digest, err := loadMapping(h.mappingFile)
+	if err != nil {
+		// unreadable files are keyed by the error, to report every failure once
+		sum := sha256.Sum256([]byte(err.Error()))
+		digest = sum[:]
+	}
+	if bytes.Equal(digest, h.digest) {
+		return
+	}
+	if err == nil {
+		err = h.load()
+	}
+	if err != nil {
+		configReloadFailures.Inc()
+		klog.Errorf("Failed to reload mapping file %q, keeping generation %d: %v", h.mappingFile, h.generation, err)
+		// do not retry until the files change again
+		h.digest = digest
+	}
+}
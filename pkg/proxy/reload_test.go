@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/util/cert"
+)
+
+func TestReloadingHandler(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+		return path
+	}
+
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey("proxy", nil, nil)
+	require.NoError(t, err)
+	clientCert := writeFile("client.crt", certPEM)
+	clientKey := writeFile("client.key", keyPEM)
+
+	newBackend := func(name string) (*httptest.Server, string) {
+		backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, name)
+		}))
+		t.Cleanup(backend.Close)
+		ca := writeFile(name+"-ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}))
+		return backend, ca
+	}
+	backendA, caA := newBackend("a")
+	backendB, caB := newBackend("b")
+
+	writeMapping := func(backend, ca string) string {
+		return writeFile("mapping.yaml", []byte(fmt.Sprintf(`- path: /
+  backend: %s
+  backend_server_ca: %s
+  proxy_client_cert: %s
+  proxy_client_key: %s
+`, backend, ca, clientCert, clientKey)))
+	}
+	get := func(h http.Handler) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	h := &reloadingHandler{mappingFile: writeMapping(backendA.URL, caA)}
+	require.NoError(t, h.load())
+	require.Equal(t, "a", get(h))
+	require.Equal(t, int64(1), h.generation)
+
+	h.reloadIfChanged(context.Background())
+	require.Equal(t, int64(1), h.generation, "unchanged files must not be reloaded")
+
+	writeMapping(backendB.URL, caB)
+	h.reloadIfChanged(context.Background())
+	require.Equal(t, "b", get(h))
+	require.Equal(t, int64(2), h.generation)
+
+	writeMapping(backendA.URL, filepath.Join(dir, "missing.crt"))
+	h.reloadIfChanged(context.Background())
+	require.Equal(t, "b", get(h), "an invalid configuration must not be applied")
+	require.Equal(t, int64(2), h.generation)
+
+	writeFile("mapping.yaml", []byte("- path: /\n  backend: \"%\"\n"))
+	h.reloadIfChanged(context.Background())
+	require.Equal(t, "b", get(h), "an invalid configuration must not be applied")
+
+	writeMapping(backendB.URL, caB)
+	writeFile("b-ca.crt", append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backendA.Certificate().Raw}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backendB.Certificate().Raw})...))
+	h.reloadIfChanged(context.Background())
+	require.Equal(t, "b", get(h))
+	require.Equal(t, int64(3), h.generation, "changed certificates must be reloaded")
+}
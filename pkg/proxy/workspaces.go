@@ -0,0 +1,278 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+var (
+	errorScheme = runtime.NewScheme()
+	errorCodecs = serializer.NewCodecFactory(errorScheme)
+)
+
+func init() {
+	errorScheme.AddUnversionedTypes(metav1.Unversioned,
+		&metav1.Status{},
+	)
+}
+
+// workspaceResolver resolves logical clusters to the base URL of the shard hosting them,
+// using the ClusterWorkspaces and ClusterWorkspaceShards of the root shard. Successful
+// resolutions are cached until the ClusterWorkspaces or ClusterWorkspaceShards they depend
+// on change. Failures are not cached, so that requests for arbitrary missing workspaces
+// cannot grow the cache.
+type workspaceResolver struct {
+	workspaceLister tenancylister.ClusterWorkspaceLister
+	shardLister     tenancylister.ClusterWorkspaceShardLister
+
+	lock sync.RWMutex
+	// generation is increased on invalidation, so that resolutions computed concurrently
+	// with a change are not cached.
+	generation int64
+	cache      map[logicalcluster.Name]resolution
+}
+
+type resolution struct {
+	// shard is the name of the ClusterWorkspaceShard the workspace is scheduled to.
+	shard    string
+	shardURL string
+}
+
+func newWorkspaceResolver(workspaceInformer tenancyinformer.ClusterWorkspaceInformer, shardInformer tenancyinformer.ClusterWorkspaceShardInformer) *workspaceResolver {
+	r := &workspaceResolver{
+		workspaceLister: workspaceInformer.Lister(),
+		shardLister:     shardInformer.Lister(),
+		cache:           map[logicalcluster.Name]resolution{},
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { r.invalidateWorkspace(obj) },
+		UpdateFunc: func(_, obj interface{}) { r.invalidateWorkspace(obj) },
+		DeleteFunc: func(obj interface{}) { r.invalidateWorkspace(obj) },
+	})
+	shardInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { r.invalidateShard(obj) },
+		UpdateFunc: func(_, obj interface{}) { r.invalidateShard(obj) },
+		DeleteFunc: func(obj interface{}) { r.invalidateShard(obj) },
+	})
+
+	return r
+}
+
+// invalidateWorkspace drops the cached resolutions of the given ClusterWorkspace and of
+// the workspaces below it.
+func (r *workspaceResolver) invalidateWorkspace(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workspace, ok := obj.(*tenancyv1alpha1.ClusterWorkspace)
+	if !ok {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generation++
+	r.invalidateTreeLocked(logicalcluster.From(workspace).Join(workspace.Name))
+}
+
+// invalidateShard drops the cached resolutions of the workspaces scheduled to the given
+// ClusterWorkspaceShard, and of the workspaces below them.
+func (r *workspaceResolver) invalidateShard(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	shard, ok := obj.(*tenancyv1alpha1.ClusterWorkspaceShard)
+	if !ok {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generation++
+	var affected []logicalcluster.Name
+	for clusterName, res := range r.cache {
+		if res.shard == shard.Name {
+			affected = append(affected, clusterName)
+		}
+	}
+	for _, clusterName := range affected {
+		r.invalidateTreeLocked(clusterName)
+	}
+}
+
+// invalidateTreeLocked drops the cached resolutions of the given logical cluster and of those
+// below it, which depend on the resolution of their parents. The lock must be held.
+func (r *workspaceResolver) invalidateTreeLocked(root logicalcluster.Name) {
+	prefix := root.String() + ":"
+	for clusterName := range r.cache {
+		if clusterName == root || strings.HasPrefix(clusterName.String(), prefix) {
+			delete(r.cache, clusterName)
+		}
+	}
+}
+
+// Resolve returns the base URL of the shard hosting the given logical cluster below root.
+// All parent workspaces must exist. It fails with a NotFound error if the workspace or
+// one of its parents does not exist, and with a ServiceUnavailable error if the workspace
+// is not scheduled or its shard is unknown.
+func (r *workspaceResolver) Resolve(clusterName logicalcluster.Name) (string, error) {
+	r.lock.RLock()
+	res, found := r.cache[clusterName]
+	generation := r.generation
+	r.lock.RUnlock()
+	if found {
+		return res.shardURL, nil
+	}
+
+	res, err := r.resolve(clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.generation == generation {
+		r.cache[clusterName] = res
+	}
+	return res.shardURL, nil
+}
+
+func (r *workspaceResolver) resolve(clusterName logicalcluster.Name) (resolution, error) {
+	parent, name := clusterName.Split()
+	if parent != tenancyv1alpha1.RootCluster {
+		if _, err := r.Resolve(parent); err != nil {
+			return resolution{}, err
+		}
+	}
+
+	workspace, err := r.workspaceLister.Get(clusters.ToClusterAwareKey(parent, name))
+	if apierrors.IsNotFound(err) {
+		return resolution{}, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspaces"), clusterName.String())
+	} else if err != nil {
+		return resolution{}, apierrors.NewInternalError(err)
+	}
+
+	if workspace.Status.Location.Current == "" {
+		return resolution{}, apierrors.NewServiceUnavailable(fmt.Sprintf("workspace %q is not scheduled", clusterName))
+	}
+	shard, err := r.shardLister.Get(clusters.ToClusterAwareKey(tenancyv1alpha1.RootCluster, workspace.Status.Location.Current))
+	if apierrors.IsNotFound(err) {
+		return resolution{}, apierrors.NewServiceUnavailable(fmt.Sprintf("shard %q of workspace %q not found", workspace.Status.Location.Current, clusterName))
+	} else if err != nil {
+		return resolution{}, apierrors.NewInternalError(err)
+	}
+	if shard.Spec.BaseURL == "" {
+		return resolution{}, apierrors.NewServiceUnavailable(fmt.Sprintf("shard %q of workspace %q has no base URL", shard.Name, clusterName))
+	}
+
+	return resolution{shard: shard.Name, shardURL: shard.Spec.BaseURL}, nil
+}
+
+// shardProxyFunc returns the handler proxying to the shard with the given base URL.
+type shardProxyFunc func(shardURL string) (http.Handler, error)
+
+// withWorkspaceRouting routes /clusters/<workspace>/ requests for workspaces below root to
+// the shard hosting them. Other requests, including those for root, system and wildcard
+// clusters, are passed to the delegate.
+func withWorkspaceRouting(delegate http.Handler, resolver *workspaceResolver, shardProxy shardProxyFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		clusterName, ok := clusterFromPath(req.URL.Path)
+		if !ok || !strings.HasPrefix(clusterName.String(), tenancyv1alpha1.RootCluster.String()+":") {
+			delegate.ServeHTTP(w, req)
+			return
+		}
+
+		shardURL, err := resolver.Resolve(clusterName)
+		if err != nil {
+			responsewriters.ErrorNegotiated(err, errorCodecs, schema.GroupVersion{}, w, req)
+			return
+		}
+		handler, err := shardProxy(shardURL)
+		if err != nil {
+			klog.Errorf("Failed to create proxy for shard %q: %v", shardURL, err)
+			responsewriters.ErrorNegotiated(apierrors.NewServiceUnavailable(fmt.Sprintf("shard of workspace %q is unavailable", clusterName)), errorCodecs, schema.GroupVersion{}, w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// clusterFromPath returns the logical cluster of a /clusters/<name>/ path. Like the kcp
+// server, it prefixes names of the deprecated /clusters/<org>:<workspace> form with root.
+func clusterFromPath(path string) (logicalcluster.Name, bool) {
+	if !strings.HasPrefix(path, "/clusters/") {
+		return logicalcluster.Name{}, false
+	}
+	path = strings.TrimPrefix(path, "/clusters/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" {
+		return logicalcluster.Name{}, false
+	}
+	if path != "*" && path != "root" && !strings.HasPrefix(path, "root:") && !strings.HasPrefix(path, "system:") {
+		path = "root:" + path
+	}
+	return logicalcluster.New(path), true
+}
+
+// shardProxies lazily creates and keeps one proxy per shard.
+type shardProxies struct {
+	newProxy func(shardURL string) (http.Handler, error)
+
+	lock    sync.Mutex
+	proxies map[string]http.Handler
+}
+
+func (p *shardProxies) Get(shardURL string) (http.Handler, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if handler, found := p.proxies[shardURL]; found {
+		return handler, nil
+	}
+	handler, err := p.newProxy(shardURL)
+	if err != nil {
+		return nil, err
+	}
+	if p.proxies == nil {
+		p.proxies = map[string]http.Handler{}
+	}
+	p.proxies[shardURL] = handler
+	return handler, nil
+}
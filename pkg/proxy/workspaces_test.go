@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+)
+
+func newTestResolver(t *testing.T, objects ...*tenancyv1alpha1.ClusterWorkspace) (*workspaceResolver, *kcpfake.Clientset) {
+	client := kcpfake.NewSimpleClientset(
+		&tenancyv1alpha1.ClusterWorkspaceShard{
+			ObjectMeta: metav1.ObjectMeta{Name: "shard-1", ClusterName: "root"},
+			Spec:       tenancyv1alpha1.ClusterWorkspaceShardSpec{BaseURL: "https://shard-1"},
+		},
+		&tenancyv1alpha1.ClusterWorkspaceShard{
+			ObjectMeta: metav1.ObjectMeta{Name: "shard-2", ClusterName: "root"},
+			Spec:       tenancyv1alpha1.ClusterWorkspaceShardSpec{BaseURL: "https://shard-2"},
+		},
+	)
+	for _, obj := range objects {
+		_, err := client.TenancyV1alpha1().ClusterWorkspaces().Create(context.Background(), obj, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	informerFactory := kcpinformers.NewSharedInformerFactory(client, 0)
+	resolver := newWorkspaceResolver(
+		informerFactory.Tenancy().V1alpha1().ClusterWorkspaces(),
+		informerFactory.Tenancy().V1alpha1().ClusterWorkspaceShards(),
+	)
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	return resolver, client
+}
+
+func clusterWorkspace(clusterName, name, shard string) *tenancyv1alpha1.ClusterWorkspace {
+	return &tenancyv1alpha1.ClusterWorkspace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: clusterName},
+		Status:     tenancyv1alpha1.ClusterWorkspaceStatus{Location: tenancyv1alpha1.ClusterWorkspaceLocation{Current: shard}},
+	}
+}
+
+func TestWorkspaceRouting(t *testing.T) {
+	resolver, _ := newTestResolver(t,
+		clusterWorkspace("root", "org", "shard-1"),
+		clusterWorkspace("root:org", "team", "shard-2"),
+		clusterWorkspace("root:org", "unscheduled", ""),
+		clusterWorkspace("root:org", "lost", "shard-3"),
+	)
+
+	handler := withWorkspaceRouting(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, "default")
+		}),
+		resolver,
+		func(shardURL string) (http.Handler, error) {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(w, shardURL)
+			}), nil
+		},
+	)
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{path: "/api", wantCode: http.StatusOK, wantBody: "default"},
+		{path: "/services/workspaces/root/personal/apis", wantCode: http.StatusOK, wantBody: "default"},
+		{path: "/clusters/root/api", wantCode: http.StatusOK, wantBody: "default"},
+		{path: "/clusters/*/api", wantCode: http.StatusOK, wantBody: "default"},
+		{path: "/clusters/system:admin/api", wantCode: http.StatusOK, wantBody: "default"},
+		{path: "/clusters/root:org/api", wantCode: http.StatusOK, wantBody: "https://shard-1"},
+		{path: "/clusters/root:org:team/apis/apps/v1/deployments", wantCode: http.StatusOK, wantBody: "https://shard-2"},
+		{path: "/clusters/org:team/api", wantCode: http.StatusOK, wantBody: "https://shard-2"},
+		{path: "/clusters/root:org:missing/api", wantCode: http.StatusNotFound},
+		{path: "/clusters/root:missing:team/api", wantCode: http.StatusNotFound},
+		{path: "/clusters/root:org:unscheduled/api", wantCode: http.StatusServiceUnavailable},
+		{path: "/clusters/root:org:lost/api", wantCode: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			if tt.wantBody != "" {
+				require.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWorkspaceResolverInvalidation(t *testing.T) {
+	resolver, client := newTestResolver(t, clusterWorkspace("root", "org", ""))
+
+	_, err := resolver.Resolve(logicalcluster.New("root:org"))
+	require.Error(t, err)
+	_, err = resolver.Resolve(logicalcluster.New("root:missing:team"))
+	require.Error(t, err)
+	resolver.lock.RLock()
+	require.Empty(t, resolver.cache, "failed resolutions must not be cached")
+	resolver.lock.RUnlock()
+
+	_, err = client.TenancyV1alpha1().ClusterWorkspaces().UpdateStatus(context.Background(), clusterWorkspace("root", "org", "shard-1"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		shardURL, err := resolver.Resolve(logicalcluster.New("root:org"))
+		return err == nil && shardURL == "https://shard-1"
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+}
+
+func TestWorkspaceResolverTargetedInvalidation(t *testing.T) {
+	resolver, _ := newTestResolver(t,
+		clusterWorkspace("root", "org", "shard-1"),
+		clusterWorkspace("root:org", "team", "shard-2"),
+		clusterWorkspace("root", "other", "shard-2"),
+	)
+	resolveAll := func() {
+		for _, clusterName := range []string{"root:org", "root:org:team", "root:other"} {
+			_, err := resolver.Resolve(logicalcluster.New(clusterName))
+			require.NoError(t, err)
+		}
+	}
+	cached := func() []string {
+		resolver.lock.RLock()
+		defer resolver.lock.RUnlock()
+		var ret []string
+		for clusterName := range resolver.cache {
+			ret = append(ret, clusterName.String())
+		}
+		sort.Strings(ret)
+		return ret
+	}
+
+	resolveAll()
+	require.Equal(t, []string{"root:org", "root:org:team", "root:other"}, cached())
+
+	resolver.invalidateWorkspace(clusterWorkspace("root", "org", "shard-1"))
+	require.Equal(t, []string{"root:other"}, cached(), "the workspace and those below it are invalidated")
+
+	resolveAll()
+	resolver.invalidateShard(&tenancyv1alpha1.ClusterWorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: "shard-2", ClusterName: "root"}})
+	require.Equal(t, []string{"root:org"}, cached(), "the workspaces scheduled to the shard are invalidated")
+}
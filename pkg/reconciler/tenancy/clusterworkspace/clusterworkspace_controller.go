@@ -405,6 +405,16 @@ func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.C
 		if len(workspace.Status.Initializers) == 0 {
 			workspace.Status.Phase = tenancyv1alpha1.ClusterWorkspacePhaseReady
 		}
+	case tenancyv1alpha1.ClusterWorkspacePhaseReady:
+		// soft-deleted, purged by the deletion controller when the retention period is over
+		if _, found := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]; found {
+			workspace.Status.Phase = tenancyv1alpha1.ClusterWorkspacePhaseDeleting
+		}
+	case tenancyv1alpha1.ClusterWorkspacePhaseDeleting:
+		// restored
+		if _, found := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]; !found {
+			workspace.Status.Phase = tenancyv1alpha1.ClusterWorkspacePhaseReady
+		}
 	}
 
 	return nil
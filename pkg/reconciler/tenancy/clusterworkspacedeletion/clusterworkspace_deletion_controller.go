@@ -175,7 +175,7 @@ func (c *Controller) process(ctx context.Context, key string) error {
 		}
 
 		if hasFinalizer {
-			return nil
+			return c.purgeExpired(ctx, key, workspaceCopy)
 		}
 
 		patch := tenancyv1alpha1.ClusterWorkspace{
@@ -206,6 +206,36 @@ func (c *Controller) process(ctx context.Context, key string) error {
 	return err
 }
 
+// purgeExpired deletes a soft-deleted workspace when its retention period is over,
+// and requeues it for that point in time otherwise.
+func (c *Controller) purgeExpired(ctx context.Context, key string, workspace *tenancyv1alpha1.ClusterWorkspace) error {
+	value, found := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]
+	if !found {
+		return nil
+	}
+	retainedUntil, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// admission makes sure this does not happen. Don't guess, but wait for a fix.
+		klog.Errorf("Invalid %s annotation on workspace %q: %v", tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey, key, err)
+		return nil
+	}
+
+	if remaining := time.Until(retainedUntil); remaining > 0 {
+		klog.V(2).Infof("Workspace %q is soft-deleted, purging in %v", key, remaining)
+		c.queue.AddAfter(key, remaining)
+		return nil
+	}
+
+	klog.Infof("Retention period of soft-deleted workspace %q is over, deleting it", key)
+	err = c.kcpClient.Cluster(logicalcluster.From(workspace)).TenancyV1alpha1().ClusterWorkspaces().Delete(ctx, workspace.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &workspace.UID, ResourceVersion: &workspace.ResourceVersion},
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
 func (c *Controller) patchCondition(ctx context.Context, old, new *tenancyv1alpha1.ClusterWorkspace) error {
 	if equality.Semantic.DeepEqual(old.Status.Conditions, new.Status.Conditions) {
 		return nil
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	schedulinginformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/scheduling/v1alpha1"
+	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
+	schedulinglisters "github.com/kcp-dev/kcp/pkg/client/listers/scheduling/v1alpha1"
+	workloadlisters "github.com/kcp-dev/kcp/pkg/client/listers/workload/v1alpha1"
+)
+
+const controllerName = "kcp-workload-drain"
+
+// resyncPeriod is the period draining WorkloadClusters are reconciled again with. The
+// syncing progress of the resources of moving namespaces is not watched.
+const resyncPeriod = 5 * time.Second
+
+type clusterDiscovery interface {
+	WithCluster(name logicalcluster.Name) discovery.DiscoveryInterface
+}
+
+// NewController returns a new Controller which moves the namespaces of the WorkloadClusters
+// being drained onto other WorkloadClusters. The locationInformer is optional, without it
+// namespaces are moved to any other ready WorkloadCluster of the workspace.
+func NewController(
+	kubeClusterClient kubernetes.ClusterInterface,
+	kcpClusterClient *kcpclient.Cluster,
+	metadataClusterClient dynamic.ClusterInterface,
+	clusterDiscoveryClient clusterDiscovery,
+	workloadClusterInformer workloadinformers.WorkloadClusterInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	locationInformer schedulinginformers.LocationInformer,
+) *Controller {
+	c := &Controller{
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+
+		kubeClusterClient:      kubeClusterClient,
+		kcpClusterClient:       kcpClusterClient,
+		metadataClusterClient:  metadataClusterClient,
+		clusterDiscoveryClient: clusterDiscoveryClient,
+
+		workloadClusterLister: workloadClusterInformer.Lister(),
+		namespaceLister:       namespaceInformer.Lister(),
+	}
+
+	workloadClusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueWorkloadCluster(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueWorkloadCluster(obj) },
+	})
+
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueAssignedWorkloadClusters(obj) },
+		UpdateFunc: func(old, obj interface{}) {
+			c.enqueueAssignedWorkloadClusters(old)
+			c.enqueueAssignedWorkloadClusters(obj)
+		},
+		DeleteFunc: func(obj interface{}) { c.enqueueAssignedWorkloadClusters(obj) },
+	})
+
+	if locationInformer != nil {
+		c.locationLister = locationInformer.Lister()
+	}
+
+	return c
+}
+
+// The Controller struct represents a drain controller instance.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kubeClusterClient      kubernetes.ClusterInterface
+	kcpClusterClient       *kcpclient.Cluster
+	metadataClusterClient  dynamic.ClusterInterface
+	clusterDiscoveryClient clusterDiscovery
+
+	workloadClusterLister workloadlisters.WorkloadClusterLister
+	namespaceLister       corelisters.NamespaceLister
+	locationLister        schedulinglisters.LocationLister
+}
+
+func (c *Controller) enqueueWorkloadCluster(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueAssignedWorkloadClusters enqueues the WorkloadClusters the given namespace is assigned to.
+func (c *Controller) enqueueAssignedWorkloadClusters(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	for k := range ns.Labels {
+		if !strings.HasPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix) {
+			continue
+		}
+		workloadClusterName := strings.TrimPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix)
+		c.queue.Add(clusters.ToClusterAwareKey(logicalcluster.From(ns), workloadClusterName))
+	}
+}
+
+// Start starts the controller workers.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting %s controller", controllerName)
+	defer klog.Infof("Shutting down %s controller", controllerName)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	workloadCluster, err := c.workloadClusterLister.Get(key)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	remaining, err := c.reconcile(ctx, workloadCluster)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		c.queue.AddAfter(key, resyncPeriod)
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+	workloadnamespace "github.com/kcp-dev/kcp/pkg/reconciler/workload/namespace"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/third_party/conditions/util/conditions"
+)
+
+// drainPlan is the outcome of one drain step over the namespaces of a workspace
+// that are assigned to the drained workload cluster.
+type drainPlan struct {
+	// moves maps namespaces to the workload cluster they are moved to.
+	moves map[string]string
+	// releases are the namespaces whose new placement has caught up, such that
+	// the drained workload cluster can let go of them.
+	releases []string
+	// waiting are the namespaces whose new placement has not caught up yet.
+	waiting []string
+	// pending are the namespaces not moved yet because of the max-unavailable budget.
+	pending []string
+	// blocked maps namespaces that cannot be moved to the reason why.
+	blocked map[string]string
+}
+
+// remaining returns the number of namespaces that are still assigned to the
+// drained workload cluster after this step.
+func (p *drainPlan) remaining() int {
+	return len(p.moves) + len(p.waiting) + len(p.pending) + len(p.blocked)
+}
+
+// drainMaxUnavailable returns the number of namespaces that can be moved off the given workload
+// cluster at the same time, and whether it is being drained at all.
+func drainMaxUnavailable(workloadCluster *workloadv1alpha1.WorkloadCluster) (int, bool) {
+	value, found := workloadCluster.Annotations[workloadv1alpha1.DrainMaxUnavailableAnnotation]
+	if !found || !workloadCluster.Spec.Unschedulable {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		klog.Errorf("Invalid %s annotation %q on WorkloadCluster %s|%s, not draining",
+			workloadv1alpha1.DrainMaxUnavailableAnnotation, value, logicalcluster.From(workloadCluster), workloadCluster.Name)
+		return 0, false
+	}
+	return n, true
+}
+
+// reconcile does one drain step of the given workload cluster if it is being drained, and
+// returns the number of namespaces that are still assigned to it. Namespaces are only
+// released from the drained workload cluster once all of their resources have been synced
+// to the new one.
+func (c *Controller) reconcile(ctx context.Context, workloadCluster *workloadv1alpha1.WorkloadCluster) (int, error) {
+	maxUnavailable, draining := drainMaxUnavailable(workloadCluster)
+	if !draining {
+		return 0, c.ensureBlockedAnnotation(ctx, workloadCluster, nil)
+	}
+	clusterName := logicalcluster.From(workloadCluster)
+
+	// TODO(ncdc): use cluster scoped generated listers when available
+	allWorkloadClusters, err := c.workloadClusterLister.List(labels.Everything())
+	if err != nil {
+		return 0, err
+	}
+	var workloadClusters []*workloadv1alpha1.WorkloadCluster
+	for _, wc := range allWorkloadClusters {
+		if logicalcluster.From(wc) == clusterName {
+			workloadClusters = append(workloadClusters, wc)
+		}
+	}
+	// Without the Location API, the locations are nil and namespaces are not placed in any.
+	var locations []*schedulingv1alpha1.Location
+	if c.locationLister != nil {
+		locations = []*schedulingv1alpha1.Location{}
+		allLocations, err := c.locationLister.List(labels.Everything())
+		if err != nil {
+			return 0, err
+		}
+		for _, location := range allLocations {
+			if logicalcluster.From(location) == clusterName {
+				locations = append(locations, location)
+			}
+		}
+	}
+	allNamespaces, err := c.namespaceLister.List(labels.Everything())
+	if err != nil {
+		return 0, err
+	}
+	var namespaces []*corev1.Namespace
+	for _, ns := range allNamespaces {
+		if logicalcluster.From(ns) == clusterName {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.clusterDiscoveryClient.WithCluster(clusterName)))
+	synced := func(ns *corev1.Namespace, target *workloadv1alpha1.WorkloadCluster) (bool, error) {
+		return c.namespaceSynced(ctx, mapper, clusterName, ns.Name, workloadCluster.Name, target)
+	}
+	plan, err := planDrain(workloadCluster.Name, workloadClusters, locations, namespaces, maxUnavailable, synced)
+	if err != nil {
+		return 0, err
+	}
+
+	namespaceClient := c.kubeClusterClient.Cluster(clusterName).CoreV1().Namespaces()
+	for _, name := range plan.releases {
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:null}}}`, workloadv1alpha1.InternalClusterResourceStateLabelPrefix+workloadCluster.Name)
+		if _, err := namespaceClient.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return 0, fmt.Errorf("failed to release namespace %s|%s: %w", clusterName, name, err)
+		}
+		klog.V(2).Infof("Released namespace %s|%s from draining WorkloadCluster %s", clusterName, name, workloadCluster.Name)
+	}
+	for _, name := range sortedKeys(plan.moves) {
+		target := plan.moves[name]
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{
+					workloadnamespace.DeprecatedScheduledClusterNamespaceLabel:        target,
+					workloadv1alpha1.InternalClusterResourceStateLabelPrefix + target: string(workloadv1alpha1.ResourceStateSync),
+				},
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if _, err := namespaceClient.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return 0, fmt.Errorf("failed to move namespace %s|%s to %s: %w", clusterName, name, target, err)
+		}
+		klog.V(2).Infof("Moving namespace %s|%s from draining WorkloadCluster %s to %s", clusterName, name, workloadCluster.Name, target)
+	}
+	for _, name := range sortedKeys(plan.blocked) {
+		klog.V(4).Infof("Namespace %s|%s cannot be moved off draining WorkloadCluster %s: %s", clusterName, name, workloadCluster.Name, plan.blocked[name])
+	}
+	if err := c.ensureBlockedAnnotation(ctx, workloadCluster, plan.blocked); err != nil {
+		return 0, err
+	}
+
+	return plan.remaining(), nil
+}
+
+// ensureBlockedAnnotation records the namespaces that cannot be moved off the workload cluster
+// on it, for its Drained condition to report them.
+func (c *Controller) ensureBlockedAnnotation(ctx context.Context, workloadCluster *workloadv1alpha1.WorkloadCluster, blocked map[string]string) error {
+	current, found := workloadCluster.Annotations[workloadv1alpha1.DrainBlockedAnnotation]
+	var value interface{}
+	if len(blocked) > 0 {
+		bs, err := json.Marshal(blocked)
+		if err != nil {
+			return err
+		}
+		if found && current == string(bs) {
+			return nil
+		}
+		value = string(bs)
+	} else if !found {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				workloadv1alpha1.DrainBlockedAnnotation: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	clusterName := logicalcluster.From(workloadCluster)
+	if _, err := c.kcpClusterClient.Cluster(clusterName).WorkloadV1alpha1().WorkloadClusters().Patch(ctx, workloadCluster.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update blocked namespaces of WorkloadCluster %s|%s: %w", clusterName, workloadCluster.Name, err)
+	}
+	return nil
+}
+
+// planDrain computes one drain step of the given workload cluster. Namespaces already
+// moving to another workload cluster are released when synced reports that their new
+// placement has caught up, and namespaces are moved to the least loaded ready workload
+// cluster sharing a location with the drained one, as long as fewer than maxUnavailable
+// namespaces are moving.
+func planDrain(
+	workloadClusterName string,
+	workloadClusters []*workloadv1alpha1.WorkloadCluster,
+	locations []*schedulingv1alpha1.Location,
+	namespaces []*corev1.Namespace,
+	maxUnavailable int,
+	synced func(ns *corev1.Namespace, target *workloadv1alpha1.WorkloadCluster) (bool, error),
+) (*drainPlan, error) {
+	plan := &drainPlan{
+		moves:   map[string]string{},
+		blocked: map[string]string{},
+	}
+
+	byName := map[string]*workloadv1alpha1.WorkloadCluster{}
+	for _, wc := range workloadClusters {
+		byName[wc.Name] = wc
+	}
+	candidates, located := drainTargets(workloadClusterName, byName, locations)
+
+	stateLabel := workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadClusterName
+	load := map[string]int{}
+	var assigned []*corev1.Namespace
+	for _, ns := range namespaces {
+		if cluster := ns.Labels[workloadnamespace.DeprecatedScheduledClusterNamespaceLabel]; cluster != "" {
+			load[cluster]++
+		}
+		if _, found := ns.Labels[stateLabel]; found {
+			assigned = append(assigned, ns)
+		}
+	}
+	sort.Slice(assigned, func(i, j int) bool {
+		return assigned[i].Name < assigned[j].Name
+	})
+
+	moving := 0
+	var unmoved []*corev1.Namespace
+	for _, ns := range assigned {
+		target := ns.Labels[workloadnamespace.DeprecatedScheduledClusterNamespaceLabel]
+		targetCluster, found := byName[target]
+		if !found || target == workloadClusterName || ns.Labels[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+target] != string(workloadv1alpha1.ResourceStateSync) {
+			unmoved = append(unmoved, ns)
+			continue
+		}
+
+		moving++
+		ok, err := synced(ns, targetCluster)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			plan.releases = append(plan.releases, ns.Name)
+			moving--
+		} else {
+			plan.waiting = append(plan.waiting, ns.Name)
+		}
+	}
+
+	for _, ns := range unmoved {
+		if _, found := ns.Labels[workloadv1alpha1.SchedulingDisabledLabel]; found {
+			plan.blocked[ns.Name] = "scheduling is disabled for the namespace"
+			continue
+		}
+		if !located {
+			plan.blocked[ns.Name] = "the drained workload cluster is not part of any location"
+			continue
+		}
+		if len(candidates) == 0 {
+			plan.blocked[ns.Name] = "no other ready workload cluster in the same location"
+			continue
+		}
+		if moving >= maxUnavailable {
+			plan.pending = append(plan.pending, ns.Name)
+			continue
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if load[candidates[i]] != load[candidates[j]] {
+				return load[candidates[i]] < load[candidates[j]]
+			}
+			return candidates[i] < candidates[j]
+		})
+		target := candidates[0]
+		plan.moves[ns.Name] = target
+		load[target]++
+		moving++
+	}
+
+	return plan, nil
+}
+
+// drainTargets returns the workload clusters that namespaces of the drained one can be
+// moved to: those that are ready and schedulable, and share a location with the drained
+// workload cluster. It also returns whether the drained workload cluster is part of any
+// location, as its namespaces cannot be moved outside of the location they are placed in.
+// Without the Location API, i.e. with nil locations, any other workload cluster will do.
+func drainTargets(workloadClusterName string, workloadClusters map[string]*workloadv1alpha1.WorkloadCluster, locations []*schedulingv1alpha1.Location) ([]string, bool) {
+	var all []*workloadv1alpha1.WorkloadCluster
+	for _, wc := range workloadClusters {
+		all = append(all, wc)
+	}
+
+	var sameLocation []*workloadv1alpha1.WorkloadCluster
+	drained, found := workloadClusters[workloadClusterName]
+	if found {
+		for _, location := range locations {
+			inLocation, err := locationreconciler.LocationWorkloadClusters(all, location)
+			if err != nil {
+				continue
+			}
+			if !containsWorkloadCluster(inLocation, drained.Name) {
+				continue
+			}
+			sameLocation = append(sameLocation, inLocation...)
+		}
+	}
+	if locations == nil {
+		sameLocation = all
+	} else if len(sameLocation) == 0 {
+		return nil, false
+	}
+
+	seen := map[string]bool{}
+	var targets []string
+	for _, wc := range sameLocation {
+		if wc.Name == workloadClusterName || seen[wc.Name] {
+			continue
+		}
+		seen[wc.Name] = true
+		if wc.Spec.Unschedulable || wc.Spec.EvictAfter != nil || !conditions.IsTrue(wc, conditionsapi.ReadyCondition) {
+			continue
+		}
+		targets = append(targets, wc.Name)
+	}
+	sort.Strings(targets)
+	return targets, true
+}
+
+func containsWorkloadCluster(wcs []*workloadv1alpha1.WorkloadCluster, name string) bool {
+	for _, wc := range wcs {
+		if wc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceSynced returns whether every resource in the namespace that is assigned to
+// the drained workload cluster, and of a type synced by the target, has been synced
+// to the target.
+func (c *Controller) namespaceSynced(ctx context.Context, mapper *restmapper.DeferredDiscoveryRESTMapper, clusterName logicalcluster.Name, namespace, workloadClusterName string, target *workloadv1alpha1.WorkloadCluster) (bool, error) {
+	targetStateLabel := workloadv1alpha1.InternalClusterResourceStateLabelPrefix + target.Name
+	targetSyncedAnnotation := workloadv1alpha1.InternalClusterSyncedAnnotationPrefix + target.Name
+	// all objects that are still assigned to the drained workload cluster
+	selector := workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadClusterName
+
+	for _, resource := range target.Status.SyncedResources {
+		gr := schema.ParseGroupResource(resource)
+		if gr.Group == "" && gr.Resource == "namespaces" {
+			continue
+		}
+		gvr, err := mapper.ResourceFor(gr.WithVersion(""))
+		if err != nil {
+			return false, fmt.Errorf("failed to find resource %q: %w", resource, err)
+		}
+		list, err := c.metadataClusterClient.Cluster(clusterName).Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s in namespace %s|%s: %w", resource, clusterName, namespace, err)
+		}
+		for _, obj := range list.Items {
+			if obj.GetLabels()[targetStateLabel] != string(workloadv1alpha1.ResourceStateSync) {
+				return false, nil
+			}
+			if _, found := obj.GetAnnotations()[targetSyncedAnnotation]; !found {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
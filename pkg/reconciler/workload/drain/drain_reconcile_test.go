@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	conditionsapi "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+)
+
+func TestPlanDrain(t *testing.T) {
+	newWorkloadCluster := func(name, region string, ready, unschedulable bool) *workloadv1alpha1.WorkloadCluster {
+		wc := &workloadv1alpha1.WorkloadCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"region": region}},
+			Spec:       workloadv1alpha1.WorkloadClusterSpec{Unschedulable: unschedulable},
+		}
+		if ready {
+			wc.Status.Conditions = conditionsapi.Conditions{{Type: conditionsapi.ReadyCondition, Status: corev1.ConditionTrue}}
+		}
+		return wc
+	}
+	newNamespace := func(name, cluster string, syncing ...string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if cluster != "" {
+			ns.Labels["workloads.kcp.dev/cluster"] = cluster
+		}
+		for _, wc := range syncing {
+			ns.Labels[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+wc] = string(workloadv1alpha1.ResourceStateSync)
+		}
+		return ns
+	}
+	workloadClusters := []*workloadv1alpha1.WorkloadCluster{
+		newWorkloadCluster("us-1", "us", true, true),
+		newWorkloadCluster("us-2", "us", true, false),
+		newWorkloadCluster("us-3", "us", true, false),
+		newWorkloadCluster("us-4", "us", false, false),
+		newWorkloadCluster("eu-1", "eu", true, false),
+	}
+	locations := []*schedulingv1alpha1.Location{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "us"},
+			Spec:       schedulingv1alpha1.LocationSpec{InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "eu"},
+			Spec:       schedulingv1alpha1.LocationSpec{InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}}},
+		},
+	}
+	syncedTo := func(synced ...string) func(*corev1.Namespace, *workloadv1alpha1.WorkloadCluster) (bool, error) {
+		return func(ns *corev1.Namespace, target *workloadv1alpha1.WorkloadCluster) (bool, error) {
+			for _, name := range synced {
+				if name == ns.Name {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+	disabled := newNamespace("disabled", "us-1", "us-1")
+	disabled.Labels[workloadv1alpha1.SchedulingDisabledLabel] = ""
+
+	tests := []struct {
+		name           string
+		clusters       []*workloadv1alpha1.WorkloadCluster
+		namespaces     []*corev1.Namespace
+		maxUnavailable int
+		synced         func(*corev1.Namespace, *workloadv1alpha1.WorkloadCluster) (bool, error)
+		noLocationAPI  bool
+
+		wantPlan      *drainPlan
+		wantRemaining int
+	}{
+		{
+			name:     "nothing assigned",
+			clusters: workloadClusters,
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-2", "us-2"),
+			},
+			maxUnavailable: 1,
+			synced:         syncedTo(),
+			wantPlan:       &drainPlan{moves: map[string]string{}, blocked: map[string]string{}},
+		},
+		{
+			name:     "moves within budget to the least loaded cluster of the same location",
+			clusters: workloadClusters,
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-1", "us-1"),
+				newNamespace("b", "us-1", "us-1"),
+				newNamespace("c", "us-1", "us-1"),
+				newNamespace("d", "us-2", "us-2"),
+			},
+			maxUnavailable: 2,
+			synced:         syncedTo(),
+			wantPlan: &drainPlan{
+				moves:   map[string]string{"a": "us-3", "b": "us-2"},
+				pending: []string{"c"},
+				blocked: map[string]string{},
+			},
+			wantRemaining: 3,
+		},
+		{
+			name:     "releases synced namespaces and refills the budget",
+			clusters: workloadClusters,
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-3", "us-1", "us-3"),
+				newNamespace("b", "us-2", "us-1", "us-2"),
+				newNamespace("c", "us-1", "us-1"),
+				disabled,
+			},
+			maxUnavailable: 1,
+			synced:         syncedTo("a"),
+			wantPlan: &drainPlan{
+				moves:    map[string]string{},
+				releases: []string{"a"},
+				waiting:  []string{"b"},
+				pending:  []string{"c"},
+				blocked:  map[string]string{"disabled": "scheduling is disabled for the namespace"},
+			},
+			wantRemaining: 3,
+		},
+		{
+			name: "no target in the same location",
+			clusters: []*workloadv1alpha1.WorkloadCluster{
+				newWorkloadCluster("us-1", "us", true, true),
+				newWorkloadCluster("us-4", "us", false, false),
+				newWorkloadCluster("eu-1", "eu", true, false),
+			},
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-1", "us-1"),
+			},
+			maxUnavailable: 1,
+			synced:         syncedTo(),
+			wantPlan: &drainPlan{
+				moves:   map[string]string{},
+				blocked: map[string]string{"a": "no other ready workload cluster in the same location"},
+			},
+			wantRemaining: 1,
+		},
+		{
+			name: "drained cluster in no location",
+			clusters: []*workloadv1alpha1.WorkloadCluster{
+				newWorkloadCluster("us-1", "none", true, true),
+				newWorkloadCluster("us-2", "us", true, false),
+				newWorkloadCluster("eu-1", "eu", true, false),
+			},
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-1", "us-1"),
+			},
+			maxUnavailable: 1,
+			synced:         syncedTo(),
+			wantPlan: &drainPlan{
+				moves:   map[string]string{},
+				blocked: map[string]string{"a": "the drained workload cluster is not part of any location"},
+			},
+			wantRemaining: 1,
+		},
+		{
+			name: "any ready cluster without the Location API",
+			clusters: []*workloadv1alpha1.WorkloadCluster{
+				newWorkloadCluster("us-1", "none", true, true),
+				newWorkloadCluster("eu-1", "eu", true, false),
+			},
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "us-1", "us-1"),
+			},
+			maxUnavailable: 1,
+			synced:         syncedTo(),
+			noLocationAPI:  true,
+			wantPlan: &drainPlan{
+				moves:   map[string]string{"a": "eu-1"},
+				blocked: map[string]string{},
+			},
+			wantRemaining: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			locations := locations
+			if tt.noLocationAPI {
+				locations = nil
+			}
+			plan, err := planDrain("us-1", tt.clusters, locations, tt.namespaces, tt.maxUnavailable, tt.synced)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPlan, plan)
+			require.Equal(t, tt.wantRemaining, plan.remaining())
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaysplitter
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+// The Gateway API resources are watched and written through the dynamic clients, as there
+// is no cluster-aware clientset for them.
+
+func toHTTPRoute(obj interface{}) (*gatewayv1alpha2.HTTPRoute, error) {
+	route := &gatewayv1alpha2.HTTPRoute{}
+	if err := fromUnstructured(obj, route); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+func toGateway(obj interface{}) (*gatewayv1alpha2.Gateway, error) {
+	gateway := &gatewayv1alpha2.Gateway{}
+	if err := fromUnstructured(obj, gateway); err != nil {
+		return nil, err
+	}
+	return gateway, nil
+}
+
+// ToHTTPRoutes converts the unstructured HTTPRoutes of a lister.
+func ToHTTPRoutes(objs []runtime.Object) ([]*gatewayv1alpha2.HTTPRoute, error) {
+	routes := make([]*gatewayv1alpha2.HTTPRoute, 0, len(objs))
+	for _, obj := range objs {
+		route, err := toHTTPRoute(obj)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// ToGateways converts the unstructured Gateways of a lister.
+func ToGateways(objs []runtime.Object) ([]*gatewayv1alpha2.Gateway, error) {
+	gateways := make([]*gatewayv1alpha2.Gateway, 0, len(objs))
+	for _, obj := range objs {
+		gateway, err := toGateway(obj)
+		if err != nil {
+			return nil, err
+		}
+		gateways = append(gateways, gateway)
+	}
+	return gateways, nil
+}
+
+func fromUnstructured(obj interface{}, into interface{}) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object type: %T", obj)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, into); err != nil {
+		return fmt.Errorf("failed to convert %s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
+
+func toUnstructured(gvr schema.GroupVersionResource, obj runtime.Object) (*unstructured.Unstructured, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: raw}
+	kind := "HTTPRoute"
+	if gvr == GatewaysGVR {
+		kind = "Gateway"
+	}
+	u.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+	return u, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaysplitter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+const controllerName = "kcp-gateway-splitter"
+
+var (
+	// GatewaysGVR is the resource of the Gateway API gateways.
+	GatewaysGVR = gatewayv1alpha2.SchemeGroupVersion.WithResource("gateways")
+	// HTTPRoutesGVR is the resource of the Gateway API HTTP routes.
+	HTTPRoutesGVR = gatewayv1alpha2.SchemeGroupVersion.WithResource("httproutes")
+)
+
+// NewController returns a new Controller which splits the Gateway API HTTPRoutes
+// into N virtual HTTPRoutes, one for each WorkloadCluster their backend services
+// are placed on, and the Gateways their routes are attached to into one virtual
+// Gateway per WorkloadCluster of the routes.
+//
+// The status of the leaves is aggregated into the root HTTPRoutes and Gateways.
+func NewController(
+	dynamicClient dynamic.ClusterInterface,
+	gatewayInformer informers.GenericInformer,
+	httpRouteInformer informers.GenericInformer,
+	serviceInformer coreinformers.ServiceInformer) *Controller {
+
+	c := &Controller{
+		queue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		client: dynamicClient,
+
+		gatewayIndexer:   gatewayInformer.Informer().GetIndexer(),
+		gatewayLister:    gatewayInformer.Lister(),
+		httpRouteIndexer: httpRouteInformer.Informer().GetIndexer(),
+		httpRouteLister:  httpRouteInformer.Lister(),
+
+		serviceLister: serviceInformer.Lister(),
+	}
+
+	// Watch for events related to Gateways
+	gatewayInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(GatewaysGVR, obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(GatewaysGVR, obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueue(GatewaysGVR, obj) },
+	})
+
+	// Watch for events related to HTTPRoutes, and the Gateways they are attached to.
+	// The Gateways a route was attached to before an update or a deletion are enqueued
+	// too, as their leaves may not be needed anymore.
+	httpRouteInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueue(HTTPRoutesGVR, obj) },
+		UpdateFunc: func(oldObj, obj interface{}) {
+			c.enqueue(HTTPRoutesGVR, obj)
+			c.enqueueParentGateways(oldObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueue(HTTPRoutesGVR, obj)
+			c.enqueueParentGateways(obj)
+		},
+	})
+
+	// Watch for events related to Services, which the HTTPRoutes are placed with
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.httpRoutesFromService(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.httpRoutesFromService(obj) },
+		DeleteFunc: func(obj interface{}) { c.httpRoutesFromService(obj) },
+	})
+
+	return c
+}
+
+// The Controller struct represents a Gateway API splitter instance.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	client dynamic.ClusterInterface
+
+	gatewayIndexer   cache.Indexer
+	gatewayLister    cache.GenericLister
+	httpRouteIndexer cache.Indexer
+	httpRouteLister  cache.GenericLister
+
+	serviceLister corelisters.ServiceLister
+}
+
+// queueKey is the key of a Gateway or HTTPRoute in the queue.
+type queueKey struct {
+	gvr schema.GroupVersionResource
+	key string
+}
+
+func (c *Controller) enqueue(gvr schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected object type: %T", obj))
+		return
+	}
+
+	// If it's a leaf, enqueue the root, which aggregates the status of its leaves
+	if rootKey := RootKeyFor(u); rootKey != "" {
+		c.queue.Add(queueKey{gvr: gvr, key: rootKey})
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(u)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(queueKey{gvr: gvr, key: key})
+}
+
+// enqueueParentGateways enqueues the root Gateways a root HTTPRoute is attached to.
+func (c *Controller) enqueueParentGateways(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	route, err := toHTTPRoute(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	// The parents of a leaf are leaves themselves, which are enqueued through their roots.
+	if RootKeyFor(route) != "" {
+		return
+	}
+	for _, key := range parentGatewayKeys(route) {
+		c.queue.Add(queueKey{gvr: GatewaysGVR, key: key})
+	}
+}
+
+// httpRoutesFromService enqueues the root HTTPRoutes with a backend reference to a given service.
+func (c *Controller) httpRoutesFromService(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected object type: %T", obj))
+		return
+	}
+
+	objs, err := c.httpRouteLister.ByNamespace(service.Namespace).List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, obj := range objs {
+		route, err := toHTTPRoute(obj)
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		if logicalcluster.From(route) != logicalcluster.From(service) || RootKeyFor(route) != "" {
+			continue
+		}
+		for _, name := range backendServiceNames(route) {
+			if name == service.Name {
+				c.enqueue(HTTPRoutesGVR, obj)
+				break
+			}
+		}
+	}
+}
+
+// Start starts the controller workers.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting workers", "controller", controllerName)
+	defer klog.InfoS("Stopping workers", "controller", controllerName)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(queueKey)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("failed to sync %s %q: %w", key.gvr.Resource, key.key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key queueKey) error {
+	indexer := c.httpRouteIndexer
+	if key.gvr == GatewaysGVR {
+		indexer = c.gatewayIndexer
+	}
+
+	obj, exists, err := indexer.GetByKey(key.key)
+	if err != nil {
+		klog.Errorf("Failed to get %s with key %q because: %v", key.gvr.Resource, key.key, err)
+		return nil
+	}
+
+	if !exists {
+		klog.Infof("Object with key %q was deleted", key.key)
+
+		// The leaves of a deleted root are deleted too.
+		namespace, clusterAwareName, err := cache.SplitMetaNamespaceKey(key.key)
+		if err != nil {
+			klog.Errorf("failed to split key %q, dropping: %v", key.key, err)
+			return nil
+		}
+		clusterName, name := clusters.SplitClusterAwareKey(clusterAwareName)
+		return c.deleteLeaves(ctx, key.gvr, clusterName, namespace, name)
+	}
+
+	klog.Infof("Processing %s %q", key.gvr.Resource, key.key)
+
+	switch key.gvr {
+	case GatewaysGVR:
+		gateway, err := toGateway(obj)
+		if err != nil {
+			return err
+		}
+		return c.reconcileGateway(ctx, gateway)
+	default:
+		route, err := toHTTPRoute(obj)
+		if err != nil {
+			return err
+		}
+		return c.reconcileHTTPRoute(ctx, route)
+	}
+}
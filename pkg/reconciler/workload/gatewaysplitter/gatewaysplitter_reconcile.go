@@ -0,0 +1,430 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaysplitter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workload/ingresssplitter"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+const (
+	OwnedByCluster   = "gateway.kcp.dev/owned-by-cluster"
+	OwnedByName      = "gateway.kcp.dev/owned-by-name"
+	OwnedByNamespace = "gateway.kcp.dev/owned-by-namespace"
+)
+
+// reconcileHTTPRoute splits a root HTTPRoute into a leaf per WorkloadCluster its backend
+// services are placed on, and aggregates the status of the leaves.
+func (c *Controller) reconcileHTTPRoute(ctx context.Context, route *gatewayv1alpha2.HTTPRoute) error {
+	clusterName := logicalcluster.From(route)
+	klog.InfoS("reconciling HTTPRoute", "ClusterName", clusterName, "Namespace", route.Namespace, "Name", route.Name)
+
+	var desired []metav1.Object
+	for _, workloadCluster := range c.workloadClustersFor(route).List() {
+		leaf := route.DeepCopy()
+		setLeafMeta(leaf, route, workloadCluster)
+		leaf.Status = gatewayv1alpha2.HTTPRouteStatus{}
+
+		// The leaf is attached to the leaf of the Gateway in the same WorkloadCluster.
+		for i, ref := range leaf.Spec.ParentRefs {
+			if IsGatewayRef(ref) {
+				leaf.Spec.ParentRefs[i].Name = gatewayv1alpha2.ObjectName(LeafName(string(ref.Name), workloadCluster))
+			}
+		}
+		desired = append(desired, leaf)
+	}
+
+	current, err := c.listLeaves(c.httpRouteLister, route)
+	if err != nil {
+		return err
+	}
+	if err := c.syncLeaves(ctx, HTTPRoutesGVR, clusterName, current, desired); err != nil {
+		return err
+	}
+
+	// The Gateways are split along the HTTPRoutes attached to them.
+	for _, key := range parentGatewayKeys(route) {
+		c.queue.Add(queueKey{gvr: GatewaysGVR, key: key})
+	}
+
+	// Aggregate the status of the leaves per parent of the root.
+	leaves := make(map[string]*gatewayv1alpha2.HTTPRoute, len(current))
+	for _, obj := range current {
+		leaf, err := toHTTPRoute(obj)
+		if err != nil {
+			return err
+		}
+		//nolint:staticcheck
+		leaves[shared.DeprecatedGetAssignedWorkloadCluster(leaf.Labels)] = leaf
+	}
+	status := gatewayv1alpha2.HTTPRouteStatus{}
+	status.Parents = []gatewayv1alpha2.RouteParentStatus{}
+	for _, ref := range route.Spec.ParentRefs {
+		var parentStatuses []gatewayv1alpha2.RouteParentStatus
+		for _, workloadCluster := range sets.StringKeySet(leaves).List() {
+			leafRef := ref.DeepCopy()
+			if IsGatewayRef(ref) {
+				leafRef.Name = gatewayv1alpha2.ObjectName(LeafName(string(ref.Name), workloadCluster))
+			}
+			for _, parentStatus := range leaves[workloadCluster].Status.Parents {
+				if equality.Semantic.DeepEqual(parentStatus.ParentRef, *leafRef) {
+					parentStatuses = append(parentStatuses, parentStatus)
+				}
+			}
+		}
+		if len(parentStatuses) == 0 {
+			continue
+		}
+
+		conditions := make([][]metav1.Condition, 0, len(parentStatuses))
+		for _, parentStatus := range parentStatuses {
+			conditions = append(conditions, parentStatus.Conditions)
+		}
+		status.Parents = append(status.Parents, gatewayv1alpha2.RouteParentStatus{
+			ParentRef:      ref,
+			ControllerName: parentStatuses[0].ControllerName,
+			Conditions:     aggregateConditions(conditions),
+		})
+	}
+
+	if equality.Semantic.DeepEqual(route.Status, status) {
+		return nil
+	}
+	updated := route.DeepCopy()
+	updated.Status = status
+	return c.updateStatus(ctx, HTTPRoutesGVR, clusterName, updated)
+}
+
+// reconcileGateway splits a root Gateway into a leaf per WorkloadCluster of the HTTPRoutes
+// attached to it, and aggregates the status of the leaves.
+func (c *Controller) reconcileGateway(ctx context.Context, gateway *gatewayv1alpha2.Gateway) error {
+	clusterName := logicalcluster.From(gateway)
+	klog.InfoS("reconciling Gateway", "ClusterName", clusterName, "Namespace", gateway.Namespace, "Name", gateway.Name)
+
+	objs, err := c.httpRouteLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	workloadClusters := sets.NewString()
+	for _, obj := range objs {
+		route, err := toHTTPRoute(obj)
+		if err != nil {
+			return err
+		}
+		if logicalcluster.From(route) != clusterName || RootKeyFor(route) != "" {
+			continue
+		}
+		for _, ref := range route.Spec.ParentRefs {
+			if IsGatewayRef(ref) && string(ref.Name) == gateway.Name && parentNamespace(ref, route.Namespace) == gateway.Namespace {
+				workloadClusters.Insert(c.workloadClustersFor(route).List()...)
+				break
+			}
+		}
+	}
+
+	var desired []metav1.Object
+	for _, workloadCluster := range workloadClusters.List() {
+		leaf := gateway.DeepCopy()
+		setLeafMeta(leaf, gateway, workloadCluster)
+		leaf.Status = gatewayv1alpha2.GatewayStatus{}
+		desired = append(desired, leaf)
+	}
+
+	current, err := c.listLeaves(c.gatewayLister, gateway)
+	if err != nil {
+		return err
+	}
+	if err := c.syncLeaves(ctx, GatewaysGVR, clusterName, current, desired); err != nil {
+		return err
+	}
+
+	// Aggregate the addresses and conditions of the leaves.
+	status := gatewayv1alpha2.GatewayStatus{}
+	addresses := sets.NewString()
+	var conditions [][]metav1.Condition
+	for _, obj := range current {
+		leaf, err := toGateway(obj)
+		if err != nil {
+			return err
+		}
+		for _, address := range leaf.Status.Addresses {
+			key := address.Value
+			if address.Type != nil {
+				key = string(*address.Type) + "/" + key
+			}
+			if !addresses.Has(key) {
+				addresses.Insert(key)
+				status.Addresses = append(status.Addresses, address)
+			}
+		}
+		conditions = append(conditions, leaf.Status.Conditions)
+	}
+	status.Conditions = aggregateConditions(conditions)
+
+	if equality.Semantic.DeepEqual(gateway.Status.Addresses, status.Addresses) && equality.Semantic.DeepEqual(gateway.Status.Conditions, status.Conditions) {
+		return nil
+	}
+	updated := gateway.DeepCopy()
+	updated.Status.Addresses = status.Addresses
+	updated.Status.Conditions = status.Conditions
+	return c.updateStatus(ctx, GatewaysGVR, clusterName, updated)
+}
+
+// workloadClustersFor returns the WorkloadClusters the backend services of the HTTPRoute are placed on.
+func (c *Controller) workloadClustersFor(route *gatewayv1alpha2.HTTPRoute) sets.String {
+	workloadClusters := sets.NewString()
+	for _, name := range backendServiceNames(route) {
+		service, err := c.serviceLister.Services(route.Namespace).Get(clusters.ToClusterAwareKey(logicalcluster.From(route), name))
+		if err != nil {
+			klog.Infof("Skipping service %q of HTTPRoute %s|%s/%s: %v", name, logicalcluster.From(route), route.Namespace, route.Name, err)
+			continue
+		}
+		//nolint:staticcheck
+		if workloadCluster := shared.DeprecatedGetAssignedWorkloadCluster(service.Labels); workloadCluster != "" {
+			workloadClusters.Insert(workloadCluster)
+		} else {
+			klog.Infof("Skipping service %q because it is not assigned to any cluster", service.Name)
+		}
+	}
+	return workloadClusters
+}
+
+// listLeaves returns the leaves of a root, sorted by name.
+func (c *Controller) listLeaves(lister cache.GenericLister, root metav1.Object) ([]runtime.Object, error) {
+	selector, err := createOwnedBySelector(logicalcluster.From(root), root.GetName(), root.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].(metav1.Object).GetName() < leaves[j].(metav1.Object).GetName()
+	})
+	return leaves, nil
+}
+
+// syncLeaves creates, updates and deletes the current leaves to match the desired ones.
+func (c *Controller) syncLeaves(ctx context.Context, gvr schema.GroupVersionResource, clusterName logicalcluster.Name, current []runtime.Object, desired []metav1.Object) error {
+	client := c.client.Cluster(clusterName).Resource(gvr)
+
+	desiredByName := make(map[string]metav1.Object, len(desired))
+	for _, leaf := range desired {
+		desiredByName[leaf.GetName()] = leaf
+	}
+
+	for _, obj := range current {
+		currentLeaf := obj.(*unstructured.Unstructured)
+		desiredLeaf, found := desiredByName[currentLeaf.GetName()]
+		delete(desiredByName, currentLeaf.GetName())
+		if !found {
+			klog.InfoS("Deleting leaf", "resource", gvr.Resource, "ClusterName", clusterName, "Namespace", currentLeaf.GetNamespace(), "Name", currentLeaf.GetName())
+			if err := client.Namespace(currentLeaf.GetNamespace()).Delete(ctx, currentLeaf.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete leaf: %w", err)
+			}
+			continue
+		}
+
+		desiredUnstructured, err := toUnstructured(gvr, desiredLeaf.(runtime.Object))
+		if err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(currentLeaf.Object["spec"], desiredUnstructured.Object["spec"]) {
+			continue
+		}
+		klog.InfoS("Updating leaf", "resource", gvr.Resource, "ClusterName", clusterName, "Namespace", currentLeaf.GetNamespace(), "Name", currentLeaf.GetName())
+		updated := currentLeaf.DeepCopy()
+		updated.Object["spec"] = desiredUnstructured.Object["spec"]
+		if _, err := client.Namespace(updated.GetNamespace()).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	for _, leaf := range desired {
+		if _, found := desiredByName[leaf.GetName()]; !found {
+			continue
+		}
+		klog.InfoS("Creating leaf", "resource", gvr.Resource, "ClusterName", clusterName, "Namespace", leaf.GetNamespace(), "Name", leaf.GetName())
+		u, err := toUnstructured(gvr, leaf.(runtime.Object))
+		if err != nil {
+			return err
+		}
+		if _, err := client.Namespace(leaf.GetNamespace()).Create(ctx, u, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			//TODO(jmprusi): Surface as user-facing condition.
+			return fmt.Errorf("failed to create leaf: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteLeaves deletes the leaves of a deleted root.
+func (c *Controller) deleteLeaves(ctx context.Context, gvr schema.GroupVersionResource, clusterName logicalcluster.Name, namespace, name string) error {
+	lister := c.httpRouteLister
+	if gvr == GatewaysGVR {
+		lister = c.gatewayLister
+	}
+	root := &metav1.ObjectMeta{ClusterName: clusterName.String(), Namespace: namespace, Name: name}
+	current, err := c.listLeaves(lister, root)
+	if err != nil {
+		return err
+	}
+	return c.syncLeaves(ctx, gvr, clusterName, current, nil)
+}
+
+func (c *Controller) updateStatus(ctx context.Context, gvr schema.GroupVersionResource, clusterName logicalcluster.Name, obj runtime.Object) error {
+	u, err := toUnstructured(gvr, obj)
+	if err != nil {
+		return err
+	}
+	// TODO(jmprusi): Use patch (safer) instead of update.
+	if _, err := c.client.Cluster(clusterName).Resource(gvr).Namespace(u.GetNamespace()).UpdateStatus(ctx, u, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update root %s status: %w", gvr.Resource, err)
+	}
+	return nil
+}
+
+// setLeafMeta sets the metadata of the leaf of a root in a WorkloadCluster.
+func setLeafMeta(leaf, root metav1.Object, workloadCluster string) {
+	leaf.SetName(LeafName(root.GetName(), workloadCluster))
+
+	// Label the leaf with the root information, so we can construct the root key from it.
+	leaf.SetLabels(map[string]string{
+		workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync),
+		OwnedByCluster:   ingresssplitter.LabelEscapeClusterName(logicalcluster.From(root)),
+		OwnedByName:      root.GetName(),
+		OwnedByNamespace: root.GetNamespace(),
+	})
+
+	// The syncer syncs the OwnerReferences, which would delete the leaves downstream.
+	leaf.SetOwnerReferences([]metav1.OwnerReference{})
+	leaf.SetResourceVersion("")
+	leaf.SetUID("")
+}
+
+// LeafName returns the name of the leaf of a root Gateway or HTTPRoute in a WorkloadCluster.
+func LeafName(name, workloadCluster string) string {
+	return name + "-" + workloadCluster
+}
+
+// aggregateConditions merges the conditions of the leaves: a condition is true if it is
+// true for any of the leaves.
+func aggregateConditions(leafConditions [][]metav1.Condition) []metav1.Condition {
+	var aggregated []metav1.Condition
+	for _, conditions := range leafConditions {
+		for _, condition := range conditions {
+			existing := meta.FindStatusCondition(aggregated, condition.Type)
+			if existing == nil {
+				aggregated = append(aggregated, condition)
+			} else if existing.Status != metav1.ConditionTrue && condition.Status == metav1.ConditionTrue {
+				*existing = condition
+			}
+		}
+	}
+	return aggregated
+}
+
+// backendServiceNames returns the names of the services the HTTPRoute references in its namespace.
+func backendServiceNames(route *gatewayv1alpha2.HTTPRoute) []string {
+	names := sets.NewString()
+	for _, rule := range route.Spec.Rules {
+		for _, backendRef := range rule.BackendRefs {
+			if IsServiceRef(backendRef.BackendObjectReference) && (backendRef.Namespace == nil || string(*backendRef.Namespace) == route.Namespace) {
+				names.Insert(string(backendRef.Name))
+			}
+		}
+	}
+	return names.List()
+}
+
+// IsServiceRef returns whether the backend reference is a core Service.
+func IsServiceRef(ref gatewayv1alpha2.BackendObjectReference) bool {
+	return (ref.Group == nil || *ref.Group == "") && (ref.Kind == nil || *ref.Kind == "Service")
+}
+
+// IsGatewayRef returns whether the parent reference is a Gateway.
+func IsGatewayRef(ref gatewayv1alpha2.ParentRef) bool {
+	return (ref.Group == nil || *ref.Group == gatewayv1alpha2.GroupName) && (ref.Kind == nil || *ref.Kind == "Gateway")
+}
+
+// parentGatewayKeys returns the keys of the Gateways the HTTPRoute is attached to.
+func parentGatewayKeys(route *gatewayv1alpha2.HTTPRoute) []string {
+	var keys []string
+	for _, ref := range route.Spec.ParentRefs {
+		if IsGatewayRef(ref) {
+			keys = append(keys, parentNamespace(ref, route.Namespace)+"/"+clusters.ToClusterAwareKey(logicalcluster.From(route), string(ref.Name)))
+		}
+	}
+	return keys
+}
+
+func parentNamespace(ref gatewayv1alpha2.ParentRef, namespace string) string {
+	if ref.Namespace != nil {
+		return string(*ref.Namespace)
+	}
+	return namespace
+}
+
+func createOwnedBySelector(clusterName logicalcluster.Name, name, namespace string) (labels.Selector, error) {
+	ownedClusterReq, err := labels.NewRequirement(OwnedByCluster, selection.Equals, []string{ingresssplitter.LabelEscapeClusterName(clusterName)})
+	if err != nil {
+		return nil, err
+	}
+	ownedNameReq, err := labels.NewRequirement(OwnedByName, selection.Equals, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	ownedNamespaceReq, err := labels.NewRequirement(OwnedByNamespace, selection.Equals, []string{namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	return labels.NewSelector().Add(*ownedClusterReq, *ownedNameReq, *ownedNamespaceReq), nil
+}
+
+// RootKeyFor returns the key of the root of a leaf Gateway or HTTPRoute, or "" if the object
+// is not a leaf.
+func RootKeyFor(obj metav1.Object) string {
+	if obj.GetLabels()[OwnedByCluster] != "" && obj.GetLabels()[OwnedByNamespace] != "" && obj.GetLabels()[OwnedByName] != "" {
+		return obj.GetLabels()[OwnedByNamespace] + "/" + clusters.ToClusterAwareKey(ingresssplitter.UnescapeClusterNameLabel(obj.GetLabels()[OwnedByCluster]), obj.GetLabels()[OwnedByName])
+	}
+
+	return ""
+}
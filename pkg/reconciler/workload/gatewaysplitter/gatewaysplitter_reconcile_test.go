@@ -0,0 +1,355 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaysplitter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	gatewayv1alpha2 "github.com/kcp-dev/kcp/third_party/gateway-api/apis/v1alpha2"
+)
+
+func TestAggregateConditions(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus, reason string) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Reason: reason}
+	}
+
+	tests := []struct {
+		name     string
+		leaves   [][]metav1.Condition
+		expected []metav1.Condition
+	}{
+		{
+			name: "no leaves",
+		},
+		{
+			name: "true if any leaf is true",
+			leaves: [][]metav1.Condition{
+				{condition("Ready", metav1.ConditionFalse, "Pending"), condition("Scheduled", metav1.ConditionTrue, "Scheduled")},
+				{condition("Ready", metav1.ConditionTrue, "Ready")},
+			},
+			expected: []metav1.Condition{condition("Ready", metav1.ConditionTrue, "Ready"), condition("Scheduled", metav1.ConditionTrue, "Scheduled")},
+		},
+		{
+			name: "first condition if no leaf is true",
+			leaves: [][]metav1.Condition{
+				{condition("Ready", metav1.ConditionUnknown, "Pending")},
+				{condition("Ready", metav1.ConditionFalse, "Invalid")},
+			},
+			expected: []metav1.Condition{condition("Ready", metav1.ConditionUnknown, "Pending")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, aggregateConditions(tc.leaves))
+		})
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	service := func(name, workloadCluster string) *corev1.Service {
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{ClusterName: "root:org:ws", Namespace: "default", Name: name}}
+		if workloadCluster != "" {
+			service.Labels = map[string]string{workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync)}
+		}
+		return service
+	}
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, s := range []*corev1.Service{service("web", "east"), service("api", "west"), service("pending", "")} {
+		require.NoError(t, serviceIndexer.Add(s))
+	}
+	c := &Controller{serviceLister: corelisters.NewServiceLister(serviceIndexer)}
+
+	port := gatewayv1alpha2.PortNumber(80)
+	backendRef := func(name string) gatewayv1alpha2.HTTPBackendRef {
+		return gatewayv1alpha2.HTTPBackendRef{BackendRef: gatewayv1alpha2.BackendRef{BackendObjectReference: gatewayv1alpha2.BackendObjectReference{Name: gatewayv1alpha2.ObjectName(name), Port: &port}}}
+	}
+	route := &gatewayv1alpha2.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{ClusterName: "root:org:ws", Namespace: "default", Name: "route"},
+		Spec: gatewayv1alpha2.HTTPRouteSpec{
+			Rules: []gatewayv1alpha2.HTTPRouteRule{
+				{BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("web"), backendRef("api")}},
+				{BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("pending"), backendRef("missing")}},
+			},
+		},
+	}
+
+	require.Equal(t, []string{"api", "missing", "pending", "web"}, backendServiceNames(route))
+	require.Equal(t, []string{"east", "west"}, c.workloadClustersFor(route).List())
+
+	leaf := route.DeepCopy()
+	setLeafMeta(leaf, route, "east")
+	require.Equal(t, "route-east", leaf.Name)
+	require.Equal(t, "default/root:org:ws#$#route", RootKeyFor(leaf))
+	require.Equal(t, "", RootKeyFor(route))
+}
+
+func TestReconcileHTTPRoute(t *testing.T) {
+	accepted := []metav1.Condition{{Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted"}}
+
+	tests := []struct {
+		name           string
+		leaves         []*gatewayv1alpha2.HTTPRoute
+		expected       []string
+		expectedStatus *gatewayv1alpha2.HTTPRouteStatus
+	}{
+		{
+			name: "creates the leaves",
+			expected: []string{
+				"create httproutes route-east",
+				"create httproutes route-west",
+			},
+		},
+		{
+			name: "updates the leaves and deletes the leaves of clusters without backends",
+			leaves: []*gatewayv1alpha2.HTTPRoute{
+				testLeafRoute("east", func(leaf *gatewayv1alpha2.HTTPRoute) { leaf.Spec.Hostnames = nil }),
+				testLeafRoute("west"),
+				testLeafRoute("gone"),
+			},
+			expected: []string{
+				"update httproutes route-east",
+				"delete httproutes route-gone",
+			},
+		},
+		{
+			name: "aggregates the status of the leaves",
+			leaves: []*gatewayv1alpha2.HTTPRoute{
+				testLeafRoute("east", func(leaf *gatewayv1alpha2.HTTPRoute) {
+					leaf.Status.Parents = []gatewayv1alpha2.RouteParentStatus{{ParentRef: gatewayv1alpha2.ParentRef{Name: "gateway-east"}, ControllerName: "kcp.dev/envoy", Conditions: accepted}}
+				}),
+				testLeafRoute("west", func(leaf *gatewayv1alpha2.HTTPRoute) {
+					leaf.Status.Parents = []gatewayv1alpha2.RouteParentStatus{{ParentRef: gatewayv1alpha2.ParentRef{Name: "gateway-west"}, ControllerName: "kcp.dev/envoy", Conditions: []metav1.Condition{{Type: "Accepted", Status: metav1.ConditionFalse, Reason: "Pending"}}}}
+				}),
+			},
+			expected: []string{
+				"update httproutes/status route",
+			},
+			expectedStatus: &gatewayv1alpha2.HTTPRouteStatus{RouteStatus: gatewayv1alpha2.RouteStatus{Parents: []gatewayv1alpha2.RouteParentStatus{
+				{ParentRef: gatewayv1alpha2.ParentRef{Name: "gateway"}, ControllerName: "kcp.dev/envoy", Conditions: accepted},
+			}}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := testRootRoute()
+			objs := []runtime.Object{root}
+			for _, leaf := range tc.leaves {
+				objs = append(objs, leaf)
+			}
+			c, client := newTestController(t, objs...)
+
+			require.NoError(t, c.reconcileHTTPRoute(context.Background(), root))
+			require.Equal(t, tc.expected, actionsOf(client))
+
+			// The Gateways the route is attached to are split along the route.
+			require.Equal(t, 1, c.queue.Len())
+			key, _ := c.queue.Get()
+			require.Equal(t, queueKey{gvr: GatewaysGVR, key: "default/root:org:ws#$#gateway"}, key)
+
+			if tc.expectedStatus != nil {
+				updated, err := client.Tracker().Get(HTTPRoutesGVR, "default", "route")
+				require.NoError(t, err)
+				route, err := toHTTPRoute(updated)
+				require.NoError(t, err)
+				require.Equal(t, *tc.expectedStatus, route.Status)
+			}
+		})
+	}
+}
+
+func TestReconcileGateway(t *testing.T) {
+	addressType := gatewayv1alpha2.IPAddressType
+	ready := []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready"}}
+
+	root := &gatewayv1alpha2.Gateway{
+		ObjectMeta: metav1.ObjectMeta{ClusterName: "root:org:ws", Namespace: "default", Name: "gateway"},
+		Spec: gatewayv1alpha2.GatewaySpec{
+			GatewayClassName: "kcp",
+			Listeners:        []gatewayv1alpha2.Listener{{Name: "http", Port: 80, Protocol: gatewayv1alpha2.HTTPProtocolType}},
+		},
+	}
+	leaf := func(workloadCluster string, addresses ...string) *gatewayv1alpha2.Gateway {
+		leaf := root.DeepCopy()
+		setLeafMeta(leaf, root, workloadCluster)
+		for _, address := range addresses {
+			leaf.Status.Addresses = append(leaf.Status.Addresses, gatewayv1alpha2.GatewayAddress{Type: &addressType, Value: address})
+		}
+		leaf.Status.Conditions = ready
+		return leaf
+	}
+
+	c, client := newTestController(t, root, testRootRoute(), leaf("east", "10.0.0.1"), leaf("gone", "10.0.0.1", "10.0.0.2"))
+
+	require.NoError(t, c.reconcileGateway(context.Background(), root))
+	require.Equal(t, []string{
+		"delete gateways gateway-gone",
+		"create gateways gateway-west",
+		"update gateways/status gateway",
+	}, actionsOf(client))
+
+	updated, err := client.Tracker().Get(GatewaysGVR, "default", "gateway")
+	require.NoError(t, err)
+	gateway, err := toGateway(updated)
+	require.NoError(t, err)
+	require.Equal(t, []gatewayv1alpha2.GatewayAddress{{Type: &addressType, Value: "10.0.0.1"}, {Type: &addressType, Value: "10.0.0.2"}}, gateway.Status.Addresses)
+	require.Equal(t, ready, gateway.Status.Conditions)
+}
+
+func TestProcessDeletedHTTPRoute(t *testing.T) {
+	root := testRootRoute()
+	c, client := newTestController(t, testLeafRoute("east"), testLeafRoute("west"))
+
+	key, err := cache.MetaNamespaceKeyFunc(root)
+	require.NoError(t, err)
+	require.NoError(t, c.process(context.Background(), queueKey{gvr: HTTPRoutesGVR, key: key}))
+	require.Equal(t, []string{
+		"delete httproutes route-east",
+		"delete httproutes route-west",
+	}, actionsOf(client))
+
+	// The Gateways of the deleted route are enqueued, so their leaves are deleted too.
+	u, err := toUnstructured(HTTPRoutesGVR, root)
+	require.NoError(t, err)
+	c.enqueueParentGateways(cache.DeletedFinalStateUnknown{Key: key, Obj: u})
+	require.Equal(t, 1, c.queue.Len())
+	queued, _ := c.queue.Get()
+	require.Equal(t, queueKey{gvr: GatewaysGVR, key: "default/root:org:ws#$#gateway"}, queued)
+}
+
+var _ dynamic.ClusterInterface = (*mockedDynamicCluster)(nil)
+
+type mockedDynamicCluster struct {
+	client *dynamicfake.FakeDynamicClient
+}
+
+func (mdc *mockedDynamicCluster) Cluster(name logicalcluster.Name) dynamic.Interface {
+	return mdc.client
+}
+
+// newTestController returns a controller whose informers and dynamic client contain the given
+// Gateways and HTTPRoutes, and the services "web" in the "east" and "api" in the "west"
+// WorkloadClusters.
+func newTestController(t *testing.T, objs ...runtime.Object) (*Controller, *dynamicfake.FakeDynamicClient) {
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for name, workloadCluster := range map[string]string{"web": "east", "api": "west"} {
+		require.NoError(t, serviceIndexer.Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			ClusterName: "root:org:ws",
+			Namespace:   "default",
+			Name:        name,
+			Labels:      map[string]string{workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync)},
+		}}))
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		GatewaysGVR:   "GatewayList",
+		HTTPRoutesGVR: "HTTPRouteList",
+	})
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	httpRouteIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objs {
+		gvr, indexer := HTTPRoutesGVR, httpRouteIndexer
+		if _, ok := obj.(*gatewayv1alpha2.Gateway); ok {
+			gvr, indexer = GatewaysGVR, gatewayIndexer
+		}
+		u, err := toUnstructured(gvr, obj)
+		require.NoError(t, err)
+		require.NoError(t, indexer.Add(u))
+		// The tracker would guess the resource of the Gateways wrong from their kind.
+		require.NoError(t, client.Tracker().Create(gvr, u, u.GetNamespace()))
+	}
+
+	return &Controller{
+		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		client:           &mockedDynamicCluster{client: client},
+		gatewayIndexer:   gatewayIndexer,
+		gatewayLister:    cache.NewGenericLister(gatewayIndexer, GatewaysGVR.GroupResource()),
+		httpRouteIndexer: httpRouteIndexer,
+		httpRouteLister:  cache.NewGenericLister(httpRouteIndexer, HTTPRoutesGVR.GroupResource()),
+		serviceLister:    corelisters.NewServiceLister(serviceIndexer),
+	}, client
+}
+
+// testRootRoute returns a root HTTPRoute attached to the Gateway "gateway", with the
+// backend services "web" and "api".
+func testRootRoute() *gatewayv1alpha2.HTTPRoute {
+	port := gatewayv1alpha2.PortNumber(80)
+	backendRef := func(name string) gatewayv1alpha2.HTTPBackendRef {
+		return gatewayv1alpha2.HTTPBackendRef{BackendRef: gatewayv1alpha2.BackendRef{BackendObjectReference: gatewayv1alpha2.BackendObjectReference{Name: gatewayv1alpha2.ObjectName(name), Port: &port}}}
+	}
+	return &gatewayv1alpha2.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{ClusterName: "root:org:ws", Namespace: "default", Name: "route"},
+		Spec: gatewayv1alpha2.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1alpha2.CommonRouteSpec{ParentRefs: []gatewayv1alpha2.ParentRef{{Name: "gateway"}}},
+			Hostnames:       []gatewayv1alpha2.Hostname{"example.com"},
+			Rules:           []gatewayv1alpha2.HTTPRouteRule{{BackendRefs: []gatewayv1alpha2.HTTPBackendRef{backendRef("web"), backendRef("api")}}},
+		},
+	}
+}
+
+// testLeafRoute returns the leaf of testRootRoute in a WorkloadCluster.
+func testLeafRoute(workloadCluster string, opts ...func(*gatewayv1alpha2.HTTPRoute)) *gatewayv1alpha2.HTTPRoute {
+	root := testRootRoute()
+	leaf := root.DeepCopy()
+	setLeafMeta(leaf, root, workloadCluster)
+	leaf.Spec.ParentRefs[0].Name = gatewayv1alpha2.ObjectName(LeafName("gateway", workloadCluster))
+	for _, opt := range opts {
+		opt(leaf)
+	}
+	return leaf
+}
+
+// actionsOf returns the verb, resource and name of the actions of the client.
+func actionsOf(client *dynamicfake.FakeDynamicClient) []string {
+	var actions []string
+	for _, action := range client.Actions() {
+		resource := action.GetResource().Resource
+		if action.GetSubresource() != "" {
+			resource += "/" + action.GetSubresource()
+		}
+		var name string
+		switch action := action.(type) {
+		case clienttesting.CreateAction:
+			name = action.GetObject().(*unstructured.Unstructured).GetName()
+		case clienttesting.UpdateAction:
+			name = action.GetObject().(*unstructured.Unstructured).GetName()
+		case clienttesting.DeleteAction:
+			name = action.GetName()
+		}
+		actions = append(actions, fmt.Sprintf("%s %s %s", action.GetVerb(), resource, name))
+	}
+	return actions
+}
@@ -23,21 +23,30 @@ import (
 
 	"github.com/kcp-dev/logicalcluster"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
 	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	networkinglisters "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clusters"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
+	workloadlisters "github.com/kcp-dev/kcp/pkg/client/listers/workload/v1alpha1"
 )
 
 const controllerName = "kcp-ingress-splitter"
@@ -48,10 +57,15 @@ const controllerName = "kcp-ingress-splitter"
 //
 // The controller can optionally aggregate the leave's status into the root
 // ingress. This makes sense if the envoy side is disabled.
+//
+// The leaves are weighted by the ready replicas of the deployments backing their
+// services, and by the health of their WorkloadCluster.
 func NewController(
 	kubeClient kubernetes.ClusterInterface,
 	ingressInformer networkinginformers.IngressInformer,
 	serviceInformer coreinformers.ServiceInformer,
+	deploymentInformer appsinformers.DeploymentInformer,
+	workloadClusterInformer workloadinformers.WorkloadClusterInformer,
 	domain string,
 	aggregateLeaveStatus bool) *Controller {
 
@@ -67,6 +81,9 @@ func NewController(
 		serviceIndexer: serviceInformer.Informer().GetIndexer(),
 		serviceLister:  serviceInformer.Lister(),
 
+		deploymentLister:      deploymentInformer.Lister(),
+		workloadClusterLister: workloadClusterInformer.Lister(),
+
 		aggregateLeavesStatus: aggregateLeaveStatus,
 	}
 
@@ -108,6 +125,20 @@ func NewController(
 		DeleteFunc: func(obj interface{}) { c.ingressesFromService(obj) },
 	})
 
+	// Watch for events related to Deployments, which the leaves are weighted by
+	deploymentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.ingressesFromDeployment(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.ingressesFromDeployment(obj) },
+		DeleteFunc: func(obj interface{}) { c.ingressesFromDeployment(obj) },
+	})
+
+	// Watch for events related to WorkloadClusters, as the leaves of unhealthy ones get no traffic
+	workloadClusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.ingressesFromWorkloadCluster(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.ingressesFromWorkloadCluster(obj) },
+		DeleteFunc: func(obj interface{}) { c.ingressesFromWorkloadCluster(obj) },
+	})
+
 	return c
 }
 
@@ -126,6 +157,9 @@ type Controller struct {
 	serviceIndexer cache.Indexer
 	serviceLister  corelisters.ServiceLister
 
+	deploymentLister      appslisters.DeploymentLister
+	workloadClusterLister workloadlisters.WorkloadClusterLister
+
 	domain  string
 	tracker tracker
 
@@ -228,16 +262,82 @@ func (c *Controller) ingressesFromService(obj interface{}) {
 		return
 	}
 
+	c.enqueueIngressesForService(serviceKey)
+}
+
+// enqueueIngressesForService enqueues all the ingresses tracked for a given service key.
+func (c *Controller) enqueueIngressesForService(serviceKey string) {
 	// Does that Service has any Ingress associated to?
 	ingresses := c.tracker.getIngressesForService(serviceKey)
 
 	// One Service can be referenced by 0..n Ingresses, so we need to enqueue all the related ingreses.
 	for _, ingress := range ingresses.List() {
-		klog.Infof("tracked service %q triggered Ingress %q reconciliation", service.Name, ingress)
+		klog.Infof("tracked service %q triggered Ingress %q reconciliation", serviceKey, ingress)
 		c.queue.Add(ingress)
 	}
 }
 
+// ingressesFromDeployment enqueues all the ingresses related to the services selecting the
+// pods of a given deployment.
+func (c *Controller) ingressesFromDeployment(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected object type: %T", obj))
+		return
+	}
+
+	services, err := c.serviceLister.Services(deployment.Namespace).List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, service := range services {
+		if logicalcluster.From(service) != logicalcluster.From(deployment) || !selectsPods(service, deployment.Spec.Template.Labels) {
+			continue
+		}
+		serviceKey, err := cache.MetaNamespaceKeyFunc(service)
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		c.enqueueIngressesForService(serviceKey)
+	}
+}
+
+// ingressesFromWorkloadCluster enqueues the root ingresses having a leaf in a given workload cluster.
+func (c *Controller) ingressesFromWorkloadCluster(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workloadCluster, ok := obj.(*workloadv1alpha1.WorkloadCluster)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected object type: %T", obj))
+		return
+	}
+
+	assignedReq, err := labels.NewRequirement(workloadv1alpha1.InternalClusterResourceStateLabelPrefix+workloadCluster.Name, selection.Exists, nil)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	leaves, err := c.ingressLister.List(labels.NewSelector().Add(*assignedReq))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, leaf := range leaves {
+		if logicalcluster.From(leaf) != logicalcluster.From(workloadCluster) {
+			continue
+		}
+		if rootIngressKey := rootIngressKeyFor(leaf); rootIngressKey != "" {
+			c.queue.Add(rootIngressKey)
+		}
+	}
+}
+
 func rootIngressKeyFor(ingress metav1.Object) string {
 	if ingress.GetLabels()[OwnedByCluster] != "" && ingress.GetLabels()[OwnedByNamespace] != "" && ingress.GetLabels()[OwnedByIngress] != "" {
 		return ingress.GetLabels()[OwnedByNamespace] + "/" + clusters.ToClusterAwareKey(UnescapeClusterNameLabel(ingress.GetLabels()[OwnedByCluster]), ingress.GetLabels()[OwnedByIngress])
@@ -19,6 +19,7 @@ package ingresssplitter
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/kcp-dev/logicalcluster"
@@ -30,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
@@ -164,7 +166,7 @@ func (c *Controller) updateLeafs(ctx context.Context, currentLeaves []*networkin
 			}
 			found = true
 
-			if equality.Semantic.DeepEqual(currentLeaf.Spec, desiredLeaf.Spec) {
+			if equality.Semantic.DeepEqual(currentLeaf.Spec, desiredLeaf.Spec) && currentLeaf.Annotations[WeightAnnotation] == desiredLeaf.Annotations[WeightAnnotation] {
 				klog.InfoS("Leaf is up to date", "ClusterName", currentLeaf.ClusterName, "Namespace", currentLeaf.Namespace, "Name", currentLeaf.Name)
 				continue
 			}
@@ -172,6 +174,10 @@ func (c *Controller) updateLeafs(ctx context.Context, currentLeaves []*networkin
 			klog.InfoS("Updating leaf", "ClusterName", currentLeaf.ClusterName, "Namespace", currentLeaf.Namespace, "Name", currentLeaf.Name)
 			updated := currentLeaf.DeepCopy()
 			updated.Spec = desiredLeaf.Spec
+			if updated.Annotations == nil {
+				updated.Annotations = map[string]string{}
+			}
+			updated.Annotations[WeightAnnotation] = desiredLeaf.Annotations[WeightAnnotation]
 			if _, err := c.client.Cluster(logicalcluster.From(currentLeaf)).NetworkingV1().Ingresses(currentLeaf.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
 				//TODO(jmprusi): Update root Ingress condition to reflect the error.
 				return nil, nil, err
@@ -210,11 +216,11 @@ func (c *Controller) desiredLeaves(ctx context.Context, root *networkingv1.Ingre
 		return nil, err
 	}
 
-	var clusterDests []string
+	clusterDests := sets.NewString()
 	for _, service := range services {
 		//nolint:staticcheck
 		if shared.DeprecatedGetAssignedWorkloadCluster(service.Labels) != "" {
-			clusterDests = append(clusterDests, shared.DeprecatedGetAssignedWorkloadCluster(service.Labels))
+			clusterDests.Insert(shared.DeprecatedGetAssignedWorkloadCluster(service.Labels))
 		} else {
 			klog.Infof("Skipping service %q because it is not assigned to any cluster", service.Name)
 		}
@@ -223,8 +229,10 @@ func (c *Controller) desiredLeaves(ctx context.Context, root *networkingv1.Ingre
 		c.tracker.add(root, service)
 	}
 
+	weights := c.leafWeights(root, services, clusterDests.List())
+
 	desiredLeaves := make([]*networkingv1.Ingress, 0, len(clusterDests))
-	for _, cl := range clusterDests {
+	for _, cl := range clusterDests.List() {
 		vd := root.DeepCopy()
 		vd.Name = root.Name + "-" + cl
 
@@ -237,6 +245,11 @@ func (c *Controller) desiredLeaves(ctx context.Context, root *networkingv1.Ingre
 		vd.Labels[OwnedByIngress] = root.Name
 		vd.Labels[OwnedByNamespace] = root.Namespace
 
+		if vd.Annotations == nil {
+			vd.Annotations = map[string]string{}
+		}
+		vd.Annotations[WeightAnnotation] = strconv.Itoa(weights[cl])
+
 		// Cleanup all the other owner references.
 		// TODO(jmprusi): Right now the syncer is syncing the OwnerReferences causing the ingresses to be deleted.
 		vd.OwnerReferences = []metav1.OwnerReference{}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingresssplitter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+	"github.com/kcp-dev/kcp/third_party/conditions/util/conditions"
+)
+
+const (
+	// WeightAnnotation is set on the leaves with the relative weight of the traffic routed to
+	// their workload cluster. Leaves of weight 0 get no traffic.
+	WeightAnnotation = "ingress.kcp.dev/weight"
+
+	// WeightsAnnotation on a root ingress overrides the weights of its leaves, as a comma
+	// separated list of <workload cluster>=<weight>, e.g. "us-east1=3,us-west1=1".
+	WeightsAnnotation = "ingress.kcp.dev/weights"
+)
+
+// leafWeights returns the weight of the leaf of the root ingress in each of the workload
+// clusters. The weight of a leaf is the number of ready replicas of the deployments backing
+// the services in its workload cluster, unless overridden by the WeightsAnnotation of the root.
+// Leaves in unschedulable workload clusters, or workload clusters failing their heartbeat,
+// get a weight of 0.
+func (c *Controller) leafWeights(root *networkingv1.Ingress, services []*corev1.Service, workloadClusters []string) map[string]int {
+	clusterName := logicalcluster.From(root)
+
+	overrides, err := parseWeights(root.Annotations[WeightsAnnotation])
+	if err != nil {
+		klog.Warningf("Ignoring the %s annotation of ingress %s|%s/%s: %v", WeightsAnnotation, clusterName, root.Namespace, root.Name, err)
+	}
+
+	weights := make(map[string]int, len(workloadClusters))
+	var computed []string
+	computedSum := 0
+	for _, workloadCluster := range workloadClusters {
+		if !c.isWorkloadClusterHealthy(clusterName, workloadCluster) {
+			weights[workloadCluster] = 0
+			continue
+		}
+		if weight, found := overrides[workloadCluster]; found {
+			weights[workloadCluster] = weight
+			continue
+		}
+		weights[workloadCluster] = c.readyReplicas(root, services, workloadCluster)
+		computed = append(computed, workloadCluster)
+		computedSum += weights[workloadCluster]
+	}
+
+	// Without any ready replica, e.g. before the status of the deployments is synced, the
+	// traffic is split evenly and the health checks of the proxy take over.
+	if computedSum == 0 {
+		for _, workloadCluster := range computed {
+			weights[workloadCluster] = 1
+		}
+	}
+
+	return weights
+}
+
+// isWorkloadClusterHealthy returns false if the workload cluster is unschedulable or failing
+// its heartbeat. An unknown workload cluster is considered healthy.
+func (c *Controller) isWorkloadClusterHealthy(clusterName logicalcluster.Name, name string) bool {
+	workloadCluster, err := c.workloadClusterLister.Get(clusters.ToClusterAwareKey(clusterName, name))
+	if err != nil {
+		return true
+	}
+
+	return !workloadCluster.Spec.Unschedulable && !conditions.IsFalse(workloadCluster, workloadv1alpha1.HeartbeatHealthy)
+}
+
+// readyReplicas returns the number of ready replicas of the deployments selected by the
+// services of the root ingress in the workload cluster.
+func (c *Controller) readyReplicas(root *networkingv1.Ingress, services []*corev1.Service, workloadCluster string) int {
+	deployments, err := c.deploymentLister.Deployments(root.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list deployments: %v", err)
+		return 0
+	}
+
+	ready := 0
+	for _, deployment := range deployments {
+		//nolint:staticcheck
+		if logicalcluster.From(deployment) != logicalcluster.From(root) || shared.DeprecatedGetAssignedWorkloadCluster(deployment.Labels) != workloadCluster {
+			continue
+		}
+		for _, service := range services {
+			if selectsPods(service, deployment.Spec.Template.Labels) {
+				ready += int(deployment.Status.ReadyReplicas)
+				break
+			}
+		}
+	}
+
+	return ready
+}
+
+// selectsPods returns whether the service selects the pods with the given labels.
+func selectsPods(service *corev1.Service, podLabels map[string]string) bool {
+	if len(service.Spec.Selector) == 0 {
+		return false
+	}
+	return labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(podLabels))
+}
+
+// parseWeights parses the value of the WeightsAnnotation.
+func parseWeights(value string) (map[string]int, error) {
+	weights := make(map[string]int)
+	if strings.TrimSpace(value) == "" {
+		return weights, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected <workload cluster>=<weight>", entry)
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for workload cluster %q", parts[1], parts[0])
+		}
+		weights[parts[0]] = weight
+	}
+
+	return weights, nil
+}
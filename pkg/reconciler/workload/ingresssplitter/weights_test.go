@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingresssplitter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	workloadlisters "github.com/kcp-dev/kcp/pkg/client/listers/workload/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/third_party/conditions/apis/conditions/v1alpha1"
+)
+
+func TestLeafWeights(t *testing.T) {
+	newDeployment := func(cluster, app, workloadCluster string, ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				ClusterName: cluster,
+				Namespace:   "ns1",
+				Name:        app + "--" + workloadCluster,
+				Labels:      map[string]string{workloadv1alpha1.InternalClusterResourceStateLabelPrefix + workloadCluster: string(workloadv1alpha1.ResourceStateSync)},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": app}}},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+
+	newWorkloadCluster := func(name string, unschedulable bool, heartbeat corev1.ConditionStatus) *workloadv1alpha1.WorkloadCluster {
+		return &workloadv1alpha1.WorkloadCluster{
+			ObjectMeta: metav1.ObjectMeta{ClusterName: "cluster1", Name: name},
+			Spec:       workloadv1alpha1.WorkloadClusterSpec{Unschedulable: unschedulable},
+			Status: workloadv1alpha1.WorkloadClusterStatus{Conditions: conditionsv1alpha1.Conditions{{
+				Type:   workloadv1alpha1.HeartbeatHealthy,
+				Status: heartbeat,
+			}}},
+		}
+	}
+
+	web := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{ClusterName: "cluster1", Namespace: "ns1", Name: "web"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+
+	tests := []struct {
+		name             string
+		annotations      map[string]string
+		deployments      []*appsv1.Deployment
+		workloadClusters []*workloadv1alpha1.WorkloadCluster
+		expected         map[string]int
+	}{
+		{
+			name: "weighted by ready replicas",
+			deployments: []*appsv1.Deployment{
+				newDeployment("cluster1", "web", "east", 3),
+				newDeployment("cluster1", "web", "west", 1),
+				newDeployment("cluster1", "other", "west", 5),
+				newDeployment("cluster2", "web", "west", 5),
+			},
+			expected: map[string]int{"east": 3, "west": 1},
+		},
+		{
+			name: "evenly without ready replicas",
+			deployments: []*appsv1.Deployment{
+				newDeployment("cluster1", "web", "east", 0),
+			},
+			expected: map[string]int{"east": 1, "west": 1},
+		},
+		{
+			name: "unhealthy workload clusters get no traffic",
+			deployments: []*appsv1.Deployment{
+				newDeployment("cluster1", "web", "east", 3),
+				newDeployment("cluster1", "web", "west", 1),
+				newDeployment("cluster1", "web", "north", 1),
+			},
+			workloadClusters: []*workloadv1alpha1.WorkloadCluster{
+				newWorkloadCluster("east", true, corev1.ConditionTrue),
+				newWorkloadCluster("west", false, corev1.ConditionFalse),
+				newWorkloadCluster("north", false, corev1.ConditionTrue),
+			},
+			expected: map[string]int{"east": 0, "west": 0, "north": 1},
+		},
+		{
+			name:        "overridden by the root ingress",
+			annotations: map[string]string{WeightsAnnotation: "east=0, west=2"},
+			deployments: []*appsv1.Deployment{
+				newDeployment("cluster1", "web", "east", 3),
+				newDeployment("cluster1", "web", "north", 4),
+			},
+			expected: map[string]int{"east": 0, "west": 2, "north": 4},
+		},
+		{
+			name:        "invalid override is ignored",
+			annotations: map[string]string{WeightsAnnotation: "east=-1"},
+			deployments: []*appsv1.Deployment{
+				newDeployment("cluster1", "web", "east", 3),
+			},
+			expected: map[string]int{"east": 3, "west": 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			deploymentIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, deployment := range tc.deployments {
+				require.NoError(t, deploymentIndexer.Add(deployment))
+			}
+			workloadClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, workloadCluster := range tc.workloadClusters {
+				require.NoError(t, workloadClusterIndexer.Add(workloadCluster))
+			}
+			c := &Controller{
+				deploymentLister:      appslisters.NewDeploymentLister(deploymentIndexer),
+				workloadClusterLister: workloadlisters.NewWorkloadClusterLister(workloadClusterIndexer),
+			}
+
+			root := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{ClusterName: "cluster1", Namespace: "ns1", Name: "root", Annotations: tc.annotations},
+			}
+			workloadClusters := make([]string, 0, len(tc.expected))
+			for workloadCluster := range tc.expected {
+				workloadClusters = append(workloadClusters, workloadCluster)
+			}
+
+			require.Equal(t, tc.expected, c.leafWeights(root, []*corev1.Service{web}, workloadClusters))
+		})
+	}
+}
@@ -19,10 +19,12 @@ package namespace
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kcp-dev/logicalcluster"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -36,6 +38,8 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	tenancyinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
 	workloadinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
 	tenancylisters "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
@@ -47,6 +51,7 @@ const controllerName = "kcp-workload-namespace"
 // NewController returns a new Controller which schedules namespaced resources to a Cluster.
 func NewController(
 	kubeClusterClient kubernetes.ClusterInterface,
+	kcpClusterClient *kcpclient.Cluster,
 	workspaceInformer tenancyinformers.ClusterWorkspaceInformer,
 	clusterInformer workloadinformer.WorkloadClusterInformer,
 	clusterLister workloadlisters.WorkloadClusterLister,
@@ -64,10 +69,11 @@ func NewController(
 		clusterQueue:   clusterQueue,
 		workspaceQueue: workspaceQueue,
 
-		workspaceLister: workspaceLister,
-		clusterLister:   clusterLister,
-		namespaceLister: namespaceLister,
-		kubeClient:      kubeClusterClient,
+		workspaceLister:  workspaceLister,
+		clusterLister:    clusterLister,
+		namespaceLister:  namespaceLister,
+		kubeClient:       kubeClusterClient,
+		kcpClusterClient: kcpClusterClient,
 	}
 
 	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -79,9 +85,12 @@ func NewController(
 	namespaceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: filterNamespace,
 		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc:    func(obj interface{}) { c.enqueueNamespace(obj) },
-			UpdateFunc: func(_, obj interface{}) { c.enqueueNamespace(obj) },
-			DeleteFunc: nil, // Nothing to do.
+			AddFunc: func(obj interface{}) { c.enqueueNamespace(obj) },
+			UpdateFunc: func(old, obj interface{}) {
+				c.enqueueNamespace(obj)
+				c.enqueueReleasedClusters(old, obj)
+			},
+			DeleteFunc: func(obj interface{}) { c.enqueueReleasedClusters(obj, nil) },
 		},
 	})
 
@@ -99,10 +108,11 @@ type Controller struct {
 	clusterQueue   workqueue.RateLimitingInterface
 	workspaceQueue workqueue.RateLimitingInterface
 
-	clusterLister   workloadlisters.WorkloadClusterLister
-	namespaceLister corelisters.NamespaceLister
-	workspaceLister tenancylisters.ClusterWorkspaceLister
-	kubeClient      kubernetes.ClusterInterface
+	clusterLister    workloadlisters.WorkloadClusterLister
+	namespaceLister  corelisters.NamespaceLister
+	workspaceLister  tenancylisters.ClusterWorkspaceLister
+	kubeClient       kubernetes.ClusterInterface
+	kcpClusterClient *kcpclient.Cluster
 }
 
 func filterNamespace(obj interface{}) bool {
@@ -142,6 +152,32 @@ func (c *Controller) enqueueCluster(obj interface{}) {
 	c.clusterQueue.Add(key)
 }
 
+// enqueueReleasedClusters enqueues the clusters whose state label was removed from
+// a namespace, such that their Drained condition is updated.
+func (c *Controller) enqueueReleasedClusters(oldObj, newObj interface{}) {
+	if tombstone, ok := oldObj.(cache.DeletedFinalStateUnknown); ok {
+		oldObj = tombstone.Obj
+	}
+	oldNs, ok := oldObj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	var newLabels map[string]string
+	if newNs, ok := newObj.(*corev1.Namespace); ok {
+		newLabels = newNs.Labels
+	}
+	for k := range oldNs.Labels {
+		if !strings.HasPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix) {
+			continue
+		}
+		if _, found := newLabels[k]; found {
+			continue
+		}
+		clusterName := strings.TrimPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix)
+		c.clusterQueue.Add(clusters.ToClusterAwareKey(logicalcluster.From(oldNs), clusterName))
+	}
+}
+
 func (c *Controller) enqueueClusterAfter(obj interface{}, dur time.Duration) {
 	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/clusters"
 	"k8s.io/klog/v2"
 
@@ -168,6 +170,10 @@ func schedulingClusterLabelPatchBytes(oldClusterName, newClusterName string) (ty
 func (c *Controller) observeCluster(ctx context.Context, cluster *workloadv1alpha1.WorkloadCluster) error {
 	klog.V(2).Infof("Observing WorkloadCluster %s|%s", logicalcluster.From(cluster), cluster.Name)
 
+	if err := c.ensureDrainedCondition(ctx, cluster); err != nil {
+		return err
+	}
+
 	strategy, pendingCordon := enqueueStrategyForCluster(cluster)
 
 	if pendingCordon {
@@ -201,6 +207,83 @@ func (c *Controller) observeCluster(ctx context.Context, cluster *workloadv1alph
 	return nil
 }
 
+// ensureDrainedCondition ensures the Drained condition of the given cluster reflects
+// whether namespaces are still assigned to it while it is unschedulable.
+func (c *Controller) ensureDrainedCondition(ctx context.Context, cluster *workloadv1alpha1.WorkloadCluster) error {
+	var remaining []string
+	if cluster.Spec.Unschedulable {
+		assigned, err := labels.NewRequirement(workloadv1alpha1.InternalClusterResourceStateLabelPrefix+cluster.Name, selection.Exists, nil)
+		if err != nil {
+			return err
+		}
+		// TODO(ncdc): use cluster scoped generated lister when available
+		namespaces, err := c.namespaceLister.List(labels.NewSelector().Add(*assigned))
+		if err != nil {
+			return err
+		}
+		for _, ns := range namespaces {
+			if logicalcluster.From(ns) == logicalcluster.From(cluster) {
+				remaining = append(remaining, ns.Name)
+			}
+		}
+	}
+
+	updated := setDrainedCondition(cluster, remaining)
+	if equality.Semantic.DeepEqual(cluster.Status, updated.Status) {
+		return nil
+	}
+
+	if _, err := c.kcpClusterClient.Cluster(logicalcluster.From(cluster)).WorkloadV1alpha1().WorkloadClusters().UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update status of WorkloadCluster %s|%s: %w", logicalcluster.From(cluster), cluster.Name, err)
+	}
+	return nil
+}
+
+// setDrainedCondition returns a copy of the given cluster with the Drained condition
+// set according to the namespaces still assigned to it, and whether the drain controller
+// reported them as blocked. The condition is removed from schedulable clusters.
+func setDrainedCondition(cluster *workloadv1alpha1.WorkloadCluster, remaining []string) *workloadv1alpha1.WorkloadCluster {
+	updated := cluster.DeepCopy()
+	switch {
+	case !cluster.Spec.Unschedulable:
+		conditions.Delete(updated, workloadv1alpha1.WorkloadClusterDrained)
+	case len(remaining) > 0:
+		if reasons := blockedReasons(cluster, remaining); reasons != nil {
+			conditions.MarkFalse(updated, workloadv1alpha1.WorkloadClusterDrained, workloadv1alpha1.NamespacesBlockedReason,
+				conditionsapi.ConditionSeverityWarning, "%d namespaces cannot be moved: %s", len(remaining), strings.Join(reasons, "; "))
+			break
+		}
+		conditions.MarkFalse(updated, workloadv1alpha1.WorkloadClusterDrained, workloadv1alpha1.NamespacesRemainingReason,
+			conditionsapi.ConditionSeverityInfo, "%d namespaces are still assigned", len(remaining))
+	default:
+		conditions.MarkTrue(updated, workloadv1alpha1.WorkloadClusterDrained)
+	}
+	return updated
+}
+
+// blockedReasons returns why each of the remaining namespaces cannot be moved off the cluster,
+// or nil if the drain controller did not report all of them as blocked.
+func blockedReasons(cluster *workloadv1alpha1.WorkloadCluster, remaining []string) []string {
+	value, found := cluster.Annotations[workloadv1alpha1.DrainBlockedAnnotation]
+	if !found {
+		return nil
+	}
+	var blocked map[string]string
+	if err := json.Unmarshal([]byte(value), &blocked); err != nil {
+		klog.Errorf("Invalid %s annotation on WorkloadCluster %s|%s: %v", workloadv1alpha1.DrainBlockedAnnotation, logicalcluster.From(cluster), cluster.Name, err)
+		return nil
+	}
+	reasons := make([]string, 0, len(remaining))
+	for _, name := range sets.NewString(remaining...).List() {
+		reason, found := blocked[name]
+		if !found {
+			return nil
+		}
+		reasons = append(reasons, name+": "+reason)
+	}
+	return reasons
+}
+
 // enqueueNamespaces adds all namespaces matching selector to the queue to allow for scheduling.
 func (c *Controller) enqueueNamespaces(clusterName logicalcluster.Name, selector labels.Selector) error {
 	// TODO(ncdc): use cluster scoped generated lister when available
@@ -23,6 +23,7 @@ import (
 	"github.com/kcp-dev/logicalcluster"
 	"github.com/stretchr/testify/require"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
@@ -140,3 +141,89 @@ func TestIsWorkspaceSchedulable(t *testing.T) {
 		})
 	}
 }
+
+func TestSetDrainedCondition(t *testing.T) {
+	testCases := map[string]struct {
+		unschedulable bool
+		drained       bool
+		remaining     []string
+		blocked       string
+		expected      *conditionsapi.Condition
+	}{
+		"schedulable -> no condition": {
+			remaining: []string{"a", "b"},
+		},
+		"schedulable, previously drained -> condition removed": {
+			drained: true,
+		},
+		"unschedulable with namespaces -> not drained": {
+			unschedulable: true,
+			remaining:     []string{"a", "b"},
+			expected: &conditionsapi.Condition{
+				Type:     workloadv1alpha1.WorkloadClusterDrained,
+				Status:   corev1.ConditionFalse,
+				Severity: conditionsapi.ConditionSeverityInfo,
+				Reason:   workloadv1alpha1.NamespacesRemainingReason,
+				Message:  "2 namespaces are still assigned",
+			},
+		},
+		"unschedulable with some blocked namespaces -> not drained": {
+			unschedulable: true,
+			remaining:     []string{"a", "b"},
+			blocked:       `{"a":"scheduling is disabled for the namespace"}`,
+			expected: &conditionsapi.Condition{
+				Type:     workloadv1alpha1.WorkloadClusterDrained,
+				Status:   corev1.ConditionFalse,
+				Severity: conditionsapi.ConditionSeverityInfo,
+				Reason:   workloadv1alpha1.NamespacesRemainingReason,
+				Message:  "2 namespaces are still assigned",
+			},
+		},
+		"unschedulable with only blocked namespaces -> blocked": {
+			unschedulable: true,
+			remaining:     []string{"b", "a"},
+			blocked:       `{"a":"scheduling is disabled for the namespace","b":"no other ready workload cluster in the same location"}`,
+			expected: &conditionsapi.Condition{
+				Type:     workloadv1alpha1.WorkloadClusterDrained,
+				Status:   corev1.ConditionFalse,
+				Severity: conditionsapi.ConditionSeverityWarning,
+				Reason:   workloadv1alpha1.NamespacesBlockedReason,
+				Message:  "2 namespaces cannot be moved: a: scheduling is disabled for the namespace; b: no other ready workload cluster in the same location",
+			},
+		},
+		"unschedulable without namespaces -> drained": {
+			unschedulable: true,
+			expected: &conditionsapi.Condition{
+				Type:   workloadv1alpha1.WorkloadClusterDrained,
+				Status: corev1.ConditionTrue,
+			},
+		},
+	}
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			cluster := &workloadv1alpha1.WorkloadCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-cluster",
+				},
+				Spec: workloadv1alpha1.WorkloadClusterSpec{
+					Unschedulable: testCase.unschedulable,
+				},
+			}
+			if testCase.drained {
+				conditions.MarkTrue(cluster, workloadv1alpha1.WorkloadClusterDrained)
+			}
+			if testCase.blocked != "" {
+				cluster.Annotations = map[string]string{workloadv1alpha1.DrainBlockedAnnotation: testCase.blocked}
+			}
+			updated := setDrainedCondition(cluster, testCase.remaining)
+			condition := conditions.Get(updated, workloadv1alpha1.WorkloadClusterDrained)
+			if testCase.expected == nil {
+				require.Nil(t, condition)
+				return
+			}
+			require.NotNil(t, condition)
+			condition.LastTransitionTime = metav1.Time{}
+			require.Equal(t, testCase.expected, condition)
+		})
+	}
+}
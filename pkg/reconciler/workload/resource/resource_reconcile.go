@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/kcp-dev/logicalcluster"
 
@@ -29,15 +30,15 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/clusters"
 	"k8s.io/klog/v2"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
-	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 )
 
-// reconcileResource is responsible for setting the cluster for a resource of
-// any type, to match the cluster where its namespace is assigned.
+// reconcileResource is responsible for setting the clusters for a resource of
+// any type, to match the clusters where its namespace is assigned.
 func (c *Controller) reconcileResource(ctx context.Context, lclusterName logicalcluster.Name, unstr *unstructured.Unstructured, gvr *schema.GroupVersionResource) error {
 	if gvr.Group == "networking.k8s.io" && gvr.Resource == "ingresses" {
 		klog.V(4).Infof("Skipping reconciliation of ingress %s/%s", unstr.GetNamespace(), unstr.GetName())
@@ -65,19 +66,15 @@ func (c *Controller) reconcileResource(ctx context.Context, lclusterName logical
 		return fmt.Errorf("error reconciling resource %s|%s/%s: error getting namespace: %w", lclusterName, unstr.GetNamespace(), unstr.GetName(), err)
 	}
 
-	lbls := unstr.GetLabels()
-	if lbls == nil {
-		lbls = map[string]string{}
-	}
-	//nolint:staticcheck
-	previousCluster, newCluster := shared.DeprecatedGetAssignedWorkloadCluster(lbls), shared.DeprecatedGetAssignedWorkloadCluster(ns.Labels)
-	if previousCluster == newCluster {
-		// Already assigned to the right cluster.
+	previousClusters, newClusters := syncingClusters(unstr.GetLabels()), syncingClusters(ns.Labels)
+	if previousClusters.Equal(newClusters) {
+		// Already assigned to the right clusters.
 		return nil
 	}
 
-	// Update the resource's assignment.
-	patchType, patchBytes, err := clusterLabelPatchBytes(previousCluster, newCluster)
+	// Update the resource's assignments. A namespace is assigned to more than one
+	// cluster while it is being moved during a drain.
+	patchType, patchBytes, err := clusterLabelPatchBytes(previousClusters, newClusters)
 	if err != nil {
 		klog.Errorf("error creating patch for %s %s|%s: %v", gvr.String(), unstr.GetClusterName(), unstr.GetName(), err)
 		return err
@@ -87,12 +84,24 @@ func (c *Controller) reconcileResource(ctx context.Context, lclusterName logical
 		Patch(ctx, unstr.GetName(), patchType, patchBytes, metav1.PatchOptions{}); err != nil {
 		return err
 	} else {
-		klog.V(2).Infof("Patched cluster assignment for %q %s|%s/%s: %q -> %q. Labels=%v",
-			gvr, lclusterName, ns.Name, unstr.GetName(), previousCluster, newCluster, updated.GetLabels())
+		klog.V(2).Infof("Patched cluster assignment for %q %s|%s/%s: %v -> %v. Labels=%v",
+			gvr, lclusterName, ns.Name, unstr.GetName(), previousClusters.List(), newClusters.List(), updated.GetLabels())
 	}
 	return nil
 }
 
+// syncingClusters returns the names of the workload clusters with a state label
+// in Sync state.
+func syncingClusters(labels map[string]string) sets.String {
+	clusters := sets.NewString()
+	for k, v := range labels {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix) && v == string(workloadv1alpha1.ResourceStateSync) {
+			clusters.Insert(strings.TrimPrefix(k, workloadv1alpha1.InternalClusterResourceStateLabelPrefix))
+		}
+	}
+	return clusters
+}
+
 func (c *Controller) reconcileGVR(gvr schema.GroupVersionResource) error {
 	// Update all resources in the namespace with the cluster assignment.
 	listers, _ := c.ddsif.Listers()
@@ -114,21 +123,26 @@ func (c *Controller) reconcileGVR(gvr schema.GroupVersionResource) error {
 }
 
 // clusterLabelPatchBytes returns a patch expressing an operation
-// to add, replace to the given value, or delete the cluster assignment label on
-// a resource.
-func clusterLabelPatchBytes(old, new string) (types.PatchType, []byte, error) {
-	patches := make(map[string]interface{})
-
-	if new == "" && old != "" {
-		patches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+old] = nil
-	} else if new != "" && old == "" {
-		patches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+new] = string(workloadv1alpha1.ResourceStateSync)
-	} else {
-		patches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+old] = nil
-		patches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+new] = string(workloadv1alpha1.ResourceStateSync)
+// to add the cluster assignment labels of the new clusters, and to delete
+// those of the old clusters that are not assigned anymore, together with their
+// synced annotations.
+func clusterLabelPatchBytes(old, new sets.String) (types.PatchType, []byte, error) {
+	labelPatches := make(map[string]interface{})
+	annotationPatches := make(map[string]interface{})
+
+	for _, cluster := range old.Difference(new).List() {
+		labelPatches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+cluster] = nil
+		annotationPatches[workloadv1alpha1.InternalClusterSyncedAnnotationPrefix+cluster] = nil
+	}
+	for _, cluster := range new.Difference(old).List() {
+		labelPatches[workloadv1alpha1.InternalClusterResourceStateLabelPrefix+cluster] = string(workloadv1alpha1.ResourceStateSync)
 	}
 
-	bs, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": patches}})
+	metadata := map[string]interface{}{"labels": labelPatches}
+	if len(annotationPatches) > 0 {
+		metadata["annotations"] = annotationPatches
+	}
+	bs, err := json.Marshal(map[string]interface{}{"metadata": metadata})
 	if err != nil {
 		return "", nil, err
 	}
@@ -31,6 +31,7 @@ import (
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -50,6 +51,8 @@ import (
 	configuniversal "github.com/kcp-dev/kcp/config/universal"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	schedulinginformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/scheduling/v1alpha1"
+	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
 	metadataclient "github.com/kcp-dev/kcp/pkg/metadata"
 	"github.com/kcp-dev/kcp/pkg/reconciler/apis/apibinding"
 	"github.com/kcp-dev/kcp/pkg/reconciler/apis/apiexport"
@@ -61,10 +64,12 @@ import (
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacedeletion"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspaceshard"
 	workloadsapiexport "github.com/kcp-dev/kcp/pkg/reconciler/workload/apiexport"
+	workloaddrain "github.com/kcp-dev/kcp/pkg/reconciler/workload/drain"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workload/heartbeat"
 	workloadnamespace "github.com/kcp-dev/kcp/pkg/reconciler/workload/namespace"
 	workloadresource "github.com/kcp-dev/kcp/pkg/reconciler/workload/resource"
 	virtualworkspaceurlscontroller "github.com/kcp-dev/kcp/pkg/reconciler/workload/virtualworkspaceurls"
+	"github.com/kcp-dev/kcp/pkg/sharding"
 )
 
 func (s *Server) installClusterRoleAggregationController(ctx context.Context, config *rest.Config) error {
@@ -266,6 +271,25 @@ func (s *Server) installRootCAConfigMapController(ctx context.Context, config *r
 	return nil
 }
 
+func (s *Server) installShardDiscoveryController(ctx context.Context, loader *sharding.ClientLoader) {
+	c := sharding.NewShardDiscoveryController(
+		loader,
+		s.rootKcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaceShards(),
+		s.rootKubeSharedInformerFactory.Core().V1().Secrets(),
+	)
+
+	s.AddPostStartHook("kcp-start-shard-discovery", func(hookContext genericapiserver.PostStartHookContext) error {
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			klog.Errorf("failed to finish post-start-hook kcp-start-shard-discovery: %v", err)
+			// nolint:nilerr
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go c.Start(ctx)
+		return nil
+	})
+}
+
 func readCA(file string) ([]byte, error) {
 	rootCA, err := ioutil.ReadFile(file)
 	if err != nil {
@@ -324,9 +348,14 @@ func (s *Server) installWorkloadNamespaceScheduler(ctx context.Context, config *
 	if err != nil {
 		return err
 	}
+	kcpClusterClient, err := kcpclient.NewClusterForConfig(config)
+	if err != nil {
+		return err
+	}
 
 	namespaceScheduler := workloadnamespace.NewController(
 		kubeClient,
+		kcpClusterClient,
 		s.kcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaces(),
 		s.kcpSharedInformerFactory.Workload().V1alpha1().WorkloadClusters(),
 		s.kcpSharedInformerFactory.Workload().V1alpha1().WorkloadClusters().Lister(),
@@ -347,6 +376,49 @@ func (s *Server) installWorkloadNamespaceScheduler(ctx context.Context, config *
 	return nil
 }
 
+func (s *Server) installWorkloadDrainController(ctx context.Context, config *rest.Config) error {
+	config = rest.AddUserAgent(rest.CopyConfig(config), "kcp-workload-drain")
+	kubeClient, err := kubernetes.NewClusterForConfig(config)
+	if err != nil {
+		return err
+	}
+	kcpClusterClient, err := kcpclient.NewClusterForConfig(config)
+	if err != nil {
+		return err
+	}
+	metadataClusterClient, err := metadataclient.NewDynamicMetadataClusterClientForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	var locationInformer schedulinginformers.LocationInformer
+	if utilfeature.DefaultFeatureGate.Enabled(kcpfeatures.LocationAPI) {
+		locationInformer = s.kcpSharedInformerFactory.Scheduling().V1alpha1().Locations()
+	}
+
+	drainController := workloaddrain.NewController(
+		kubeClient,
+		kcpClusterClient,
+		metadataClusterClient,
+		kubeClient.DiscoveryClient,
+		s.kcpSharedInformerFactory.Workload().V1alpha1().WorkloadClusters(),
+		s.kubeSharedInformerFactory.Core().V1().Namespaces(),
+		locationInformer,
+	)
+
+	s.AddPostStartHook("kcp-install-workload-drain-controller", func(hookContext genericapiserver.PostStartHookContext) error {
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			klog.Errorf("failed to finish post-start-hook kcp-install-workload-drain-controller: %v", err)
+			// nolint:nilerr
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go drainController.Start(ctx, 2)
+		return nil
+	})
+	return nil
+}
+
 func (s *Server) installWorkloadResourceScheduler(ctx context.Context, config *rest.Config) error {
 	config = rest.AddUserAgent(rest.CopyConfig(config), "kcp-workload-resource-scheduler")
 	kubeClient, err := kubernetes.NewClusterForConfig(config)
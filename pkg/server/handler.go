@@ -286,6 +286,17 @@ func WithWildcardIdentity(handler http.Handler) http.Handler {
 	})
 }
 
+// requestAttributes returns the logical cluster and the request info of a request
+// for the per-workspace request metrics.
+func requestAttributes(req *http.Request) (logicalcluster.Name, *request.RequestInfo) {
+	var clusterName logicalcluster.Name
+	if cluster := request.ClusterFrom(req.Context()); cluster != nil {
+		clusterName = cluster.Name
+	}
+	requestInfo, _ := request.RequestInfoFrom(req.Context())
+	return clusterName, requestInfo
+}
+
 func processResourceIdentity(req *http.Request, requestInfo *request.RequestInfo) (*http.Request, error) {
 	if !requestInfo.IsResourceRequest {
 		return req, nil
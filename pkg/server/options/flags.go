@@ -36,6 +36,8 @@ var (
 		"KCP Authentication",
 		"KCP Authorization",
 		"KCP Virtual Workspaces",
+		"KCP Workspace Fairness",
+		"KCP Workspace Metrics",
 		"KCP Controllers",
 		"KCP",
 	}
@@ -206,7 +208,18 @@ var (
 		"proxy-client-key-file",                 // Private key for the client certificate used to prove the identity of the aggregator or kube-apiserver when it must call out during a request. This includes proxying requests to a user api-server and calling out to webhook admission plugins.
 
 		// KCP Virtual Workspaces flags
-		"virtual-workspace-address", // Address of a stand-alone virtual workspace apiserver.
+		"virtual-workspace-address",                        // Address of a stand-alone virtual workspace apiserver.
+		"virtual-workspaces-workspaces-deletion-retention", // The time deleted workspaces are kept in the Deleting phase, inaccessible but intact, before their content is purged.
+
+		// KCP Workspace Fairness flags
+		"workspace-fairness",                   // Queue requests by logical cluster and dispatch them fairly between workspaces.
+		"workspace-fairness-config-file",       // File with the priority levels and flow schemas for workspace fairness. If empty, a default configuration is used.
+		"workspace-fairness-concurrency-limit", // Maximum number of requests executing concurrently, divided between the priority levels by their shares.
+
+		// KCP Workspace Metrics flags
+		"workspace-metrics-depth",          // Depth to which logical clusters are aggregated in the workspace label of request metrics, e.g. 2 for root:<org>. 0 keeps the full logical cluster names.
+		"workspace-metrics-max-workspaces", // Maximum number of workspace label values in request metrics. Workspaces beyond are counted as "other".
+		"access-log",                       // Log every request with user, logical cluster, verb, resource and response code.
 	)
 
 	disallowedFlags = sets.NewString(
@@ -37,6 +37,8 @@ import (
 	kcpadmission "github.com/kcp-dev/kcp/pkg/admission"
 	_ "github.com/kcp-dev/kcp/pkg/features"
 	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
+	flowcontroloptions "github.com/kcp-dev/kcp/pkg/flowcontrol/options"
+	workspacemetricsoptions "github.com/kcp-dev/kcp/pkg/workspacemetrics/options"
 )
 
 type Options struct {
@@ -46,6 +48,8 @@ type Options struct {
 	Authorization       Authorization
 	AdminAuthentication AdminAuthentication
 	Virtual             Virtual
+	WorkspaceFairness   flowcontroloptions.WorkspaceFairness
+	WorkspaceMetrics    workspacemetricsoptions.WorkspaceMetrics
 
 	Extra ExtraOptions
 }
@@ -66,6 +70,8 @@ type completedOptions struct {
 	Authorization       Authorization
 	AdminAuthentication AdminAuthentication
 	Virtual             Virtual
+	WorkspaceFairness   flowcontroloptions.WorkspaceFairness
+	WorkspaceMetrics    workspacemetricsoptions.WorkspaceMetrics
 
 	Extra ExtraOptions
 }
@@ -85,6 +91,8 @@ func NewOptions() *Options {
 		Authorization:       *NewAuthorization(),
 		AdminAuthentication: *NewAdminAuthentication(),
 		Virtual:             *NewVirtual(),
+		WorkspaceFairness:   *flowcontroloptions.NewWorkspaceFairness(),
+		WorkspaceMetrics:    *workspacemetricsoptions.NewWorkspaceMetrics(),
 
 		Extra: ExtraOptions{
 			RootDirectory:            ".kcp",
@@ -143,6 +151,8 @@ func (o *Options) rawFlags() cliflag.NamedFlagSets {
 	o.Authorization.AddFlags(fss.FlagSet("KCP Authorization"))
 	o.AdminAuthentication.AddFlags(fss.FlagSet("KCP Authentication"))
 	o.Virtual.AddFlags(fss.FlagSet("KCP Virtual Workspaces"))
+	o.WorkspaceFairness.AddFlags(fss.FlagSet("KCP Workspace Fairness"))
+	o.WorkspaceMetrics.AddFlags(fss.FlagSet("KCP Workspace Metrics"))
 
 	fs := fss.FlagSet("KCP")
 	fs.StringVar(&o.Extra.ProfilerAddress, "profiler-address", o.Extra.ProfilerAddress, "[Address]:port to bind the profiler to")
@@ -172,6 +182,8 @@ func (o *CompletedOptions) Validate() []error {
 	errs = append(errs, o.Authorization.Validate()...)
 	errs = append(errs, o.AdminAuthentication.Validate()...)
 	errs = append(errs, o.Virtual.Validate()...)
+	errs = append(errs, o.WorkspaceFairness.Validate()...)
+	errs = append(errs, o.WorkspaceMetrics.Validate()...)
 
 	if o.Extra.DiscoveryPollInterval == 0 {
 		errs = append(errs, fmt.Errorf("--discovery-poll-interval not set"))
@@ -259,6 +271,8 @@ func (o *Options) Complete() (*CompletedOptions, error) {
 			Authorization:       o.Authorization,
 			AdminAuthentication: o.AdminAuthentication,
 			Virtual:             o.Virtual,
+			WorkspaceFairness:   o.WorkspaceFairness,
+			WorkspaceMetrics:    o.WorkspaceMetrics,
 			Extra:               o.Extra,
 		},
 	}, nil
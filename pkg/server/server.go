@@ -55,12 +55,17 @@ import (
 	kcpexternalversions "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	"github.com/kcp-dev/kcp/pkg/etcd"
 	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
+	"github.com/kcp-dev/kcp/pkg/flowcontrol"
 	kcpserveroptions "github.com/kcp-dev/kcp/pkg/server/options"
 	"github.com/kcp-dev/kcp/pkg/sharding"
+	"github.com/kcp-dev/kcp/pkg/workspacemetrics"
 )
 
 const resyncPeriod = 10 * time.Hour
 
+// rootShardName is the name of the ClusterWorkspaceShard this server bootstraps for itself.
+const rootShardName = "root"
+
 // Server manages the configuration and kcp api-server. It allows callers to easily use kcp
 // as a library rather than as a single binary. Using its constructor function, you can easily
 // setup a new api-server and start it:
@@ -195,6 +200,26 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 
+	workspaceFairness, err := s.options.WorkspaceFairness.NewController(s.kcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaces().Lister())
+	if err != nil {
+		return err
+	}
+	s.options.WorkspaceFairness.ApplyTo(genericConfig)
+
+	workspaceMetrics := s.options.WorkspaceMetrics.NewRecorder()
+
+	// the shards wildcard requests are fanned out to: this shard, the peer shards of the
+	// --shard-kubeconfig-file and the shards discovered from ClusterWorkspaceShards.
+	var shardClientLoader *sharding.ClientLoader
+	if s.options.Extra.EnableSharding {
+		shardClientLoader = sharding.NewClientLoader()
+		if s.options.Extra.ShardKubeconfigFile != "" {
+			if err := shardClientLoader.AddKubeConfigContexts(s.options.Extra.ShardKubeconfigFile); err != nil {
+				return fmt.Errorf("failed to load --shard-kubeconfig-file: %w", err)
+			}
+		}
+	}
+
 	// preHandlerChainMux is called before the actual handler chain. Note that BuildHandlerChainFunc below
 	// is called multiple times, but only one of the handler chain will actually be used. Hence, we wrap it
 	// to give handlers below one mux.Handle func to call.
@@ -205,13 +230,17 @@ func (s *Server) Run(ctx context.Context) error {
 		// - shard proxy (sharding.ServeHTTP)
 		// - original handler chain
 		// the lcluster handler is a pass-through, not a delegate, so the wrapping looks weird
+		apiHandler = WithWildcardListWatchGuard(apiHandler)
 		if s.options.Extra.EnableSharding {
-			clientLoader := sharding.NewClientLoader()
-			clientLoader.Add(genericConfig.ExternalAddress, genericConfig.LoopbackClientConfig)
-			apiHandler = sharding.WithSharding(apiHandler, clientLoader)
+			// this shard is reached through loopback, overriding its discovered ClusterWorkspaceShard.
+			// Wildcard gets are fanned out to the shards, hence the sharding handler wraps the guard.
+			// Wildcard mutations are rejected as not supported by the sharded storage instead.
+			shardClientLoader.Add(rootShardName, genericConfig.LoopbackClientConfig)
+			apiHandler = sharding.WithSharding(apiHandler, shardClientLoader)
 		}
-		apiHandler = WithWildcardListWatchGuard(apiHandler)
 		apiHandler = WithWildcardIdentity(apiHandler)
+		apiHandler = flowcontrol.WithPriorityAndFairness(apiHandler, workspaceFairness, c.LongRunningFunc)
+		apiHandler = workspacemetrics.WithRequestMetrics(apiHandler, workspaceMetrics, requestAttributes, c.LongRunningFunc)
 		apiHandler = genericapiserver.DefaultBuildHandlerChain(apiHandler, c)
 
 		// this will be replaced in DefaultBuildHandlerChain. So at worst we get twice as many warning.
@@ -240,6 +269,8 @@ func (s *Server) Run(ctx context.Context) error {
 		// The external address is provided as a function, as its value may be updated
 		// with the default secure port, when the config is later completed.
 		kcpadmissioninitializers.NewExternalAddressInitializer(func() string { return genericConfig.ExternalAddress }),
+		// Deleted workspaces are retained for as long as the workspaces virtual workspace soft-deletes them.
+		kcpadmissioninitializers.NewWorkspaceDeletionRetentionInitializer(s.options.Virtual.VirtualWorkspaces.Workspaces.DeletionRetention),
 	}
 
 	apisConfig, err := genericcontrolplane.CreateKubeAPIServerConfig(genericConfig, s.options.GenericControlPlane, s.kubeSharedInformerFactory, admissionPluginInitializers, storageFactory)
@@ -355,7 +386,7 @@ func (s *Server) Run(ctx context.Context) error {
 		if err := configroot.Bootstrap(goContext(ctx),
 			apiextensionsClusterClient.Cluster(v1alpha1.RootCluster).Discovery(),
 			dynamicClusterClient.Cluster(v1alpha1.RootCluster),
-			"root",
+			rootShardName,
 
 			// TODO(sttts): move away from loopback, use external advertise address, an external CA and an access header enabled client servingCert for authentication
 			clientcmdapi.Config{
@@ -406,6 +437,10 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 
+	if s.options.Extra.EnableSharding {
+		s.installShardDiscoveryController(ctx, shardClientLoader)
+	}
+
 	enabled := sets.NewString(s.options.Controllers.IndividuallyEnabled...)
 	if len(enabled) > 0 {
 		klog.Infof("Starting controllers individually: %v", enabled)
@@ -438,6 +473,9 @@ func (s *Server) Run(ctx context.Context) error {
 		if err := s.installWorkloadNamespaceScheduler(ctx, controllerConfig); err != nil {
 			return err
 		}
+		if err := s.installWorkloadDrainController(ctx, controllerConfig); err != nil {
+			return err
+		}
 	}
 
 	if s.options.Controllers.EnableAll || enabled.Has("resource-scheduler") {
@@ -473,7 +511,7 @@ func (s *Server) Run(ctx context.Context) error {
 	}
 
 	if s.options.Virtual.Enabled {
-		if err := s.installVirtualWorkspaces(ctx, kubeClusterClient, dynamicClusterClient, kcpClusterClient, genericConfig.Authentication, genericConfig.ExternalAddress, preHandlerChainMux); err != nil {
+		if err := s.installVirtualWorkspaces(ctx, kubeClusterClient, dynamicClusterClient, kcpClusterClient, genericConfig.Authentication, genericConfig.ExternalAddress, workspaceFairness, preHandlerChainMux); err != nil {
 			return err
 		}
 	} else if err := s.installVirtualWorkspacesRedirect(ctx, preHandlerChainMux); err != nil {
@@ -57,20 +57,24 @@ import (
 	"k8s.io/kubernetes/pkg/printers/storage"
 )
 
-func NewShardedHandler(clients map[string]*clientrest.Config, maxRequestBodyBytes int64, requestTimeout time.Duration) *ShardedHandler {
+// NewShardedHandler returns a handler fanning out requests to the given shards. shardResourceVersion
+// is the version of the set of shards, encoded into resource versions and continue tokens.
+func NewShardedHandler(clients map[string]*clientrest.Config, shardResourceVersion int64, maxRequestBodyBytes int64, requestTimeout time.Duration) *ShardedHandler {
 	return &ShardedHandler{
-		clients:             clients,
-		wg:                  &utilwaitgroup.SafeWaitGroup{},
-		maxRequestBodyBytes: maxRequestBodyBytes,
-		requestTimeout:      requestTimeout,
+		clients:              clients,
+		shardResourceVersion: shardResourceVersion,
+		wg:                   &utilwaitgroup.SafeWaitGroup{},
+		maxRequestBodyBytes:  maxRequestBodyBytes,
+		requestTimeout:       requestTimeout,
 	}
 }
 
 type ShardedHandler struct {
-	clients             map[string]*clientrest.Config
-	wg                  *utilwaitgroup.SafeWaitGroup
-	maxRequestBodyBytes int64
-	requestTimeout      time.Duration
+	clients              map[string]*clientrest.Config
+	shardResourceVersion int64
+	wg                   *utilwaitgroup.SafeWaitGroup
+	maxRequestBodyBytes  int64
+	requestTimeout       time.Duration
 }
 
 // Follow-ups:
@@ -121,7 +125,8 @@ func (h *ShardedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		},
 	}
 
-	// TODO: or, trust the delegate servers with table-writing and just pass along what they give?
+	// tables of wildcard requests are built from the tables of the shards, this is the fallback
+	// for everything else
 	tableGenerator := printers.NewTableGenerator()
 	if err := tableGenerator.DefaultHandler([]metav1.TableColumnDefinition{
 		{Name: "cluster", Type: "string", Description: metav1.ObjectMeta{}.SwaggerDoc()["cluster"], Priority: 0},
@@ -169,7 +174,7 @@ func (h *ShardedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		)
 		return
 	}
-	tableConverter := storage.TableConvertor{TableGenerator: tableGenerator}
+	tableConverter := tableConvertor{delegate: storage.TableConvertor{TableGenerator: tableGenerator}}
 
 	nopAuthorizer := authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
 		return authorizer.DecisionAllow, "", nil
@@ -249,7 +254,8 @@ func (h *ShardedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	s := NewMux(storageBase{
 		TableConvertor:                 tableConverter,
 		shards:                         h.clients,
-		shardIdentifierResourceVersion: 1,
+		shardIdentifierResourceVersion: h.shardResourceVersion,
+		tableOptions:                   tableOptionsFor(req),
 		requestFor:                     requestFor(cloned),
 		clientFor:                      clientFor(userInfo, negotiatedSerializer),
 	})
@@ -20,17 +20,27 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
 )
 
 type shardedStorage struct {
@@ -50,10 +60,16 @@ func (s *shardedStorage) Watch(ctx context.Context, options *internalversion.Lis
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse sharded resource version state: %w", err)
 	}
+	var stateIdentifiers []string
+	for _, rv := range state.ResourceVersions {
+		stateIdentifiers = append(stateIdentifiers, rv.Identifier)
+	}
+	if err := s.checkShards(state.ShardResourceVersion, stateIdentifiers); err != nil {
+		return nil, err
+	}
 
-	watchers := map[string]watch.Interface{}
-	for i := range state.ResourceVersions {
-		client, err := s.clientFor(s.shards[state.ResourceVersions[i].Identifier])
+	watchFor := func(ctx context.Context, identifier string, resourceVersion int64) (watch.Interface, error) {
+		client, err := s.clientFor(s.shards[identifier])
 		if err != nil {
 			return nil, fmt.Errorf("failed to create sharded client: %w", err)
 		}
@@ -62,94 +78,258 @@ func (s *shardedStorage) Watch(ctx context.Context, options *internalversion.Lis
 			return nil, fmt.Errorf("failed to create sharded request: %w", err)
 		}
 		request.OverwriteParam("limit", "500")
-		request.OverwriteParam("resourceVersion", strconv.FormatInt(state.ResourceVersions[i].ResourceVersion, 10))
+		request.OverwriteParam("resourceVersion", strconv.FormatInt(resourceVersion, 10))
+		// we always want bookmarks from the shards to make progress on quiet shards
+		request.OverwriteParam("allowWatchBookmarks", "true")
 		request.SetHeader("X-Kubernetes-Cluster", "*")
-		watcher, err := request.Watch(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("error executing watch request: %w", err)
-		}
-		watchers[state.ResourceVersions[i].Identifier] = watcher
+		return request.Watch(ctx)
 	}
 
-	return NewAggregateWatcher(state, watchers), nil
+	return NewAggregateWatcher(ctx, state, options.AllowWatchBookmarks, watchFor)
 }
 
-type stopper interface {
-	Stop()
+// shardWatchFunc starts a watch on the given shard at the given resource version.
+type shardWatchFunc func(ctx context.Context, identifier string, resourceVersion int64) (watch.Interface, error)
+
+// shardWatchBackoff is used to restart the watch of a shard after transient errors. When
+// the steps are exhausted, the aggregate watch fails.
+var shardWatchBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      30 * time.Second,
 }
 
+// aggregateWatcher merges the watches of all shards into one, with the composite resource
+// version of all shards on every event. The watches of the shards are restarted from their
+// last resource version when they end, and the aggregate watch only fails if this is not
+// possible.
 type aggregateWatcher struct {
-	delegates []stopper
-	wg        *sync.WaitGroup
-	events    chan watch.Event
+	ctx      context.Context
+	cancel   context.CancelFunc
+	watchFor shardWatchFunc
+	backoff  wait.Backoff
+
+	wg     sync.WaitGroup
+	events chan watch.Event
+
+	// allowBookmarks is true if the client wants bookmarks. They are sent when
+	// all shards have progressed since the last bookmark.
+	allowBookmarks bool
 
-	state *ShardedResourceVersions
-	lock  *sync.Mutex
+	lock       sync.Mutex
+	state      *ShardedResourceVersions
+	progressed sets.String
 }
 
 func (a *aggregateWatcher) Stop() {
-	for i := range a.delegates {
-		a.delegates[i].Stop()
-	}
-	a.wg.Wait()
-	close(a.events)
+	a.cancel()
 }
 
 func (a *aggregateWatcher) ResultChan() <-chan watch.Event {
 	return a.events
 }
 
+// send sends the event to the client unless the watch is stopped.
+func (a *aggregateWatcher) send(event watch.Event) bool {
+	select {
+	case a.events <- event:
+		return true
+	case <-a.ctx.Done():
+		return false
+	}
+}
+
+// fail sends the error to the client and stops the watch.
+func (a *aggregateWatcher) fail(err error) {
+	var status metav1.Status
+	if statusErr, ok := err.(errors.APIStatus); ok {
+		status = statusErr.Status()
+	} else {
+		status = errors.NewInternalError(err).ErrStatus
+	}
+	a.send(watch.Event{Type: watch.Error, Object: &status})
+	a.cancel()
+}
+
 func (a *aggregateWatcher) process(identifier string, event watch.Event) {
 	obj, ok := event.Object.(metav1.Common)
 	if !ok {
-		a.events <- watch.Event{
+		a.send(watch.Event{
 			Type:   watch.Error,
 			Object: &errors.NewInternalError(fmt.Errorf("watch event contained a %T which could not cast to metav1.Common", event.Object)).ErrStatus,
-		}
+		})
 		return
 	}
+
 	a.lock.Lock()
-	err := a.state.UpdateWith(identifier, obj)
+	encoded, err := a.updateWith(identifier, obj)
+	sendBookmark := event.Type == watch.Bookmark && a.allowBookmarks && a.progressed.Len() == len(a.state.ResourceVersions)
+	if sendBookmark {
+		a.progressed = sets.NewString()
+	}
 	a.lock.Unlock()
 	if err != nil {
-		a.events <- watch.Event{
+		a.send(watch.Event{
 			Type:   watch.Error,
-			Object: &errors.NewInternalError(fmt.Errorf("failed to update resource version vector clock: %w", err)).ErrStatus,
-		}
+			Object: &errors.NewInternalError(err).ErrStatus,
+		})
 		return
 	}
+
+	if event.Type == watch.Bookmark {
+		if !sendBookmark {
+			return
+		}
+		// a bookmark of a shard only carries the shard's own resource version
+		event.Object = event.Object.DeepCopyObject()
+		obj = event.Object.(metav1.Common)
+	}
+	obj.SetResourceVersion(encoded)
+	a.send(event)
+}
+
+// updateWith records the progress of a shard and returns the encoded composite resource version.
+// The lock must be held.
+func (a *aggregateWatcher) updateWith(identifier string, obj metav1.Common) (string, error) {
+	if err := a.state.UpdateWith(identifier, obj); err != nil {
+		return "", fmt.Errorf("failed to update resource version vector clock: %w", err)
+	}
+	a.progressed.Insert(identifier)
 	encoded, err := a.state.Encode()
 	if err != nil {
-		a.events <- watch.Event{
-			Type:   watch.Error,
-			Object: &errors.NewInternalError(fmt.Errorf("failed to encode resource version vector clock: %w", err)).ErrStatus,
+		return "", fmt.Errorf("failed to encode resource version vector clock: %w", err)
+	}
+	return encoded, nil
+}
+
+// consume processes the events of a shard watch until it ends. It returns an error if
+// the watch cannot be resumed.
+func (a *aggregateWatcher) consume(identifier string, w watch.Interface) error {
+	for {
+		select {
+		case <-a.ctx.Done():
+			return nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+			if event.Type == watch.Error {
+				err := errors.FromObject(event.Object)
+				if errors.IsResourceExpired(err) || errors.IsGone(err) {
+					return err
+				}
+				klog.V(4).Infof("Restarting watch of shard %q after error: %v", identifier, err)
+				return nil
+			}
+			a.process(identifier, event)
 		}
-		return
 	}
-	obj.SetResourceVersion(encoded)
-	a.events <- event
-}
-
-func NewAggregateWatcher(state *ShardedResourceVersions, delegates map[string]watch.Interface) watch.Interface {
-	w := &aggregateWatcher{
-		delegates: []stopper{},
-		events:    make(chan watch.Event),
-		wg:        &sync.WaitGroup{},
-		state:     state,
-		lock:      &sync.Mutex{},
-	}
-	for identifier := range delegates {
-		w.wg.Add(1)
-		go func(identifier string, events <-chan watch.Event) {
-			defer utilruntime.HandleCrash()
-			defer w.wg.Done()
-			for event := range events {
-				w.process(identifier, event)
+}
+
+// run consumes the watch of a shard, and restarts it from the last resource version of
+// the shard when it ends.
+func (a *aggregateWatcher) run(identifier string, w watch.Interface) {
+	defer utilruntime.HandleCrash()
+	defer a.wg.Done()
+
+	for {
+		err := a.consume(identifier, w)
+		w.Stop()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			a.fail(err)
+			return
+		}
+
+		a.lock.Lock()
+		resourceVersion, _ := a.state.ResourceVersionFor(identifier)
+		a.lock.Unlock()
+		if resourceVersion == 0 {
+			// without a resource version, a restarted watch would replay all objects
+			a.fail(errors.NewResourceExpired(fmt.Sprintf("watch of shard %q ended before it reported a resource version", identifier)))
+			return
+		}
+
+		if w, err = a.restart(identifier, resourceVersion); err != nil {
+			a.fail(err)
+			return
+		} else if w == nil {
+			return
+		}
+	}
+}
+
+// restart starts the watch of a shard again, retrying transient errors with backoff. It returns
+// a nil watch if the aggregate watch was stopped.
+func (a *aggregateWatcher) restart(identifier string, resourceVersion int64) (watch.Interface, error) {
+	backoff := a.backoff
+	for {
+		w, err := a.watchFor(a.ctx, identifier, resourceVersion)
+		if err == nil {
+			return w, nil
+		}
+		if errors.IsResourceExpired(err) || errors.IsGone(err) {
+			return nil, err
+		}
+		if backoff.Steps <= 1 {
+			return nil, fmt.Errorf("failed to restart watch of shard %q: %w", identifier, err)
+		}
+		klog.V(4).Infof("Failed to restart watch of shard %q: %v", identifier, err)
+
+		select {
+		case <-time.After(backoff.Step()):
+		case <-a.ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// NewAggregateWatcher starts the watches of all shards in the state at their resource versions,
+// and merges them into one watch. allowBookmarks enables bookmarks with the composite resource
+// version.
+func NewAggregateWatcher(ctx context.Context, state *ShardedResourceVersions, allowBookmarks bool, watchFor shardWatchFunc) (watch.Interface, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	a := &aggregateWatcher{
+		ctx:            ctx,
+		cancel:         cancel,
+		watchFor:       watchFor,
+		backoff:        shardWatchBackoff,
+		events:         make(chan watch.Event),
+		allowBookmarks: allowBookmarks,
+		state:          state,
+		progressed:     sets.NewString(),
+	}
+
+	watchers := make(map[string]watch.Interface, len(state.ResourceVersions))
+	for _, rv := range state.ResourceVersions {
+		w, err := watchFor(ctx, rv.Identifier, rv.ResourceVersion)
+		if err != nil {
+			cancel()
+			for _, w := range watchers {
+				w.Stop()
+			}
+			if _, ok := err.(errors.APIStatus); ok {
+				return nil, err
 			}
-		}(identifier, delegates[identifier].ResultChan())
-		w.delegates = append(w.delegates, delegates[identifier])
+			return nil, fmt.Errorf("error executing watch request: %w", err)
+		}
+		watchers[rv.Identifier] = w
+	}
+
+	for identifier, w := range watchers {
+		a.wg.Add(1)
+		go a.run(identifier, w)
 	}
-	return w
+	go func() {
+		a.wg.Wait()
+		close(a.events)
+	}()
+
+	return a, nil
 }
 
 var _ watch.Interface = &aggregateWatcher{}
@@ -168,7 +348,14 @@ func (s *shardedStorage) List(ctx context.Context, options *internalversion.List
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse sharded chunked state: %w", err)
 	}
-	var output *unstructured.UnstructuredList
+	var stateIdentifiers []string
+	for _, rv := range state.ResourceVersions {
+		stateIdentifiers = append(stateIdentifiers, rv.Identifier)
+	}
+	if err := s.checkShards(state.ShardResourceVersion, stateIdentifiers); err != nil {
+		return nil, err
+	}
+	var output runtime.Object
 	for {
 		shard, continueToken, err := state.NextQuery()
 		if err != nil {
@@ -185,52 +372,60 @@ func (s *shardedStorage) List(ctx context.Context, options *internalversion.List
 		request.OverwriteParam("limit", strconv.FormatInt(options.Limit, 10))
 		request.OverwriteParam("continue", continueToken)
 		request.SetHeader("X-Kubernetes-Cluster", "*")
+		if s.tableOptions != nil {
+			requestTable(request, s.tableOptions)
+		}
 		result, err := request.Do(ctx).Get()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get data from shard %q: %w", shard, err)
 		}
-		var list *unstructured.UnstructuredList
-		switch r := result.(type) {
-		case *unstructured.UnstructuredList:
-			list = r
-		case *unstructured.Unstructured:
-			var conversionErr error
-			list, conversionErr = r.ToList()
-			if conversionErr != nil {
-				return nil, fmt.Errorf("could not convert sharded response from %T to a list: %w", result, conversionErr)
-			}
-		default:
-			return nil, fmt.Errorf("could not parse sharded response as a list, got %T", result)
+		page, items, err := s.pageFrom(result)
+		if err != nil {
+			return nil, err
 		}
-		if err := state.UpdateWith(shard, list); err != nil {
+		if err := state.UpdateWith(shard, page); err != nil {
 			return nil, fmt.Errorf("could not update sharded state: %w", err)
 		}
 		updatedResourceVersion, err := state.ToResourceVersions().Encode()
 		if err != nil {
 			return nil, fmt.Errorf("could not format new resource version: %w", err)
 		}
-		list.SetResourceVersion(updatedResourceVersion)
-		for i := range list.Items {
+		page.SetResourceVersion(updatedResourceVersion)
+		for _, item := range items {
 			uniqueVersion := state.ToResourceVersions()
-			if err := uniqueVersion.UpdateWith(shard, &list.Items[i]); err != nil {
+			if err := uniqueVersion.UpdateWith(shard, item); err != nil {
 				return nil, fmt.Errorf("could not update sharded state: %w", err)
 			}
 			updatedUniqueVersion, err := uniqueVersion.Encode()
 			if err != nil {
 				return nil, fmt.Errorf("could not format new resource version: %w", err)
 			}
-			list.Items[i].SetResourceVersion(updatedUniqueVersion)
+			item.SetResourceVersion(updatedUniqueVersion)
 		}
 		updatedContinueToken, err := state.Encode()
 		if err != nil {
 			return nil, fmt.Errorf("could not format new continue token: %w", err)
 		}
-		list.SetContinue(updatedContinueToken)
-		if output == nil {
-			output = list
-		} else {
-			output.SetResourceVersion(updatedResourceVersion)
-			output.Items = append(output.Items, list.Items...)
+		page.SetContinue(updatedContinueToken)
+		switch p := page.(type) {
+		case *unstructured.UnstructuredList:
+			if output == nil {
+				output = p
+			} else {
+				list := output.(*unstructured.UnstructuredList)
+				list.SetResourceVersion(updatedResourceVersion)
+				list.SetContinue(updatedContinueToken)
+				list.Items = append(list.Items, p.Items...)
+			}
+		case *metav1.Table:
+			if output == nil {
+				output = &metav1.Table{TypeMeta: p.TypeMeta}
+			}
+			table := output.(*metav1.Table)
+			table.ListMeta = p.ListMeta
+			if err := appendTable(table, p); err != nil {
+				return nil, err
+			}
 		}
 		if clientWantsPaging || updatedContinueToken == "" {
 			break
@@ -239,9 +434,161 @@ func (s *shardedStorage) List(ctx context.Context, options *internalversion.List
 	return output, nil
 }
 
-type listWatcher interface {
+// pageFrom parses a page of a list from a shard, either a list or a table, and returns
+// the objects in it.
+func (s *shardedStorage) pageFrom(result runtime.Object) (metav1.ListInterface, []metav1.Common, error) {
+	if s.tableOptions != nil {
+		table, err := tableFrom(result)
+		if err != nil {
+			return nil, nil, err
+		}
+		objs, err := tableObjects(table)
+		if err != nil {
+			return nil, nil, err
+		}
+		return table, objs, nil
+	}
+
+	var list *unstructured.UnstructuredList
+	switch r := result.(type) {
+	case *unstructured.UnstructuredList:
+		list = r
+	case *unstructured.Unstructured:
+		var conversionErr error
+		list, conversionErr = r.ToList()
+		if conversionErr != nil {
+			return nil, nil, fmt.Errorf("could not convert sharded response from %T to a list: %w", result, conversionErr)
+		}
+	default:
+		return nil, nil, fmt.Errorf("could not parse sharded response as a list, got %T", result)
+	}
+	objs := make([]metav1.Common, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return list, objs, nil
+}
+
+// Get returns the object with the cluster-qualified name from the shard serving the
+// logical cluster. As the shards of the logical clusters are not known, all shards are asked.
+func (s *shardedStorage) Get(ctx context.Context, qualifiedName string, options *metav1.GetOptions) (runtime.Object, error) {
+	requestInfo, ok := request.RequestInfoFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("missing requestInfo")
+	}
+	groupResource := schema.GroupResource{Group: requestInfo.APIGroup, Resource: requestInfo.Resource}
+	clusterName, name := clusters.SplitClusterAwareKey(qualifiedName)
+	if clusterName.Empty() || name == "" {
+		return nil, errors.NewBadRequest(fmt.Sprintf("the name %q must be qualified with a logical cluster in a cross-cluster context, e.g. %q",
+			qualifiedName, clusters.ToClusterAwareKey(logicalcluster.New("root:org"), qualifiedName)))
+	}
+	path, err := unqualifiedPath(requestInfo.Path, qualifiedName, name)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	type response struct {
+		obj runtime.Object
+		err error
+	}
+	responses := make(chan response, len(s.shards))
+	for identifier := range s.shards {
+		go func(identifier string) {
+			obj, err := s.getFrom(ctx, identifier, clusterName, path, options)
+			responses <- response{obj: obj, err: err}
+		}(identifier)
+	}
+
+	var errs []error
+	for range s.shards {
+		r := <-responses
+		switch {
+		case r.err == nil:
+			return r.obj, nil
+		case !errors.IsNotFound(r.err):
+			errs = append(errs, r.err)
+		}
+	}
+	if len(errs) > 0 {
+		if _, ok := errs[0].(errors.APIStatus); ok {
+			return nil, errs[0]
+		}
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return nil, errors.NewNotFound(groupResource, qualifiedName)
+}
+
+// getFrom gets the object at the path in the logical cluster from the shard.
+func (s *shardedStorage) getFrom(ctx context.Context, identifier string, clusterName logicalcluster.Name, path string, options *metav1.GetOptions) (runtime.Object, error) {
+	client, err := s.clientFor(s.shards[identifier])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sharded client: %w", err)
+	}
+	request, err := s.requestFor(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sharded request: %w", err)
+	}
+	request.AbsPath(path)
+	if options.ResourceVersion != "" {
+		// a resource version of another shard does not mean anything here
+		if rv, err := collapseResourceVersion(&options.ResourceVersion, identifier); err == nil {
+			request.OverwriteParam("resourceVersion", rv)
+		} else {
+			request.OverwriteParam("resourceVersion", "")
+		}
+	}
+	request.SetHeader("X-Kubernetes-Cluster", clusterName.String())
+	if s.tableOptions != nil {
+		requestTable(request, s.tableOptions)
+	}
+	result, err := request.Do(ctx).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.tableOptions == nil {
+		if err := mutateOutputResourceVersion(identifier)(result); err != nil {
+			return nil, fmt.Errorf("failed to update response: %w", err)
+		}
+		return result, nil
+	}
+
+	table, err := tableFrom(result)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := tableObjects(table)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if err := inflateResourceVersion(obj.(runtime.Object), identifier); err != nil {
+			return nil, err
+		}
+	}
+	output := &metav1.Table{TypeMeta: table.TypeMeta, ListMeta: table.ListMeta}
+	if err := appendTable(output, table); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// unqualifiedPath replaces the cluster-qualified name in the request path with the plain name.
+func unqualifiedPath(path, qualifiedName, name string) (string, error) {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == qualifiedName {
+			segments[i] = name
+			return strings.Join(segments, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("name %q not found in path %q", qualifiedName, path)
+}
+
+type getListWatcher interface {
+	rest.Getter
 	rest.Lister
 	rest.Watcher
 }
 
-var _ listWatcher = &shardedStorage{}
+var _ getListWatcher = &shardedStorage{}
@@ -0,0 +1,236 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// fakeShards hands out fake watches and records at which resource versions they were started.
+type fakeShards struct {
+	lock     sync.Mutex
+	watchers map[string]*watch.FakeWatcher
+	started  map[string][]int64
+	errs     map[string]error
+}
+
+func newFakeShards() *fakeShards {
+	return &fakeShards{
+		watchers: map[string]*watch.FakeWatcher{},
+		started:  map[string][]int64{},
+		errs:     map[string]error{},
+	}
+}
+
+func (f *fakeShards) watchFor(_ context.Context, identifier string, resourceVersion int64) (watch.Interface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.started[identifier] = append(f.started[identifier], resourceVersion)
+	if err := f.errs[identifier]; err != nil {
+		return nil, err
+	}
+	w := watch.NewFakeWithChanSize(10, false)
+	f.watchers[identifier] = w
+	return w, nil
+}
+
+func (f *fakeShards) watcher(identifier string) *watch.FakeWatcher {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.watchers[identifier]
+}
+
+func (f *fakeShards) startedAt(identifier string) []int64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]int64(nil), f.started[identifier]...)
+}
+
+func object(resourceVersion int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName("cm")
+	obj.SetResourceVersion(strconv.FormatInt(resourceVersion, 10))
+	return obj
+}
+
+func decodeResourceVersions(t *testing.T, event watch.Event) map[string]int64 {
+	var state ShardedResourceVersions
+	require.NoError(t, state.Decode(event.Object.(metav1.Common).GetResourceVersion()))
+	versions := map[string]int64{}
+	for _, rv := range state.ResourceVersions {
+		versions[rv.Identifier] = rv.ResourceVersion
+	}
+	return versions
+}
+
+func nextEvent(t *testing.T, w watch.Interface) watch.Event {
+	select {
+	case event, ok := <-w.ResultChan():
+		require.True(t, ok, "watch closed unexpectedly")
+		return event
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for event")
+	}
+	return watch.Event{}
+}
+
+func requireNoEvent(t *testing.T, w watch.Interface) {
+	select {
+	case event := <-w.ResultChan():
+		t.Fatalf("unexpected event: %#v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func requireClosed(t *testing.T, w watch.Interface) {
+	select {
+	case _, ok := <-w.ResultChan():
+		require.False(t, ok, "expected watch to be closed")
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for the watch to close")
+	}
+}
+
+func newTestState(versions map[string]int64) *ShardedResourceVersions {
+	state := &ShardedResourceVersions{ShardResourceVersion: 1}
+	for _, identifier := range []string{"first", "second"} {
+		state.ResourceVersions = append(state.ResourceVersions, ShardedResourceVersion{Identifier: identifier, ResourceVersion: versions[identifier]})
+	}
+	return state
+}
+
+func TestAggregateWatcherBookmarks(t *testing.T) {
+	for _, allowBookmarks := range []bool{true, false} {
+		shards := newFakeShards()
+		w, err := NewAggregateWatcher(context.Background(), newTestState(map[string]int64{"first": 1, "second": 1}), allowBookmarks, shards.watchFor)
+		require.NoError(t, err)
+
+		shards.watcher("first").Action(watch.Bookmark, object(10))
+		requireNoEvent(t, w)
+
+		shards.watcher("second").Add(object(20))
+		event := nextEvent(t, w)
+		require.Equal(t, watch.Added, event.Type)
+		require.Equal(t, map[string]int64{"first": 10, "second": 20}, decodeResourceVersions(t, event))
+
+		shards.watcher("second").Action(watch.Bookmark, object(30))
+		if allowBookmarks {
+			event = nextEvent(t, w)
+			require.Equal(t, watch.Bookmark, event.Type)
+			require.Equal(t, map[string]int64{"first": 10, "second": 30}, decodeResourceVersions(t, event))
+
+			// the first shard has not progressed since the last bookmark
+			shards.watcher("second").Action(watch.Bookmark, object(40))
+			requireNoEvent(t, w)
+		} else {
+			requireNoEvent(t, w)
+		}
+
+		w.Stop()
+		requireClosed(t, w)
+	}
+}
+
+func TestAggregateWatcherRestart(t *testing.T) {
+	shards := newFakeShards()
+	w, err := NewAggregateWatcher(context.Background(), newTestState(map[string]int64{"first": 1, "second": 2}), false, shards.watchFor)
+	require.NoError(t, err)
+	defer w.Stop()
+
+	first := shards.watcher("first")
+	first.Add(object(10))
+	nextEvent(t, w)
+
+	// the shard closes its watch, e.g. on a server side timeout
+	first.Stop()
+	require.Eventually(t, func() bool { return len(shards.startedAt("first")) == 2 }, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []int64{1, 10}, shards.startedAt("first"), "watch must be restarted from the last resource version")
+
+	// transient errors restart the watch
+	shards.watcher("first").Error(&errors.NewInternalError(context.DeadlineExceeded).ErrStatus)
+	require.Eventually(t, func() bool { return len(shards.startedAt("first")) == 3 }, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	shards.watcher("first").Modify(object(11))
+	event := nextEvent(t, w)
+	require.Equal(t, watch.Modified, event.Type)
+	require.Equal(t, map[string]int64{"first": 11, "second": 2}, decodeResourceVersions(t, event))
+	require.Equal(t, []int64{2}, shards.startedAt("second"), "other shards are not restarted")
+}
+
+func TestAggregateWatcherExpired(t *testing.T) {
+	gone := errors.NewResourceExpired("too old resource version")
+
+	tests := []struct {
+		name    string
+		initial map[string]int64
+		expire  func(shards *fakeShards)
+	}{
+		{name: "shard reports expired", initial: map[string]int64{"first": 1, "second": 2}, expire: func(shards *fakeShards) {
+			shards.watcher("first").Error(&gone.ErrStatus)
+		}},
+		{name: "restart is expired", initial: map[string]int64{"first": 1, "second": 2}, expire: func(shards *fakeShards) {
+			shards.lock.Lock()
+			shards.errs["first"] = gone
+			shards.lock.Unlock()
+			shards.watcher("first").Stop()
+		}},
+		{name: "no resource version to restart from", initial: map[string]int64{"second": 2}, expire: func(shards *fakeShards) {
+			shards.watcher("first").Stop()
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			shards := newFakeShards()
+			w, err := NewAggregateWatcher(context.Background(), newTestState(tt.initial), false, shards.watchFor)
+			require.NoError(t, err)
+
+			tt.expire(shards)
+			event := nextEvent(t, w)
+			require.Equal(t, watch.Error, event.Type)
+			require.Equal(t, int32(http.StatusGone), event.Object.(*metav1.Status).Code)
+			requireClosed(t, w)
+		})
+	}
+}
+
+func TestAggregateWatcherInitialError(t *testing.T) {
+	shards := newFakeShards()
+	shards.errs["second"] = errors.NewGone("gone")
+	_, err := NewAggregateWatcher(context.Background(), newTestState(nil), false, shards.watchFor)
+	require.True(t, errors.IsGone(err), "status errors must not be wrapped, got %v", err)
+
+	shards.errs["second"] = errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "")
+	_, err = NewAggregateWatcher(context.Background(), newTestState(nil), false, shards.watchFor)
+	require.Error(t, err)
+}
@@ -241,6 +241,16 @@ func (s *ShardedResourceVersions) UpdateWith(identifier string, resp metav1.Comm
 	return nil
 }
 
+// ResourceVersionFor returns the resource version of the shard, and whether the shard is part of the state.
+func (s *ShardedResourceVersions) ResourceVersionFor(identifier string) (int64, bool) {
+	for _, shard := range s.ResourceVersions {
+		if shard.Identifier == identifier {
+			return shard.ResourceVersion, true
+		}
+	}
+	return 0, false
+}
+
 func resourceVersionFor(identifier string, resp metav1.Common) (ShardedResourceVersion, error) {
 	resourceVersion := resp.GetResourceVersion()
 	if resourceVersion == "" {
@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
@@ -33,10 +35,16 @@ import (
 type storageBase struct {
 	rest.TableConvertor
 
-	// TODO: resolve this information at runtime using an index
+	// shards are the shards known when the request came in, and shardIdentifierResourceVersion
+	// is the version of that set of shards. It is derived from their names and addresses, so it
+	// changes whenever shards are added or removed, and is the same on every server.
 	shards                         map[string]*clientrest.Config
 	shardIdentifierResourceVersion int64
 
+	// tableOptions are set if the client asked for a table, which is then built from the
+	// server-side tables of the shards.
+	tableOptions *metav1.TableOptions
+
 	// we know we will have to delegate to the shards we know about, but the rest.StandardStorage
 	// interface is usually used in a 1:1 mapping to underlying resources, whereas here we want
 	// one of these to handle all request URIs - so, we need to inject information from our callers
@@ -45,6 +53,20 @@ type storageBase struct {
 	requestFor func(client *clientrest.RESTClient, mutators ...func(runtime.Object) error) (*clientrest.Request, error)
 }
 
+// checkShards returns an expired error if resource versions or continue tokens were issued for
+// a different set of shards, as the result would silently miss or duplicate objects.
+func (s *storageBase) checkShards(shardResourceVersion int64, identifiers []string) error {
+	if shardResourceVersion != s.shardIdentifierResourceVersion {
+		return errors.NewResourceExpired(fmt.Sprintf("the set of shards changed since version %d, current version is %d", shardResourceVersion, s.shardIdentifierResourceVersion))
+	}
+	for _, identifier := range identifiers {
+		if _, found := s.shards[identifier]; !found {
+			return errors.NewResourceExpired(fmt.Sprintf("shard %q is unknown", identifier))
+		}
+	}
+	return nil
+}
+
 func (s *storageBase) New() runtime.Object {
 	return &unstructured.Unstructured{}
 }
@@ -63,17 +85,30 @@ func NewMux(base storageBase) *storageMux {
 
 type storageMux struct {
 	storageBase
-	sharded    listWatcher
+	sharded    getListWatcher
 	delegating rest.StandardStorage
 }
 
+// methodNotSupported returns a MethodNotSupported error for the mutating verbs, which
+// cannot be served in the `*` logical cluster.
+func methodNotSupported(ctx context.Context, verb string) error {
+	var groupResource schema.GroupResource
+	if requestInfo, ok := request.RequestInfoFrom(ctx); ok {
+		groupResource = schema.GroupResource{Group: requestInfo.APIGroup, Resource: requestInfo.Resource}
+		verb = requestInfo.Verb
+	}
+	err := errors.NewMethodNotSupported(groupResource, verb)
+	err.ErrStatus.Message += " in the `*` logical cluster"
+	return err
+}
+
 func (s *storageMux) Destroy() {
 	// Do nothing
 }
 
 func (s *storageMux) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
 	if c := request.ClusterFrom(ctx); c.Wildcard {
-		return nil, fmt.Errorf("method not supported in a cross-cluster context")
+		return s.sharded.Get(ctx, name, options)
 	} else {
 		return s.delegating.Get(ctx, name, options)
 	}
@@ -81,7 +116,7 @@ func (s *storageMux) Get(ctx context.Context, name string, options *metav1.GetOp
 
 func (s *storageMux) Create(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
 	if c := request.ClusterFrom(ctx); c.Wildcard {
-		return nil, fmt.Errorf("method not supported in a cross-cluster context")
+		return nil, methodNotSupported(ctx, "create")
 	} else {
 		return s.delegating.Create(ctx, obj, createValidation, options)
 	}
@@ -89,7 +124,7 @@ func (s *storageMux) Create(ctx context.Context, obj runtime.Object, createValid
 
 func (s *storageMux) Update(ctx context.Context, name string, objInfo rest.UpdatedObjectInfo, createValidation rest.ValidateObjectFunc, updateValidation rest.ValidateObjectUpdateFunc, forceAllowCreate bool, options *metav1.UpdateOptions) (runtime.Object, bool, error) {
 	if c := request.ClusterFrom(ctx); c.Wildcard {
-		return nil, false, fmt.Errorf("method not supported in a cross-cluster context")
+		return nil, false, methodNotSupported(ctx, "update")
 	} else {
 		return s.delegating.Update(ctx, name, objInfo, createValidation, updateValidation, forceAllowCreate, options)
 	}
@@ -97,7 +132,7 @@ func (s *storageMux) Update(ctx context.Context, name string, objInfo rest.Updat
 
 func (s *storageMux) Delete(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
 	if c := request.ClusterFrom(ctx); c.Wildcard {
-		return nil, false, fmt.Errorf("method not supported in a cross-cluster context")
+		return nil, false, methodNotSupported(ctx, "delete")
 	} else {
 		return s.delegating.Delete(ctx, name, deleteValidation, options)
 	}
@@ -105,7 +140,7 @@ func (s *storageMux) Delete(ctx context.Context, name string, deleteValidation r
 
 func (s *storageMux) DeleteCollection(ctx context.Context, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions, listOptions *internalversion.ListOptions) (runtime.Object, error) {
 	if c := request.ClusterFrom(ctx); c.Wildcard {
-		return nil, fmt.Errorf("method not supported in a cross-cluster context")
+		return nil, methodNotSupported(ctx, "deletecollection")
 	} else {
 		return s.delegating.DeleteCollection(ctx, deleteValidation, options, listOptions)
 	}
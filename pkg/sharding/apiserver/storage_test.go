@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	clientrest "k8s.io/client-go/rest"
+)
+
+func TestCheckShards(t *testing.T) {
+	s := &storageBase{
+		shards:                         map[string]*clientrest.Config{"first": {}, "second": {}},
+		shardIdentifierResourceVersion: 2,
+	}
+
+	require.NoError(t, s.checkShards(2, []string{"first", "second"}))
+	require.NoError(t, s.checkShards(2, []string{"first"}))
+
+	err := s.checkShards(1, []string{"first", "second"})
+	require.True(t, errors.IsResourceExpired(err), "tokens of an older set of shards are expired, got %v", err)
+
+	err = s.checkShards(2, []string{"first", "third"})
+	require.True(t, errors.IsResourceExpired(err), "tokens of unknown shards are expired, got %v", err)
+}
+
+func TestStorageMuxWildcardMutations(t *testing.T) {
+	s := NewMux(storageBase{})
+	ctx := request.WithCluster(context.Background(), request.Cluster{Wildcard: true})
+	ctx = request.WithRequestInfo(ctx, &request.RequestInfo{IsResourceRequest: true, Verb: "patch", APIGroup: "apps", Resource: "deployments"})
+
+	_, err := s.Create(ctx, nil, nil, nil)
+	require.True(t, errors.IsMethodNotSupported(err), "got %v", err)
+	_, _, err = s.Update(ctx, "name", nil, nil, nil, false, nil)
+	require.True(t, errors.IsMethodNotSupported(err), "got %v", err)
+	require.Contains(t, err.Error(), "patch")
+	require.Contains(t, err.Error(), "deployments.apps")
+	_, _, err = s.Delete(ctx, "name", nil, nil)
+	require.True(t, errors.IsMethodNotSupported(err), "got %v", err)
+	_, err = s.DeleteCollection(ctx, nil, nil, nil)
+	require.True(t, errors.IsMethodNotSupported(err), "got %v", err)
+}
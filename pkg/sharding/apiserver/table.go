@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+	clientrest "k8s.io/client-go/rest"
+)
+
+// tableMediaType is used to request server-side tables from the shards.
+const tableMediaType = "application/json;as=Table;v=v1;g=meta.k8s.io"
+
+// clusterColumn is prepended to the columns of the shards.
+var clusterColumn = metav1.TableColumnDefinition{
+	Name:        "Cluster",
+	Type:        "string",
+	Description: metav1.ObjectMeta{}.SwaggerDoc()["clusterName"],
+	Priority:    0,
+}
+
+// tableOptionsFor returns the table options if the request is served as a table, or nil. As
+// the shards only speak JSON to us, the first JSON or YAML media type in the Accept header wins.
+func tableOptionsFor(req *http.Request) *metav1.TableOptions {
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "application/yaml", "application/*", "*/*":
+		default:
+			continue
+		}
+		if params["as"] != "Table" || params["g"] != metav1.GroupName {
+			return nil
+		}
+		return &metav1.TableOptions{
+			NoHeaders:     req.URL.Query().Get("noHeaders") == "true",
+			IncludeObject: metav1.IncludeObjectPolicy(req.URL.Query().Get("includeObject")),
+		}
+	}
+	return nil
+}
+
+// requestTable asks the shard for a server-side table. The objects are always included
+// in the rows, at least their metadata, to know their clusters.
+func requestTable(request *clientrest.Request, options *metav1.TableOptions) {
+	request.SetHeader("Accept", tableMediaType)
+	if options.IncludeObject == metav1.IncludeObject {
+		request.OverwriteParam("includeObject", string(metav1.IncludeObject))
+	} else {
+		request.OverwriteParam("includeObject", string(metav1.IncludeMetadata))
+	}
+}
+
+// tableFrom decodes the table of a shard, with the objects of the rows decoded as unstructured.
+func tableFrom(obj runtime.Object) (*metav1.Table, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u.GetKind() != "Table" {
+		return nil, fmt.Errorf("could not parse sharded response as a table, got %T", obj)
+	}
+	raw, err := json.Marshal(u.Object)
+	if err != nil {
+		return nil, err
+	}
+	table := &metav1.Table{}
+	if err := json.Unmarshal(raw, table); err != nil {
+		return nil, fmt.Errorf("could not parse sharded response as a table: %w", err)
+	}
+	for i := range table.Rows {
+		row := &table.Rows[i]
+		if len(row.Object.Raw) == 0 {
+			continue
+		}
+		obj, _, err := unstructured.UnstructuredJSONScheme.Decode(row.Object.Raw, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode table row object: %w", err)
+		}
+		row.Object = runtime.RawExtension{Object: obj}
+	}
+	return table, nil
+}
+
+// tableObjects returns the objects of the rows of the table.
+func tableObjects(table *metav1.Table) ([]metav1.Common, error) {
+	objs := make([]metav1.Common, 0, len(table.Rows))
+	for i := range table.Rows {
+		obj, ok := table.Rows[i].Object.Object.(metav1.Common)
+		if !ok {
+			return nil, fmt.Errorf("table row object was a %T which could not cast to metav1.Common", table.Rows[i].Object.Object)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// appendTable appends the rows of the table of a shard to the output, with the cluster of
+// the row objects as first cell. The columns of the output are those of the first table.
+// Later tables are matched by column name, as shards might serve different versions.
+func appendTable(output, table *metav1.Table) error {
+	if output.ColumnDefinitions == nil {
+		output.ColumnDefinitions = append([]metav1.TableColumnDefinition{clusterColumn}, table.ColumnDefinitions...)
+	}
+
+	columns := make(map[string]int, len(table.ColumnDefinitions))
+	for i, column := range table.ColumnDefinitions {
+		columns[column.Name] = i
+	}
+	for _, row := range table.Rows {
+		m, err := meta.Accessor(row.Object.Object)
+		if err != nil {
+			return fmt.Errorf("could not get the cluster of a table row: %w", err)
+		}
+		cells := []interface{}{m.GetClusterName()}
+		for _, column := range output.ColumnDefinitions[1:] {
+			var cell interface{}
+			if i, found := columns[column.Name]; found && i < len(row.Cells) {
+				cell = row.Cells[i]
+			}
+			cells = append(cells, cell)
+		}
+		row.Cells = cells
+		output.Rows = append(output.Rows, row)
+	}
+	return nil
+}
+
+// tableConvertor passes through the tables built from the tables of the shards, and
+// falls back to the delegate for everything else.
+type tableConvertor struct {
+	delegate rest.TableConvertor
+}
+
+func (c tableConvertor) ConvertToTable(ctx context.Context, obj runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	table, ok := obj.(*metav1.Table)
+	if !ok {
+		return c.delegate.ConvertToTable(ctx, obj, tableOptions)
+	}
+	if options, ok := tableOptions.(*metav1.TableOptions); ok && options.NoHeaders {
+		table.ColumnDefinitions = nil
+	}
+	return table, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	clientrest "k8s.io/client-go/rest"
+)
+
+func TestTableOptionsFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		query  string
+		want   *metav1.TableOptions
+	}{
+		{name: "no accept header"},
+		{name: "json", accept: "application/json"},
+		{name: "kubectl", accept: "application/json;as=Table;v=v1;g=meta.k8s.io,application/json;as=Table;v=v1beta1;g=meta.k8s.io,application/json", want: &metav1.TableOptions{}},
+		{name: "protobuf is skipped", accept: "application/vnd.kubernetes.protobuf, application/json;as=Table;v=v1;g=meta.k8s.io", want: &metav1.TableOptions{}},
+		{name: "table only as fallback", accept: "application/json, application/json;as=Table;v=v1;g=meta.k8s.io"},
+		{name: "other group", accept: "application/json;as=Table;v=v1;g=example.com"},
+		{name: "options", accept: "application/json;as=Table;v=v1;g=meta.k8s.io", query: "?includeObject=Object&noHeaders=true", want: &metav1.TableOptions{NoHeaders: true, IncludeObject: metav1.IncludeObject}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/configmaps"+tt.query, nil)
+			req.Header.Set("Accept", tt.accept)
+			require.Equal(t, tt.want, tableOptionsFor(req))
+		})
+	}
+}
+
+func tableRow(cluster, name string, cells ...interface{}) metav1.TableRow {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("meta.k8s.io/v1")
+	obj.SetKind("PartialObjectMetadata")
+	obj.SetClusterName(cluster)
+	obj.SetName(name)
+	obj.SetResourceVersion("1")
+	return metav1.TableRow{Cells: cells, Object: runtime.RawExtension{Object: obj}}
+}
+
+func TestAppendTable(t *testing.T) {
+	output := &metav1.Table{}
+	require.NoError(t, appendTable(output, &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name"}, {Name: "Replicas"}, {Name: "Age"}},
+		Rows:              []metav1.TableRow{tableRow("root:a", "foo", "foo", int64(1), "1d")},
+	}))
+	// another shard serving a version with other printer columns
+	require.NoError(t, appendTable(output, &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name"}, {Name: "Age"}, {Name: "Ready"}},
+		Rows:              []metav1.TableRow{tableRow("root:b", "bar", "bar", "2d", true)},
+	}))
+
+	var columns []string
+	for _, column := range output.ColumnDefinitions {
+		columns = append(columns, column.Name)
+	}
+	require.Equal(t, []string{"Cluster", "Name", "Replicas", "Age"}, columns)
+	require.Len(t, output.Rows, 2)
+	require.Equal(t, []interface{}{"root:a", "foo", int64(1), "1d"}, output.Rows[0].Cells)
+	require.Equal(t, []interface{}{"root:b", "bar", nil, "2d"}, output.Rows[1].Cells)
+
+	require.Error(t, appendTable(output, &metav1.Table{Rows: []metav1.TableRow{{Cells: []interface{}{"x"}}}}), "rows without objects have no cluster")
+}
+
+func TestTableFrom(t *testing.T) {
+	raw := `{"kind":"Table","apiVersion":"meta.k8s.io/v1","metadata":{"resourceVersion":"5","continue":"abc"},
+"columnDefinitions":[{"name":"Name","type":"string","format":"name","description":"","priority":0}],
+"rows":[{"cells":["foo"],"object":{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"foo","clusterName":"root:org","resourceVersion":"3"}}}]}`
+	obj, _, err := unstructured.UnstructuredJSONScheme.Decode([]byte(raw), nil, nil)
+	require.NoError(t, err)
+
+	table, err := tableFrom(obj)
+	require.NoError(t, err)
+	require.Equal(t, "5", table.ResourceVersion)
+	require.Equal(t, "abc", table.Continue)
+	objs, err := tableObjects(table)
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	require.Equal(t, "3", objs[0].GetResourceVersion())
+
+	_, err = tableFrom(&unstructured.Unstructured{Object: map[string]interface{}{"kind": "ConfigMap"}})
+	require.Error(t, err)
+}
+
+// newShard serves the configmap "cm" in logical cluster root:org, as an object or as a table.
+func newShard(t *testing.T, serves bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !serves || req.Header.Get("X-Kubernetes-Cluster") != "root:org" || req.URL.Path != "/api/v1/namespaces/default/configmaps/cm" {
+			w.WriteHeader(http.StatusNotFound)
+			require.NoError(t, json.NewEncoder(w).Encode(errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "cm").ErrStatus))
+			return
+		}
+		object := `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm","namespace":"default","clusterName":"root:org","resourceVersion":"5"}}`
+		if strings.Contains(req.Header.Get("Accept"), "as=Table") {
+			require.Equal(t, "Metadata", req.URL.Query().Get("includeObject"))
+			fmt.Fprintf(w, `{"kind":"Table","apiVersion":"meta.k8s.io/v1","metadata":{"resourceVersion":"5"},"columnDefinitions":[{"name":"Name","type":"string"},{"name":"Data","type":"integer"}],"rows":[{"cells":["cm",0],"object":%s}]}`, object)
+			return
+		}
+		fmt.Fprint(w, object)
+	}))
+}
+
+func TestShardedGet(t *testing.T) {
+	serving, other := newShard(t, true), newShard(t, false)
+	defer serving.Close()
+	defer other.Close()
+
+	negotiatedSerializer := &unstructuredNegotiatedSerializer{scheme: &delegatingUnstructuredScheme{delegate: runtime.NewScheme()}}
+	qualifiedName := "root:org#$#cm"
+	get := func(qualifiedName string, tableOptions *metav1.TableOptions) (runtime.Object, error) {
+		s := &shardedStorage{storageBase: storageBase{
+			shards:       map[string]*clientrest.Config{"serving": {Host: serving.URL}, "other": {Host: other.URL}},
+			tableOptions: tableOptions,
+			requestFor:   requestFor(httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/configmaps/root:org%23$%23cm", nil)),
+			clientFor:    clientFor(&user.DefaultInfo{Name: "admin"}, negotiatedSerializer),
+		}}
+		ctx := request.WithRequestInfo(context.Background(), &request.RequestInfo{
+			IsResourceRequest: true,
+			Verb:              "get",
+			Path:              "/api/v1/namespaces/default/configmaps/" + qualifiedName,
+			APIVersion:        "v1",
+			Namespace:         "default",
+			Resource:          "configmaps",
+			Name:              qualifiedName,
+		})
+		return s.Get(ctx, qualifiedName, &metav1.GetOptions{})
+	}
+
+	obj, err := get(qualifiedName, nil)
+	require.NoError(t, err)
+	u := obj.(*unstructured.Unstructured)
+	require.Equal(t, "cm", u.GetName())
+	var state ShardedResourceVersions
+	require.NoError(t, state.Decode(u.GetResourceVersion()))
+	require.Equal(t, []ShardedResourceVersion{{Identifier: "serving", ResourceVersion: 5}}, state.ResourceVersions)
+
+	obj, err = get(qualifiedName, &metav1.TableOptions{IncludeObject: metav1.IncludeNone})
+	require.NoError(t, err)
+	table := obj.(*metav1.Table)
+	require.Equal(t, "Cluster", table.ColumnDefinitions[0].Name)
+	require.Len(t, table.Rows, 1)
+	require.Equal(t, []interface{}{"root:org", "cm", float64(0)}, table.Rows[0].Cells)
+
+	_, err = get("root:org#$#missing", nil)
+	require.True(t, errors.IsNotFound(err), "expected not found, got %v", err)
+
+	_, err = get("cm", nil)
+	require.True(t, errors.IsBadRequest(err), "expected bad request for unqualified name, got %v", err)
+}
@@ -17,28 +17,45 @@ limitations under the License.
 package sharding
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// ClientLoader holds the client configs of the shards requests are fanned out to. Shards
+// are either added statically, or discovered from ClusterWorkspaceShards at runtime.
+// Static shards take precedence over discovered shards of the same name.
 type ClientLoader struct {
 	sync.RWMutex
-	clients map[string]*rest.Config
+	static     map[string]*rest.Config
+	discovered map[string]*rest.Config
+
+	// resourceVersion is derived from the names and addresses of the shards, such that it
+	// changes whenever shards are added or removed, or their address changes, and is the same
+	// on every server fanning out to the same shards. Continue tokens and resource versions
+	// of another set of shards are invalid.
+	resourceVersion int64
 }
 
 func NewClientLoader() *ClientLoader {
 	return &ClientLoader{
-		clients: make(map[string]*rest.Config),
+		static:          make(map[string]*rest.Config),
+		discovered:      make(map[string]*rest.Config),
+		resourceVersion: shardSetVersion(nil),
 	}
 }
 
 func (c *ClientLoader) Add(name string, config *rest.Config) {
 	c.Lock()
 	defer c.Unlock()
-	c.clients[name] = config
+
+	c.update(func() { c.static[name] = config })
 }
 
 func (c *ClientLoader) AddKubeConfigContexts(path string) error {
@@ -48,30 +65,86 @@ func (c *ClientLoader) AddKubeConfigContexts(path string) error {
 		return fmt.Errorf("failed to load: %w", err)
 	}
 
-	c.Lock()
-	defer c.Unlock()
-
+	clients := make(map[string]*rest.Config, len(cfg.Contexts))
 	for context := range cfg.Contexts {
 		contextCfg, err := clientcmd.NewNonInteractiveClientConfig(*cfg, context, &clientcmd.ConfigOverrides{}, loader).ClientConfig()
 		if err != nil {
 			return fmt.Errorf("create %s client: %w", context, err)
 		}
 		contextCfg.ContentType = "application/json"
-		c.clients[context] = contextCfg
-		c.Unlock()
+		clients[context] = contextCfg
 	}
 
+	c.Lock()
+	defer c.Unlock()
+
+	c.update(func() {
+		for name, config := range clients {
+			c.static[name] = config
+		}
+	})
+
 	return nil
 }
 
-func (c *ClientLoader) Clients() map[string]*rest.Config {
+// SetDiscovered replaces the discovered shards.
+func (c *ClientLoader) SetDiscovered(clients map[string]*rest.Config) {
 	c.Lock()
 	defer c.Unlock()
 
-	out := make(map[string]*rest.Config, len(c.clients))
-	for key, value := range c.clients {
+	c.update(func() { c.discovered = clients })
+}
+
+// update applies fn and updates the resource version to the new set of shard addresses.
+// The lock must be held.
+func (c *ClientLoader) update(fn func()) {
+	fn()
+	c.resourceVersion = shardSetVersion(c.hosts())
+}
+
+// shardSetVersion returns a positive hash of the given shard names and addresses.
+func shardSetVersion(hosts map[string]string) int64 {
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\n", name, hosts[name])
+	}
+	version := int64(binary.BigEndian.Uint64(h.Sum(nil)) & math.MaxInt64)
+	if version == 0 {
+		// zero stands for a missing version in resource versions
+		version = 1
+	}
+	return version
+}
+
+func (c *ClientLoader) hosts() map[string]string {
+	hosts := make(map[string]string, len(c.static)+len(c.discovered))
+	for name, config := range c.discovered {
+		hosts[name] = config.Host
+	}
+	for name, config := range c.static {
+		hosts[name] = config.Host
+	}
+	return hosts
+}
+
+// Clients returns copies of the shard client configs, and the resource version of this set of shards.
+func (c *ClientLoader) Clients() (map[string]*rest.Config, int64) {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := make(map[string]*rest.Config, len(c.static)+len(c.discovered))
+	for key, value := range c.discovered {
+		out[key] = rest.CopyConfig(value)
+	}
+	for key, value := range c.static {
 		out[key] = rest.CopyConfig(value)
 	}
 
-	return out
+	return out, c.resourceVersion
 }
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const (
+	discoveryControllerName = "kcp-shard-discovery"
+
+	// ShardCredentialsKey is the key of the kubeconfig in the secret referenced by
+	// the credentials of a ClusterWorkspaceShard.
+	ShardCredentialsKey = "kubeconfig"
+
+	// discoveryKey is the only queue key, as every change recomputes all shards.
+	discoveryKey = "shards"
+)
+
+// NewShardDiscoveryController returns a controller that keeps the discovered shards of
+// the loader in sync with the ClusterWorkspaceShards of the root workspace and their
+// credential secrets.
+func NewShardDiscoveryController(
+	loader *ClientLoader,
+	rootShardInformer tenancyinformer.ClusterWorkspaceShardInformer,
+	rootSecretInformer coreinformers.SecretInformer,
+) *ShardDiscoveryController {
+	c := &ShardDiscoveryController{
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), discoveryControllerName),
+		loader:       loader,
+		shardLister:  rootShardInformer.Lister(),
+		secretLister: rootSecretInformer.Lister(),
+	}
+
+	enqueue := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.queue.Add(discoveryKey) },
+		UpdateFunc: func(interface{}, interface{}) { c.queue.Add(discoveryKey) },
+		DeleteFunc: func(interface{}) { c.queue.Add(discoveryKey) },
+	}
+	rootShardInformer.Informer().AddEventHandler(enqueue)
+	rootSecretInformer.Informer().AddEventHandler(enqueue)
+
+	return c
+}
+
+// ShardDiscoveryController discovers the shards to fan out wildcard requests to.
+type ShardDiscoveryController struct {
+	queue workqueue.RateLimitingInterface
+
+	loader       *ClientLoader
+	shardLister  tenancylister.ClusterWorkspaceShardLister
+	secretLister corelisters.SecretLister
+}
+
+func (c *ShardDiscoveryController) Start(ctx context.Context) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting shard discovery controller")
+	defer klog.Info("Shutting down shard discovery controller")
+
+	// a single worker, as every item recomputes all shards
+	go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+
+	<-ctx.Done()
+}
+
+func (c *ShardDiscoveryController) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *ShardDiscoveryController) processNextWorkItem(ctx context.Context) bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(k)
+
+	if err := c.process(ctx); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync shards, err: %w", discoveryControllerName, err))
+		c.queue.AddRateLimited(k)
+		return true
+	}
+	c.queue.Forget(k)
+	return true
+}
+
+// process replaces the discovered shards of the loader. Shards without credentials are
+// skipped, and shards whose credentials cannot be loaded are left out until they can.
+func (c *ShardDiscoveryController) process(ctx context.Context) error {
+	shards, err := c.shardLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	clients := make(map[string]*rest.Config, len(shards))
+	var errs []error
+	for _, shard := range shards {
+		config, err := c.clientConfigFor(shard)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shard %q: %w", shard.Name, err))
+			continue
+		}
+		if config == nil {
+			klog.V(4).Infof("Skipping shard %q without credentials", shard.Name)
+			continue
+		}
+		clients[shard.Name] = config
+	}
+	c.loader.SetDiscovered(clients)
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// clientConfigFor returns the client config of the shard from its credentials secret,
+// with the host set to the shard's base URL. It returns nil if the shard has no credentials.
+func (c *ShardDiscoveryController) clientConfigFor(shard *tenancyv1alpha1.ClusterWorkspaceShard) (*rest.Config, error) {
+	ref := shard.Spec.Credentials
+	if ref == nil {
+		return nil, nil
+	}
+
+	secret, err := c.secretLister.Secrets(ref.Namespace).Get(clusters.ToClusterAwareKey(tenancyv1alpha1.RootCluster, ref.Name))
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("credentials secret %s/%s not found", ref.Namespace, ref.Name)
+	} else if err != nil {
+		return nil, err
+	}
+	kubeconfig, found := secret.Data[ShardCredentialsKey]
+	if !found {
+		return nil, fmt.Errorf("credentials secret %s/%s has no %q key", ref.Namespace, ref.Name, ShardCredentialsKey)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in credentials secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	if shard.Spec.BaseURL != "" {
+		config.Host = shard.Spec.BaseURL
+	}
+	config.ContentType = "application/json"
+
+	return config, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const testKubeconfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: shard
+  cluster:
+    server: https://ignored:6443
+contexts:
+- name: shard
+  context:
+    cluster: shard
+    user: admin
+current-context: shard
+users:
+- name: admin
+  user:
+    token: secret-token
+`
+
+func shard(name, baseURL, secretName string) *tenancyv1alpha1.ClusterWorkspaceShard {
+	s := &tenancyv1alpha1.ClusterWorkspaceShard{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: tenancyv1alpha1.RootCluster.String()},
+		Spec:       tenancyv1alpha1.ClusterWorkspaceShardSpec{BaseURL: baseURL},
+	}
+	if secretName != "" {
+		s.Spec.Credentials = &corev1.SecretReference{Namespace: "default", Name: secretName}
+	}
+	return s
+}
+
+func secret(name string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, ClusterName: tenancyv1alpha1.RootCluster.String()},
+		Data:       data,
+	}
+}
+
+func TestShardDiscovery(t *testing.T) {
+	shardIndexer := cache.NewIndexer(func(obj interface{}) (string, error) {
+		return clusters.ToClusterAwareKey(tenancyv1alpha1.RootCluster, obj.(metav1.Object).GetName()), nil
+	}, cache.Indexers{})
+	secretIndexer := cache.NewIndexer(func(obj interface{}) (string, error) {
+		o := obj.(metav1.Object)
+		return o.GetNamespace() + "/" + clusters.ToClusterAwareKey(tenancyv1alpha1.RootCluster, o.GetName()), nil
+	}, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	loader := NewClientLoader()
+	loader.Add("root", &rest.Config{Host: "https://localhost:6443"})
+	c := &ShardDiscoveryController{
+		loader:       loader,
+		shardLister:  tenancylister.NewClusterWorkspaceShardLister(shardIndexer),
+		secretLister: corelisters.NewSecretLister(secretIndexer),
+	}
+
+	_, initialVersion := loader.Clients()
+
+	require.NoError(t, shardIndexer.Add(shard("root", "https://root:6443", "root-kubeconfig")))
+	require.NoError(t, secretIndexer.Add(secret("root-kubeconfig", map[string][]byte{ShardCredentialsKey: []byte(testKubeconfig)})))
+	require.NoError(t, c.process(context.Background()))
+	clients, version := loader.Clients()
+	require.Equal(t, "https://localhost:6443", clients["root"].Host, "static shards override discovered ones")
+	require.Equal(t, initialVersion, version, "the set of shards did not change")
+
+	require.NoError(t, shardIndexer.Add(shard("beta", "https://beta:6443", "beta-kubeconfig")))
+	require.NoError(t, shardIndexer.Add(shard("gamma", "https://gamma:6443", "")))
+	require.NoError(t, secretIndexer.Add(secret("beta-kubeconfig", map[string][]byte{ShardCredentialsKey: []byte(testKubeconfig)})))
+	require.NoError(t, c.process(context.Background()))
+	clients, version = loader.Clients()
+	require.Len(t, clients, 2, "shards without credentials are skipped")
+	require.Equal(t, "https://beta:6443", clients["beta"].Host)
+	require.Equal(t, "secret-token", clients["beta"].BearerToken)
+	require.NotEqual(t, initialVersion, version)
+
+	require.NoError(t, secretIndexer.Update(secret("beta-kubeconfig", nil)))
+	require.Error(t, c.process(context.Background()))
+	clients, removedVersion := loader.Clients()
+	require.Len(t, clients, 1, "shards with invalid credentials are removed")
+	require.NotEqual(t, version, removedVersion)
+	require.Equal(t, initialVersion, removedVersion, "the version only depends on the set of shards")
+
+	other := NewClientLoader()
+	other.Add("root", &rest.Config{Host: "https://localhost:6443"})
+	_, otherVersion := other.Clients()
+	require.Equal(t, initialVersion, otherVersion, "servers with the same shards agree on the version")
+}
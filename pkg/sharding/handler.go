@@ -42,7 +42,8 @@ func WithSharding(apiHandler http.Handler, loader *ClientLoader) http.Handler {
 			apiHandler.ServeHTTP(w, req)
 			return
 		}
-		handler := apiserver.NewShardedHandler(loader.Clients(), 0, 10*time.Minute)
+		clients, shardResourceVersion := loader.Clients()
+		handler := apiserver.NewShardedHandler(clients, shardResourceVersion, 0, 10*time.Minute)
 		handler.ServeHTTP(w, req)
 	})
 }
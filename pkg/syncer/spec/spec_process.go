@@ -57,7 +57,8 @@ func deepEqualApartFromStatus(oldUnstrob, newUnstrob *unstructured.Unstructured)
 		return false
 	}
 	for k := range oldAnnotations {
-		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) ||
+			strings.HasPrefix(k, workloadv1alpha1.InternalClusterSyncedAnnotationPrefix) {
 			delete(oldAnnotations, k)
 		}
 	}
@@ -68,7 +69,8 @@ func deepEqualApartFromStatus(oldUnstrob, newUnstrob *unstructured.Unstructured)
 		return false
 	}
 	for k := range newAnnotations {
-		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) ||
+			strings.HasPrefix(k, workloadv1alpha1.InternalClusterSyncedAnnotationPrefix) {
 			delete(newAnnotations, k)
 		}
 	}
@@ -258,6 +260,16 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 	labels[workloadv1alpha1.InternalDownstreamClusterLabel] = c.workloadClusterName
 	downstreamObj.SetLabels(labels)
 
+	// The synced annotations are upstream bookkeeping only.
+	if annotations := downstreamObj.GetAnnotations(); annotations != nil {
+		for k := range annotations {
+			if strings.HasPrefix(k, workloadv1alpha1.InternalClusterSyncedAnnotationPrefix) {
+				delete(annotations, k)
+			}
+		}
+		downstreamObj.SetAnnotations(annotations)
+	}
+
 	// Run name transformations on the downstreamObj.
 	transformName(downstreamObj)
 
@@ -340,6 +352,31 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 	}
 	klog.Infof("Upserted %s %s/%s from upstream %s|%s/%s", gvr.Resource, downstreamObj.GetNamespace(), downstreamObj.GetName(), upstreamObj.GetClusterName(), upstreamObj.GetNamespace(), upstreamObj.GetName())
 
+	return c.ensureSyncedAnnotation(ctx, gvr, upstreamObj)
+}
+
+// ensureSyncedAnnotation marks the upstream object as synced to the workload cluster, such that
+// a drain of another workload cluster can tell when this placement has caught up.
+func (c *Controller) ensureSyncedAnnotation(ctx context.Context, gvr schema.GroupVersionResource, upstreamObj *unstructured.Unstructured) error {
+	annotation := workloadv1alpha1.InternalClusterSyncedAnnotationPrefix + c.workloadClusterName
+	if _, found := upstreamObj.GetAnnotations()[annotation]; found {
+		return nil
+	}
+
+	name := upstreamObj.GetName()
+	namespace := upstreamObj.GetNamespace()
+	logicalCluster := logicalcluster.From(upstreamObj)
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, annotation)
+	if _, err := c.upstreamClient.Cluster(logicalCluster).Resource(gvr).Namespace(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("Failed marking resource %s|%s/%s as synced upstream: %v", logicalCluster, namespace, name, err)
+		return err
+	}
+	klog.V(2).Infof("Marked resource %s|%s/%s as synced upstream", logicalCluster, namespace, name)
+
 	return nil
 }
 
@@ -491,7 +491,9 @@ func TestSyncerProcess(t *testing.T) {
 			resourceToProcessName:               "theDeployment",
 			workloadClusterName:                 "us-west1",
 
-			expectActionsOnFrom: []clienttesting.Action{},
+			expectActionsOnFrom: []clienttesting.Action{
+				markSyncedDeploymentAction("theDeployment", "test", "us-west1"),
+			},
 			expectActionsOnTo: []clienttesting.Action{
 				createNamespaceAction(
 					"",
@@ -562,6 +564,7 @@ func TestSyncerProcess(t *testing.T) {
 							"state.internal.workloads.kcp.dev/us-west1": "Sync",
 						}, nil, []string{"workloads.kcp.dev/syncer-us-west1"}),
 					))),
+				markSyncedDeploymentAction("theDeployment", "test", "us-west1"),
 			},
 			expectActionsOnTo: []clienttesting.Action{
 				createNamespaceAction(
@@ -614,6 +617,7 @@ func TestSyncerProcess(t *testing.T) {
 							"state.internal.workloads.kcp.dev/us-west1": "Sync",
 						}, map[string]string{"experimental.spec-diff.workloads.kcp.dev/us-west1": "[{\"op\":\"replace\",\"path\":\"/replicas\",\"value\":3}]"}, []string{shared.SyncerFinalizerNamePrefix + "us-west1"}),
 					))),
+				markSyncedDeploymentAction("theDeployment", "test", "us-west1"),
 			},
 			expectActionsOnTo: []clienttesting.Action{
 				createNamespaceAction(
@@ -794,7 +798,9 @@ func TestSyncerProcess(t *testing.T) {
 			workloadClusterName:                 "us-west1",
 			advancedSchedulingEnabled:           true,
 
-			expectActionsOnFrom: []clienttesting.Action{},
+			expectActionsOnFrom: []clienttesting.Action{
+				markSyncedDeploymentAction("theDeployment", "test", "us-west1"),
+			},
 			expectActionsOnTo: []clienttesting.Action{
 				createNamespaceAction(
 					"",
@@ -1083,6 +1089,10 @@ func patchDeploymentAction(name, namespace string, patchType types.PatchType, pa
 	}
 }
 
+func markSyncedDeploymentAction(name, namespace, workloadClusterName string) clienttesting.PatchActionImpl {
+	return patchDeploymentAction(name, namespace, types.MergePatchType, []byte(`{"metadata":{"annotations":{"synced.internal.workloads.kcp.dev/`+workloadClusterName+`":"true"}}}`))
+}
+
 func deleteDeploymentAction(name, namespace string, subresources ...string) clienttesting.DeleteActionImpl {
 	return clienttesting.DeleteActionImpl{
 		ActionImpl:    deploymentAction("delete", namespace, subresources...),
@@ -33,6 +33,7 @@ import (
 	"k8s.io/client-go/rest"
 	componentbaseversion "k8s.io/component-base/version"
 
+	"github.com/kcp-dev/kcp/pkg/flowcontrol"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework"
 	virtualcontext "github.com/kcp-dev/kcp/pkg/virtual/framework/context"
 )
@@ -45,6 +46,9 @@ type RootAPIExtraConfig struct {
 	informerStart func(stopCh <-chan struct{})
 
 	VirtualWorkspaces []framework.VirtualWorkspace
+
+	// WorkspaceFairness, if set, queues requests to the virtual workspaces by logical cluster.
+	WorkspaceFairness *flowcontrol.Controller
 }
 
 // Validate helps ensure that we build this config correctly, because there are lots of bits to remember for now
@@ -159,6 +163,11 @@ func (c completedConfig) resolveRootPaths(urlPath string, requestContext context
 
 func (c completedConfig) getRootHandlerChain(delegateAPIServer genericapiserver.DelegationTarget) func(http.Handler, *genericapiserver.Config) http.Handler {
 	return func(apiHandler http.Handler, genericConfig *genericapiserver.Config) http.Handler {
+		var delegatedHandler http.Handler
+		if unprotected := delegateAPIServer.UnprotectedHandler(); unprotected != nil {
+			delegatedHandler = flowcontrol.WithPriorityAndFairness(unprotected, c.ExtraConfig.WorkspaceFairness, genericConfig.LongRunningFunc)
+		}
+
 		return genericapiserver.DefaultBuildHandlerChain(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			// detect old kubectl plugins and inject warning headers
 			if req.UserAgent() == "Go-http-client/2.0" {
@@ -171,7 +180,6 @@ func (c completedConfig) getRootHandlerChain(delegateAPIServer genericapiserver.
 				req.URL.Path = strings.TrimPrefix(req.URL.Path, prefixToStrip)
 				req.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefixToStrip)
 				req = req.WithContext(context)
-				delegatedHandler := delegateAPIServer.UnprotectedHandler()
 				if delegatedHandler != nil {
 					delegatedHandler.ServeHTTP(w, req)
 				}
@@ -178,6 +178,9 @@ type AuthorizationCache struct {
 
 	skip      skipSynchronizer
 	lastState string
+	// lastSyncWorkspaceResourceVersion is the resource version of the workspace informer
+	// the last synchronization was started with.
+	lastSyncWorkspaceResourceVersion string
 
 	reviewTemplate authorizer.AttributesRecord
 	reviewer       *Reviewer
@@ -351,9 +354,13 @@ func (ac *AuthorizationCache) synchronize() {
 	ac.rwMutex.Lock()
 	defer ac.rwMutex.Unlock()
 
+	// the listers are at least as fresh as this resource version when synchronizing below
+	workspaceResourceVersion := ac.lastSyncResourceVersioner.LastSyncResourceVersion()
+
 	// if none of our internal reflectors changed, then we can skip reviewing the cache
 	skip, currentState := ac.skip.SkipSynchronize(ac.lastState, ac.lastSyncResourceVersioner, ac.roleLastSyncResourceVersioner)
 	if skip {
+		ac.lastSyncWorkspaceResourceVersion = workspaceResourceVersion
 		return
 	}
 
@@ -384,6 +391,7 @@ func (ac *AuthorizationCache) synchronize() {
 
 	// we were able to update our cache since this last observation period
 	ac.lastState = currentState
+	ac.lastSyncWorkspaceResourceVersion = workspaceResourceVersion
 }
 
 // syncRequest takes a reviewRequest and determines if it should update the caches supplied, it is not thread-safe
@@ -506,6 +514,15 @@ func (ac *AuthorizationCache) List(userInfo user.Info, labelSelector labels.Sele
 	return workspaceList, nil
 }
 
+// LastSyncResourceVersion returns the ClusterWorkspace resource version the cache reflects at least,
+// or an empty string if it has not been synchronized yet.
+func (ac *AuthorizationCache) LastSyncResourceVersion() string {
+	ac.rwMutex.RLock()
+	defer ac.rwMutex.RUnlock()
+
+	return ac.lastSyncWorkspaceResourceVersion
+}
+
 func (ac *AuthorizationCache) ReadyForAccess() bool {
 	ac.rwMutex.RLock()
 	defer ac.rwMutex.RUnlock()
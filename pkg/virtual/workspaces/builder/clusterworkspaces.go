@@ -89,6 +89,10 @@ func (o *authCacheClusterWorkspaces) AddWatcher(watcher workspaceauth.CacheWatch
 	o.authCache.AddWatcher(watcher)
 }
 
+func (o *authCacheClusterWorkspaces) LastSyncResourceVersion() string {
+	return o.authCache.LastSyncResourceVersion()
+}
+
 func (o *authCacheClusterWorkspaces) Ready() bool {
 	return o.authCache.ReadyForAccess()
 }
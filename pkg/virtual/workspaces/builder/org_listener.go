@@ -214,6 +214,15 @@ func (cws *preCreationClusterWorkspaces) List(user user.Info, labelSelector labe
 	return &tenancyv1alpha1.ClusterWorkspaceList{}, nil
 }
 
+func (cws *preCreationClusterWorkspaces) LastSyncResourceVersion() string {
+	cws.lock.RLock()
+	defer cws.lock.RUnlock()
+	if cws.delegate != nil {
+		return cws.delegate.LastSyncResourceVersion()
+	}
+	return ""
+}
+
 func (cws *preCreationClusterWorkspaces) RemoveWatcher(watcher authorization.CacheWatcher) {
 	// fast path
 	cws.lock.RLock()
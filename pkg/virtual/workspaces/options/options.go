@@ -17,7 +17,9 @@ limitations under the License.
 package options
 
 import (
+	"fmt"
 	"path"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -25,6 +27,7 @@ import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	kcpinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework"
@@ -32,7 +35,11 @@ import (
 	"github.com/kcp-dev/kcp/pkg/virtual/workspaces/builder"
 )
 
-type Workspaces struct{}
+type Workspaces struct {
+	// DeletionRetention is the time deleted workspaces are kept in the Deleting phase before
+	// they are purged. Zero means workspaces are deleted immediately.
+	DeletionRetention time.Duration
+}
 
 func NewWorkspaces() *Workspaces {
 	return &Workspaces{}
@@ -42,6 +49,12 @@ func (o *Workspaces) AddFlags(flags *pflag.FlagSet, prefix string) {
 	if o == nil {
 		return
 	}
+
+	flags.DurationVar(&o.DeletionRetention, prefix+"workspaces-deletion-retention", o.DeletionRetention, ""+
+		"The time deleted workspaces are kept in the Deleting phase, inaccessible but intact, before their content "+
+		"is purged. During that time they can be restored by removing the "+tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey+
+		" annotation from the ClusterWorkspace. On kcp, ClusterWorkspaces with content can then only be deleted after "+
+		"their retention period. Zero means workspaces are deleted immediately.")
 }
 
 func (o *Workspaces) Validate(flagPrefix string) []error {
@@ -50,6 +63,10 @@ func (o *Workspaces) Validate(flagPrefix string) []error {
 	}
 	errs := []error{}
 
+	if o.DeletionRetention < 0 {
+		errs = append(errs, fmt.Errorf("--%sworkspaces-deletion-retention must not be negative", flagPrefix))
+	}
+
 	return errs
 }
 
@@ -62,7 +79,7 @@ func (o *Workspaces) NewVirtualWorkspaces(
 	wildcardKcpInformers kcpinformer.SharedInformerFactory,
 ) (extraInformers []rootapiserver.InformerStart, workspaces []framework.VirtualWorkspace, err error) {
 	virtualWorkspaces := []framework.VirtualWorkspace{
-		builder.BuildVirtualWorkspace(path.Join(rootPathPrefix, o.Name()), wildcardKcpInformers.Tenancy().V1alpha1().ClusterWorkspaces(), wildcardKubeInformers.Rbac().V1(), kubeClusterClient, kcpClusterClient),
+		builder.BuildVirtualWorkspace(path.Join(rootPathPrefix, o.Name()), wildcardKcpInformers.Tenancy().V1alpha1().ClusterWorkspaces(), wildcardKubeInformers.Rbac().V1(), kubeClusterClient, kcpClusterClient, o.DeletionRetention),
 	}
 	return nil, virtualWorkspaces, nil
 }
@@ -18,8 +18,11 @@ package registry
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kcp-dev/logicalcluster"
 
@@ -30,13 +33,16 @@ import (
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/authentication/user"
 	kuser "k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	apirequest "k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/apiserver/pkg/storage"
 	rbacinformers "k8s.io/client-go/informers/rbac/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -72,6 +78,9 @@ type FilteredClusterWorkspaces interface {
 	workspaceauth.Lister
 	workspaceauth.WatchableCache
 	AddWatcher(watcher workspaceauth.CacheWatcher)
+	// LastSyncResourceVersion returns the ClusterWorkspace resource version the filtered
+	// workspaces reflect at least, or an empty string if unknown yet.
+	LastSyncResourceVersion() string
 	Stop()
 }
 
@@ -100,6 +109,9 @@ type REST struct {
 	// delegatedAuthz implements cluster-aware SubjectAccessReview
 	delegatedAuthz delegated.DelegatedAuthorizerFactory
 
+	// deletionRetention is the time deleted workspaces are kept soft-deleted before they are purged.
+	deletionRetention time.Duration
+
 	createStrategy rest.RESTCreateStrategy
 	updateStrategy rest.RESTUpdateStrategy
 	rest.TableConvertor
@@ -143,6 +155,7 @@ func NewREST(
 	clusterWorkspaceCache *workspacecache.ClusterWorkspaceCache,
 	wilcardsCRBInformer rbacinformers.ClusterRoleBindingInformer,
 	getFilteredClusterWorkspaces func(orgClusterName logicalcluster.Name) FilteredClusterWorkspaces,
+	deletionRetention time.Duration,
 ) *REST {
 	mainRest := &REST{
 		getFilteredClusterWorkspaces: getFilteredClusterWorkspaces,
@@ -155,6 +168,8 @@ func NewREST(
 
 		clusterWorkspaceCache: clusterWorkspaceCache,
 
+		deletionRetention: deletionRetention,
+
 		createStrategy: Strategy,
 		updateStrategy: Strategy,
 
@@ -276,17 +291,48 @@ func (s *REST) List(ctx context.Context, options *metainternal.ListOptions) (run
 	usePersonalScope := shouldUsePersonalScope(ctx.Value(WorkspacesScopeKey).(string), orgClusterName)
 	clusterWorkspaceList := &tenancyv1alpha1.ClusterWorkspaceList{}
 	if clusterWorkspaces := s.getFilteredClusterWorkspaces(orgClusterName); clusterWorkspaces != nil {
-		// TODO:
-		// The workspaceLister is informer driven, so it's important to note that the lister can be stale.
-		// It breaks the API guarantees of lists.
-		// To make it correct we have to know the latest RV of the org workspace shard,
-		// and then wait for freshness relative to that RV of the lister.
 		labelSelector, fieldSelector := InternalListOptionsToSelectors(options)
+
+		// The workspaceLister is informer driven, so it can be stale. In order to keep the API
+		// guarantees of lists, wait for it to catch up with the requested resource version, or
+		// by default with the current ClusterWorkspaces of the org.
+		resourceVersion := ""
+		if options != nil {
+			resourceVersion = options.ResourceVersion
+		}
+		switch resourceVersion {
+		case "0":
+			// any resource version is fine
+		case "":
+			// The resource version of a quorum list is that of the whole storage, which the
+			// informer only reaches with the next ClusterWorkspace event. Wait for the latest
+			// change of the ClusterWorkspaces of the org instead. Workspaces without the
+			// ClusterWorkspace API have none to wait for.
+			latest, err := s.kcpClusterClient.Cluster(orgClusterName).TenancyV1alpha1().ClusterWorkspaces().List(ctx, metav1.ListOptions{})
+			if err != nil && !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+			if err == nil {
+				if err := waitForResourceVersion(ctx, clusterWorkspaces, maxResourceVersion(latest.Items)); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+			if err != nil {
+				return nil, kerrors.NewBadRequest(fmt.Sprintf("invalid resource version %q", resourceVersion))
+			}
+			if err := waitForResourceVersion(ctx, clusterWorkspaces, rv); err != nil {
+				return nil, err
+			}
+		}
+
 		var err error
 		clusterWorkspaceList, err = clusterWorkspaces.List(withoutGroupsWhenPersonal(userInfo, usePersonalScope), labelSelector, fieldSelector)
 		if err != nil {
 			return nil, err
 		}
+		clusterWorkspaceList.ResourceVersion = clusterWorkspaces.LastSyncResourceVersion()
 	}
 
 	if usePersonalScope {
@@ -333,6 +379,44 @@ func (s *REST) Watch(ctx context.Context, options *metainternal.ListOptions) (wa
 	return watcher, nil
 }
 
+// defaultResourceVersionWaitTimeout bounds waiting for freshness of the authorization cache
+// for requests without deadline.
+const defaultResourceVersionWaitTimeout = 3 * time.Second
+
+// waitForResourceVersion waits until the filtered workspaces reflect at least the given resource
+// version, bounded by the request deadline.
+func waitForResourceVersion(ctx context.Context, clusterWorkspaces FilteredClusterWorkspaces, resourceVersion uint64) error {
+	if resourceVersion == 0 {
+		return nil
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultResourceVersionWaitTimeout)
+		defer cancel()
+	}
+
+	var current uint64
+	if err := wait.PollImmediateUntil(50*time.Millisecond, func() (bool, error) {
+		current, _ = strconv.ParseUint(clusterWorkspaces.LastSyncResourceVersion(), 10, 64)
+		return current >= resourceVersion, nil
+	}, ctx.Done()); err != nil {
+		return storage.NewTooLargeResourceVersionError(resourceVersion, current, 1)
+	}
+	return nil
+}
+
+// maxResourceVersion returns the highest resource version of the given workspaces.
+func maxResourceVersion(workspaces []tenancyv1alpha1.ClusterWorkspace) uint64 {
+	var max uint64
+	for _, ws := range workspaces {
+		if rv, err := strconv.ParseUint(ws.ResourceVersion, 10, 64); err == nil && rv > max {
+			max = rv
+		}
+	}
+	return max
+}
+
 var _ = rest.Getter(&REST{})
 
 // Get retrieves a Workspace by name
@@ -381,6 +465,9 @@ func (s *REST) getClusterWorkspace(ctx context.Context, name string, options *me
 	if err != nil {
 		return nil, err
 	}
+	if err := waitForResourceVersion(ctx, clusterWorkspaces, maxResourceVersion([]tenancyv1alpha1.ClusterWorkspace{*workspace})); err != nil {
+		return nil, err
+	}
 
 	// TODO:
 	// Filtering by applying the lister operation might not be necessary anymore
@@ -708,6 +795,11 @@ func (s *REST) Delete(ctx context.Context, name string, deleteValidation rest.Va
 		}
 	}
 
+	if s.deletionRetention > 0 {
+		// soft-delete, keeping the RBAC bindings such that the workspace can be restored
+		return nil, false, s.softDelete(ctx, orgClusterName, name, internalName)
+	}
+
 	errorToReturn := s.kcpClusterClient.Cluster(orgClusterName).TenancyV1alpha1().ClusterWorkspaces().Delete(ctx, internalName, *options)
 	if err != nil && !kerrors.IsNotFound(errorToReturn) {
 		return nil, false, err
@@ -727,6 +819,38 @@ func (s *REST) Delete(ctx context.Context, name string, deleteValidation rest.Va
 	return nil, false, errorToReturn
 }
 
+// softDelete marks the ClusterWorkspace as deleted by setting the retained-until annotation. Workspaces
+// which are not ready yet have no content worth retaining and are deleted right away.
+func (s *REST) softDelete(ctx context.Context, orgClusterName logicalcluster.Name, name, internalName string) error {
+	cws, err := s.kcpClusterClient.Cluster(orgClusterName).TenancyV1alpha1().ClusterWorkspaces().Get(ctx, internalName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return kerrors.NewNotFound(tenancyv1beta1.Resource("workspaces"), name)
+	} else if err != nil {
+		return err
+	}
+	if _, found := cws.Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey]; found {
+		return nil
+	}
+	if cws.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+		return s.kcpClusterClient.Cluster(orgClusterName).TenancyV1alpha1().ClusterWorkspaces().Delete(ctx, internalName, metav1.DeleteOptions{})
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey: time.Now().Add(s.deletionRetention).UTC().Format(time.RFC3339),
+			},
+			"resourceVersion": cws.ResourceVersion,
+		},
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = s.kcpClusterClient.Cluster(orgClusterName).TenancyV1alpha1().ClusterWorkspaces().Patch(ctx, internalName, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
+
 var decisions = map[authorizer.Decision]string{
 	authorizer.DecisionAllow:     "allowed",
 	authorizer.DecisionDeny:      "denied",
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kcp-dev/logicalcluster"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +31,7 @@ import (
 
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metainternal "k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -39,6 +41,7 @@ import (
 	kuser "k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	apistorage "k8s.io/apiserver/pkg/storage"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
@@ -55,8 +58,9 @@ import (
 
 // mockLister returns the workspaces in the list
 type mockLister struct {
-	checkedUsers []kuser.Info
-	workspaces   []tenancyv1alpha1.ClusterWorkspace
+	checkedUsers    []kuser.Info
+	workspaces      []tenancyv1alpha1.ClusterWorkspace
+	resourceVersion string
 }
 
 func (m *mockLister) CheckedUsers() []kuser.Info {
@@ -81,6 +85,7 @@ type TestData struct {
 	reviewer               *workspaceauth.Reviewer
 	rootReviewer           *workspaceauth.Reviewer
 	orgName                logicalcluster.Name
+	deletionRetention      time.Duration
 }
 
 type TestDescription struct {
@@ -208,6 +213,7 @@ func applyTest(t *testing.T, test TestDescription) {
 		kubeClusterClient:     mockKubeClusterClient(func(logicalcluster.Name) kubernetes.Interface { return mockKubeClient }),
 		kcpClusterClient:      mockKcpClusterClient(func(logicalcluster.Name) kcpclientset.Interface { return mockKCPClient }),
 		clusterWorkspaceCache: nil,
+		deletionRetention:     test.deletionRetention,
 		delegatedAuthz: func(clusterName logicalcluster.Name, client kubernetes.ClusterInterface) (authorizer.Authorizer, error) {
 			if clusterName == tenancyv1alpha1.RootCluster {
 				return test.rootReviewer, nil
@@ -638,6 +644,130 @@ func TestListOrganizationWorkspaces(t *testing.T) {
 	applyTest(t, test)
 }
 
+func TestListOrganizationWorkspacesWithResourceVersion(t *testing.T) {
+	user := &kuser.DefaultInfo{
+		Name:   "test-user",
+		UID:    "test-uid",
+		Groups: []string{"test-group"},
+	}
+	rootReviewer := workspaceauth.NewReviewer(&mockSubjectLocator{
+		subjects: map[string]map[string][]rbacv1.Subject{
+			"access/tenancy.kcp.dev/v1alpha1/clusterworkspaces/content": {
+				"orgName": rbacGroups("test-group"),
+			},
+		},
+	})
+	foo := tenancyv1alpha1.ClusterWorkspace{ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "root:orgName", ResourceVersion: "5"}}
+	bar := tenancyv1alpha1.ClusterWorkspace{ObjectMeta: metav1.ObjectMeta{Name: "bar", ClusterName: "root:orgName", ResourceVersion: "3"}}
+
+	tests := []struct {
+		name                  string
+		listerResourceVersion string
+		serverResourceVersion string
+		serverNotFound        bool
+		resourceVersion       string
+		wantTooLarge          bool
+		wantNames             []string
+		wantResourceVersion   string
+	}{
+		{
+			name:                  "latest, lister caught up",
+			listerResourceVersion: "5",
+			serverResourceVersion: "5",
+			wantNames:             []string{"bar", "foo"},
+			wantResourceVersion:   "5",
+		},
+		{
+			name:                  "latest, lister stale",
+			listerResourceVersion: "4",
+			serverResourceVersion: "5",
+			wantTooLarge:          true,
+		},
+		{
+			name:                  "latest, no ClusterWorkspace API",
+			listerResourceVersion: "4",
+			serverNotFound:        true,
+			wantNames:             []string{"bar", "foo"},
+			wantResourceVersion:   "4",
+		},
+		{
+			name:                  "any, lister stale",
+			listerResourceVersion: "4",
+			serverResourceVersion: "5",
+			resourceVersion:       "0",
+			wantNames:             []string{"bar", "foo"},
+			wantResourceVersion:   "4",
+		},
+		{
+			name:                  "not older than, lister caught up",
+			listerResourceVersion: "7",
+			serverResourceVersion: "7",
+			resourceVersion:       "6",
+			wantNames:             []string{"bar", "foo"},
+			wantResourceVersion:   "7",
+		},
+		{
+			name:                  "not older than, lister stale",
+			listerResourceVersion: "7",
+			serverResourceVersion: "7",
+			resourceVersion:       "8",
+			wantTooLarge:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := TestDescription{
+				TestData: TestData{
+					user:              user,
+					scope:             OrganizationScope,
+					orgName:           logicalcluster.New("root:orgName"),
+					reviewer:          workspaceauth.NewReviewer(nil),
+					rootReviewer:      rootReviewer,
+					clusterWorkspaces: []tenancyv1alpha1.ClusterWorkspace{foo},
+					workspaceLister: &mockLister{
+						workspaces:      []tenancyv1alpha1.ClusterWorkspace{bar, foo},
+						resourceVersion: tt.listerResourceVersion,
+					},
+				},
+				apply: func(t *testing.T, storage *REST, ctx context.Context, kubeClient *fake.Clientset, kcpClient *tenancyv1fake.Clientset, listerCheckedUsers func() []kuser.Info, testData TestData) {
+					ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+					defer cancel()
+
+					// The latest ClusterWorkspace of the server has serverResourceVersion, while the
+					// storage as a whole is ahead of it.
+					kcpClient.PrependReactor("list", "clusterworkspaces", func(action clienttesting.Action) (bool, runtime.Object, error) {
+						if tt.serverNotFound {
+							return true, nil, errors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspaces"), "")
+						}
+						return true, &tenancyv1alpha1.ClusterWorkspaceList{
+							ListMeta: metav1.ListMeta{ResourceVersion: "100"},
+							Items: []tenancyv1alpha1.ClusterWorkspace{
+								{ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "root:orgName", ResourceVersion: tt.serverResourceVersion}},
+							},
+						}, nil
+					})
+
+					response, err := storage.List(ctx, &metainternal.ListOptions{ResourceVersion: tt.resourceVersion})
+					if tt.wantTooLarge {
+						require.Error(t, err)
+						require.True(t, apistorage.IsTooLargeResourceVersion(err), "expected too large resource version error, got %v", err)
+						return
+					}
+					require.NoError(t, err)
+					workspaces := response.(*tenancyv1beta1.WorkspaceList)
+					var names []string
+					for _, ws := range workspaces.Items {
+						names = append(names, ws.Name)
+					}
+					assert.Equal(t, tt.wantNames, names)
+					assert.Equal(t, tt.wantResourceVersion, workspaces.ResourceVersion)
+				},
+			}
+			applyTest(t, test)
+		})
+	}
+}
+
 func TestListOrganizationWorkspacesWithPrettyName(t *testing.T) {
 	user := &kuser.DefaultInfo{
 		Name:   "test-user",
@@ -1851,6 +1981,97 @@ func TestDeletePersonalWorkspace(t *testing.T) {
 	applyTest(t, test)
 }
 
+func TestSoftDeletePersonalWorkspace(t *testing.T) {
+	user := &kuser.DefaultInfo{
+		Name:   "test-user",
+		UID:    "test-uid",
+		Groups: []string{"test-group"},
+	}
+	test := TestDescription{
+		TestData: TestData{
+			user:              user,
+			scope:             PersonalScope,
+			orgName:           logicalcluster.New("root:orgName"),
+			deletionRetention: time.Hour,
+			reviewer: workspaceauth.NewReviewer(&mockSubjectLocator{
+				subjects: map[string]map[string][]rbacv1.Subject{
+					"delete/tenancy.kcp.dev/v1alpha1/clusterworkspaces/workspace": {
+						"foo": rbacUsers("test-user"),
+					},
+				},
+			}),
+			rootReviewer: workspaceauth.NewReviewer(&mockSubjectLocator{
+				subjects: map[string]map[string][]rbacv1.Subject{
+					"access/tenancy.kcp.dev/v1alpha1/clusterworkspaces/content": {
+						"orgName": rbacGroups("test-group"),
+					},
+				},
+			}),
+			clusterWorkspaces: []tenancyv1alpha1.ClusterWorkspace{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "root:orgName"},
+					Status:     tenancyv1alpha1.ClusterWorkspaceStatus{Phase: tenancyv1alpha1.ClusterWorkspacePhaseReady},
+				},
+			},
+			clusterRoleBindings: []rbacv1.ClusterRoleBinding{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        getRoleBindingName(OwnerRoleType, "foo", user),
+						ClusterName: "root:orgName",
+						Labels: map[string]string{
+							PrettyNameLabel:   "foo",
+							InternalNameLabel: "foo",
+						},
+					},
+					Subjects: []rbacv1.Subject{
+						{
+							Kind: "User",
+							Name: user.Name,
+						},
+					},
+				},
+			},
+			clusterRoles: []rbacv1.ClusterRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        getRoleBindingName(OwnerRoleType, "foo", user),
+						ClusterName: "root:orgName",
+						Labels: map[string]string{
+							InternalNameLabel: "foo",
+						},
+					},
+					Rules: []rbacv1.PolicyRule{
+						{
+							Verbs:         []string{"get", "delete"},
+							ResourceNames: []string{"foo"},
+							Resources:     []string{"clusterworkspaces/workspace"},
+							APIGroups:     []string{"tenancy.kcp.dev"},
+						},
+					},
+				},
+			},
+		},
+		apply: func(t *testing.T, storage *REST, ctx context.Context, kubeClient *fake.Clientset, kcpClient *tenancyv1fake.Clientset, listerCheckedUsers func() []kuser.Info, testData TestData) {
+			response, deletedNow, err := storage.Delete(ctx, "foo", nil, &metav1.DeleteOptions{})
+			assert.NoError(t, err)
+			assert.Nil(t, response)
+			assert.False(t, deletedNow)
+			crbList, err := kubeClient.Tracker().List(rbacv1.SchemeGroupVersion.WithResource("clusterrolebindings"), rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), "")
+			require.NoError(t, err)
+			crbs := crbList.(*rbacv1.ClusterRoleBindingList)
+			assert.Len(t, crbs.Items, 1, "expected owner binding to be kept for restore")
+			workspaceList, err := kcpClient.Tracker().List(tenancyv1alpha1.SchemeGroupVersion.WithResource("clusterworkspaces"), tenancyv1alpha1.SchemeGroupVersion.WithKind("ClusterWorkspace"), "")
+			require.NoError(t, err)
+			wsList := workspaceList.(*tenancyv1alpha1.ClusterWorkspaceList)
+			require.Len(t, wsList.Items, 1)
+			retainedUntil, err := time.Parse(time.RFC3339, wsList.Items[0].Annotations[tenancyv1alpha1.ClusterWorkspaceRetainedUntilAnnotationKey])
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), retainedUntil, time.Minute)
+		},
+	}
+	applyTest(t, test)
+}
+
 func TestDeleteWorkspaceByOrgAdmin(t *testing.T) {
 	user := &kuser.DefaultInfo{
 		Name:   "test-user",
@@ -2045,6 +2266,10 @@ func (c clusterWorkspaces) List(user kuser.Info, labelSelector labels.Selector,
 	return c.clusterWorkspaceLister.List(user, labelSelector, fieldSelector)
 }
 
+func (c clusterWorkspaces) LastSyncResourceVersion() string {
+	return c.clusterWorkspaceLister.resourceVersion
+}
+
 func (c clusterWorkspaces) RemoveWatcher(watcher workspaceauth.CacheWatcher) {
 }
 
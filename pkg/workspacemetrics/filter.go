@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacemetrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/endpoints/responsewriter"
+	"k8s.io/klog/v2"
+)
+
+// AttributesFunc returns the logical cluster and the request info of a request. The request
+// info is nil for requests that are not API requests.
+type AttributesFunc func(req *http.Request) (logicalcluster.Name, *request.RequestInfo)
+
+// Recorder records requests in the workspace metrics and the access log.
+type Recorder struct {
+	labeler   *workspaceLabeler
+	accessLog bool
+}
+
+// NewRecorder returns a recorder aggregating logical clusters to the given depth, with at most
+// maxWorkspaces workspace label values. Depth 0 keeps the full logical cluster names.
+func NewRecorder(depth, maxWorkspaces int, accessLog bool) *Recorder {
+	return &Recorder{
+		labeler:   newWorkspaceLabeler(depth, maxWorkspaces),
+		accessLog: accessLog,
+	}
+}
+
+// WithRequestMetrics records the requests passing through the handler. It must be placed
+// after authentication, such that the user is known for the access log. Long-running
+// requests count as inflight and are counted when they finish, but have no latency. If
+// the recorder is nil, the handler is returned unchanged.
+func WithRequestMetrics(handler http.Handler, r *Recorder, attributes AttributesFunc, longRunningRequestCheck request.LongRunningRequestCheck) http.Handler {
+	if r == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		clusterName, requestInfo := attributes(req)
+		workspace := r.labeler.label(clusterName)
+		verb := strings.ToLower(req.Method)
+		if requestInfo != nil && requestInfo.IsResourceRequest {
+			verb = requestInfo.Verb
+		}
+		longRunning := requestInfo != nil && longRunningRequestCheck != nil && longRunningRequestCheck(req, requestInfo)
+
+		inflight := r.labeler.startRequest(workspace)
+		defer inflight.Dec()
+
+		start := time.Now()
+		delegate := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(responsewriter.WrapForHTTP1Or2(delegate), req)
+		elapsed := time.Since(start)
+
+		code := delegate.code()
+		r.labeler.finishRequest(workspace, verb, code, elapsed, longRunning)
+
+		if r.accessLog {
+			logRequest(req, clusterName, requestInfo, verb, code, elapsed)
+		}
+	})
+}
+
+// logRequest writes the access log entry of a request.
+func logRequest(req *http.Request, clusterName logicalcluster.Name, requestInfo *request.RequestInfo, verb string, code int, elapsed time.Duration) {
+	var userName string
+	if u, ok := request.UserFrom(req.Context()); ok {
+		userName = u.GetName()
+	}
+	keysAndValues := []interface{}{
+		"user", userName,
+		"cluster", clusterName.String(),
+		"verb", verb,
+	}
+	if requestInfo != nil && requestInfo.IsResourceRequest {
+		keysAndValues = append(keysAndValues,
+			"apiGroup", requestInfo.APIGroup,
+			"resource", requestInfo.Resource,
+			"subresource", requestInfo.Subresource,
+			"namespace", requestInfo.Namespace,
+			"name", requestInfo.Name,
+		)
+	} else {
+		keysAndValues = append(keysAndValues, "path", req.URL.Path)
+	}
+	keysAndValues = append(keysAndValues,
+		"code", code,
+		"latency", elapsed,
+		"userAgent", req.UserAgent(),
+	)
+	klog.InfoS("Request", keysAndValues...)
+}
+
+// statusRecorder records the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+var _ responsewriter.UserProvidedDecorator = &statusRecorder{}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// code returns the status code of the response. Responses without a written status,
+// e.g. of hijacked connections, count as 200.
+func (r *statusRecorder) code() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
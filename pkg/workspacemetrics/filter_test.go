@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacemetrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestWorkspaceLabeler(t *testing.T) {
+	l := newWorkspaceLabeler(2, 2)
+
+	require.Equal(t, NoWorkspace, l.label(logicalcluster.Name{}))
+	require.Equal(t, "*", l.label(logicalcluster.Wildcard))
+	require.Equal(t, "root", l.label(logicalcluster.New("root")))
+	require.Equal(t, "root:org", l.label(logicalcluster.New("root:org:team:project")), "aggregated to depth 2")
+	require.Equal(t, OtherWorkspaces, l.label(logicalcluster.New("root:other-org")), "beyond the maximum")
+	require.Equal(t, "root:org", l.label(logicalcluster.New("root:org:other-team")), "known label values are kept")
+
+	l = newWorkspaceLabeler(0, 10)
+	require.Equal(t, "root:org:team", l.label(logicalcluster.New("root:org:team")), "not aggregated with depth 0")
+
+	l = newWorkspaceLabeler(2, 0)
+	require.Equal(t, OtherWorkspaces, l.label(logicalcluster.New("root:org")), "no label values")
+}
+
+func TestWorkspaceLabelerEviction(t *testing.T) {
+	l := newWorkspaceLabeler(2, 2)
+	busy, quiet, rising := logicalcluster.New("root:test-busy"), logicalcluster.New("root:test-quiet"), logicalcluster.New("root:test-rising")
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "root:test-busy", l.label(busy))
+	}
+	require.Equal(t, "root:test-quiet", l.label(quiet))
+	l.finishRequest("root:test-quiet", "get", 200, time.Millisecond, false)
+
+	require.Equal(t, OtherWorkspaces, l.label(rising), "not more requests than the label value with the fewest")
+	require.Equal(t, "root:test-rising", l.label(rising), "more requests than the label value with the fewest")
+	require.False(t, requestCounter.Delete(map[string]string{"workspace": "root:test-quiet", "verb": "get", "code": "200"}), "series of replaced label values are deleted")
+	require.False(t, requestLatencies.Delete(map[string]string{"workspace": "root:test-quiet", "verb": "get"}), "series of replaced label values are deleted")
+
+	l.finishRequest("root:test-quiet", "get", 200, time.Millisecond, false)
+	other, err := testutil.GetCounterMetricValue(requestCounter.WithLabelValues(OtherWorkspaces, "get", "200"))
+	require.NoError(t, err)
+	require.Equal(t, 1.0, other, "requests in flight of replaced label values are recorded as other")
+
+	require.Equal(t, OtherWorkspaces, l.label(quiet), "replaced label values keep their count as candidates")
+	require.Equal(t, "root:test-busy", l.label(busy))
+
+	for i := 0; i < 3; i++ {
+		l.label(logicalcluster.New(fmt.Sprintf("root:test-one-off-%d", i)))
+	}
+	require.Len(t, l.candidates, 2, "candidates are bounded")
+	require.Len(t, l.labels, 2)
+}
+
+func TestWithRequestMetrics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing" {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	attributes := func(req *http.Request) (logicalcluster.Name, *request.RequestInfo) {
+		return logicalcluster.New("root:test-metrics"), &request.RequestInfo{IsResourceRequest: true, Verb: "list"}
+	}
+	h := WithRequestMetrics(handler, NewRecorder(2, 10, false), attributes, nil)
+
+	for _, path := range []string{"/ok", "/ok", "/missing"} {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	ok, err := testutil.GetCounterMetricValue(requestCounter.WithLabelValues("root:test-metrics", "list", "200"))
+	require.NoError(t, err)
+	require.Equal(t, 2.0, ok)
+	notFound, err := testutil.GetCounterMetricValue(requestCounter.WithLabelValues("root:test-metrics", "list", "404"))
+	require.NoError(t, err)
+	require.Equal(t, 1.0, notFound)
+	observed, err := testutil.GetHistogramMetricCount(requestLatencies.WithLabelValues("root:test-metrics", "list"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), observed)
+	inflight, err := testutil.GetGaugeMetricValue(inflightRequests.WithLabelValues("root:test-metrics"))
+	require.NoError(t, err)
+	require.Equal(t, 0.0, inflight)
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspacemetrics records the requests of logical clusters, as metrics with a
+// workspace label of bounded cardinality and optionally in a structured access log.
+package workspacemetrics
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	// OtherWorkspaces is the workspace label of the workspaces beyond the maximum.
+	OtherWorkspaces = "other"
+	// NoWorkspace is the workspace label of requests without a logical cluster.
+	NoWorkspace = "none"
+)
+
+var (
+	requestCounter = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Name:           "kcp_workspace_requests_total",
+			Help:           "Number of requests by workspace, verb and HTTP response code.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"workspace", "verb", "code"},
+	)
+	requestLatencies = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "kcp_workspace_request_duration_seconds",
+			Help:           "Response latency distribution in seconds by workspace and verb, excluding long-running requests.",
+			Buckets:        []float64{0.005, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"workspace", "verb"},
+	)
+	inflightRequests = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "kcp_workspace_inflight_requests",
+			Help:           "Number of requests currently being served by workspace, including long-running requests.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"workspace"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(requestCounter)
+	legacyregistry.MustRegister(requestLatencies)
+	legacyregistry.MustRegister(inflightRequests)
+}
+
+// workspaceLabeler maps logical clusters to workspace label values. Logical clusters are
+// aggregated to their ancestor at depth, e.g. root:org:team to root:org at depth 2.
+//
+// At most max label values are in use, for the workspaces with the most requests. The
+// requests of up to max further workspaces are counted as candidates; once a candidate has
+// more requests than the label value with the fewest, it takes its place. The series of the
+// replaced label value are deleted and its requests are recorded as "other" from then on. A
+// workspace that is not counted replaces the candidate with the fewest requests, starting
+// from its count, such that the counts of the candidates are upper bounds.
+type workspaceLabeler struct {
+	depth int
+	max   int
+
+	lock       sync.Mutex
+	labels     map[string]*labelSeries
+	candidates map[string]int64
+}
+
+// labelSeries are the request count and the metric series of a label value in use.
+type labelSeries struct {
+	requests int64
+	// counters are the verb and code label values of the request counter.
+	counters map[[2]string]bool
+	// verbs are the verb label values of the latency histogram.
+	verbs sets.String
+}
+
+func newWorkspaceLabeler(depth, max int) *workspaceLabeler {
+	return &workspaceLabeler{
+		depth:      depth,
+		max:        max,
+		labels:     map[string]*labelSeries{},
+		candidates: map[string]int64{},
+	}
+}
+
+// label counts a request of the given logical cluster and returns its label value.
+func (l *workspaceLabeler) label(clusterName logicalcluster.Name) string {
+	switch {
+	case clusterName.Empty():
+		return NoWorkspace
+	case clusterName == logicalcluster.Wildcard:
+		return logicalcluster.Wildcard.String()
+	}
+
+	name := clusterName.String()
+	if parts := strings.Split(name, ":"); l.depth > 0 && len(parts) > l.depth {
+		name = strings.Join(parts[:l.depth], ":")
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if series, found := l.labels[name]; found {
+		series.requests++
+		return name
+	}
+	if len(l.labels) < l.max {
+		l.labels[name] = &labelSeries{requests: 1, counters: map[[2]string]bool{}, verbs: sets.NewString()}
+		return name
+	}
+	if l.max == 0 {
+		return OtherWorkspaces
+	}
+
+	requests, found := l.candidates[name]
+	if !found && len(l.candidates) >= l.max {
+		fewest, fewestRequests := minRequests(l.candidates)
+		delete(l.candidates, fewest)
+		requests = fewestRequests
+	}
+	requests++
+	l.candidates[name] = requests
+
+	replaced := l.fewestRequestsLocked()
+	if requests <= l.labels[replaced].requests {
+		return OtherWorkspaces
+	}
+	l.deleteSeriesLocked(replaced)
+	l.candidates[replaced] = l.labels[replaced].requests
+	delete(l.labels, replaced)
+	delete(l.candidates, name)
+	l.labels[name] = &labelSeries{requests: requests, counters: map[[2]string]bool{}, verbs: sets.NewString()}
+	return name
+}
+
+// fewestRequestsLocked returns the label value in use with the fewest requests, the
+// smallest on ties. The lock must be held.
+func (l *workspaceLabeler) fewestRequestsLocked() string {
+	var fewest string
+	var fewestRequests int64
+	for name, series := range l.labels {
+		if fewest == "" || series.requests < fewestRequests || (series.requests == fewestRequests && name < fewest) {
+			fewest, fewestRequests = name, series.requests
+		}
+	}
+	return fewest
+}
+
+// minRequests returns the name with the fewest requests, the smallest on ties.
+func minRequests(requests map[string]int64) (string, int64) {
+	var fewest string
+	var fewestRequests int64
+	for name, n := range requests {
+		if fewest == "" || n < fewestRequests || (n == fewestRequests && name < fewest) {
+			fewest, fewestRequests = name, n
+		}
+	}
+	return fewest, fewestRequests
+}
+
+// deleteSeriesLocked deletes the metric series of a label value in use. The lock must be held.
+func (l *workspaceLabeler) deleteSeriesLocked(workspace string) {
+	series := l.labels[workspace]
+	for verbAndCode := range series.counters {
+		requestCounter.Delete(map[string]string{"workspace": workspace, "verb": verbAndCode[0], "code": verbAndCode[1]})
+	}
+	for _, verb := range series.verbs.List() {
+		requestLatencies.Delete(map[string]string{"workspace": workspace, "verb": verb})
+	}
+	inflightRequests.Delete(map[string]string{"workspace": workspace})
+}
+
+// currentLocked returns the label value to record a request of the given label value with,
+// which is "other" if the label value was replaced meanwhile. The lock must be held.
+func (l *workspaceLabeler) currentLocked(workspace string) (string, *labelSeries) {
+	switch workspace {
+	case NoWorkspace, OtherWorkspaces, logicalcluster.Wildcard.String():
+		return workspace, nil
+	}
+	if series, found := l.labels[workspace]; found {
+		return workspace, series
+	}
+	return OtherWorkspaces, nil
+}
+
+// startRequest increments the inflight requests of the label value and returns the gauge
+// to decrement when the request finishes.
+func (l *workspaceLabeler) startRequest(workspace string) metrics.GaugeMetric {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	workspace, _ = l.currentLocked(workspace)
+	inflight := inflightRequests.WithLabelValues(workspace)
+	inflight.Inc()
+	return inflight
+}
+
+// finishRequest records a finished request of the label value. The latency of long-running
+// requests is not observed.
+func (l *workspaceLabeler) finishRequest(workspace, verb string, code int, elapsed time.Duration, longRunning bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	workspace, series := l.currentLocked(workspace)
+	codeValue := strconv.Itoa(code)
+	requestCounter.WithLabelValues(workspace, verb, codeValue).Inc()
+	if series != nil {
+		series.counters[[2]string{verb, codeValue}] = true
+	}
+	if longRunning {
+		return
+	}
+	requestLatencies.WithLabelValues(workspace, verb).Observe(elapsed.Seconds())
+	if series != nil {
+		series.verbs.Insert(verb)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/kcp-dev/kcp/pkg/workspacemetrics"
+)
+
+type WorkspaceMetrics struct {
+	Depth         int
+	MaxWorkspaces int
+	AccessLog     bool
+}
+
+func NewWorkspaceMetrics() *WorkspaceMetrics {
+	return &WorkspaceMetrics{
+		// root:<org>
+		Depth:         2,
+		MaxWorkspaces: 100,
+	}
+}
+
+func (o *WorkspaceMetrics) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.Depth, "workspace-metrics-depth", o.Depth, "Depth to which logical clusters are aggregated in the workspace label of request metrics, e.g. 2 for root:<org>. 0 keeps the full logical cluster names.")
+	fs.IntVar(&o.MaxWorkspaces, "workspace-metrics-max-workspaces", o.MaxWorkspaces, "Maximum number of workspace label values in request metrics. Workspaces beyond are counted as \"other\".")
+	fs.BoolVar(&o.AccessLog, "access-log", o.AccessLog, "Log every request with user, logical cluster, verb, resource and response code.")
+}
+
+func (o *WorkspaceMetrics) Validate() []error {
+	var errs []error
+
+	if o.Depth < 0 {
+		errs = append(errs, fmt.Errorf("--workspace-metrics-depth must not be negative"))
+	}
+	if o.MaxWorkspaces < 0 {
+		errs = append(errs, fmt.Errorf("--workspace-metrics-max-workspaces must not be negative"))
+	}
+
+	return errs
+}
+
+// NewRecorder returns the recorder of workspace request metrics and access logs.
+func (o *WorkspaceMetrics) NewRecorder() *workspacemetrics.Recorder {
+	return workspacemetrics.NewRecorder(o.Depth, o.MaxWorkspaces, o.AccessLog)
+}